@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// artworkPolicy selects the artwork of a program type: the largest image of each aspect,
+// of the first category in the priority list which has an image of the aspect
+type artworkPolicy struct {
+	Aspects    []string `yaml:"Aspects" json:"aspects"`
+	Categories []string `yaml:"Categories" json:"categories"`
+}
+
+// defaultArtworkPolicies are the artwork policies of new configurations:
+// movies prefer posters, episodes stills and series banners
+func defaultArtworkPolicies() map[string]artworkPolicy {
+	return map[string]artworkPolicy{
+		"movie":   {Aspects: []string{"2x3"}, Categories: []string{"Poster Art", "Box Art", "VOD Art"}},
+		"episode": {Aspects: []string{"16x9"}, Categories: []string{"Iconic", "Scene Still", "Banner-L1"}},
+		"series":  {Aspects: []string{"16x9"}, Categories: []string{"Banner-L1", "Banner", "Banner-L2", "Iconic"}},
+		"sports":  {Aspects: []string{"16x9"}, Categories: []string{"Iconic", "Banner-L1", "Banner"}},
+	}
+}
+
+// programType returns the program type of an artwork policy by the prefix of a program ID.
+// The series root of an episode ID, e.g. EP01234567, has the artwork of the series.
+func programType(id string) string {
+	switch {
+	case strings.HasPrefix(id, "MV"):
+		return "movie"
+	case episodeArtworkID(id):
+		return "episode"
+	case strings.HasPrefix(id, "EP"), strings.HasPrefix(id, "SH"):
+		return "series"
+	case strings.HasPrefix(id, "SP"):
+		return "sports"
+	}
+	return ""
+}
+
+// selectArtwork returns the images of the policy, one per aspect which has an image of a category of the policy
+func selectArtwork(data []Data, policy artworkPolicy) (selected []Data) {
+	for _, aspect := range policy.Aspects {
+		for _, category := range policy.Categories {
+			var best Data
+			var bestWidth int
+			for _, image := range data {
+				if image.Aspect != aspect || image.Category != category {
+					continue
+				}
+				if width, err := strconv.Atoi(image.Width); err == nil && width > bestWidth {
+					best, bestWidth = image, width
+				}
+			}
+
+			if bestWidth > 0 {
+				selected = append(selected, best)
+				break
+			}
+		}
+	}
+	return
+}
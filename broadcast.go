@@ -0,0 +1,52 @@
+package main
+
+import "strings"
+
+// broadcastFlags are the XMLTV flags of a programme which the broadcast flags option selects
+var broadcastFlags = []string{"new", "live", "premiere", "previously-shown"}
+
+// defaultBroadcastFlags are the flags of new configurations: live broadcasts are live,
+// new broadcasts and premieres are new, all other broadcasts are previously shown
+func defaultBroadcastFlags() map[string][]string {
+	return map[string][]string{
+		"live":      {"live", "previously-shown"},
+		"live-new":  {"new", "live", "premiere"},
+		"tape":      {"previously-shown"},
+		"tape-new":  {"new", "premiere"},
+		"delay":     {"previously-shown"},
+		"delay-new": {"new", "premiere"},
+		"other":     {"previously-shown"},
+		"other-new": {"new", "premiere"},
+	}
+}
+
+// broadcastKind returns the key of the broadcast flags option: the LiveTapeDelay of the schedule, live, tape, delay or other,
+// with the suffix -new for new broadcasts. A premiere is the first broadcast even without the new flag.
+func broadcastKind(schedule G2GCache) string {
+	kind := strings.ToLower(schedule.LiveTapeDelay)
+	switch kind {
+	case "live", "tape", "delay":
+	default:
+		kind = "other"
+	}
+
+	if schedule.New || schedule.Premiere || strings.HasSuffix(schedule.PremiereFinale, "Premiere") {
+		kind += "-new"
+	}
+	return kind
+}
+
+// selectBroadcastFlags returns the flags of a broadcast, broadcasts missing in the option have the default flags
+func selectBroadcastFlags(schedule G2GCache, option map[string][]string) map[string]bool {
+	kind := broadcastKind(schedule)
+	flags, ok := option[kind]
+	if !ok {
+		flags = defaultBroadcastFlags()[kind]
+	}
+
+	selected := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		selected[flag] = true
+	}
+	return selected
+}
@@ -2,26 +2,45 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
 )
 
 const (
-	defaultCacheExpiration = 24 * time.Hour
-	maxCacheSize           = 100 * 1024 * 1024 // 100MB
+	maxCacheSize = 100 * 1024 * 1024 // 100MB of programs and metadata, least recently used entries are evicted
+
+	// Default time to live of the cached entries by data type.
+	// Schedules change daily, programs rarely and artwork almost never.
+	defaultScheduleTTL = 24 * time.Hour
+	defaultProgramTTL  = 7 * 24 * time.Hour
+	defaultMetadataTTL = 30 * 24 * time.Hour
 )
 
+// ErrCacheCorrupt is returned for a cache file which is truncated or no valid JSON
+var ErrCacheCorrupt = errors.New("cache file is corrupted")
+
 // Cache represents the global cache instance
 var ImageError bool = false
 
@@ -31,8 +50,6 @@ var bufferPool = sync.Pool{
 	},
 }
 
-var httpClient = &http.Client{}
-
 // Data struct for metadata (restored from struct_sd.go)
 type Data struct {
 	Aspect   string `json:"aspect"`
@@ -46,27 +63,14 @@ type Data struct {
 
 // SDProgram struct for program data (restored from struct_sd.go)
 type SDProgram struct {
-	Cast []struct {
-		BillingOrder  string `json:"billingOrder"`
-		CharacterName string `json:"characterName"`
-		Name          string `json:"name"`
-		NameID        string `json:"nameId"`
-		PersonID      string `json:"personId"`
-		Role          string `json:"role"`
-	} `json:"cast"`
-	ContentAdvisory []string `json:"contentAdvisory"`
+	Cast            []CastMember `json:"cast"`
+	ContentAdvisory []string     `json:"contentAdvisory"`
 	ContentRating   []struct {
 		Body    string `json:"body"`
 		Code    string `json:"code"`
 		Country string `json:"country"`
 	} `json:"contentRating"`
-	Crew []struct {
-		BillingOrder string `json:"billingOrder"`
-		Name         string `json:"name"`
-		NameID       string `json:"nameId"`
-		PersonID     string `json:"personId"`
-		Role         string `json:"role"`
-	} `json:"crew"`
+	Crew         []CrewMember `json:"crew"`
 	Descriptions struct {
 		Description1000 []struct {
 			Description         string `json:"description"`
@@ -77,28 +81,78 @@ type SDProgram struct {
 			Description         string `json:"description"`
 		} `json:"description100"`
 	} `json:"descriptions"`
-	EntityType        string   `json:"entityType"`
-	EpisodeTitle150   string   `json:"episodeTitle150"`
-	Genres            []string `json:"genres"`
-	HasEpisodeArtwork bool     `json:"hasEpisodeArtwork"`
-	HasImageArtwork   bool     `json:"hasImageArtwork"`
-	HasSeriesArtwork  bool     `json:"hasSeriesArtwork"`
-	Md5               string   `json:"md5"`
+	EntityType        string              `json:"entityType"`
+	EpisodeTitle150   string              `json:"episodeTitle150"`
+	EventDetails      *SDEventDetails     `json:"eventDetails"`
+	Genres            []string            `json:"genres"`
+	HasEpisodeArtwork bool                `json:"hasEpisodeArtwork"`
+	HasImageArtwork   bool                `json:"hasImageArtwork"`
+	HasSeriesArtwork  bool                `json:"hasSeriesArtwork"`
+	KeyWords          map[string][]string `json:"keyWords"`
+	Md5               string              `json:"md5"`
 	Metadata          []struct {
 		Gracenote struct {
 			Episode int `json:"episode"`
 			Season  int `json:"season"`
 		} `json:"Gracenote"`
 	} `json:"metadata"`
-	OriginalAirDate string `json:"originalAirDate"`
-	ProgramID       string `json:"programID"`
-	ResourceID      string `json:"resourceID"`
-	ShowType        string `json:"showType"`
+	Movie           *SDMovie `json:"movie"`
+	OriginalAirDate string   `json:"originalAirDate"`
+	ProgramID       string   `json:"programID"`
+	ResourceID      string   `json:"resourceID"`
+	ShowType        string   `json:"showType"`
 	Titles          []struct {
 		Title120 string `json:"title120"`
 	} `json:"titles"`
 }
 
+// SDMovie struct for the movie details of a program
+type SDMovie struct {
+	Year          string          `json:"year,omitempty"`
+	Duration      int             `json:"duration,omitempty"`
+	QualityRating []QualityRating `json:"qualityRating,omitempty"`
+}
+
+// SDEventDetails struct for the teams and venue of a sports event
+type SDEventDetails struct {
+	Venue100 string `json:"venue100,omitempty"`
+	Teams    []struct {
+		Name   string `json:"name"`
+		IsHome bool   `json:"isHome,omitempty"`
+	} `json:"teams,omitempty"`
+	GameDate string `json:"gameDate,omitempty"`
+}
+
+// matchup returns the teams of the event as "Away at Home", or joined by "vs." without a home team
+func (e *SDEventDetails) matchup() string {
+	var home string
+	var away []string
+	for _, team := range e.Teams {
+		if team.IsHome && len(home) == 0 {
+			home = team.Name
+		} else {
+			away = append(away, team.Name)
+		}
+	}
+
+	if len(home) == 0 {
+		return strings.Join(away, " vs. ")
+	}
+	if len(away) == 0 {
+		return home
+	}
+	return strings.Join(away, " vs. ") + " at " + home
+}
+
+// QualityRating is the rating of a movie by a ratings body, e.g. 3 of 4 stars by Gracenote
+type QualityRating struct {
+	RatingsBody string `json:"ratingsBody"`
+	Rating      string `json:"rating"`
+	MinRating   string `json:"minRating"`
+	MaxRating   string `json:"maxRating"`
+	Increment   string `json:"increment"`
+}
+
 // SDMetadata struct for metadata (restored from struct_sd.go)
 type SDMetadata struct {
 	Data      []Data `json:"data"`
@@ -122,14 +176,18 @@ type SDError struct {
 // This struct is used for caching channel, program, metadata, and schedule data.
 type G2GCache struct {
 	// Global
-	Md5       string `json:"md5,omitempty"`
-	ProgramID string `json:"programID,omitempty"`
+	Md5       string    `json:"md5,omitempty"` // MD5 of Schedules Direct, of the artwork list for metadata
+	ProgramID string    `json:"programID,omitempty"`
+	Cached    time.Time `json:"cached,omitempty"` // Download time of schedules, programs and metadata
+	Used      time.Time `json:"used,omitempty"`   // Last download of a schedule with the program, for the LRU eviction
+	Size      int       `json:"size,omitempty"`   // Approximate size of programs and metadata as JSON
 
 	// Channel
 	StationID         string   `json:"stationID,omitempty"`
 	Name              string   `json:"name,omitempty"`
 	Callsign          string   `json:"callsign,omitempty"`
 	Affiliate         string   `json:"affiliate,omitempty"`
+	ChannelNumber     string   `json:"channelNumber,omitempty"` // Channel number of the lineup, major.minor for antenna lineups
 	BroadcastLanguage []string `json:"broadcastLanguage"`
 	StationLogo       []struct {
 		URL    string `json:"URL"`
@@ -151,6 +209,8 @@ type G2GCache struct {
 	Duration        int       `json:"duration,omitempty"`
 	LiveTapeDelay   string    `json:"liveTapeDelay,omitempty"`
 	New             bool      `json:"new,omitempty"`
+	Premiere        bool      `json:"premiere,omitempty"`
+	PremiereFinale  string    `json:"isPremiereOrFinale,omitempty"` // Series Premiere, Season Premiere, Series Finale or Season Finale
 	Ratings         []struct {
 		Body string `json:"body"`
 		Code string `json:"code"`
@@ -158,27 +218,15 @@ type G2GCache struct {
 	VideoProperties []string `json:"videoProperties,omitempty"`
 
 	// Program
-	Cast []struct {
-		BillingOrder  string `json:"billingOrder"`
-		CharacterName string `json:"characterName"`
-		Name          string `json:"name"`
-		NameID        string `json:"nameId"`
-		PersonID      string `json:"personId"`
-		Role          string `json:"role"`
-	} `json:"cast"`
-	Crew []struct {
-		BillingOrder string `json:"billingOrder"`
-		Name         string `json:"name"`
-		NameID       string `json:"nameId"`
-		PersonID     string `json:"personId"`
-		Role         string `json:"role"`
-	} `json:"crew"`
+	Cast          []CastMember `json:"cast"`
+	Crew          []CrewMember `json:"crew"`
 	ContentRating []struct {
 		Body    string `json:"body"`
 		Code    string `json:"code"`
 		Country string `json:"country"`
 	} `json:"contentRating"`
-	Descriptions struct {
+	ContentAdvisory []string `json:"contentAdvisory,omitempty"` // e.g. Violence or Adult Language
+	Descriptions    struct {
 		Description1000 []struct {
 			Description         string `json:"description"`
 			DescriptionLanguage string `json:"descriptionLanguage"`
@@ -189,11 +237,13 @@ type G2GCache struct {
 		} `json:"description100"`
 	} `json:"descriptions"`
 
-	EpisodeTitle150   string   `json:"episodeTitle150,omitempty"`
-	Genres            []string `json:"genres,omitempty"`
-	HasEpisodeArtwork bool     `json:"hasEpisodeArtwork,omitempty"`
-	HasImageArtwork   bool     `json:"hasImageArtwork,omitempty"`
-	HasSeriesArtwork  bool     `json:"hasSeriesArtwork,omitempty"`
+	EpisodeTitle150   string              `json:"episodeTitle150,omitempty"`
+	EventDetails      *SDEventDetails     `json:"eventDetails,omitempty"`
+	Genres            []string            `json:"genres,omitempty"`
+	HasEpisodeArtwork bool                `json:"hasEpisodeArtwork,omitempty"`
+	HasImageArtwork   bool                `json:"hasImageArtwork,omitempty"`
+	HasSeriesArtwork  bool                `json:"hasSeriesArtwork,omitempty"`
+	KeyWords          map[string][]string `json:"keyWords,omitempty"` // Keywords by group, e.g. Mood, Theme or Time Period
 
 	Metadata []struct {
 		Gracenote struct {
@@ -202,9 +252,10 @@ type G2GCache struct {
 		} `json:"Gracenote"`
 	} `json:"metadata,omitempty"`
 
-	OriginalAirDate string `json:"originalAirDate,omitempty"`
-	ResourceID      string `json:"resourceID,omitempty"`
-	ShowType        string `json:"showType,omitempty"`
+	Movie           *SDMovie `json:"movie,omitempty"`
+	OriginalAirDate string   `json:"originalAirDate,omitempty"`
+	ResourceID      string   `json:"resourceID,omitempty"`
+	ShowType        string   `json:"showType,omitempty"`
 	Titles          []struct {
 		Title120 string `json:"title120"`
 	} `json:"titles"`
@@ -215,19 +266,83 @@ type G2GCache struct {
 
 // cache represents the application's cache system
 type cache struct {
+	Version  int                   `json:"Version"` // Schema version of the cache file
 	Channel  map[string]G2GCache   `json:"Channel"`
 	Program  map[string]G2GCache   `json:"Program"`
 	Metadata map[string]G2GCache   `json:"Metadata"`
 	Schedule map[string][]G2GCache `json:"Schedule"`
+	Lineup   map[string]string     `json:"Lineup"` // Lineup ID -> modified timestamp of the last download
+	Person   map[string]Person     `json:"Person"` // Person ID -> cast or crew member of the programs
+
+	stats cacheStats
+
+	// Every data type has its own lock, so downloads of different types are added in parallel.
+	// Operations on the whole cache take all locks with Lock or RLock, always in the order of the fields.
+	channelMu  sync.RWMutex // Channel and Lineup
+	scheduleMu sync.RWMutex // Schedule
+	programMu  sync.RWMutex // Program and Person
+	metadataMu sync.RWMutex // Metadata
+}
+
+// Lock locks all data types of the cache for writing
+func (c *cache) Lock() {
+	c.channelMu.Lock()
+	c.scheduleMu.Lock()
+	c.programMu.Lock()
+	c.metadataMu.Lock()
+}
+
+// Unlock unlocks all data types of the cache
+func (c *cache) Unlock() {
+	c.metadataMu.Unlock()
+	c.programMu.Unlock()
+	c.scheduleMu.Unlock()
+	c.channelMu.Unlock()
+}
+
+// RLock locks all data types of the cache for reading
+func (c *cache) RLock() {
+	c.channelMu.RLock()
+	c.scheduleMu.RLock()
+	c.programMu.RLock()
+	c.metadataMu.RLock()
+}
+
+// RUnlock undoes RLock
+func (c *cache) RUnlock() {
+	c.metadataMu.RUnlock()
+	c.programMu.RUnlock()
+	c.scheduleMu.RUnlock()
+	c.channelMu.RUnlock()
+}
+
+// cacheStats counts the lookups of programs and metadata and the size of the added data since the cache was created.
+// All backends count their lookups, it is safe for concurrent use.
+type cacheStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+	size   atomic.Int64
+}
 
-	stats struct {
-		Hits   int64 `json:"hits"`
-		Misses int64 `json:"misses"`
-		Size   int64 `json:"size"`
+// lookup counts a lookup which found an entry or missed it
+func (s *cacheStats) lookup(found bool) {
+	if found {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
 	}
+}
+
+// added counts the size of downloaded data added to the cache
+func (s *cacheStats) added(data *[]byte) {
+	s.size.Add(int64(len(*data)))
+}
 
-	expiration time.Time `json:"expiration"`
-	sync.RWMutex
+// report adds the counters to the statistics of GetStats
+func (s *cacheStats) report(stats map[string]interface{}) {
+	stats["hits"] = s.hits.Load()
+	stats["misses"] = s.misses.Load()
+	stats["size"] = s.size.Load()
 }
 
 // CacheStore defines the interface for cache operations
@@ -237,19 +352,105 @@ type CacheStore interface {
 	Save(app *App) error
 	Init()
 	CleanUp(app *App)
+	GetChannels(app *App) []G2GCache
+	GetSchedule(stationID string, app *App) []G2GCache
 	GetTitle(id, lang string, app *App) []Title
 	GetSubTitle(id, lang string, app *App) SubTitle
 	GetDescs(id, subTitle string, app *App) []Desc
 	GetCredits(id string, app *App) Credits
 	GetCategory(id string, app *App) []Category
+	GetKeywords(id string, app *App) []Keyword
+	GetContentAdvisory(id string, app *App) []string
 	GetEpisodeNum(id string, app *App) []EpisodeNum
 	GetIcon(id string, app *App) []Icon
 	GetRating(id, countryCode string, app *App) []Rating
 	GetPreviouslyShown(id string, app *App) *PreviouslyShown
+	GetStarRating(id string, app *App) []StarRating
 	AddStations(ctx context.Context, data *[]byte, lineup string, app *App) error
+	LineupUnchanged(lineup, modified string, app *App) bool
+	SetLineupModified(lineup, modified string)
+	RemoveChannels(app *App)
 	AddSchedule(ctx context.Context, data *[]byte, app *App) error
-	AddProgram(ctx context.Context, gzip *[]byte, wg *sync.WaitGroup, app *App) error
-	AddMetadata(ctx context.Context, gzip *[]byte, wg *sync.WaitGroup, app *App) error
+	AddProgram(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error
+	GetAllProgramIDs() []string
+	GetRequiredProgramIDs() []string
+	MissingPrograms(ids []string) []string
+	UseSeriesProgram(episodeID, seriesID string) bool
+	AddMetadata(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error
+	GetRequiredMetaIDs() []string
+	GetStats() map[string]interface{}
+	Inspect(id string, app *App) map[string]interface{}
+	Export(app *App) (*cache, error)
+	Import(c *cache, app *App) error
+}
+
+// cacheBackend returns the configured cache backend, without one the extension of the cache file decides
+func cacheBackend(c config) string {
+	if len(c.Options.CacheBackend) != 0 {
+		return c.Options.CacheBackend
+	}
+
+	if isRedisURL(c.Files.Cache) {
+		return "redis"
+	}
+
+	switch filepath.Ext(c.Files.Cache) {
+	case ".db", ".sqlite", ".sqlite3":
+		return "sqlite"
+	case ".bolt", ".bbolt":
+		return "bolt"
+	}
+
+	return "json"
+}
+
+// selectCacheStore replaces the cache of the app if the configuration selects another backend
+func (app *App) selectCacheStore() {
+	backend := cacheBackend(app.Config)
+
+	switch app.Cache.(type) {
+	case *cache:
+		if backend == "json" {
+			return
+		}
+	case *sqliteCache:
+		if backend == "sqlite" {
+			return
+		}
+	case *boltCache:
+		if backend == "bolt" {
+			return
+		}
+	case *redisCache:
+		if backend == "redis" {
+			return
+		}
+	default:
+		// Stores set by the caller, e.g. a mock
+		return
+	}
+
+	if closer, ok := app.Cache.(io.Closer); ok {
+		closer.Close()
+	}
+
+	switch backend {
+	case "sqlite":
+		app.Cache = &sqliteCache{}
+	case "bolt":
+		app.Cache = &boltCache{}
+	case "redis":
+		app.Cache = &redisCache{}
+	default:
+		app.Cache = &cache{}
+	}
+}
+
+// newCache returns an initialized in-memory cache
+func newCache() *cache {
+	c := &cache{}
+	c.Init()
+	return c
 }
 
 // Init initializes the cache with default values
@@ -257,6 +458,11 @@ func (c *cache) Init() {
 	c.Lock()
 	defer c.Unlock()
 
+	c.initMaps()
+}
+
+// initMaps creates the missing maps, the caller holds the lock
+func (c *cache) initMaps() {
 	if c.Schedule == nil {
 		c.Schedule = make(map[string][]G2GCache)
 	}
@@ -269,8 +475,15 @@ func (c *cache) Init() {
 	if c.Metadata == nil {
 		c.Metadata = make(map[string]G2GCache)
 	}
-
-	c.expiration = time.Now().Add(defaultCacheExpiration)
+	if c.Lineup == nil {
+		c.Lineup = make(map[string]string)
+	}
+	if c.Person == nil {
+		c.Person = make(map[string]Person)
+	}
+	if c.Version == 0 {
+		c.Version = cacheSchemaVersion
+	}
 }
 
 // Remove removes the cache file and reinitializes the cache
@@ -287,14 +500,14 @@ func (c *cache) Remove(app *App) error {
 		return errors.Wrap(err, "failed to remove cache file")
 	}
 
-	c.Init()
+	c.initMaps()
 	return nil
 }
 
 // AddStations adds station data to the cache
 func (c *cache) AddStations(ctx context.Context, data *[]byte, lineup string, app *App) error {
-	c.Lock()
-	defer c.Unlock()
+	c.channelMu.Lock()
+	defer c.channelMu.Unlock()
 
 	var g2gCache G2GCache
 	var sdData SDStation
@@ -302,9 +515,11 @@ func (c *cache) AddStations(ctx context.Context, data *[]byte, lineup string, ap
 	if err := json.Unmarshal(*data, &sdData); err != nil {
 		return errors.Wrap(err, "failed to unmarshal station data")
 	}
+	c.stats.added(data)
 
 	channelIDs := app.Config.GetChannelList(lineup)
 	added := 0
+	channelMap := schedulesdirect.LineupMap{Map: sdData.Map}
 
 	for _, sd := range sdData.Stations {
 		if ContainsString(channelIDs, sd.StationID) != -1 {
@@ -313,6 +528,7 @@ func (c *cache) AddStations(ctx context.Context, data *[]byte, lineup string, ap
 				Name:              sd.Name,
 				Callsign:          sd.Callsign,
 				Affiliate:         sd.Affiliate,
+				ChannelNumber:     channelMap.Channel(sd.StationID),
 				BroadcastLanguage: sd.BroadcastLanguage,
 				Logo:              sd.Logo,
 			}
@@ -330,22 +546,68 @@ func (c *cache) AddStations(ctx context.Context, data *[]byte, lineup string, ap
 	return nil
 }
 
+// LineupUnchanged reports whether the station map of a lineup has not been modified since the last download
+// and all configured channels of the lineup are cached
+func (c *cache) LineupUnchanged(lineup, modified string, app *App) bool {
+	c.channelMu.RLock()
+	defer c.channelMu.RUnlock()
+
+	if len(modified) == 0 || c.Lineup[lineup] != modified {
+		return false
+	}
+
+	for _, id := range app.Config.GetChannelList(lineup) {
+		if _, ok := c.Channel[id]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SetLineupModified stores the modified timestamp of a downloaded lineup
+func (c *cache) SetLineupModified(lineup, modified string) {
+	c.channelMu.Lock()
+	defer c.channelMu.Unlock()
+
+	c.Lineup[lineup] = modified
+}
+
+// RemoveChannels removes channels from the cache which are no longer configured
+func (c *cache) RemoveChannels(app *App) {
+	c.channelMu.Lock()
+	defer c.channelMu.Unlock()
+
+	channelIDs := app.Config.GetChannelList("")
+	for id := range c.Channel {
+		if ContainsString(channelIDs, id) == -1 {
+			delete(c.Channel, id)
+		}
+	}
+}
+
 // AddSchedule adds schedule data to the cache
 func (c *cache) AddSchedule(ctx context.Context, data *[]byte, app *App) error {
-	c.Lock()
-	defer c.Unlock()
-
 	var g2gCache G2GCache
 	var sdData []SDSchedule
 
 	if err := json.Unmarshal(*data, &sdData); err != nil {
 		return errors.Wrap(err, "failed to unmarshal schedule data")
 	}
+	c.stats.added(data)
 
+	now := time.Now()
 	added := 0
+	used := make(map[string]bool)
+
+	c.scheduleMu.Lock()
 	for _, sd := range sdData {
-		if _, ok := c.Schedule[sd.StationID]; !ok {
-			c.Schedule[sd.StationID] = []G2GCache{}
+		schedule := c.Schedule[sd.StationID]
+
+		// A downloaded broadcast replaces the cached broadcast of the same time
+		index := make(map[int64]int, len(schedule))
+		for i, broadcast := range schedule {
+			index[broadcast.AirDateTime.Unix()] = i
 		}
 
 		for _, p := range sd.Programs {
@@ -355,110 +617,423 @@ func (c *cache) AddSchedule(ctx context.Context, data *[]byte, app *App) error {
 				Duration:        p.Duration,
 				LiveTapeDelay:   p.LiveTapeDelay,
 				New:             p.New,
+				Premiere:        p.Premiere,
+				PremiereFinale:  p.PremiereFinale,
 				Md5:             p.Md5,
 				ProgramID:       p.ProgramID,
 				Ratings:         p.Ratings,
 				VideoProperties: p.VideoProperties,
+				Cached:          now,
 			}
 
-			c.Schedule[sd.StationID] = append(c.Schedule[sd.StationID], g2gCache)
+			if i, ok := index[p.AirDateTime.Unix()]; ok {
+				schedule[i] = g2gCache
+			} else {
+				index[p.AirDateTime.Unix()] = len(schedule)
+				schedule = append(schedule, g2gCache)
+			}
+			used[p.ProgramID] = true
 			added++
 		}
+
+		c.Schedule[sd.StationID] = schedule
 	}
+	c.scheduleMu.Unlock()
+
+	// The programs and artwork of the schedule are used now
+	c.programMu.Lock()
+	c.metadataMu.Lock()
+	for programID := range used {
+		c.touch(programID, now)
+	}
+	c.metadataMu.Unlock()
+	c.programMu.Unlock()
 
 	app.Logger.WithField("added", added).Debug("Added schedule data to cache")
 	return nil
 }
 
-// AddProgram adds program data to the cache
-func (c *cache) AddProgram(ctx context.Context, gzip *[]byte, wg *sync.WaitGroup, app *App) error {
+// AddProgram adds program data to the cache.
+// The programs are decoded one at a time and the cache is only locked to insert them,
+// so a large batch neither holds all programs in memory twice nor blocks the other downloads.
+func (c *cache) AddProgram(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error {
 	defer wg.Done()
 
-	c.Lock()
-	defer c.Unlock()
-
-	b, err := gUnzip(*gzip)
-	if err != nil {
-		return errors.Wrap(err, "failed to decompress program data")
-	}
-
-	var g2gCache G2GCache
-	var sdData []SDProgram
+	now := time.Now()
+	added := 0
+	err := decodeArray(*data, func(dec *json.Decoder) error {
+		var sd SDProgram
+		if err := dec.Decode(&sd); err != nil {
+			return err
+		}
 
-	if err := json.Unmarshal(b, &sdData); err != nil {
-		return errors.Wrap(err, "failed to unmarshal program data")
-	}
+		// Programs which could not be downloaded are returned without titles
+		if len(sd.Titles) == 0 {
+			if app.Config.Options.SDDownloadErrors {
+				app.Logger.WithField("programID", sd.ProgramID).Error("SD API error: program not available")
+			}
+			return nil
+		}
 
-	added := 0
-	for _, sd := range sdData {
-		g2gCache = G2GCache{
+		g2gCache := G2GCache{
+			Md5:               sd.Md5,
+			ProgramID:         sd.ProgramID,
 			Descriptions:      sd.Descriptions,
 			EpisodeTitle150:   sd.EpisodeTitle150,
+			EventDetails:      sd.EventDetails,
 			Genres:            sd.Genres,
 			HasEpisodeArtwork: sd.HasEpisodeArtwork,
 			HasImageArtwork:   sd.HasImageArtwork,
 			HasSeriesArtwork:  sd.HasSeriesArtwork,
+			KeyWords:          sd.KeyWords,
 			Metadata:          sd.Metadata,
+			Movie:             sd.Movie,
 			OriginalAirDate:   sd.OriginalAirDate,
 			ResourceID:        sd.ResourceID,
 			ShowType:          sd.ShowType,
 			Titles:            sd.Titles,
 			ContentRating:     sd.ContentRating,
+			ContentAdvisory:   sd.ContentAdvisory,
 			Cast:              sd.Cast,
 			Crew:              sd.Crew,
+			Cached:            now,
+			Used:              now,
 		}
 
+		c.programMu.Lock()
+		g2gCache.splitPeople(c.Person)
+		g2gCache.Size = jsonSize(g2gCache)
 		c.Program[sd.ProgramID] = g2gCache
+		c.programMu.Unlock()
+
 		added++
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal program data")
 	}
+	c.stats.added(data)
 
 	app.Logger.WithField("added", added).Debug("Added program data to cache")
 	return nil
 }
 
-// AddMetadata adds metadata to the cache
-func (c *cache) AddMetadata(ctx context.Context, gzip *[]byte, wg *sync.WaitGroup, app *App) error {
-	defer wg.Done()
-
-	c.Lock()
-	defer c.Unlock()
+// decodeArray calls fn for every element of the JSON array in data, fn decodes the element with dec
+func decodeArray(data []byte, fn func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
 
-	b, err := gUnzip(*gzip)
+	token, err := dec.Token()
 	if err != nil {
-		return errors.Wrap(err, "failed to decompress metadata")
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return errors.Errorf("expected a JSON array, got %v", token)
 	}
 
-	var tmp = make([]interface{}, 0)
-	if err := json.Unmarshal(b, &tmp); err != nil {
-		return errors.Wrap(err, "failed to unmarshal metadata")
+	for dec.More() {
+		if err := fn(dec); err != nil {
+			return err
+		}
 	}
 
-	added := 0
-	for _, t := range tmp {
-		var sdData SDMetadata
-		jsonByte, _ := json.Marshal(t)
+	_, err = dec.Token()
+	return err
+}
+
+// GetAllProgramIDs returns the sorted IDs of the programs of the cached schedules
+func (c *cache) GetAllProgramIDs() []string {
+	return sortedKeys(c.scheduledPrograms())
+}
+
+// GetRequiredProgramIDs returns the programs of the cached schedules which are not cached or changed since
+func (c *cache) GetRequiredProgramIDs() []string {
+	return requiredProgramIDs(c)
+}
+
+// GetRequiredMetaIDs returns the root IDs of the scheduled programs with artwork which is not cached
+func (c *cache) GetRequiredMetaIDs() []string {
+	return requiredMetaIDs(c)
+}
+
+// scheduledPrograms returns the MD5 of Schedules Direct by program ID of the cached schedules
+func (c *cache) scheduledPrograms() map[string]string {
+	c.scheduleMu.RLock()
+	defer c.scheduleMu.RUnlock()
+
+	scheduled := make(map[string]string)
+	for _, schedule := range c.Schedule {
+		for _, broadcast := range schedule {
+			scheduled[broadcast.ProgramID] = broadcast.Md5
+		}
+	}
+	return scheduled
+}
+
+// programStates returns the state of the cached programs of ids
+func (c *cache) programStates(ids []string) map[string]programState {
+	c.programMu.RLock()
+	defer c.programMu.RUnlock()
+
+	states := make(map[string]programState)
+	for _, id := range ids {
+		if p, ok := c.Program[id]; ok {
+			states[id] = programState{
+				Md5:               p.Md5,
+				ProgramID:         p.ProgramID,
+				HasEpisodeArtwork: p.HasEpisodeArtwork,
+				HasImageArtwork:   p.HasImageArtwork,
+				HasSeriesArtwork:  p.HasSeriesArtwork,
+			}
+		}
+	}
+	return states
+}
+
+// cachedMetadata returns which of ids have cached artwork
+func (c *cache) cachedMetadata(ids []string) map[string]bool {
+	c.metadataMu.RLock()
+	defer c.metadataMu.RUnlock()
+
+	cached := make(map[string]bool)
+	for _, id := range ids {
+		_, cached[id] = c.Metadata[id]
+	}
+	return cached
+}
 
-		if err := json.Unmarshal(jsonByte, &sdData); err != nil {
+// programIndex is implemented by every cache backend, the update decides with it which programs and artwork are downloaded
+type programIndex interface {
+	scheduledPrograms() map[string]string
+	programStates(ids []string) map[string]programState
+	cachedMetadata(ids []string) map[string]bool
+}
+
+// programState is the part of a cached program which decides whether the program and its artwork are downloaded
+type programState struct {
+	Md5               string `json:"md5"`
+	ProgramID         string `json:"programID"`
+	HasEpisodeArtwork bool   `json:"hasEpisodeArtwork"`
+	HasImageArtwork   bool   `json:"hasImageArtwork"`
+	HasSeriesArtwork  bool   `json:"hasSeriesArtwork"`
+}
+
+// requiredProgramIDs returns the sorted programs of the cached schedules which are not cached or whose MD5 changed.
+// Programs without MD5, cached by an older version or the series program of an episode, are kept until the program TTL.
+func requiredProgramIDs(x programIndex) (required []string) {
+	scheduled := x.scheduledPrograms()
+	ids := sortedKeys(scheduled)
+	states := x.programStates(ids)
+
+	for _, id := range ids {
+		state, ok := states[id]
+		if !ok || (state.ProgramID == id && len(state.Md5) != 0 && len(scheduled[id]) != 0 && state.Md5 != scheduled[id]) {
+			required = append(required, id)
+		}
+	}
+	return
+}
+
+// requiredMetaIDs returns the sorted root IDs of the cached programs with artwork which is not cached.
+// Artwork is cached by the first 10 characters of the program ID, so the episodes of a series share one request.
+// Programs which could not be downloaded have no artwork flags, their artwork is not requested.
+func requiredMetaIDs(x programIndex) []string {
+	states := x.programStates(sortedKeys(x.scheduledPrograms()))
+
+	roots := make(map[string]string)
+	for id, state := range states {
+		if state.HasEpisodeArtwork || state.HasImageArtwork || state.HasSeriesArtwork {
+			roots[truncateID(id)] = id
+		}
+
+		// The artwork of an episode, e.g. stills, has the ID of the episode instead of the series root
+		if state.HasEpisodeArtwork && episodeArtworkID(id) {
+			roots[id] = id
+		}
+	}
+
+	ids := sortedKeys(roots)
+	cached := x.cachedMetadata(ids)
+
+	required := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !cached[id] {
+			required = append(required, id)
+		}
+	}
+	return required
+}
+
+// episodeArtworkID reports whether id is the full ID of an episode, which has its own artwork besides the series artwork
+func episodeArtworkID(id string) bool {
+	return len(id) == 14 && strings.HasPrefix(id, "EP")
+}
+
+// sortedKeys returns the sorted keys of m
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MissingPrograms returns the program IDs which are not in the cache
+func (c *cache) MissingPrograms(ids []string) (missing []string) {
+	c.programMu.RLock()
+	defer c.programMu.RUnlock()
+
+	for _, id := range ids {
+		if _, ok := c.Program[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	return
+}
+
+// UseSeriesProgram caches the generic series program for an episode which could not be downloaded
+func (c *cache) UseSeriesProgram(episodeID, seriesID string) bool {
+	c.programMu.Lock()
+	defer c.programMu.Unlock()
+
+	series, ok := c.Program[seriesID]
+	if !ok {
+		return false
+	}
+
+	c.Program[episodeID] = series
+	return true
+}
+
+// AddMetadata adds metadata to the cache, the entries are decoded one at a time like the programs of AddProgram.
+// Artwork which did not change since it was cached only gets a new download time, so it is kept for another metadata TTL.
+func (c *cache) AddMetadata(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error {
+	defer wg.Done()
+
+	now := time.Now()
+	added, unchanged := 0, 0
+	err := decodeArray(*data, func(dec *json.Decoder) error {
+		// An entry is either the artwork of a program or an error
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		var sdData SDMetadata
+		if err := json.Unmarshal(raw, &sdData); err != nil {
 			var sdError SDError
-			if err := json.Unmarshal(jsonByte, &sdError); err == nil && app.Config.Options.SDDownloadErrors {
+			if err := json.Unmarshal(raw, &sdError); err == nil && app.Config.Options.SDDownloadErrors {
 				app.Logger.WithFields(logrus.Fields{
 					"code":      sdError.Data.Code,
 					"message":   sdError.Data.Message,
 					"programID": sdError.ProgramID,
 				}).Error("SD API error")
 			}
-			continue
+			return nil
 		}
 
-		c.Metadata[sdData.ProgramID] = G2GCache{Data: sdData.Data}
-		added++
+		metadata := G2GCache{Data: sdData.Data, Md5: artworkMD5(sdData.Data), Cached: now, Used: now}
+		metadata.Size = jsonSize(metadata)
+
+		c.metadataMu.Lock()
+		if cached, ok := c.Metadata[sdData.ProgramID]; ok && cached.Md5 == metadata.Md5 {
+			cached.Cached, cached.Used = now, now
+			metadata = cached
+			unchanged++
+		} else {
+			added++
+		}
+		c.Metadata[sdData.ProgramID] = metadata
+		c.metadataMu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal metadata")
 	}
+	c.stats.added(data)
 
-	app.Logger.WithField("added", added).Debug("Added metadata to cache")
+	app.Logger.WithFields(logrus.Fields{
+		"added":     added,
+		"unchanged": unchanged,
+	}).Debug("Added metadata to cache")
 	return nil
 }
 
-// Open loads the cache from disk
+// artworkMD5 returns the MD5 of the artwork list of a program, Schedules Direct has no MD5 for metadata
+func artworkMD5(data []Data) string {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return ""
+	}
+	sum := md5.Sum(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Export returns a copy of all entries of the cache
+func (c *cache) Export(app *App) (*cache, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	export := newCache()
+	export.merge(c)
+	return export, nil
+}
+
+// Import adds all entries of src to the cache, entries with the same key are replaced
+func (c *cache) Import(src *cache, app *App) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.initMaps()
+	c.merge(src)
+	return nil
+}
+
+// merge copies the entries of src into the cache, the caller holds the lock.
+// A broadcast of src replaces the cached broadcast of the same time like a downloaded one.
+func (c *cache) merge(src *cache) {
+	for id, channel := range src.Channel {
+		c.Channel[id] = channel
+	}
+	for id, program := range src.Program {
+		c.Program[id] = program
+	}
+	for id, metadata := range src.Metadata {
+		c.Metadata[id] = metadata
+	}
+	for lineup, modified := range src.Lineup {
+		c.Lineup[lineup] = modified
+	}
+	for id, person := range src.Person {
+		c.Person[id] = person
+	}
+
+	for stationID, broadcasts := range src.Schedule {
+		schedule := append([]G2GCache(nil), c.Schedule[stationID]...)
+
+		index := make(map[int64]int, len(schedule))
+		for i, broadcast := range schedule {
+			index[broadcast.AirDateTime.Unix()] = i
+		}
+
+		for _, broadcast := range broadcasts {
+			if i, ok := index[broadcast.AirDateTime.Unix()]; ok {
+				schedule[i] = broadcast
+			} else {
+				index[broadcast.AirDateTime.Unix()] = len(schedule)
+				schedule = append(schedule, broadcast)
+			}
+		}
+
+		c.Schedule[stationID] = schedule
+	}
+}
+
+// Open loads the cache from disk, gzip compressed files are detected by their header.
+// The file is decoded while it is read, so it is never held in memory next to the cache.
+// A corrupted file is moved aside and the run continues with an empty cache.
 func (c *cache) Open(app *App) error {
 	c.Lock()
 	defer c.Unlock()
@@ -467,30 +1042,134 @@ func (c *cache) Open(app *App) error {
 		return errors.New("cache file path not configured")
 	}
 
-	data, err := os.ReadFile(app.Config.Files.Cache)
+	file, err := os.Open(app.Config.Files.Cache)
 	if err != nil {
 		if os.IsNotExist(err) {
-			c.Init()
+			c.initMaps()
 			return nil
 		}
-		return errors.Wrap(err, "failed to read cache file")
+		return errors.Wrap(err, "failed to open cache file")
 	}
 
-	if err := json.Unmarshal(data, c); err != nil {
-		return errors.Wrap(err, "failed to unmarshal cache data")
+	err = c.decode(file, app)
+	file.Close()
+	if errors.Is(err, ErrCacheCorrupt) {
+		err = c.recoverCorrupt(app, err)
 	}
+	if err != nil {
+		return err
+	}
+	c.initMaps()
 
-	// Check cache expiration
-	if time.Now().After(c.expiration) {
-		app.Logger.Info("Cache expired, reinitializing")
-		c.Init()
-		return nil
+	// Entries older than the TTL of their data type are downloaded again
+	if expired := c.removeExpired(app); expired != 0 {
+		app.Logger.WithField("expired", expired).Info("Removed expired entries from cache")
 	}
+	c.evict(app, maxCacheSize)
 
 	return nil
 }
 
-// Save persists the cache to disk
+// decode reads a cache file from r and migrates it to the current schema version, the caller holds the lock
+func (c *cache) decode(r io.Reader, app *App) error {
+	br := bufio.NewReader(r)
+	r = br
+	if header, _ := br.Peek(2); isGzip(header) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return errors.Wrap(err, "failed to decompress cache file")
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	// Files without a version are decoded with version 0
+	c.Version = 0
+	if err := json.NewDecoder(r).Decode(c); err != nil {
+		return errors.Wrapf(ErrCacheCorrupt, "failed to unmarshal cache data: %v", err)
+	}
+
+	return c.migrate(app)
+}
+
+// recoverCorrupt moves a corrupted cache file aside with the time as suffix and empties the cache, the caller holds the lock.
+// The file is kept to find out what corrupted it, the entries are downloaded again.
+func (c *cache) recoverCorrupt(app *App, cause error) error {
+	corrupt := fmt.Sprintf("%s.corrupt-%s", app.Config.Files.Cache, time.Now().Format("20060102-150405"))
+	if err := os.Rename(app.Config.Files.Cache, corrupt); err != nil {
+		return errors.Wrap(err, "failed to move corrupted cache file")
+	}
+
+	app.Logger.WithError(cause).WithField("file", corrupt).Warn("Cache file is corrupted, moved it aside and continuing with an empty cache")
+
+	// The decoder stops at the corruption, entries before it may be incomplete
+	c.Schedule = nil
+	c.Channel = nil
+	c.Program = nil
+	c.Metadata = nil
+	c.Lineup = nil
+	c.Person = nil
+	c.Version = 0
+	return nil
+}
+
+// jsonMigrations upgrade the JSON cache from the schema version of the key to the next version
+var jsonMigrations = map[int]func(c *cache, now time.Time){
+	1: func(c *cache, now time.Time) {
+		stampEntries(c.Program, now)
+		stampEntries(c.Metadata, now)
+		for _, schedule := range c.Schedule {
+			for i := range schedule {
+				if schedule[i].Cached.IsZero() {
+					schedule[i].Cached = now
+				}
+			}
+		}
+	},
+	2: func(c *cache, now time.Time) {
+		for _, entries := range []map[string]G2GCache{c.Program, c.Metadata} {
+			for id, entry := range entries {
+				entry.Used = entry.Cached
+				entry.Size = jsonSize(entry)
+				entries[id] = entry
+			}
+		}
+	},
+	3: func(c *cache, now time.Time) {
+		c.initMaps()
+		for id, program := range c.Program {
+			program.splitPeople(c.Person)
+			program.Size = jsonSize(program)
+			c.Program[id] = program
+		}
+	},
+	// The last use of programs is already recorded for the LRU eviction
+	4: func(c *cache, now time.Time) {},
+}
+
+// migrate upgrades a cache file of an older schema version, the caller holds the lock.
+// Files without a version are version 1, files of a newer version are not read.
+func (c *cache) migrate(app *App) error {
+	if c.Version == 0 {
+		c.Version = 1
+	}
+	if err := checkCacheVersion(c.Version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for c.Version < cacheSchemaVersion {
+		jsonMigrations[c.Version](c, now)
+		c.Version++
+
+		app.Logger.WithField("version", c.Version).Info("Migrated cache")
+	}
+
+	return nil
+}
+
+// Save persists the cache to disk.
+// The cache is encoded directly into a temporary file, which replaces the cache file once it is complete.
 func (c *cache) Save(app *App) error {
 	c.Lock()
 	defer c.Unlock()
@@ -505,16 +1184,13 @@ func (c *cache) Save(app *App) error {
 		return errors.Wrap(err, "failed to create cache directory")
 	}
 
-	// Marshal cache data
-	data, err := json.MarshalIndent(c, "", "  ")
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal cache data")
-	}
+	c.evict(app, maxCacheSize)
 
 	// Write to temporary file first
 	tmpFile := app.Config.Files.Cache + ".tmp"
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		return errors.Wrap(err, "failed to write temporary cache file")
+	if err := c.write(tmpFile, app.Config.Options.CompressCache); err != nil {
+		os.Remove(tmpFile) // Clean up temp file
+		return err
 	}
 
 	// Rename temporary file to actual file
@@ -526,19 +1202,71 @@ func (c *cache) Save(app *App) error {
 	return nil
 }
 
-// CleanUp removes expired entries from the cache
+// write encodes the cache into filename, the caller holds the lock
+func (c *cache) write(filename string, compressed bool) error {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary cache file")
+	}
+	defer file.Close()
+
+	if err := c.encode(file, compressed); err != nil {
+		return err
+	}
+
+	return errors.Wrap(file.Close(), "failed to write temporary cache file")
+}
+
+// encode writes the cache as JSON to w, the caller holds the lock
+func (c *cache) encode(dst io.Writer, compressed bool) error {
+	buf := bufio.NewWriter(dst)
+	var w io.Writer = buf
+
+	var zw *gzip.Writer
+	if compressed {
+		zw = gzip.NewWriter(buf)
+		w = zw
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return errors.Wrap(err, "failed to marshal cache data")
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return errors.Wrap(err, "failed to compress cache data")
+		}
+	}
+	return errors.Wrap(buf.Flush(), "failed to write cache data")
+}
+
+// isGzip reports whether data starts with the gzip header
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// CleanUp removes past broadcasts and entries older than the TTL of their data type
 func (c *cache) CleanUp(app *App) {
 	c.Lock()
 	defer c.Unlock()
 
+	expired := c.removeExpired(app)
+	app.Logger.WithField("expired", expired).Info("Cleaned up cache")
+}
+
+// removeExpired removes past broadcasts and expired entries and returns their number, the caller holds the lock
+func (c *cache) removeExpired(app *App) int {
 	now := time.Now()
+	ttl := app.Config.Options.CacheTTL
 	expired := 0
 
 	// Clean up schedules
 	for stationID, schedules := range c.Schedule {
 		var validSchedules []G2GCache
 		for _, schedule := range schedules {
-			if schedule.AirDateTime.After(now) {
+			if schedule.AirDateTime.After(now) && !cacheExpired(schedule.Cached, ttl.Schedules, now) {
 				validSchedules = append(validSchedules, schedule)
 			} else {
 				expired++
@@ -551,18 +1279,127 @@ func (c *cache) CleanUp(app *App) {
 		}
 	}
 
-	// Clean up programs
-	for programID, program := range c.Program {
-		if program.OriginalAirDate != "" {
-			airDate, err := time.Parse("2006-01-02", program.OriginalAirDate)
-			if err == nil && airDate.Before(now.AddDate(0, -1, 0)) {
-				delete(c.Program, programID)
+	// Programs which no cached schedule refers to are removed once they were not used for the retention
+	if retention := app.Config.Options.ProgramRetention; retention > 0 {
+		referenced := make(map[string]bool)
+		for _, schedule := range c.Schedule {
+			for _, broadcast := range schedule {
+				referenced[broadcast.ProgramID] = true
+			}
+		}
+		for id, program := range c.Program {
+			if !referenced[id] && retentionExpired(program, retention, now) {
+				delete(c.Program, id)
 				expired++
 			}
 		}
 	}
 
-	app.Logger.WithField("expired", expired).Info("Cleaned up cache")
+	// Clean up programs and metadata
+	for _, entries := range []struct {
+		m   map[string]G2GCache
+		ttl time.Duration
+	}{{c.Program, ttl.Programs}, {c.Metadata, ttl.Metadata}} {
+		for id, entry := range entries.m {
+			if cacheExpired(entry.Cached, entries.ttl, now) {
+				delete(entries.m, id)
+				expired++
+			}
+		}
+	}
+
+	// People are kept as long as a program refers to them
+	c.prunePeople()
+
+	return expired
+}
+
+// touch records that a downloaded schedule uses a program and its artwork, the caller holds the program and metadata locks
+func (c *cache) touch(programID string, now time.Time) {
+	if p, ok := c.Program[programID]; ok {
+		p.Used = now
+		c.Program[programID] = p
+	}
+	for _, id := range []string{programID, truncateID(programID)} {
+		if m, ok := c.Metadata[id]; ok {
+			m.Used = now
+			c.Metadata[id] = m
+		}
+	}
+}
+
+// evict removes the least recently used programs and metadata until their size is below maxSize, the caller holds the lock.
+// Entries of the cached schedules are never evicted, they are needed for the XMLTV file even if they alone exceed maxSize.
+func (c *cache) evict(app *App, maxSize int) {
+	used := make(map[string]bool)
+	for _, schedule := range c.Schedule {
+		for _, broadcast := range schedule {
+			used[broadcast.ProgramID] = true
+			used[truncateID(broadcast.ProgramID)] = true
+		}
+	}
+
+	type candidate struct {
+		entries map[string]G2GCache
+		id      string
+		entry   G2GCache
+	}
+
+	size := 0
+	var candidates []candidate
+	for _, entries := range []map[string]G2GCache{c.Program, c.Metadata} {
+		for id, entry := range entries {
+			size += entry.Size
+			if !used[id] {
+				candidates = append(candidates, candidate{entries, id, entry})
+			}
+		}
+	}
+	if size <= maxSize {
+		return
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].entry.Used.Before(candidates[j].entry.Used)
+	})
+
+	evicted := 0
+	for _, e := range candidates {
+		if size <= maxSize {
+			break
+		}
+		delete(e.entries, e.id)
+		size -= e.entry.Size
+		evicted++
+	}
+	c.prunePeople()
+
+	app.Logger.WithFields(logrus.Fields{
+		"evicted":  evicted,
+		"size":     size,
+		"max_size": maxSize,
+	}).Info("Evicted least recently used entries from cache")
+}
+
+// jsonSize returns the size of an entry as JSON
+func jsonSize(entry G2GCache) int {
+	data, _ := json.Marshal(entry)
+	return len(data)
+}
+
+// retentionExpired reports whether a program was last used by a downloaded schedule longer than retention ago,
+// programs cached before the last use was recorded count from their download
+func retentionExpired(program G2GCache, retention time.Duration, now time.Time) bool {
+	used := program.Used
+	if used.IsZero() {
+		used = program.Cached
+	}
+	return now.Sub(used) > retention
+}
+
+// cacheExpired reports whether an entry cached at cached is older than ttl, a ttl of 0 never expires
+func cacheExpired(cached time.Time, ttl time.Duration, now time.Time) bool {
+	return ttl > 0 && now.Sub(cached) > ttl
 }
 
 // GetStats returns cache statistics
@@ -570,22 +1407,130 @@ func (c *cache) GetStats() map[string]interface{} {
 	c.RLock()
 	defer c.RUnlock()
 
-	return map[string]interface{}{
-		"hits":     c.stats.Hits,
-		"misses":   c.stats.Misses,
-		"size":     c.stats.Size,
-		"channels": len(c.Channel),
-		"programs": len(c.Program),
-		"metadata": len(c.Metadata),
-		"schedule": len(c.Schedule),
-		"expires":  c.expiration,
+	broadcasts := 0
+	for _, schedule := range c.Schedule {
+		broadcasts += len(schedule)
+	}
+
+	size := 0
+	for _, entries := range []map[string]G2GCache{c.Program, c.Metadata} {
+		for _, entry := range entries {
+			size += entry.Size
+		}
 	}
+
+	stats := map[string]interface{}{
+		"channels":   len(c.Channel),
+		"programs":   len(c.Program),
+		"metadata":   len(c.Metadata),
+		"schedule":   len(c.Schedule),
+		"broadcasts": broadcasts,
+		"people":     len(c.Person),
+		"version":    c.Version,
+		"entry size": size,
+		"max size":   maxCacheSize,
+	}
+	c.stats.report(stats)
+
+	return stats
+}
+
+// Inspect returns the cached entries of a program, series or station ID by data type
+func (c *cache) Inspect(id string, app *App) map[string]interface{} {
+	c.RLock()
+	defer c.RUnlock()
+
+	entries := make(map[string]interface{})
+
+	if p, ok := c.Program[id]; ok {
+		entries["program"] = p
+
+		// Names of the cast and crew in the person store
+		ids := make(map[string]bool)
+		p.personIDs(ids)
+		people := make(map[string]Person)
+		for personID := range ids {
+			if person, ok := c.Person[personID]; ok {
+				people[personID] = person
+			}
+		}
+		if len(people) != 0 {
+			entries["people"] = people
+		}
+	}
+	// Artwork is cached for the series, the first 10 characters of the program ID
+	for _, metadataID := range []string{id, truncateID(id)} {
+		if m, ok := c.Metadata[metadataID]; ok {
+			entries["metadata"] = m
+			break
+		}
+	}
+	if ch, ok := c.Channel[id]; ok {
+		entries["channel"] = ch
+	}
+	if schedule, ok := c.Schedule[id]; ok {
+		entries["schedule"] = schedule
+	}
+
+	return entries
+}
+
+// truncateID returns the series part of a program ID
+func truncateID(id string) string {
+	if len(id) > 10 {
+		return id[:10]
+	}
+	return id
+}
+
+// program returns the cached program of id and counts the lookup
+func (c *cache) program(id string) (G2GCache, bool) {
+	c.programMu.RLock()
+	p, ok := c.Program[id]
+	c.programMu.RUnlock()
+
+	c.stats.lookup(ok)
+	return p, ok
+}
+
+// metadata returns the cached artwork metadata of id and counts the lookup
+func (c *cache) metadata(id string) (G2GCache, bool) {
+	c.metadataMu.RLock()
+	m, ok := c.Metadata[id]
+	c.metadataMu.RUnlock()
+
+	c.stats.lookup(ok)
+	return m, ok
+}
+
+// GetChannels returns the cached channels
+func (c *cache) GetChannels(app *App) []G2GCache {
+	c.channelMu.RLock()
+	defer c.channelMu.RUnlock()
+
+	channels := make([]G2GCache, 0, len(c.Channel))
+	for _, channel := range c.Channel {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// GetSchedule returns the cached broadcasts of the station sorted by the air time
+func (c *cache) GetSchedule(stationID string, app *App) []G2GCache {
+	c.scheduleMu.RLock()
+	schedule := slices.Clone(c.Schedule[stationID])
+	c.scheduleMu.RUnlock()
+
+	sort.SliceStable(schedule, func(i, j int) bool {
+		return schedule[i].AirDateTime.Before(schedule[j].AirDateTime)
+	})
+	return schedule
 }
 
 // Get data from cache
 func (c *cache) GetTitle(id, lang string, app *App) (t []Title) {
 
-	if p, ok := c.Program[id]; ok {
+	if p, ok := c.program(id); ok {
 
 		var title Title
 
@@ -599,8 +1544,14 @@ func (c *cache) GetTitle(id, lang string, app *App) (t []Title) {
 
 	if len(t) == 0 {
 		var title Title
-		title.Value = "No EPG Info"
-		title.Lang = "en"
+		title.Value = app.Config.Options.Placeholder.Title
+		title.Lang = app.Config.Options.Placeholder.Lang
+		if len(title.Value) == 0 {
+			title.Value = defaultPlaceholderTitle
+		}
+		if len(title.Lang) == 0 {
+			title.Lang = "en"
+		}
 		t = append(t, title)
 	}
 
@@ -609,13 +1560,19 @@ func (c *cache) GetTitle(id, lang string, app *App) (t []Title) {
 
 func (c *cache) GetSubTitle(id, lang string, app *App) (s SubTitle) {
 
-	if p, ok := c.Program[id]; ok {
+	if p, ok := c.program(id); ok {
 
 		if len(p.EpisodeTitle150) != 0 {
 
 			s.Value = p.EpisodeTitle150
 			s.Lang = lang
 
+		} else if p.EventDetails != nil && len(p.EventDetails.Teams) != 0 {
+
+			// Sports events without an episode title get the teams, e.g. Boston Celtics at Los Angeles Lakers
+			s.Value = p.EventDetails.matchup()
+			s.Lang = lang
+
 		} else {
 
 			for _, d := range p.Descriptions.Description100 {
@@ -634,7 +1591,7 @@ func (c *cache) GetSubTitle(id, lang string, app *App) (s SubTitle) {
 
 func (c *cache) GetDescs(id, subTitle string, app *App) (de []Desc) {
 
-	if p, ok := c.Program[id]; ok {
+	if p, ok := c.program(id); ok {
 
 		d := p.Descriptions
 
@@ -661,6 +1618,22 @@ func (c *cache) GetDescs(id, subTitle string, app *App) (de []Desc) {
 			de = append(de, desc)
 		}
 
+		// The venue of a sports event is added to the descriptions, events without a description get the teams and the venue
+		if e := p.EventDetails; e != nil && len(e.Venue100) != 0 {
+			venue := "Venue: " + e.Venue100
+			if len(de) == 0 {
+				if teams := e.matchup(); len(teams) != 0 && teams != subTitle {
+					venue = teams + "\n" + venue
+				}
+				de = append(de, Desc{Value: venue, Lang: "en"})
+			}
+			for i := range de {
+				if !strings.Contains(de[i].Value, venue) {
+					de[i].Value += "\n" + venue
+				}
+			}
+		}
+
 	}
 
 	return
@@ -670,7 +1643,7 @@ func (c *cache) GetCredits(id string, app *App) (cr Credits) {
 
 	if app.Config.Options.Credits {
 
-		if p, ok := c.Program[id]; ok {
+		if p, ok := c.program(id); ok {
 
 			// Crew
 			for _, crew := range p.Crew {
@@ -678,16 +1651,16 @@ func (c *cache) GetCredits(id string, app *App) (cr Credits) {
 				switch crew.Role {
 
 				case "Director":
-					cr.Director = append(cr.Director, Director{Value: crew.Name})
+					cr.Director = append(cr.Director, Director{Value: c.personName(crew.PersonID, crew.Name)})
 
 				case "Producer":
-					cr.Producer = append(cr.Producer, Producer{Value: crew.Name})
+					cr.Producer = append(cr.Producer, Producer{Value: c.personName(crew.PersonID, crew.Name)})
 
 				case "Presenter":
-					cr.Presenter = append(cr.Presenter, Presenter{Value: crew.Name})
+					cr.Presenter = append(cr.Presenter, Presenter{Value: c.personName(crew.PersonID, crew.Name)})
 
 				case "Writer":
-					cr.Writer = append(cr.Writer, Writer{Value: crew.Name})
+					cr.Writer = append(cr.Writer, Writer{Value: c.personName(crew.PersonID, crew.Name)})
 
 				}
 
@@ -699,7 +1672,11 @@ func (c *cache) GetCredits(id string, app *App) (cr Credits) {
 				switch cast.Role {
 
 				case "Actor":
-					cr.Actor = append(cr.Actor, Actor{Value: cast.Name, Role: cast.CharacterName})
+					actor := Actor{Value: c.personName(cast.PersonID, cast.Name), Role: cast.CharacterName}
+					if app.Config.Options.CastImages && isValidImageID(cast.PersonID) {
+						actor.Image = []Image{{Value: app.Config.serverURL("/images/celebrity/" + cast.PersonID), Type: "person"}}
+					}
+					cr.Actor = append(cr.Actor, actor)
 
 				}
 
@@ -712,11 +1689,17 @@ func (c *cache) GetCredits(id string, app *App) (cr Credits) {
 	return
 }
 
+// GetCategory returns the genres of a program as categories, mapped by the category profile and mapping options
 func (c *cache) GetCategory(id string, app *App) (ca []Category) {
 
-	if p, ok := c.Program[id]; ok {
+	if p, ok := c.program(id); ok {
+
+		genres := p.Genres
+		if p.EventDetails != nil && !slices.Contains(genres, "Sports event") {
+			genres = append(slices.Clip(genres), "Sports event")
+		}
 
-		for _, g := range p.Genres {
+		for _, g := range mapCategories(genres, app.Config.Options.CategoryProfile, app.Config.Options.CategoryMapping) {
 
 			var category Category
 			category.Value = g
@@ -731,69 +1714,160 @@ func (c *cache) GetCategory(id string, app *App) (ca []Category) {
 	return
 }
 
-func (c *cache) GetEpisodeNum(id string, app *App) (ep []EpisodeNum) {
+// GetKeywords returns the keywords of all keyword groups of a program, sorted by group
+func (c *cache) GetKeywords(id string, app *App) (kw []Keyword) {
+	if !app.Config.Options.Keywords {
+		return
+	}
 
-	var seaseon, episode int
+	if p, ok := c.program(id); ok {
+		seen := make(map[string]bool)
+		for _, group := range sortedKeys(p.KeyWords) {
+			for _, keyword := range p.KeyWords[group] {
+				if len(keyword) == 0 || seen[keyword] {
+					continue
+				}
+				seen[keyword] = true
+				kw = append(kw, Keyword{Value: keyword, Lang: "en"})
+			}
+		}
+	}
 
-	if p, ok := c.Program[id]; ok {
+	return
+}
 
-		for _, m := range p.Metadata {
+// episodeNumSystems are the episode number systems of the XMLTV file, in the default order
+var episodeNumSystems = []string{"xmltv_ns", "onscreen", "dd_progid", "series-id", "original-air-date"}
 
-			seaseon = m.Gracenote.Season
-			episode = m.Gracenote.Episode
+// optionalEpisodeNumSystems are episode number systems which are only added if the option lists them
+var optionalEpisodeNumSystems = []string{"episode-id"}
 
-			var episodeNum EpisodeNum
+// GetEpisodeNum returns the episode numbers of a program in the systems and order of the episode number systems option
+// GetContentAdvisory returns the content advisories of a program, none if the content advisory option is none
+func (c *cache) GetContentAdvisory(id string, app *App) (advisories []string) {
+	switch app.Config.Options.Rating.ContentAdvisory {
+	case "", "none":
+		return
+	}
+
+	if p, ok := c.program(id); ok {
+		advisories = p.ContentAdvisory
+	}
 
-			if seaseon != 0 && episode != 0 {
+	return
+}
 
-				episodeNum.Value = fmt.Sprintf("%d.%d.", seaseon-1, episode-1)
-				episodeNum.System = "xmltv_ns"
+func (c *cache) GetEpisodeNum(id string, app *App) (ep []EpisodeNum) {
 
-				ep = append(ep, episodeNum)
-			}
+	p, ok := c.program(id)
+	if !ok {
+		return
+	}
 
+	var season, episode int
+	for _, m := range p.Metadata {
+		if m.Gracenote.Season != 0 || m.Gracenote.Episode != 0 {
+			season, episode = m.Gracenote.Season, m.Gracenote.Episode
 		}
+	}
 
-		if seaseon != 0 && episode != 0 {
+	systems := app.Config.Options.EpisodeNumSystems
+	if len(systems) == 0 {
+		systems = episodeNumSystems
+	}
 
-			var episodeNum EpisodeNum
-			episodeNum.Value = fmt.Sprintf("S%d E%d", seaseon, episode)
-			episodeNum.System = "onscreen"
-			ep = append(ep, episodeNum)
+	for _, system := range systems {
 
-		}
+		var value string
 
-		if len(ep) == 0 {
+		switch system {
 
-			var episodeNum EpisodeNum
+		case "xmltv_ns":
+			value = xmltvNS(season, episode)
 
-			switch id[0:2] {
+		case "onscreen":
+			value = onscreenEpisode(season, episode)
 
-			case "EP":
-				episodeNum.Value = id[0:10] + "." + id[10:]
+		case "dd_progid":
+			value = ddProgID(id)
 
-			case "SH", "MV":
-				episodeNum.Value = id[0:10] + ".0000"
+		case "series-id":
+			value = seriesID(id)
 
-			default:
-				episodeNum.Value = id
+		case "episode-id":
+			if strings.HasPrefix(id, "EP") {
+				value = id
 			}
 
-			episodeNum.System = "dd_progid"
+		case "original-air-date":
+			value = p.OriginalAirDate
 
-			ep = append(ep, episodeNum)
+		}
 
+		if len(value) != 0 {
+			ep = append(ep, EpisodeNum{Value: value, System: system})
 		}
 
-		if len(p.OriginalAirDate) > 0 {
+	}
 
-			var episodeNum EpisodeNum
-			episodeNum.Value = p.OriginalAirDate
-			episodeNum.System = "original-air-date"
-			ep = append(ep, episodeNum)
+	return
+}
 
-		}
+// xmltvNS returns the zero based season and episode, e.g. 3.0. for S4 E1. Unknown parts are empty, e.g. 3.. for season 4
+func xmltvNS(season, episode int) string {
+	if season == 0 && episode == 0 {
+		return ""
+	}
+
+	var s, e string
+	if season != 0 {
+		s = strconv.Itoa(season - 1)
+	}
+	if episode != 0 {
+		e = strconv.Itoa(episode - 1)
+	}
+	return s + "." + e + "."
+}
+
+// onscreenEpisode returns season and episode as shown on screen, e.g. S4 E1
+func onscreenEpisode(season, episode int) string {
+	switch {
+	case season != 0 && episode != 0:
+		return fmt.Sprintf("S%d E%d", season, episode)
+	case season != 0:
+		return fmt.Sprintf("S%d", season)
+	case episode != 0:
+		return fmt.Sprintf("E%d", episode)
+	}
+	return ""
+}
 
+// seriesID returns the program ID of the series of an episode or series, e.g. SH012345670000 for EP012345670042.
+// Movies and other programs have no series.
+func seriesID(id string) string {
+	if len(id) != 14 || !(strings.HasPrefix(id, "EP") || strings.HasPrefix(id, "SH")) {
+		return ""
+	}
+	return "SH" + id[2:10] + "0000"
+}
+
+// ddProgID returns the program ID with a period between series and episode, e.g. EP01234567.0042 for EP012345670042
+func ddProgID(id string) string {
+	if len(id) != 14 {
+		return id
+	}
+	return id[:10] + "." + id[10:]
+}
+
+// GetStarRating returns the quality ratings of a movie as star ratings, e.g. 3/4 of Gracenote
+func (c *cache) GetStarRating(id string, app *App) (sr []StarRating) {
+	if p, ok := c.program(id); ok && p.Movie != nil {
+		for _, rating := range p.Movie.QualityRating {
+			if len(rating.Rating) == 0 || len(rating.MaxRating) == 0 {
+				continue
+			}
+			sr = append(sr, StarRating{System: rating.RatingsBody, Value: rating.Rating + "/" + rating.MaxRating})
+		}
 	}
 
 	return
@@ -803,7 +1877,7 @@ func (c *cache) GetPreviouslyShown(id string, app *App) (prev *PreviouslyShown)
 
 	prev = &PreviouslyShown{}
 
-	if p, ok := c.Program[id]; ok {
+	if p, ok := c.program(id); ok {
 		prev.Start = p.OriginalAirDate
 	}
 
@@ -812,31 +1886,30 @@ func (c *cache) GetPreviouslyShown(id string, app *App) (prev *PreviouslyShown)
 
 // GetImageUrl downloads an image from Schedules Direct and saves it locally.
 // It skips download if the image already exists and is valid.
-func (app *App) GetImageUrl(urlid string, name string) error {
-	url := urlid + "?token=" + app.Token
+func (app *App) GetImageUrl(uri string, name string) error {
 	filename := app.Config.Options.ImagesPath + name
 
 	a, err := os.Stat(filename)
-	if err == nil && a.Size() >= 500 {
+	if err == nil && a.Size() >= minImageSize {
 		// File exists and is valid
 		return nil
 	}
 
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", filename, err)
+	if app.Images == nil {
+		return errors.New("Schedules Direct client is not initialized")
 	}
-	defer file.Close()
 
-	req, err := http.NewRequest("GET", url, nil)
+	resp, err := app.Images.Image(context.Background(), uri)
 	if err != nil {
-		return fmt.Errorf("failed to create request for %s: %w", url, err)
+		return err
 	}
-	resp, err := httpClient.Do(req)
+	defer resp.Body.Close()
+
+	file, err := os.Create(filename)
 	if err != nil {
-		return fmt.Errorf("failed to fetch image from %s: %w", url, err)
+		return fmt.Errorf("failed to create file %s: %w", filename, err)
 	}
-	defer resp.Body.Close()
+	defer file.Close()
 
 	buf := bufferPool.Get().([]byte)
 	defer bufferPool.Put(buf)
@@ -848,20 +1921,78 @@ func (app *App) GetImageUrl(urlid string, name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to stat file %s after download: %w", filename, err)
 	}
-	if info.Size() < 500 {
+	if info.Size() < minImageSize {
 		return fmt.Errorf("downloaded image %s is too small (%d bytes)", filename, info.Size())
 	}
 
 	return nil
 }
 
+// artworkIcon returns the icon of an image, the image is downloaded into the local images cache if it is enabled
+func artworkIcon(uri string, width, height int, app *App) (Icon, bool) {
+	name := imageName(uri)
+	if app.Config.Options.TVShowImages {
+		if err := app.GetImageUrl(uri, name); err != nil {
+			app.Logger.WithError(err).WithFields(logrus.Fields{
+				"uri":  uri,
+				"name": name,
+			}).Error("Failed to download image")
+			return Icon{}, false
+		}
+	}
+
+	return Icon{Src: app.Config.serverURL("/images/" + name), Height: height, Width: width}, true
+}
+
+// logoIcon returns the icon of a channel logo, the logo is downloaded into the images path if the local logos option is enabled.
+// The Schedules Direct URL is kept if the download fails.
+func logoIcon(uri string, width, height int, app *App) Icon {
+	icon := Icon{Src: uri, Height: height, Width: width}
+	if !app.Config.Options.LocalLogos || len(uri) == 0 {
+		return icon
+	}
+
+	name := imageName(uri)
+	if err := app.GetImageUrl(uri, name); err != nil {
+		app.Logger.WithError(err).WithFields(logrus.Fields{
+			"uri":  uri,
+			"name": name,
+		}).Warn("Failed to download channel logo")
+		return icon
+	}
+
+	icon.Src = app.Config.serverURL("/images/" + name)
+	return icon
+}
+
+// imageName returns the local file name of an artwork URI, relative URIs are already the file name
+func imageName(uri string) string {
+	if u, err := url.Parse(uri); err == nil && u.IsAbs() {
+		return path.Base(u.Path)
+	}
+	return strings.TrimPrefix(uri, "/")
+}
+
+// GetIcon returns the artwork of a program, by the artwork policy of its program type or by the poster aspect
 func (c *cache) GetIcon(id string, app *App) (i []Icon) {
 
+	if policy, ok := app.Config.Options.ArtworkPolicies[programType(id)]; ok {
+		if m, ok := c.metadata(id); ok {
+			for _, image := range selectArtwork(m.Data, policy) {
+				width, _ := strconv.Atoi(image.Width)
+				height, _ := strconv.Atoi(image.Height)
+				if icon, ok := artworkIcon(image.URI, width, height, app); ok {
+					i = append(i, icon)
+				}
+			}
+		}
+		return
+	}
+
 	var aspects = []string{"2x3", "4x3", "3x4", "16x9"}
 	var uri string
 	var width, height int
 	var err error
-	var nameFinal string
 	switch app.Config.Options.PosterAspect {
 
 	case "all":
@@ -872,8 +2003,7 @@ func (c *cache) GetIcon(id string, app *App) (i []Icon) {
 
 	}
 
-	if m, ok := c.Metadata[id]; ok {
-		var nameTemp string
+	if m, ok := c.metadata(id); ok {
 		for _, aspect := range aspects {
 			var maxWidth, maxHeight int
 			var finalCategory string = ""
@@ -887,11 +2017,6 @@ func (c *cache) GetIcon(id string, app *App) (i []Icon) {
 					continue
 				}
 
-				if icon.URI[0:7] != "http://" && icon.URI[0:8] != "https://" {
-					nameTemp = icon.URI
-					icon.URI = fmt.Sprintf("https://json.schedulesdirect.org/20141201/image/%s", icon.URI)
-				}
-
 				if icon.Aspect == aspect {
 
 					width, err = strconv.Atoi(icon.Width)
@@ -908,7 +2033,6 @@ func (c *cache) GetIcon(id string, app *App) (i []Icon) {
 						maxWidth = width
 						maxHeight = height
 						uri = icon.URI
-						nameFinal = nameTemp
 					}
 
 				}
@@ -916,18 +2040,9 @@ func (c *cache) GetIcon(id string, app *App) (i []Icon) {
 			}
 
 			if maxWidth > 0 {
-				if app.Config.Options.TVShowImages {
-					err := app.GetImageUrl(uri, nameFinal)
-					if err != nil {
-						app.Logger.WithError(err).WithFields(logrus.Fields{
-							"uri":  uri,
-							"name": nameFinal,
-						}).Error("Failed to download image")
-						continue
-					}
+				if icon, ok := artworkIcon(uri, maxWidth, maxHeight, app); ok {
+					i = append(i, icon)
 				}
-				path := "http://" + app.Config.Options.Hostname + "/images/" + nameFinal
-				i = append(i, Icon{Src: path, Height: maxHeight, Width: maxWidth})
 			}
 
 		}
@@ -973,7 +2088,7 @@ func (c *cache) GetRating(id, countryCode string, app *App) (ra []Rating) {
 	   }
 	*/
 
-	if p, ok := c.Program[id]; ok {
+	if p, ok := c.program(id); ok {
 
 		switch len(app.Config.Options.Rating.Countries) {
 
@@ -1030,10 +2145,7 @@ func (c *cache) GetRating(id, countryCode string, app *App) (ra []Rating) {
 
 // SDStation struct for station data (restored from struct_sd.go)
 type SDStation struct {
-	Map []struct {
-		Channel   string `json:"channel"`
-		StationID string `json:"stationID"`
-	} `json:"map"`
+	Map      []schedulesdirect.ChannelMap `json:"map"`
 	Metadata struct {
 		Lineup    string `json:"lineup"`
 		Modified  string `json:"modified"`
@@ -1075,6 +2187,8 @@ type SDSchedule struct {
 		Duration        int       `json:"duration"`
 		LiveTapeDelay   string    `json:"liveTapeDelay"`
 		New             bool      `json:"new"`
+		Premiere        bool      `json:"premiere"`
+		PremiereFinale  string    `json:"isPremiereOrFinale"`
 		Md5             string    `json:"md5"`
 		ProgramID       string    `json:"programID"`
 		Ratings         []struct {
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultAutosaveInterval is the time between two saves of the cache during a download
+const defaultAutosaveInterval = 5 * time.Minute
+
+// autosave saves the cache while programs and schedules are downloaded.
+// Without it a run which is interrupted near the end loses all downloaded entries of the JSON cache,
+// with it the next run only downloads the entries since the last save.
+type autosave struct {
+	app      *App
+	batches  int
+	interval time.Duration
+
+	mu    sync.Mutex
+	count int
+	last  time.Time
+}
+
+// newAutosave returns the autosave of the configured batches and interval, 0 disables either of them
+func newAutosave(app *App) *autosave {
+	return &autosave{
+		app:      app,
+		batches:  app.Config.Options.CacheAutosave.Batches,
+		interval: app.Config.Options.CacheAutosave.Interval,
+		last:     time.Now(),
+	}
+}
+
+// batchDone counts a batch added to the cache and saves the cache
+// once the configured number of batches was added or the interval passed since the last save
+func (a *autosave) batchDone() {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.count++
+	if (a.batches <= 0 || a.count < a.batches) && (a.interval <= 0 || time.Since(a.last) < a.interval) {
+		return
+	}
+
+	logger := a.app.Logger.WithFields(logrus.Fields{
+		"batches": a.count,
+		"since":   time.Since(a.last).Round(time.Second),
+	})
+	a.count = 0
+	a.last = time.Now()
+
+	// A failed autosave doesn't stop the download, the cache is saved again at the end of the run
+	if err := a.app.Cache.Save(a.app); err != nil {
+		logger.WithError(err).Warn("Failed to autosave cache")
+		return
+	}
+	logger.Info("Autosaved cache")
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAutosave(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	app := &App{Logger: logger, Config: config{}, Cache: newCache()}
+	app.Config.Files.Cache = filepath.Join(t.TempDir(), "guide_cache.json")
+	app.Config.Options.CacheAutosave.Batches = 2
+
+	a := newAutosave(app)
+	a.batchDone()
+	if _, err := os.Stat(app.Config.Files.Cache); !os.IsNotExist(err) {
+		t.Fatal("Cache saved before the configured number of batches")
+	}
+	a.batchDone()
+	if _, err := os.Stat(app.Config.Files.Cache); err != nil {
+		t.Errorf("Cache not saved after the configured number of batches: %v", err)
+	}
+
+	// Without a download there is no autosave
+	var none *autosave
+	none.batchDone()
+}
@@ -0,0 +1,829 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the bbolt cache, the values are the G2GCache entries as JSON
+var (
+	boltChannels  = []byte("channels")
+	boltSchedules = []byte("schedules") // key: station ID/air time as Unix timestamp
+	boltPrograms  = []byte("programs")
+	boltMetadata  = []byte("metadata")
+	boltLineups   = []byte("lineups") // value: modified timestamp of the last download
+	boltPeople    = []byte("people")  // key: person ID, value: Person as JSON
+	boltMeta      = []byte("meta")    // key "version": schema version of the cache
+)
+
+// boltMigrations upgrade the database from the schema version of the key to the next version
+var boltMigrations = map[int]func(tx *bolt.Tx, now time.Time) error{
+	// Entries without download time get the time of the migration
+	1: func(tx *bolt.Tx, now time.Time) error {
+		for _, name := range [][]byte{boltSchedules, boltPrograms, boltMetadata} {
+			bucket := tx.Bucket(name)
+
+			entries := make(map[string]G2GCache)
+			bucket.ForEach(func(k, v []byte) error {
+				var entry G2GCache
+				if json.Unmarshal(v, &entry) == nil {
+					entries[string(k)] = entry
+				}
+				return nil
+			})
+
+			stampEntries(entries, now)
+			for key, entry := range entries {
+				if err := put(bucket, key, entry); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+	// Last use and size are only used by the JSON cache
+	2: func(tx *bolt.Tx, now time.Time) error { return nil },
+	// The people bucket is created with the others, cached programs keep the names until they are downloaded again
+	3: func(tx *bolt.Tx, now time.Time) error { return nil },
+	// Programs without last use count from their download
+	4: func(tx *bolt.Tx, now time.Time) error { return nil },
+}
+
+// boltCache stores the cache in a bbolt file.
+// Only the added entries are written, a save doesn't rewrite the whole cache.
+type boltCache struct {
+	db   *bolt.DB
+	path string
+
+	stats cacheStats
+}
+
+// Open opens the database and creates the buckets, an open database of the same file is reused
+func (b *boltCache) Open(app *App) error {
+	if len(app.Config.Files.Cache) == 0 {
+		return errors.New("cache file path not configured")
+	}
+
+	if b.db != nil {
+		if b.path == app.Config.Files.Cache {
+			return nil
+		}
+		b.db.Close()
+		b.db = nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(app.Config.Files.Cache), 0755); err != nil {
+		return errors.Wrap(err, "failed to create cache directory")
+	}
+
+	// The file is locked while it is open, a second guide2go process waits for it instead of blocking forever
+	db, err := bolt.Open(app.Config.Files.Cache, 0644, &bolt.Options{Timeout: 30 * time.Second})
+	if err != nil {
+		return errors.Wrap(err, "failed to open cache database")
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error { return migrateBolt(tx, app) }); err != nil {
+		db.Close()
+		return errors.Wrap(err, "failed to prepare cache database")
+	}
+
+	b.db = db
+	b.path = app.Config.Files.Cache
+
+	app.Logger.WithField("path", b.path).Debug("Opened bbolt cache")
+	return nil
+}
+
+// migrateBolt upgrades a database of an older schema version and creates the missing buckets.
+// Databases with buckets but without a version are version 1, databases of a newer version are not changed.
+func migrateBolt(tx *bolt.Tx, app *App) error {
+	version := cacheSchemaVersion
+	if meta := tx.Bucket(boltMeta); meta != nil {
+		version, _ = strconv.Atoi(string(meta.Get([]byte("version"))))
+		version = max(version, 1)
+	} else if tx.Bucket(boltPrograms) != nil {
+		version = 1
+	}
+	if err := checkCacheVersion(version); err != nil {
+		return err
+	}
+
+	for _, bucket := range [][]byte{boltChannels, boltSchedules, boltPrograms, boltMetadata, boltLineups, boltPeople, boltMeta} {
+		if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for ; version < cacheSchemaVersion; version++ {
+		if err := boltMigrations[version](tx, now); err != nil {
+			return errors.Wrapf(err, "failed to migrate cache from schema version %d", version)
+		}
+		app.Logger.WithField("version", version+1).Info("Migrated cache")
+	}
+
+	return tx.Bucket(boltMeta).Put([]byte("version"), []byte(strconv.Itoa(cacheSchemaVersion)))
+}
+
+// Save flushes the database file, the entries are already stored when they are added
+func (b *boltCache) Save(app *App) error {
+	if b.db == nil {
+		return errors.New("cache database not opened")
+	}
+
+	if err := b.db.Sync(); err != nil {
+		return errors.Wrap(err, "failed to save cache database")
+	}
+
+	return nil
+}
+
+// Close closes the database
+func (b *boltCache) Close() error {
+	if b.db == nil {
+		return nil
+	}
+
+	err := b.db.Close()
+	b.db = nil
+	return err
+}
+
+// Init has nothing to initialize, the buckets are created by Open
+func (b *boltCache) Init() {}
+
+// CleanUp removes past broadcasts and entries older than the TTL of their data type
+func (b *boltCache) CleanUp(app *App) {
+	if b.db == nil {
+		return
+	}
+
+	now := time.Now()
+	ttl := app.Config.Options.CacheTTL
+	expired := 0
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []struct {
+			name []byte
+			ttl  time.Duration
+		}{{boltSchedules, ttl.Schedules}, {boltPrograms, ttl.Programs}, {boltMetadata, ttl.Metadata}} {
+			// The keys point into the database pages, they are copied before the deletes change them
+			var remove [][]byte
+
+			entries := tx.Bucket(bucket.name)
+			entries.ForEach(func(k, v []byte) error {
+				if bytes.Equal(bucket.name, boltSchedules) && airTime(k) <= now.Unix() {
+					remove = append(remove, append([]byte(nil), k...))
+					return nil
+				}
+
+				var entry G2GCache
+				if bucket.ttl > 0 && json.Unmarshal(v, &entry) == nil && cacheExpired(entry.Cached, bucket.ttl, now) {
+					remove = append(remove, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			for _, k := range remove {
+				if err := entries.Delete(k); err != nil {
+					return err
+				}
+			}
+			expired += len(remove)
+		}
+
+		// Programs which no cached schedule refers to are removed once they were not used for the retention
+		if retention := app.Config.Options.ProgramRetention; retention > 0 {
+			referenced := make(map[string]bool)
+			tx.Bucket(boltSchedules).ForEach(func(k, v []byte) error {
+				var broadcast G2GCache
+				if json.Unmarshal(v, &broadcast) == nil {
+					referenced[broadcast.ProgramID] = true
+				}
+				return nil
+			})
+
+			var remove [][]byte
+			programs := tx.Bucket(boltPrograms)
+			programs.ForEach(func(k, v []byte) error {
+				var program G2GCache
+				if !referenced[string(k)] && json.Unmarshal(v, &program) == nil && retentionExpired(program, retention, now) {
+					remove = append(remove, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			for _, k := range remove {
+				if err := programs.Delete(k); err != nil {
+					return err
+				}
+			}
+			expired += len(remove)
+		}
+
+		// People are kept as long as a program refers to them
+		return prunePeopleBolt(tx)
+	})
+	if err != nil {
+		app.Logger.WithError(err).Error("Failed to clean up cache")
+		return
+	}
+
+	app.Logger.WithField("expired", expired).Info("Cleaned up cache")
+}
+
+// AddStations adds station data to the cache
+func (b *boltCache) AddStations(ctx context.Context, data *[]byte, lineup string, app *App) error {
+	tmp := newCache()
+	if err := tmp.AddStations(ctx, data, lineup, app); err != nil {
+		return err
+	}
+	b.stats.added(data)
+
+	return b.store(tmp)
+}
+
+// LineupUnchanged reports whether the station map of a lineup has not been modified since the last download
+// and all configured channels of the lineup are cached
+func (b *boltCache) LineupUnchanged(lineup, modified string, app *App) bool {
+	unchanged := false
+
+	b.db.View(func(tx *bolt.Tx) error {
+		if len(modified) == 0 || string(tx.Bucket(boltLineups).Get([]byte(lineup))) != modified {
+			return nil
+		}
+
+		channels := tx.Bucket(boltChannels)
+		for _, id := range app.Config.GetChannelList(lineup) {
+			if channels.Get([]byte(id)) == nil {
+				return nil
+			}
+		}
+
+		unchanged = true
+		return nil
+	})
+
+	return unchanged
+}
+
+// SetLineupModified stores the modified timestamp of a downloaded lineup
+func (b *boltCache) SetLineupModified(lineup, modified string) {
+	b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltLineups).Put([]byte(lineup), []byte(modified))
+	})
+}
+
+// RemoveChannels removes channels from the cache which are no longer configured
+func (b *boltCache) RemoveChannels(app *App) {
+	channelIDs := app.Config.GetChannelList("")
+
+	err := b.update(func(tx *bolt.Tx) error {
+		channels := tx.Bucket(boltChannels)
+
+		var remove [][]byte
+		channels.ForEach(func(k, v []byte) error {
+			if ContainsString(channelIDs, string(k)) == -1 {
+				remove = append(remove, append([]byte(nil), k...))
+			}
+			return nil
+		})
+
+		for _, k := range remove {
+			if err := channels.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		app.Logger.WithError(err).Error("Failed to remove channels from cache")
+	}
+}
+
+// AddSchedule adds schedule data to the cache, a downloaded day replaces the cached broadcasts of the same time
+func (b *boltCache) AddSchedule(ctx context.Context, data *[]byte, app *App) error {
+	tmp := newCache()
+	if err := tmp.AddSchedule(ctx, data, app); err != nil {
+		return err
+	}
+	b.stats.added(data)
+
+	return b.store(tmp)
+}
+
+// AddProgram adds program data to the cache
+func (b *boltCache) AddProgram(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error {
+	defer wg.Done()
+
+	tmp := newCache()
+	var local sync.WaitGroup
+	local.Add(1)
+	if err := tmp.AddProgram(ctx, data, &local, app); err != nil {
+		return err
+	}
+	b.stats.added(data)
+
+	return b.store(tmp)
+}
+
+// GetAllProgramIDs returns the sorted IDs of the programs of the cached schedules
+func (b *boltCache) GetAllProgramIDs() []string {
+	return sortedKeys(b.scheduledPrograms())
+}
+
+// GetRequiredProgramIDs returns the programs of the cached schedules which are not cached or changed since
+func (b *boltCache) GetRequiredProgramIDs() []string {
+	return requiredProgramIDs(b)
+}
+
+// GetRequiredMetaIDs returns the root IDs of the scheduled programs with artwork which is not cached
+func (b *boltCache) GetRequiredMetaIDs() []string {
+	return requiredMetaIDs(b)
+}
+
+// scheduledPrograms returns the MD5 of Schedules Direct by program ID of the cached schedules
+func (b *boltCache) scheduledPrograms() map[string]string {
+	scheduled := make(map[string]string)
+	b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltSchedules).ForEach(func(k, v []byte) error {
+			var broadcast G2GCache
+			if json.Unmarshal(v, &broadcast) == nil {
+				scheduled[broadcast.ProgramID] = broadcast.Md5
+			}
+			return nil
+		})
+	})
+	return scheduled
+}
+
+// programStates returns the state of the cached programs of ids, programs which can't be read are not cached
+func (b *boltCache) programStates(ids []string) map[string]programState {
+	states := make(map[string]programState)
+	b.db.View(func(tx *bolt.Tx) error {
+		programs := tx.Bucket(boltPrograms)
+		for _, id := range ids {
+			var state programState
+			if data := programs.Get([]byte(id)); data != nil && json.Unmarshal(data, &state) == nil {
+				states[id] = state
+			}
+		}
+		return nil
+	})
+	return states
+}
+
+// cachedMetadata returns which of ids have cached artwork
+func (b *boltCache) cachedMetadata(ids []string) map[string]bool {
+	cached := make(map[string]bool)
+	b.db.View(func(tx *bolt.Tx) error {
+		metadata := tx.Bucket(boltMetadata)
+		for _, id := range ids {
+			cached[id] = metadata.Get([]byte(id)) != nil
+		}
+		return nil
+	})
+	return cached
+}
+
+// MissingPrograms returns the program IDs which are not in the cache
+func (b *boltCache) MissingPrograms(ids []string) (missing []string) {
+	b.db.View(func(tx *bolt.Tx) error {
+		programs := tx.Bucket(boltPrograms)
+		for _, id := range ids {
+			if programs.Get([]byte(id)) == nil {
+				missing = append(missing, id)
+			}
+		}
+		return nil
+	})
+
+	return
+}
+
+// UseSeriesProgram caches the generic series program for an episode which could not be downloaded
+func (b *boltCache) UseSeriesProgram(episodeID, seriesID string) bool {
+	used := false
+
+	b.db.Update(func(tx *bolt.Tx) error {
+		programs := tx.Bucket(boltPrograms)
+
+		series := programs.Get([]byte(seriesID))
+		if series == nil {
+			return nil
+		}
+
+		// The value points into the database pages which change with the Put
+		if err := programs.Put([]byte(episodeID), append([]byte(nil), series...)); err != nil {
+			return err
+		}
+		used = true
+		return nil
+	})
+
+	return used
+}
+
+// AddMetadata adds metadata to the cache
+func (b *boltCache) AddMetadata(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error {
+	defer wg.Done()
+
+	tmp := newCache()
+	var local sync.WaitGroup
+	local.Add(1)
+	if err := tmp.AddMetadata(ctx, data, &local, app); err != nil {
+		return err
+	}
+	b.stats.added(data)
+
+	return b.store(tmp)
+}
+
+// Export returns all entries of the database
+func (b *boltCache) Export(app *App) (*cache, error) {
+	if b.db == nil {
+		return nil, errors.New("cache database not opened")
+	}
+
+	c := newCache()
+	err := b.db.View(func(tx *bolt.Tx) error {
+		for name, add := range map[string]func(key string, entry G2GCache){
+			string(boltChannels):  func(key string, entry G2GCache) { c.Channel[key] = entry },
+			string(boltSchedules): func(key string, entry G2GCache) { c.Schedule[key] = append(c.Schedule[key], entry) },
+			string(boltPrograms):  func(key string, entry G2GCache) { c.Program[key] = entry },
+			string(boltMetadata):  func(key string, entry G2GCache) { c.Metadata[key] = entry },
+		} {
+			err := tx.Bucket([]byte(name)).ForEach(func(k, v []byte) error {
+				var entry G2GCache
+				if err := json.Unmarshal(v, &entry); err != nil {
+					return errors.Wrapf(err, "failed to unmarshal cache entry %s", k)
+				}
+
+				// Schedule keys are station ID/air time, the keys are sorted by station and air time
+				key := string(k)
+				if name == string(boltSchedules) {
+					key = key[:strings.LastIndex(key, "/")]
+				}
+				add(key, entry)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.Bucket(boltLineups).ForEach(func(k, v []byte) error {
+			c.Lineup[string(k)] = string(v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(boltPeople).ForEach(func(k, v []byte) error {
+			var person Person
+			if err := json.Unmarshal(v, &person); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal person %s", k)
+			}
+			c.Person[string(k)] = person
+			return nil
+		})
+	})
+
+	return c, errors.Wrap(err, "failed to read cache")
+}
+
+// Import adds all entries of c to the database, entries with the same key are replaced
+func (b *boltCache) Import(c *cache, app *App) error {
+	return b.store(c)
+}
+
+// GetChannels returns the cached channels
+func (b *boltCache) GetChannels(app *App) []G2GCache {
+	var channels []G2GCache
+	if b.db == nil {
+		return channels
+	}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltChannels).ForEach(func(k, v []byte) error {
+			var channel G2GCache
+			if err := json.Unmarshal(v, &channel); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal channel %s", k)
+			}
+			channels = append(channels, channel)
+			return nil
+		})
+	})
+	if err != nil {
+		app.Logger.WithError(err).Error("Failed to read channels from cache")
+	}
+	return channels
+}
+
+// GetSchedule returns the cached broadcasts of the station sorted by the air time
+func (b *boltCache) GetSchedule(stationID string, app *App) []G2GCache {
+	var schedule []G2GCache
+	if b.db == nil {
+		return schedule
+	}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		// The keys of a station are sorted by the air time, all timestamps have the same number of digits
+		prefix := []byte(stationID + "/")
+		cursor := tx.Bucket(boltSchedules).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var broadcast G2GCache
+			if err := json.Unmarshal(v, &broadcast); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal cache entry %s", k)
+			}
+			schedule = append(schedule, broadcast)
+		}
+		return nil
+	})
+	if err != nil {
+		app.Logger.WithError(err).WithField("stationID", stationID).Error("Failed to read schedule from cache")
+	}
+	return schedule
+}
+
+// Get data from cache, the entries of the program are loaded into a cache of their own
+// which formats them like the JSON cache
+func (b *boltCache) GetTitle(id, lang string, app *App) []Title {
+	return b.lookup(id, app).GetTitle(id, lang, app)
+}
+
+func (b *boltCache) GetSubTitle(id, lang string, app *App) SubTitle {
+	return b.lookup(id, app).GetSubTitle(id, lang, app)
+}
+
+func (b *boltCache) GetDescs(id, subTitle string, app *App) []Desc {
+	return b.lookup(id, app).GetDescs(id, subTitle, app)
+}
+
+func (b *boltCache) GetCredits(id string, app *App) Credits {
+	return b.lookup(id, app).GetCredits(id, app)
+}
+
+func (b *boltCache) GetCategory(id string, app *App) []Category {
+	return b.lookup(id, app).GetCategory(id, app)
+}
+
+func (b *boltCache) GetKeywords(id string, app *App) []Keyword {
+	return b.lookup(id, app).GetKeywords(id, app)
+}
+
+func (b *boltCache) GetContentAdvisory(id string, app *App) []string {
+	return b.lookup(id, app).GetContentAdvisory(id, app)
+}
+
+func (b *boltCache) GetEpisodeNum(id string, app *App) []EpisodeNum {
+	return b.lookup(id, app).GetEpisodeNum(id, app)
+}
+
+func (b *boltCache) GetIcon(id string, app *App) []Icon {
+	return b.lookup(id, app).GetIcon(id, app)
+}
+
+func (b *boltCache) GetRating(id, countryCode string, app *App) []Rating {
+	return b.lookup(id, app).GetRating(id, countryCode, app)
+}
+
+func (b *boltCache) GetStarRating(id string, app *App) []StarRating {
+	return b.lookup(id, app).GetStarRating(id, app)
+}
+
+func (b *boltCache) GetPreviouslyShown(id string, app *App) *PreviouslyShown {
+	return b.lookup(id, app).GetPreviouslyShown(id, app)
+}
+
+// GetStats returns the number of entries per data type and the lookup counters
+func (b *boltCache) GetStats() map[string]interface{} {
+	stats := make(map[string]interface{})
+	if b.db == nil {
+		return stats
+	}
+
+	b.db.View(func(tx *bolt.Tx) error {
+		stats["channels"] = tx.Bucket(boltChannels).Stats().KeyN
+		stats["programs"] = tx.Bucket(boltPrograms).Stats().KeyN
+		stats["metadata"] = tx.Bucket(boltMetadata).Stats().KeyN
+		stats["people"] = tx.Bucket(boltPeople).Stats().KeyN
+		stats["version"], _ = strconv.Atoi(string(tx.Bucket(boltMeta).Get([]byte("version"))))
+
+		stations := make(map[string]bool)
+		broadcasts := 0
+		tx.Bucket(boltSchedules).ForEach(func(k, v []byte) error {
+			key := string(k)
+			stations[key[:strings.LastIndex(key, "/")+1]] = true
+			broadcasts++
+			return nil
+		})
+		stats["schedule"] = len(stations)
+		stats["broadcasts"] = broadcasts
+
+		return nil
+	})
+
+	b.stats.report(stats)
+	return stats
+}
+
+// Inspect returns the cached entries of a program, series or station ID by data type
+func (b *boltCache) Inspect(id string, app *App) map[string]interface{} {
+	c := newCache()
+	for _, key := range []string{id, truncateID(id)} {
+		found := b.lookup(key, app)
+		for k, v := range found.Program {
+			c.Program[k] = v
+		}
+		for k, v := range found.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+
+	b.db.View(func(tx *bolt.Tx) error {
+		var channel G2GCache
+		if data := tx.Bucket(boltChannels).Get([]byte(id)); data != nil && json.Unmarshal(data, &channel) == nil {
+			c.Channel[id] = channel
+		}
+
+		// The keys of a station are sorted by the air time, all timestamps have the same number of digits
+		prefix := []byte(id + "/")
+		cursor := tx.Bucket(boltSchedules).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var broadcast G2GCache
+			if json.Unmarshal(v, &broadcast) == nil {
+				c.Schedule[id] = append(c.Schedule[id], broadcast)
+			}
+		}
+
+		return nil
+	})
+
+	return c.Inspect(id, app)
+}
+
+// lookup returns a cache with the program and the metadata of id
+func (b *boltCache) lookup(id string, app *App) *cache {
+	c := newCache()
+
+	b.db.View(func(tx *bolt.Tx) error {
+		for bucket, entries := range map[string]map[string]G2GCache{"programs": c.Program, "metadata": c.Metadata} {
+			data := tx.Bucket([]byte(bucket)).Get([]byte(id))
+			if data == nil {
+				continue
+			}
+
+			var entry G2GCache
+			if err := json.Unmarshal(data, &entry); err != nil {
+				app.Logger.WithError(err).WithFields(logrus.Fields{"bucket": bucket, "programID": id}).Error("Failed to unmarshal cache entry")
+				continue
+			}
+			entries[id] = entry
+		}
+
+		// Cast and crew of the program
+		ids := make(map[string]bool)
+		for _, program := range c.Program {
+			program.personIDs(ids)
+		}
+		people := tx.Bucket(boltPeople)
+		for personID := range ids {
+			var person Person
+			if data := people.Get([]byte(personID)); data != nil && json.Unmarshal(data, &person) == nil {
+				c.Person[personID] = person
+			}
+		}
+		return nil
+	})
+
+	b.stats.lookup(len(c.Program) != 0 || len(c.Metadata) != 0)
+	return c
+}
+
+// store writes all entries of c in one transaction
+func (b *boltCache) store(c *cache) error {
+	return b.update(func(tx *bolt.Tx) error {
+		for id, channel := range c.Channel {
+			if err := put(tx.Bucket(boltChannels), id, channel); err != nil {
+				return err
+			}
+		}
+		used := make(map[string]time.Time)
+		for stationID, schedule := range c.Schedule {
+			for _, broadcast := range schedule {
+				key := fmt.Sprintf("%s/%d", stationID, broadcast.AirDateTime.Unix())
+				if err := put(tx.Bucket(boltSchedules), key, broadcast); err != nil {
+					return err
+				}
+				if broadcast.Cached.After(used[broadcast.ProgramID]) {
+					used[broadcast.ProgramID] = broadcast.Cached
+				}
+			}
+		}
+
+		// A downloaded schedule uses the program
+		for id, t := range used {
+			var program G2GCache
+			data := tx.Bucket(boltPrograms).Get([]byte(id))
+			if data == nil || json.Unmarshal(data, &program) != nil || !t.After(program.Used) {
+				continue
+			}
+			program.Used = t
+			if err := put(tx.Bucket(boltPrograms), id, program); err != nil {
+				return err
+			}
+		}
+		for id, program := range c.Program {
+			if err := put(tx.Bucket(boltPrograms), id, program); err != nil {
+				return err
+			}
+		}
+		for id, metadata := range c.Metadata {
+			if err := put(tx.Bucket(boltMetadata), id, metadata); err != nil {
+				return err
+			}
+		}
+		for lineup, modified := range c.Lineup {
+			if err := tx.Bucket(boltLineups).Put([]byte(lineup), []byte(modified)); err != nil {
+				return err
+			}
+		}
+		for id, person := range c.Person {
+			data, err := json.Marshal(person)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal person")
+			}
+			if err := tx.Bucket(boltPeople).Put([]byte(id), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// prunePeopleBolt removes the people no cached program refers to
+func prunePeopleBolt(tx *bolt.Tx) error {
+	used := make(map[string]bool)
+	tx.Bucket(boltPrograms).ForEach(func(k, v []byte) error {
+		var program G2GCache
+		if json.Unmarshal(v, &program) == nil {
+			program.personIDs(used)
+		}
+		return nil
+	})
+
+	// The keys point into the database pages, they are copied before the deletes change them
+	people := tx.Bucket(boltPeople)
+	var remove [][]byte
+	people.ForEach(func(k, v []byte) error {
+		if !used[string(k)] {
+			remove = append(remove, append([]byte(nil), k...))
+		}
+		return nil
+	})
+
+	for _, k := range remove {
+		if err := people.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update runs fn in a read-write transaction
+func (b *boltCache) update(fn func(tx *bolt.Tx) error) error {
+	if b.db == nil {
+		return errors.New("cache database not opened")
+	}
+
+	return errors.Wrap(b.db.Update(fn), "failed to update cache")
+}
+
+// put stores entry as JSON
+func put(bucket *bolt.Bucket, key string, entry G2GCache) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cache entry")
+	}
+
+	return bucket.Put([]byte(key), data)
+}
+
+// airTime returns the air time of a schedule key
+func airTime(key []byte) int64 {
+	k := string(key)
+	unix, _ := strconv.ParseInt(k[strings.LastIndex(k, "/")+1:], 10, 64)
+	return unix
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltCache(t *testing.T) {
+	testCacheStore(t, filepath.Join(t.TempDir(), "guide_cache.bolt"), func() CacheStore { return &boltCache{} })
+}
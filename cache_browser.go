@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yourusername/guide2go/web/handlers"
+)
+
+// searchPrograms returns the programs of the cached schedules whose program ID or title contains the query, ignoring the case.
+// The programs are read with Inspect, the search does not count as lookups of the cache statistics.
+// A program which is cached but no longer scheduled is only found by its full program ID.
+func searchPrograms(app *App, query string, limit int) ([]handlers.ProgramMatch, bool) {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if len(q) == 0 {
+		return nil, false
+	}
+
+	match := func(id string) (handlers.ProgramMatch, bool) {
+		p, ok := app.Cache.Inspect(id, app)["program"].(G2GCache)
+		if !ok {
+			return handlers.ProgramMatch{}, false
+		}
+		m := handlers.ProgramMatch{ProgramID: id, SubTitle: p.EpisodeTitle150, ShowType: p.ShowType}
+		if len(p.Titles) != 0 {
+			m.Title = p.Titles[0].Title120
+		}
+		return m, strings.Contains(strings.ToLower(id), q) || strings.Contains(strings.ToLower(m.Title), q)
+	}
+
+	var matches []handlers.ProgramMatch
+	exact, scheduled := strings.ToUpper(q), false
+	for _, id := range app.Cache.GetAllProgramIDs() {
+		scheduled = scheduled || id == exact
+		m, ok := match(id)
+		if !ok {
+			continue
+		}
+		if len(matches) == limit {
+			return matches, true
+		}
+		m.Scheduled = true
+		matches = append(matches, m)
+	}
+
+	if m, ok := match(exact); ok && !scheduled && len(matches) < limit {
+		matches = append(matches, m)
+	}
+	return matches, false
+}
+
+// SearchPrograms searches the programs of the cache for the cache browser of the web UI
+func (app *App) SearchPrograms(query string, limit int) (*handlers.ProgramSearch, error) {
+	search := *app
+	if err := search.openConfig(context.Background()); err != nil {
+		return nil, err
+	}
+	closeCache, err := search.openCache()
+	if err != nil {
+		return nil, err
+	}
+	defer closeCache()
+
+	result := &handlers.ProgramSearch{Query: query, Programs: []handlers.ProgramMatch{}}
+	if matches, more := searchPrograms(&search, query, limit); len(matches) != 0 {
+		result.Programs, result.More = matches, more
+	}
+	return result, nil
+}
+
+// CacheEntries returns the raw cache entries of a program or station ID like guide2go -cache show
+func (app *App) CacheEntries(id string) (map[string]interface{}, error) {
+	show := *app
+	if err := show.openConfig(context.Background()); err != nil {
+		return nil, err
+	}
+	closeCache, err := show.openCache()
+	if err != nil {
+		return nil, err
+	}
+	defer closeCache()
+
+	entries := show.Cache.Inspect(id, &show)
+	if len(entries) == 0 {
+		return nil, errors.Errorf("%s is not in the cache", id)
+	}
+	return entries, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSearchPrograms(t *testing.T) {
+	c := newCache()
+	program := func(id, title string) G2GCache {
+		var p G2GCache
+		p.ProgramID = id
+		p.Titles = append(p.Titles, struct {
+			Title120 string `json:"title120"`
+		}{title})
+		return p
+	}
+	c.Program["EP012345670001"] = program("EP012345670001", "The Evening News")
+	c.Program["MV000111220000"] = program("MV000111220000", "Evening Star")
+	c.Program["SH099999990000"] = program("SH099999990000", "Morning Show") // Retained, no longer scheduled
+	c.Schedule["10021"] = []G2GCache{{ProgramID: "EP012345670001"}, {ProgramID: "MV000111220000"}}
+	app := &App{Cache: c}
+
+	matches, more := searchPrograms(app, "evening", 10)
+	if len(matches) != 2 || more || matches[0].Title != "The Evening News" || !matches[0].Scheduled {
+		t.Errorf("searchPrograms(evening) = %+v, %v", matches, more)
+	}
+	if matches, more := searchPrograms(app, "evening", 1); len(matches) != 1 || !more {
+		t.Errorf("searchPrograms(evening) with limit 1 = %+v, %v", matches, more)
+	}
+	if matches, _ := searchPrograms(app, "mv0001", 10); len(matches) != 1 || matches[0].ProgramID != "MV000111220000" {
+		t.Errorf("searchPrograms(mv0001) = %+v", matches)
+	}
+	if matches, _ := searchPrograms(app, "morning", 10); len(matches) != 0 {
+		t.Errorf("searchPrograms(morning) = %+v, want only scheduled programs", matches)
+	}
+	if matches, _ := searchPrograms(app, "sh099999990000", 10); len(matches) != 1 || matches[0].Scheduled {
+		t.Errorf("searchPrograms(sh099999990000) = %+v, want the retained program", matches)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// migrateBatchSize is the number of programs, metadata entries or people copied at once by a cache migration
+const migrateBatchSize = 5000
+
+// CacheCommand runs the cache subcommand with its arguments:
+//
+//	stats        number of entries per data type and size of the cache
+//	show [ID]      cached program, artwork, channel and schedule of a program or station ID as JSON
+//	export [FILE]  all entries of the cache as a JSON cache file, gzip compressed if FILE ends with .gz
+//	import [FILE]  entries of an exported cache file, cached entries with the same key are replaced
+//	migrate --from BACKEND --to BACKEND [FILE]  all entries of the cache into a cache of another backend
+func (app *App) CacheCommand(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("cache command required: stats, show [ID], export [FILE], import [FILE] or migrate --from BACKEND --to BACKEND [FILE]")
+	}
+
+	if err := app.openConfig(ctx); err != nil {
+		return err
+	}
+
+	// A migration opens the caches of both backends itself
+	if args[0] == "migrate" {
+		return app.migrateCache(ctx, w, args[1:])
+	}
+
+	// An import is locked like an update, the cache is opened once the lock is held
+	if args[0] == "import" {
+		unlock, err := app.lockCache(ctx)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	closeCache, err := app.openCache()
+	if err != nil {
+		return err
+	}
+	defer closeCache()
+
+	switch args[0] {
+	case "stats":
+		stats := app.Cache.GetStats()
+		stats["backend"] = cacheBackend(app.Config)
+		stats["file"] = app.Config.Files.Cache
+		if info, err := os.Stat(app.Config.Files.Cache); err == nil {
+			stats["disk size"] = info.Size()
+		}
+
+		keys := make([]string, 0, len(stats))
+		for key := range stats {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fmt.Fprintf(w, "%-12s %v\n", key+":", stats[key])
+		}
+		return nil
+
+	case "show":
+		if len(args) < 2 {
+			return errors.New("cache show requires a program or station ID")
+		}
+
+		entries := app.Cache.Inspect(args[1], app)
+		if len(entries) == 0 {
+			return errors.Errorf("%s is not in the cache", args[1])
+		}
+
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal cache entries")
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+
+	case "export":
+		if len(args) < 2 {
+			return errors.New("cache export requires a file")
+		}
+
+		if err := app.exportCache(args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Exported cache to %s\n", args[1])
+		return nil
+
+	case "import":
+		if len(args) < 2 {
+			return errors.New("cache import requires a file")
+		}
+
+		file, err := os.Open(args[1])
+		if err != nil {
+			return errors.Wrap(err, "failed to open cache export")
+		}
+		defer file.Close()
+
+		if err := app.importCache(file); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Imported cache from %s\n", args[1])
+		return nil
+	}
+
+	return errors.Errorf("unknown cache command %q, use stats, show [ID], export [FILE], import [FILE] or migrate --from BACKEND --to BACKEND [FILE]", args[0])
+}
+
+// migrateCache parses the arguments of the migrate command and copies the cache of the configuration into the new backend.
+// The cache of the configuration is locked like an update, it must not change while it is copied.
+func (app *App) migrateCache(ctx context.Context, w io.Writer, args []string) error {
+	flags := flag.NewFlagSet("cache migrate", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	from := flags.String("from", cacheBackend(app.Config), "Backend of the cache file of the configuration")
+	to := flags.String("to", "", "Backend of the new cache")
+	if err := flags.Parse(args); err != nil {
+		return errors.Wrap(err, "invalid cache migrate arguments")
+	}
+	if len(*to) == 0 {
+		return errors.New("cache migrate requires the backend of the new cache: --to json, sqlite, bolt or redis")
+	}
+
+	dst := flags.Arg(0)
+	if len(dst) == 0 {
+		var err error
+		if dst, err = migrationTarget(app.Config.Files.Cache, *to); err != nil {
+			return err
+		}
+	}
+
+	unlock, err := app.lockCache(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := app.copyCache(w, *from, *to, dst); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Migrated %s cache %s to %s cache %s, set it as cache file of the configuration to use it\n", *from, app.Config.Files.Cache, *to, dst)
+	return nil
+}
+
+// migrationTarget returns the cache file of backend next to file, e.g. guide_cache.db for guide_cache.json and sqlite
+func migrationTarget(file, backend string) (string, error) {
+	var ext string
+	switch backend {
+	case "json":
+		ext = ".json"
+	case "sqlite":
+		ext = ".db"
+	case "bolt":
+		ext = ".bolt"
+	case "redis":
+		return "", errors.New("cache migrate to redis requires the URL of the server")
+	default:
+		return "", errors.Errorf("unknown cache backend %q, use json, sqlite, bolt or redis", backend)
+	}
+
+	if isRedisURL(file) {
+		return "", errors.New("cache migrate from redis requires the file of the new cache")
+	}
+	return strings.TrimSuffix(file, filepath.Ext(file)) + ext, nil
+}
+
+// copyCache copies all entries of the cache file of the configuration, read by the backend from,
+// into the cache dst of the backend to. Programs, metadata and people are copied in batches, w gets the progress.
+func (app *App) copyCache(w io.Writer, from, to, dst string) error {
+	if from == to && dst == app.Config.Files.Cache {
+		return errors.New("cache migrate requires another backend or cache file")
+	}
+
+	// Both caches are opened by copies of the app, so the cache of the app is not changed
+	src := *app
+	src.Config.Options.CacheBackend = from
+	src.Cache = &cache{}
+	closeSrc, err := src.openCache()
+	if err != nil {
+		return err
+	}
+	entries, err := src.Cache.Export(&src)
+	closeSrc()
+	if err != nil {
+		return errors.Wrap(err, "failed to export cache")
+	}
+
+	target := *app
+	target.Config.Files.Cache = dst
+	target.Config.Options.CacheBackend = to
+	target.Cache = &cache{}
+	closeTarget, err := target.openCache()
+	if err != nil {
+		return err
+	}
+	defer closeTarget()
+
+	// Channels, schedules and lineups depend on each other, they are copied at once
+	batch := newCache()
+	batch.Channel, batch.Schedule, batch.Lineup = entries.Channel, entries.Schedule, entries.Lineup
+	if err := target.Cache.Import(batch, &target); err != nil {
+		return errors.Wrap(err, "failed to migrate channels and schedules")
+	}
+	fmt.Fprintf(w, "%-9s %d/%d\n", "channels", len(entries.Channel), len(entries.Channel))
+
+	// People are copied before the programs which refer to them
+	for _, kind := range []struct {
+		name string
+		keys []string
+		add  func(batch *cache, id string)
+	}{
+		{"people", sortedKeys(entries.Person), func(batch *cache, id string) { batch.Person[id] = entries.Person[id] }},
+		{"programs", sortedKeys(entries.Program), func(batch *cache, id string) { batch.Program[id] = entries.Program[id] }},
+		{"metadata", sortedKeys(entries.Metadata), func(batch *cache, id string) { batch.Metadata[id] = entries.Metadata[id] }},
+	} {
+		for i := 0; i < len(kind.keys); i += migrateBatchSize {
+			end := i + migrateBatchSize
+			if end > len(kind.keys) {
+				end = len(kind.keys)
+			}
+
+			batch := newCache()
+			for _, id := range kind.keys[i:end] {
+				kind.add(batch, id)
+			}
+			if err := target.Cache.Import(batch, &target); err != nil {
+				return errors.Wrapf(err, "failed to migrate %s", kind.name)
+			}
+			fmt.Fprintf(w, "%-9s %d/%d\n", kind.name, end, len(kind.keys))
+		}
+	}
+
+	return errors.Wrap(target.Cache.Save(&target), "failed to save migrated cache")
+}
+
+// openCache opens the cache store of the configuration, the returned function closes it
+func (app *App) openCache() (func(), error) {
+	app.selectCacheStore()
+	if err := app.Cache.Open(app); err != nil {
+		return nil, errors.Wrap(err, "failed to open cache")
+	}
+
+	return func() {
+		if closer, ok := app.Cache.(io.Closer); ok {
+			closer.Close()
+		}
+	}, nil
+}
+
+// exportCache writes all entries of the opened cache to filename.
+// The export is a JSON cache file of any backend, it is written to a temporary file which replaces filename once it is complete.
+func (app *App) exportCache(filename string) error {
+	tmpFile := filename + ".tmp"
+	if err := app.writeCacheExport(tmpFile, strings.HasSuffix(filename, ".gz")); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+
+	if err := os.Rename(tmpFile, filename); err != nil {
+		os.Remove(tmpFile)
+		return errors.Wrap(err, "failed to rename temporary export file")
+	}
+	return nil
+}
+
+// writeCacheExport writes the export into filename
+func (app *App) writeCacheExport(filename string, compressed bool) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to create export file")
+	}
+	defer file.Close()
+
+	if err := app.writeCache(file, compressed); err != nil {
+		return err
+	}
+	return errors.Wrap(file.Close(), "failed to write export file")
+}
+
+// writeCache writes all entries of the opened cache to w as a JSON cache file
+func (app *App) writeCache(w io.Writer, compressed bool) error {
+	export, err := app.Cache.Export(app)
+	if err != nil {
+		return errors.Wrap(err, "failed to export cache")
+	}
+
+	app.Logger.WithFields(logrus.Fields{
+		"programs": len(export.Program),
+		"metadata": len(export.Metadata),
+		"channels": len(export.Channel),
+	}).Info("Exported cache")
+
+	return export.encode(w, compressed)
+}
+
+// importCache adds the entries of an exported cache file from r to the opened cache and saves it.
+// Compressed exports and exports of older schema versions are detected like cache files.
+// The caller holds the cache lock, it has to be taken before the cache is opened.
+func (app *App) importCache(r io.Reader) error {
+	src := &cache{}
+	if err := src.decode(r, app); err != nil {
+		return errors.Wrap(err, "failed to read cache export")
+	}
+	src.initMaps()
+
+	if err := app.Cache.Import(src, app); err != nil {
+		return errors.Wrap(err, "failed to import cache")
+	}
+
+	app.Logger.WithFields(logrus.Fields{
+		"programs": len(src.Program),
+		"metadata": len(src.Metadata),
+		"channels": len(src.Channel),
+	}).Info("Imported cache")
+
+	return app.Cache.Save(app)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+)
+
+// ErrCacheLocked is returned if another update holds the lock of the cache
+var ErrCacheLocked = errors.New("cache is locked by another update")
+
+// cacheLockFile returns the lock file of the cache, Redis caches are locked next to the configuration file
+func cacheLockFile(c config) string {
+	if isRedisURL(c.Files.Cache) {
+		return c.File + ".lock"
+	}
+	return c.Files.Cache + ".lock"
+}
+
+// lockCache locks the cache against concurrent updates, e.g. a cron job and a run started by the web UI.
+// The lock is an advisory file lock, the operating system releases it if the process dies.
+// Without a lock timeout a locked cache fails at once, otherwise the update waits up to the timeout.
+// The returned function releases the lock.
+func (app *App) lockCache(ctx context.Context) (func(), error) {
+	file := cacheLockFile(app.Config)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create cache directory")
+	}
+
+	lock := flock.New(file)
+
+	var locked bool
+	var err error
+	if timeout := app.Config.Options.CacheLockTimeout; timeout > 0 {
+		app.Logger.WithField("timeout", timeout).Debug("Waiting for the cache lock")
+
+		lockCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		locked, err = lock.TryLockContext(lockCtx, time.Second)
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = nil
+		}
+	} else {
+		locked, err = lock.TryLock()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to lock cache")
+	}
+
+	if !locked {
+		if pid, err := os.ReadFile(file); err == nil && len(pid) != 0 {
+			return nil, errors.Wrapf(ErrCacheLocked, "%s is locked by process %s", file, strings.TrimSpace(string(pid)))
+		}
+		return nil, errors.Wrapf(ErrCacheLocked, "%s", file)
+	}
+
+	// The PID only tells which process holds the lock, the lock itself is the flock of the file
+	if err := os.WriteFile(file, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		app.Logger.WithError(err).WithField("file", file).Warn("Failed to write PID into the cache lock file")
+	}
+
+	return func() {
+		if err := lock.Unlock(); err != nil {
+			app.Logger.WithError(err).WithField("file", file).Error("Failed to unlock cache")
+		}
+	}, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLockCache(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	app := &App{Logger: logger, Config: config{}}
+	app.Config.Files.Cache = filepath.Join(t.TempDir(), "guide_cache.json")
+
+	unlock, err := app.lockCache(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to lock cache: %v", err)
+	}
+
+	pid, _ := os.ReadFile(app.Config.Files.Cache + ".lock")
+	if strings.TrimSpace(string(pid)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Lock file contains %q, want the PID %d", pid, os.Getpid())
+	}
+
+	// A second update fails at once or after the lock timeout
+	if _, err := app.lockCache(context.Background()); !errors.Is(err, ErrCacheLocked) {
+		t.Errorf("lockCache() of a locked cache = %v, want ErrCacheLocked", err)
+	}
+	app.Config.Options.CacheLockTimeout = 100 * time.Millisecond
+	if _, err := app.lockCache(context.Background()); !errors.Is(err, ErrCacheLocked) {
+		t.Errorf("lockCache() after the lock timeout = %v, want ErrCacheLocked", err)
+	}
+
+	unlock()
+
+	unlock, err = app.lockCache(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to lock the unlocked cache: %v", err)
+	}
+	unlock()
+}
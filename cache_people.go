@@ -0,0 +1,87 @@
+package main
+
+// Person is a cast or crew member of the person store.
+// The same people appear in thousands of programs, so their names are stored once by person ID
+// and the programs only keep the ID, the role and the billing order.
+type Person struct {
+	Name   string `json:"name"`
+	NameID string `json:"nameId,omitempty"`
+}
+
+// CastMember is a cast entry of a program, the name is empty if the person is in the person store
+type CastMember struct {
+	BillingOrder  string `json:"billingOrder"`
+	CharacterName string `json:"characterName,omitempty"`
+	Name          string `json:"name,omitempty"`
+	NameID        string `json:"nameId,omitempty"`
+	PersonID      string `json:"personId,omitempty"`
+	Role          string `json:"role"`
+}
+
+// CrewMember is a crew entry of a program, the name is empty if the person is in the person store
+type CrewMember struct {
+	BillingOrder string `json:"billingOrder"`
+	Name         string `json:"name,omitempty"`
+	NameID       string `json:"nameId,omitempty"`
+	PersonID     string `json:"personId,omitempty"`
+	Role         string `json:"role"`
+}
+
+// splitPeople moves the names of the cast and crew into people, members without person ID keep their name
+func (p *G2GCache) splitPeople(people map[string]Person) {
+	for i, member := range p.Cast {
+		if len(member.PersonID) != 0 && len(member.Name) != 0 {
+			people[member.PersonID] = Person{Name: member.Name, NameID: member.NameID}
+			p.Cast[i].Name, p.Cast[i].NameID = "", ""
+		}
+	}
+	for i, member := range p.Crew {
+		if len(member.PersonID) != 0 && len(member.Name) != 0 {
+			people[member.PersonID] = Person{Name: member.Name, NameID: member.NameID}
+			p.Crew[i].Name, p.Crew[i].NameID = "", ""
+		}
+	}
+}
+
+// personIDs adds the IDs of the people the program refers to
+func (p G2GCache) personIDs(ids map[string]bool) {
+	for _, member := range p.Cast {
+		if len(member.PersonID) != 0 {
+			ids[member.PersonID] = true
+		}
+	}
+	for _, member := range p.Crew {
+		if len(member.PersonID) != 0 {
+			ids[member.PersonID] = true
+		}
+	}
+}
+
+// personName returns the name of a cast or crew member, programs cached before the person store keep the name themselves
+func (c *cache) personName(personID, name string) string {
+	if len(name) != 0 {
+		return name
+	}
+
+	c.programMu.RLock()
+	defer c.programMu.RUnlock()
+
+	return c.Person[personID].Name
+}
+
+// prunePeople removes the people no cached program refers to and returns their number, the caller holds the lock
+func (c *cache) prunePeople() int {
+	used := make(map[string]bool, len(c.Person))
+	for _, program := range c.Program {
+		program.personIDs(used)
+	}
+
+	pruned := 0
+	for id := range c.Person {
+		if !used[id] {
+			delete(c.Person, id)
+			pruned++
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,804 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const redisPrefix = "guide2go:"
+
+// redisMigrations upgrade the shared entries from the schema version of the key to the next version
+var redisMigrations = map[int]func(ctx context.Context, client *redis.Client) error{
+	// Programs and metadata expire with the key, broadcasts without download time are downloaded again by the next run
+	1: func(ctx context.Context, client *redis.Client) error { return nil },
+	// Last use and size are only used by the JSON cache
+	2: func(ctx context.Context, client *redis.Client) error { return nil },
+	// Cached programs keep the names of cast and crew until they expire
+	3: func(ctx context.Context, client *redis.Client) error { return nil },
+	// Shared programs expire with their TTL, the program retention doesn't apply to them
+	4: func(ctx context.Context, client *redis.Client) error { return nil },
+}
+
+// redisCache stores the cache in Redis.
+// Programs, metadata and people are shared by all instances, so an instance doesn't download what another one already has.
+// They expire after the TTL of their data type, Redis removes them by itself. People expire with the program TTL,
+// every download of a program with them renews it.
+// Channels, schedules and lineups depend on the configuration and are stored per configuration file name.
+//
+// Keys:
+//
+//	guide2go:version                     schema version of the cache
+//	guide2go:program:<programID>         program as JSON
+//	guide2go:metadata:<programID>        metadata as JSON
+//	guide2go:person:<personID>           cast or crew member as JSON
+//	guide2go:<config>:channels           hash station ID -> channel as JSON
+//	guide2go:<config>:schedule:<station> sorted set of broadcasts as JSON, scored by the air time
+//	guide2go:<config>:lineups            hash lineup ID -> modified timestamp of the last download
+type redisCache struct {
+	client    *redis.Client
+	url       string
+	namespace string
+
+	programTTL  time.Duration
+	metadataTTL time.Duration
+
+	stats cacheStats
+}
+
+// Open connects to the Redis server of the cache URL, an open connection to the same URL is reused
+func (r *redisCache) Open(app *App) error {
+	if len(app.Config.Files.Cache) == 0 {
+		return errors.New("cache file path not configured")
+	}
+
+	r.namespace = redisPrefix + filepath.Base(app.Config.File) + ":"
+	r.programTTL = app.Config.Options.CacheTTL.Programs
+	r.metadataTTL = app.Config.Options.CacheTTL.Metadata
+
+	if r.client != nil {
+		if r.url == app.Config.Files.Cache {
+			return nil
+		}
+		r.client.Close()
+		r.client = nil
+	}
+
+	opt, err := redis.ParseURL(app.Config.Files.Cache)
+	if err != nil {
+		return errors.Wrap(err, "invalid Redis cache URL")
+	}
+
+	client := redis.NewClient(opt)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return errors.Wrap(err, "failed to connect to Redis")
+	}
+	if err := migrateRedis(context.Background(), client, app); err != nil {
+		client.Close()
+		return err
+	}
+
+	r.client = client
+	r.url = app.Config.Files.Cache
+
+	app.Logger.WithFields(logrus.Fields{
+		"addr":      opt.Addr,
+		"namespace": r.namespace,
+	}).Debug("Opened Redis cache")
+	return nil
+}
+
+// migrateRedis upgrades the entries of an older schema version, the version is shared by all instances.
+// Servers with entries but without a version are version 1, servers of a newer version are not changed.
+func migrateRedis(ctx context.Context, client *redis.Client, app *App) error {
+	version, err := client.Get(ctx, redisPrefix+"version").Int()
+	if err == redis.Nil {
+		version = cacheSchemaVersion
+
+		if client.Scan(ctx, 0, redisPrefix+"*", 1000).Iterator().Next(ctx) {
+			version = 1
+		}
+	} else if err != nil {
+		return errors.Wrap(err, "failed to read cache schema version")
+	}
+	if err := checkCacheVersion(version); err != nil {
+		return err
+	}
+
+	for ; version < cacheSchemaVersion; version++ {
+		if err := redisMigrations[version](ctx, client); err != nil {
+			return errors.Wrapf(err, "failed to migrate cache from schema version %d", version)
+		}
+		app.Logger.WithField("version", version+1).Info("Migrated cache")
+	}
+
+	return errors.Wrap(client.Set(ctx, redisPrefix+"version", cacheSchemaVersion, 0).Err(), "failed to write cache schema version")
+}
+
+// Save has nothing to write, the entries are already stored when they are added
+func (r *redisCache) Save(app *App) error {
+	if r.client == nil {
+		return errors.New("cache not opened")
+	}
+	return nil
+}
+
+// Close closes the connection
+func (r *redisCache) Close() error {
+	if r.client == nil {
+		return nil
+	}
+
+	err := r.client.Close()
+	r.client = nil
+	return err
+}
+
+// Init has nothing to initialize
+func (r *redisCache) Init() {}
+
+// CleanUp removes past broadcasts and broadcasts older than the schedule TTL,
+// programs and metadata expire by themselves
+func (r *redisCache) CleanUp(app *App) {
+	if r.client == nil {
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	ttl := app.Config.Options.CacheTTL.Schedules
+	var expired int64
+
+	iter := r.client.Scan(ctx, 0, r.namespace+"schedule:*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		n, err := r.client.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10)).Result()
+		if err != nil {
+			app.Logger.WithError(err).Error("Failed to clean up cache")
+			return
+		}
+		expired += n
+
+		if ttl == 0 {
+			continue
+		}
+
+		members, err := r.client.ZRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			app.Logger.WithError(err).Error("Failed to clean up cache")
+			return
+		}
+
+		var remove []interface{}
+		for _, member := range members {
+			var broadcast G2GCache
+			if json.Unmarshal([]byte(member), &broadcast) == nil && cacheExpired(broadcast.Cached, ttl, now) {
+				remove = append(remove, member)
+			}
+		}
+		if len(remove) != 0 {
+			n, err := r.client.ZRem(ctx, key, remove...).Result()
+			if err != nil {
+				app.Logger.WithError(err).Error("Failed to clean up cache")
+				return
+			}
+			expired += n
+		}
+	}
+	if err := iter.Err(); err != nil {
+		app.Logger.WithError(err).Error("Failed to clean up cache")
+		return
+	}
+
+	app.Logger.WithField("expired", expired).Info("Cleaned up cache")
+}
+
+// AddStations adds station data to the cache
+func (r *redisCache) AddStations(ctx context.Context, data *[]byte, lineup string, app *App) error {
+	tmp := newCache()
+	if err := tmp.AddStations(ctx, data, lineup, app); err != nil {
+		return err
+	}
+	r.stats.added(data)
+
+	return r.store(ctx, tmp)
+}
+
+// LineupUnchanged reports whether the station map of a lineup has not been modified since the last download
+// and all configured channels of the lineup are cached
+func (r *redisCache) LineupUnchanged(lineup, modified string, app *App) bool {
+	ctx := context.Background()
+
+	cached, err := r.client.HGet(ctx, r.namespace+"lineups", lineup).Result()
+	if err != nil || len(modified) == 0 || cached != modified {
+		return false
+	}
+
+	for _, id := range app.Config.GetChannelList(lineup) {
+		if ok, err := r.client.HExists(ctx, r.namespace+"channels", id).Result(); err != nil || !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SetLineupModified stores the modified timestamp of a downloaded lineup
+func (r *redisCache) SetLineupModified(lineup, modified string) {
+	r.client.HSet(context.Background(), r.namespace+"lineups", lineup, modified)
+}
+
+// RemoveChannels removes channels from the cache which are no longer configured
+func (r *redisCache) RemoveChannels(app *App) {
+	ctx := context.Background()
+	channelIDs := app.Config.GetChannelList("")
+
+	ids, err := r.client.HKeys(ctx, r.namespace+"channels").Result()
+	if err != nil {
+		app.Logger.WithError(err).Error("Failed to remove channels from cache")
+		return
+	}
+
+	var remove []string
+	for _, id := range ids {
+		if ContainsString(channelIDs, id) == -1 {
+			remove = append(remove, id)
+		}
+	}
+
+	if len(remove) != 0 {
+		if err := r.client.HDel(ctx, r.namespace+"channels", remove...).Err(); err != nil {
+			app.Logger.WithError(err).Error("Failed to remove channels from cache")
+		}
+	}
+}
+
+// AddSchedule adds schedule data to the cache, a downloaded day replaces the cached broadcasts of the same time
+func (r *redisCache) AddSchedule(ctx context.Context, data *[]byte, app *App) error {
+	tmp := newCache()
+	if err := tmp.AddSchedule(ctx, data, app); err != nil {
+		return err
+	}
+	r.stats.added(data)
+
+	return r.store(ctx, tmp)
+}
+
+// AddProgram adds program data to the cache
+func (r *redisCache) AddProgram(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error {
+	defer wg.Done()
+
+	tmp := newCache()
+	var local sync.WaitGroup
+	local.Add(1)
+	if err := tmp.AddProgram(ctx, data, &local, app); err != nil {
+		return err
+	}
+	r.stats.added(data)
+
+	return r.store(ctx, tmp)
+}
+
+// GetAllProgramIDs returns the sorted IDs of the programs of the cached schedules
+func (r *redisCache) GetAllProgramIDs() []string {
+	return sortedKeys(r.scheduledPrograms())
+}
+
+// GetRequiredProgramIDs returns the programs of the cached schedules which are not cached or changed since
+func (r *redisCache) GetRequiredProgramIDs() []string {
+	return requiredProgramIDs(r)
+}
+
+// GetRequiredMetaIDs returns the root IDs of the scheduled programs with artwork which is not cached
+func (r *redisCache) GetRequiredMetaIDs() []string {
+	return requiredMetaIDs(r)
+}
+
+// scheduledPrograms returns the MD5 of Schedules Direct by program ID of the cached schedules of the configuration
+func (r *redisCache) scheduledPrograms() map[string]string {
+	ctx := context.Background()
+
+	scheduled := make(map[string]string)
+	iter := r.client.Scan(ctx, 0, r.namespace+"schedule:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		schedule, _ := r.client.ZRange(ctx, iter.Val(), 0, -1).Result()
+		for _, data := range schedule {
+			var broadcast G2GCache
+			if json.Unmarshal([]byte(data), &broadcast) == nil {
+				scheduled[broadcast.ProgramID] = broadcast.Md5
+			}
+		}
+	}
+	return scheduled
+}
+
+// programStates returns the state of the shared programs of ids, programs which can't be read are not cached
+func (r *redisCache) programStates(ids []string) map[string]programState {
+	ctx := context.Background()
+
+	cmds := make([]*redis.StringCmd, len(ids))
+	r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.Get(ctx, redisPrefix+"program:"+id)
+		}
+		return nil
+	})
+
+	states := make(map[string]programState)
+	for i, cmd := range cmds {
+		var state programState
+		if data, err := cmd.Bytes(); err == nil && json.Unmarshal(data, &state) == nil {
+			states[ids[i]] = state
+		}
+	}
+	return states
+}
+
+// cachedMetadata returns which of ids have shared artwork
+func (r *redisCache) cachedMetadata(ids []string) map[string]bool {
+	ctx := context.Background()
+
+	cmds := make([]*redis.IntCmd, len(ids))
+	r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.Exists(ctx, redisPrefix+"metadata:"+id)
+		}
+		return nil
+	})
+
+	cached := make(map[string]bool)
+	for i, cmd := range cmds {
+		cached[ids[i]] = cmd.Val() != 0
+	}
+	return cached
+}
+
+// MissingPrograms returns the program IDs which are not in the cache
+func (r *redisCache) MissingPrograms(ids []string) (missing []string) {
+	ctx := context.Background()
+
+	cmds := make([]*redis.IntCmd, len(ids))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.Exists(ctx, redisPrefix+"program:"+id)
+		}
+		return nil
+	})
+	if err != nil {
+		return ids
+	}
+
+	for i, cmd := range cmds {
+		if cmd.Val() == 0 {
+			missing = append(missing, ids[i])
+		}
+	}
+
+	return
+}
+
+// UseSeriesProgram caches the generic series program for an episode which could not be downloaded
+func (r *redisCache) UseSeriesProgram(episodeID, seriesID string) bool {
+	ctx := context.Background()
+
+	series, err := r.client.Get(ctx, redisPrefix+"program:"+seriesID).Bytes()
+	if err != nil {
+		return false
+	}
+
+	return r.client.Set(ctx, redisPrefix+"program:"+episodeID, series, r.programTTL).Err() == nil
+}
+
+// AddMetadata adds metadata to the cache
+func (r *redisCache) AddMetadata(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error {
+	defer wg.Done()
+
+	tmp := newCache()
+	var local sync.WaitGroup
+	local.Add(1)
+	if err := tmp.AddMetadata(ctx, data, &local, app); err != nil {
+		return err
+	}
+	r.stats.added(data)
+
+	return r.store(ctx, tmp)
+}
+
+// Export returns the entries of the configuration and all shared programs and metadata
+func (r *redisCache) Export(app *App) (*cache, error) {
+	ctx := context.Background()
+	if r.client == nil {
+		return nil, errors.New("cache not opened")
+	}
+
+	c := newCache()
+
+	channels, err := r.client.HGetAll(ctx, r.namespace+"channels").Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cache")
+	}
+	for id, data := range channels {
+		var channel G2GCache
+		if err := json.Unmarshal([]byte(data), &channel); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal channel %s", id)
+		}
+		c.Channel[id] = channel
+	}
+
+	if c.Lineup, err = r.client.HGetAll(ctx, r.namespace+"lineups").Result(); err != nil {
+		return nil, errors.Wrap(err, "failed to read cache")
+	}
+
+	iter := r.client.Scan(ctx, 0, r.namespace+"schedule:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		stationID := strings.TrimPrefix(iter.Val(), r.namespace+"schedule:")
+		schedule, err := r.client.ZRange(ctx, iter.Val(), 0, -1).Result()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read cache")
+		}
+		for _, data := range schedule {
+			var broadcast G2GCache
+			if err := json.Unmarshal([]byte(data), &broadcast); err != nil {
+				return nil, errors.Wrapf(err, "failed to unmarshal schedule of %s", stationID)
+			}
+			c.Schedule[stationID] = append(c.Schedule[stationID], broadcast)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read cache")
+	}
+
+	for kind, entries := range map[string]map[string]G2GCache{"program:": c.Program, "metadata:": c.Metadata} {
+		iter := r.client.Scan(ctx, 0, redisPrefix+kind+"*", 1000).Iterator()
+		for iter.Next(ctx) {
+			data, err := r.client.Get(ctx, iter.Val()).Bytes()
+			if err == redis.Nil {
+				// Expired since the scan
+				continue
+			} else if err != nil {
+				return nil, errors.Wrap(err, "failed to read cache")
+			}
+
+			var entry G2GCache
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return nil, errors.Wrapf(err, "failed to unmarshal cache entry %s", iter.Val())
+			}
+			entries[strings.TrimPrefix(iter.Val(), redisPrefix+kind)] = entry
+		}
+		if err := iter.Err(); err != nil {
+			return nil, errors.Wrap(err, "failed to read cache")
+		}
+	}
+
+	iter = r.client.Scan(ctx, 0, redisPrefix+"person:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		data, err := r.client.Get(ctx, iter.Val()).Bytes()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(err, "failed to read cache")
+		}
+
+		var person Person
+		if err := json.Unmarshal(data, &person); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal person %s", iter.Val())
+		}
+		c.Person[strings.TrimPrefix(iter.Val(), redisPrefix+"person:")] = person
+	}
+
+	return c, errors.Wrap(iter.Err(), "failed to read cache")
+}
+
+// Import adds all entries of c to the cache, entries with the same key are replaced
+func (r *redisCache) Import(c *cache, app *App) error {
+	return r.store(context.Background(), c)
+}
+
+// GetChannels returns the cached channels of the configuration
+func (r *redisCache) GetChannels(app *App) []G2GCache {
+	var channels []G2GCache
+	if r.client == nil {
+		return channels
+	}
+
+	values, err := r.client.HGetAll(context.Background(), r.namespace+"channels").Result()
+	if err != nil {
+		app.Logger.WithError(err).Error("Failed to read channels from cache")
+		return channels
+	}
+	for id, data := range values {
+		var channel G2GCache
+		if err := json.Unmarshal([]byte(data), &channel); err != nil {
+			app.Logger.WithError(err).WithField("stationID", id).Error("Failed to unmarshal channel")
+			continue
+		}
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// GetSchedule returns the cached broadcasts of the station sorted by the air time
+func (r *redisCache) GetSchedule(stationID string, app *App) []G2GCache {
+	var schedule []G2GCache
+	if r.client == nil {
+		return schedule
+	}
+
+	values, err := r.client.ZRange(context.Background(), r.namespace+"schedule:"+stationID, 0, -1).Result()
+	if err != nil {
+		app.Logger.WithError(err).WithField("stationID", stationID).Error("Failed to read schedule from cache")
+		return schedule
+	}
+	for _, data := range values {
+		var broadcast G2GCache
+		if err := json.Unmarshal([]byte(data), &broadcast); err != nil {
+			app.Logger.WithError(err).WithField("stationID", stationID).Error("Failed to unmarshal broadcast")
+			continue
+		}
+		schedule = append(schedule, broadcast)
+	}
+	return schedule
+}
+
+// Get data from cache, the entries of the program are loaded into a cache of their own
+// which formats them like the JSON cache
+func (r *redisCache) GetTitle(id, lang string, app *App) []Title {
+	return r.lookup(id, app).GetTitle(id, lang, app)
+}
+
+func (r *redisCache) GetSubTitle(id, lang string, app *App) SubTitle {
+	return r.lookup(id, app).GetSubTitle(id, lang, app)
+}
+
+func (r *redisCache) GetDescs(id, subTitle string, app *App) []Desc {
+	return r.lookup(id, app).GetDescs(id, subTitle, app)
+}
+
+func (r *redisCache) GetCredits(id string, app *App) Credits {
+	return r.lookup(id, app).GetCredits(id, app)
+}
+
+func (r *redisCache) GetCategory(id string, app *App) []Category {
+	return r.lookup(id, app).GetCategory(id, app)
+}
+
+func (r *redisCache) GetKeywords(id string, app *App) []Keyword {
+	return r.lookup(id, app).GetKeywords(id, app)
+}
+
+func (r *redisCache) GetContentAdvisory(id string, app *App) []string {
+	return r.lookup(id, app).GetContentAdvisory(id, app)
+}
+
+func (r *redisCache) GetEpisodeNum(id string, app *App) []EpisodeNum {
+	return r.lookup(id, app).GetEpisodeNum(id, app)
+}
+
+func (r *redisCache) GetIcon(id string, app *App) []Icon {
+	return r.lookup(id, app).GetIcon(id, app)
+}
+
+func (r *redisCache) GetRating(id, countryCode string, app *App) []Rating {
+	return r.lookup(id, app).GetRating(id, countryCode, app)
+}
+
+func (r *redisCache) GetStarRating(id string, app *App) []StarRating {
+	return r.lookup(id, app).GetStarRating(id, app)
+}
+
+func (r *redisCache) GetPreviouslyShown(id string, app *App) *PreviouslyShown {
+	return r.lookup(id, app).GetPreviouslyShown(id, app)
+}
+
+// GetStats returns the number of entries per data type and the lookup counters,
+// programs and metadata are counted for all instances
+func (r *redisCache) GetStats() map[string]interface{} {
+	ctx := context.Background()
+	stats := make(map[string]interface{})
+	if r.client == nil {
+		return stats
+	}
+
+	count := func(pattern string, fn func(key string)) int {
+		n := 0
+		iter := r.client.Scan(ctx, 0, pattern, 1000).Iterator()
+		for iter.Next(ctx) {
+			if fn != nil {
+				fn(iter.Val())
+			}
+			n++
+		}
+		return n
+	}
+
+	broadcasts := int64(0)
+	stats["programs"] = count(redisPrefix+"program:*", nil)
+	stats["metadata"] = count(redisPrefix+"metadata:*", nil)
+	stats["people"] = count(redisPrefix+"person:*", nil)
+	stats["schedule"] = count(r.namespace+"schedule:*", func(key string) {
+		broadcasts += r.client.ZCard(ctx, key).Val()
+	})
+	stats["broadcasts"] = broadcasts
+	stats["channels"] = r.client.HLen(ctx, r.namespace+"channels").Val()
+	stats["version"], _ = r.client.Get(ctx, redisPrefix+"version").Int()
+
+	r.stats.report(stats)
+	return stats
+}
+
+// Inspect returns the cached entries of a program, series or station ID by data type
+func (r *redisCache) Inspect(id string, app *App) map[string]interface{} {
+	ctx := context.Background()
+
+	c := newCache()
+	for _, key := range []string{id, truncateID(id)} {
+		found := r.lookup(key, app)
+		for k, v := range found.Program {
+			c.Program[k] = v
+		}
+		for k, v := range found.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+
+	var channel G2GCache
+	if data, err := r.client.HGet(ctx, r.namespace+"channels", id).Bytes(); err == nil && json.Unmarshal(data, &channel) == nil {
+		c.Channel[id] = channel
+	}
+
+	schedule, _ := r.client.ZRange(ctx, r.namespace+"schedule:"+id, 0, -1).Result()
+	for _, data := range schedule {
+		var broadcast G2GCache
+		if json.Unmarshal([]byte(data), &broadcast) == nil {
+			c.Schedule[id] = append(c.Schedule[id], broadcast)
+		}
+	}
+
+	return c.Inspect(id, app)
+}
+
+// lookup returns a cache with the program and the metadata of id
+func (r *redisCache) lookup(id string, app *App) *cache {
+	c := newCache()
+
+	values, err := r.client.MGet(context.Background(), redisPrefix+"program:"+id, redisPrefix+"metadata:"+id).Result()
+	if err != nil {
+		app.Logger.WithError(err).WithField("programID", id).Error("Failed to read cache")
+		return c
+	}
+
+	for i, entries := range []map[string]G2GCache{c.Program, c.Metadata} {
+		data, ok := values[i].(string)
+		if !ok {
+			continue
+		}
+
+		var entry G2GCache
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			app.Logger.WithError(err).WithField("programID", id).Error("Failed to unmarshal cache entry")
+			continue
+		}
+		entries[id] = entry
+	}
+
+	// Cast and crew of the program
+	ids := make(map[string]bool)
+	for _, program := range c.Program {
+		program.personIDs(ids)
+	}
+	if len(ids) != 0 {
+		keys := make([]string, 0, len(ids))
+		for personID := range ids {
+			keys = append(keys, redisPrefix+"person:"+personID)
+		}
+
+		people, err := r.client.MGet(context.Background(), keys...).Result()
+		if err != nil {
+			app.Logger.WithError(err).WithField("programID", id).Error("Failed to read cast and crew")
+		}
+		for i, value := range people {
+			var person Person
+			if data, ok := value.(string); ok && json.Unmarshal([]byte(data), &person) == nil {
+				c.Person[strings.TrimPrefix(keys[i], redisPrefix+"person:")] = person
+			}
+		}
+	}
+
+	r.stats.lookup(len(c.Program) != 0 || len(c.Metadata) != 0)
+	return c
+}
+
+// store writes all entries of c in one transaction
+func (r *redisCache) store(ctx context.Context, c *cache) error {
+	return r.update(ctx, func(pipe redis.Pipeliner) error {
+		for id, channel := range c.Channel {
+			data, err := json.Marshal(channel)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal cache entry")
+			}
+			pipe.HSet(ctx, r.namespace+"channels", id, data)
+		}
+
+		for stationID, schedule := range c.Schedule {
+			key := r.namespace + "schedule:" + stationID
+			for _, broadcast := range schedule {
+				data, err := json.Marshal(broadcast)
+				if err != nil {
+					return errors.Wrap(err, "failed to marshal cache entry")
+				}
+
+				score := strconv.FormatInt(broadcast.AirDateTime.Unix(), 10)
+				pipe.ZRemRangeByScore(ctx, key, score, score)
+				pipe.ZAdd(ctx, key, redis.Z{Score: float64(broadcast.AirDateTime.Unix()), Member: data})
+			}
+		}
+
+		if err := setAll(ctx, pipe, "program:", c.Program, r.programTTL); err != nil {
+			return err
+		}
+		if err := setAll(ctx, pipe, "metadata:", c.Metadata, r.metadataTTL); err != nil {
+			return err
+		}
+
+		for lineup, modified := range c.Lineup {
+			pipe.HSet(ctx, r.namespace+"lineups", lineup, modified)
+		}
+
+		for id, person := range c.Person {
+			data, err := json.Marshal(person)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal person")
+			}
+			pipe.Set(ctx, redisPrefix+"person:"+id, data, r.programTTL)
+		}
+		return nil
+	})
+}
+
+// setAll stores the shared entries, they expire after ttl from their download time, a ttl of 0 never expires
+func setAll(ctx context.Context, pipe redis.Pipeliner, kind string, entries map[string]G2GCache, ttl time.Duration) error {
+	now := time.Now()
+	for id, entry := range entries {
+		expiration := ttl
+		if ttl > 0 && !entry.Cached.IsZero() {
+			// Imported entries keep their download time, Redis expires keys in seconds
+			if expiration = entry.Cached.Add(ttl).Sub(now).Round(time.Second); expiration <= 0 {
+				continue
+			}
+		}
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal cache entry")
+		}
+		pipe.Set(ctx, redisPrefix+kind+id, data, expiration)
+	}
+	return nil
+}
+
+// update sends the commands of fn in one transaction
+func (r *redisCache) update(ctx context.Context, fn func(pipe redis.Pipeliner) error) error {
+	if r.client == nil {
+		return errors.New("cache not opened")
+	}
+
+	if _, err := r.client.TxPipelined(ctx, fn); err != nil {
+		return errors.Wrap(err, "failed to update cache")
+	}
+	return nil
+}
+
+// isRedisURL reports whether the cache file is the URL of a Redis server
+func isRedisURL(file string) bool {
+	return strings.HasPrefix(file, "redis://") || strings.HasPrefix(file, "rediss://")
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisCache(t *testing.T) {
+	srv := miniredis.RunT(t)
+	testCacheStore(t, "redis://"+srv.Addr()+"/0", func() CacheStore { return &redisCache{} })
+
+	if !srv.Exists("guide2go:program:SH012345670000") || srv.TTL("guide2go:program:SH012345670000") != defaultProgramTTL {
+		t.Error("Program is not stored as shared entry with the program TTL")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cacheSchemaVersion is the version of the cache layout, it is stored in the cache of every backend.
+// Older caches are migrated when they are opened, every change of the layout adds a version and a migration.
+//
+//	1  first layout, caches without a version
+//	2  download time of every schedule, program and metadata entry for the cache TTLs
+//	3  last use and size of programs and metadata for the LRU eviction of the JSON cache
+//	4  names of cast and crew in the person store, programs refer to them by person ID
+//	5  last use of programs in the SQLite cache for the program retention
+const cacheSchemaVersion = 5
+
+// ErrCacheVersion is returned for a cache written by a newer version of guide2go, it is neither read nor changed
+var ErrCacheVersion = errors.New("cache was written by a newer version of guide2go")
+
+// checkCacheVersion returns an error for caches of a newer schema version
+func checkCacheVersion(version int) error {
+	if version > cacheSchemaVersion {
+		return errors.Wrapf(ErrCacheVersion, "schema version %d, supported up to %d", version, cacheSchemaVersion)
+	}
+	return nil
+}
+
+// stampEntries sets the download time of entries without one to now and returns their number.
+// Entries of caches before version 2 have no download time, without it they would expire at once.
+func stampEntries(entries map[string]G2GCache, now time.Time) int {
+	stamped := 0
+	for id, entry := range entries {
+		if entry.Cached.IsZero() {
+			entry.Cached = now
+			entries[id] = entry
+			stamped++
+		}
+	}
+	return stamped
+}
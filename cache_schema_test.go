@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newMigrationApp(t *testing.T, file string) *App {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	app := &App{Logger: logger, Config: config{}}
+	app.Config.Files.Cache = filepath.Join(t.TempDir(), file)
+	app.Config.Options.CacheTTL.Programs = defaultProgramTTL
+	return app
+}
+
+func TestMigrateJSONCache(t *testing.T) {
+	app := newMigrationApp(t, "guide_cache.json")
+
+	// Cache files before the schema version have no download times
+	legacy := `{"Program":{"SH012345670000":{"showType":"Series","cast":[{"personId":"123","name":"Jane Doe","role":"Actor"}]}},"Schedule":{"10001":[{"programID":"SH012345670000","airDateTime":"2030-01-01T20:00:00Z"}]}}`
+	if err := os.WriteFile(app.Config.Files.Cache, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cache{}
+	if err := c.Open(app); err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	if c.Version != cacheSchemaVersion {
+		t.Errorf("Version = %d, want %d", c.Version, cacheSchemaVersion)
+	}
+	if c.Program["SH012345670000"].Cached.IsZero() || c.Schedule["10001"][0].Cached.IsZero() {
+		t.Error("Migration did not set the download time of the entries")
+	}
+	if p := c.Program["SH012345670000"]; p.Size == 0 || !p.Used.Equal(p.Cached) {
+		t.Errorf("Migration did not set size and last use of the program: %+v", p)
+	}
+	if c.Person["123"].Name != "Jane Doe" || c.Program["SH012345670000"].Cast[0].Name != "" {
+		t.Errorf("Migration did not move the cast into the person store: %v", c.Person)
+	}
+
+	if err := os.WriteFile(app.Config.Files.Cache, []byte(`{"Version":99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&cache{}).Open(app); !errors.Is(err, ErrCacheVersion) {
+		t.Errorf("Open() of a newer cache = %v, want ErrCacheVersion", err)
+	}
+}
+
+func TestMigrateSQLiteCache(t *testing.T) {
+	app := newMigrationApp(t, "guide_cache.db")
+
+	// Schema of version 1
+	db, err := sql.Open("sqlite", app.Config.Files.Cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE channels (station_id TEXT PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE schedules (station_id TEXT NOT NULL, air_date_time INTEGER NOT NULL, program_id TEXT NOT NULL, data BLOB NOT NULL, PRIMARY KEY (station_id, air_date_time));
+CREATE TABLE programs (program_id TEXT PRIMARY KEY, original_air_date TEXT NOT NULL, data BLOB NOT NULL);
+CREATE INDEX programs_original_air_date ON programs (original_air_date);
+CREATE TABLE metadata (program_id TEXT PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE lineups (lineup TEXT PRIMARY KEY, modified TEXT NOT NULL);
+INSERT INTO programs VALUES ('SH012345670000', '2001-01-01', '{"showType":"Series"}');`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &sqliteCache{}
+	if err := s.Open(app); err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer s.Close()
+
+	if stats := s.GetStats(); stats["version"] != cacheSchemaVersion || stats["programs"] != 1 {
+		t.Errorf("GetStats() = %v, want version %d and the migrated program", stats, cacheSchemaVersion)
+	}
+
+	// The migrated program is kept for the program TTL
+	s.CleanUp(app)
+	if missing := s.MissingPrograms([]string{"SH012345670000"}); len(missing) != 0 {
+		t.Error("CleanUp() removed the migrated program")
+	}
+}
+
+func TestMigrateBoltCache(t *testing.T) {
+	app := newMigrationApp(t, "guide_cache.bolt")
+
+	// Buckets of version 1 without the meta bucket
+	db, err := bolt.Open(app.Config.Files.Cache, 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		programs, err := tx.CreateBucket(boltPrograms)
+		if err != nil {
+			return err
+		}
+		return programs.Put([]byte("SH012345670000"), []byte(`{"showType":"Series"}`))
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &boltCache{}
+	if err := b.Open(app); err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	defer b.Close()
+
+	if version := b.GetStats()["version"]; version != cacheSchemaVersion {
+		t.Errorf("Version = %v, want %d", version, cacheSchemaVersion)
+	}
+	b.CleanUp(app)
+	if missing := b.MissingPrograms([]string{"SH012345670000"}); len(missing) != 0 {
+		t.Error("CleanUp() removed the migrated program")
+	}
+}
@@ -0,0 +1,853 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the tables of the SQLite cache, the data columns hold the G2GCache entries as JSON,
+// the cached columns their download time and the used column the last download of a schedule with the program as Unix timestamp
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS channels (
+	station_id TEXT PRIMARY KEY,
+	data       BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS schedules (
+	station_id    TEXT NOT NULL,
+	air_date_time INTEGER NOT NULL,
+	program_id    TEXT NOT NULL,
+	cached        INTEGER NOT NULL,
+	data          BLOB NOT NULL,
+	PRIMARY KEY (station_id, air_date_time)
+);
+CREATE INDEX IF NOT EXISTS schedules_air_date_time ON schedules (air_date_time);
+CREATE INDEX IF NOT EXISTS schedules_program_id ON schedules (program_id);
+CREATE TABLE IF NOT EXISTS programs (
+	program_id TEXT PRIMARY KEY,
+	cached     INTEGER NOT NULL,
+	used       INTEGER NOT NULL DEFAULT 0,
+	data       BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS programs_cached ON programs (cached);
+CREATE INDEX IF NOT EXISTS programs_used ON programs (used);
+CREATE TABLE IF NOT EXISTS metadata (
+	program_id TEXT PRIMARY KEY,
+	cached     INTEGER NOT NULL,
+	data       BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS metadata_cached ON metadata (cached);
+CREATE TABLE IF NOT EXISTS lineups (
+	lineup   TEXT PRIMARY KEY,
+	modified TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS people (
+	person_id TEXT PRIMARY KEY,
+	name      TEXT NOT NULL,
+	name_id   TEXT NOT NULL
+);
+`
+
+// sqliteMigrations upgrade the database from the schema version of the key to the next version
+var sqliteMigrations = map[int]string{
+	// The download time replaces the original air date, which removed programs by their age
+	1: `
+DROP INDEX IF EXISTS programs_original_air_date;
+ALTER TABLE programs DROP COLUMN original_air_date;
+ALTER TABLE programs ADD COLUMN cached INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE schedules ADD COLUMN cached INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE metadata ADD COLUMN cached INTEGER NOT NULL DEFAULT 0;
+UPDATE programs SET cached = strftime('%s', 'now');
+UPDATE schedules SET cached = strftime('%s', 'now');
+UPDATE metadata SET cached = strftime('%s', 'now');
+`,
+	// Last use and size are only used by the JSON cache
+	2: "",
+	// The people table is created by the schema, cached programs keep the names until they are downloaded again
+	3: "",
+	// Cached programs were last used when they were downloaded
+	4: `
+ALTER TABLE programs ADD COLUMN used INTEGER NOT NULL DEFAULT 0;
+UPDATE programs SET used = cached;
+`,
+}
+
+// sqliteCache stores the cache in a SQLite database.
+// Entries are written when they are added and read when the XMLTV file is created,
+// so the cache is never loaded into memory as a whole.
+type sqliteCache struct {
+	db   *sql.DB
+	path string
+
+	stats cacheStats
+}
+
+// Open opens the database and creates the tables, an open database of the same file is reused
+func (s *sqliteCache) Open(app *App) error {
+	if len(app.Config.Files.Cache) == 0 {
+		return errors.New("cache file path not configured")
+	}
+
+	if s.db != nil {
+		if s.path == app.Config.Files.Cache {
+			return nil
+		}
+		s.db.Close()
+		s.db = nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(app.Config.Files.Cache), 0755); err != nil {
+		return errors.Wrap(err, "failed to create cache directory")
+	}
+
+	db, err := sql.Open("sqlite", app.Config.Files.Cache+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return errors.Wrap(err, "failed to open cache database")
+	}
+	// SQLite allows a single writer, concurrent downloads wait for the connection instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(db, app); err != nil {
+		db.Close()
+		return err
+	}
+
+	s.db = db
+	s.path = app.Config.Files.Cache
+
+	app.Logger.WithField("path", s.path).Debug("Opened SQLite cache")
+	return nil
+}
+
+// migrateSQLite upgrades a database of an older schema version and creates the missing tables.
+// The version is the user_version of the database, databases with tables but without a version are version 1.
+// Databases of a newer version are not changed.
+func migrateSQLite(db *sql.DB, app *App) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return errors.Wrap(err, "failed to read cache schema version")
+	}
+	if version == 0 {
+		version = cacheSchemaVersion
+
+		var tables int
+		if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'programs'").Scan(&tables); err == nil && tables != 0 {
+			version = 1
+		}
+	}
+	if err := checkCacheVersion(version); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin cache transaction")
+	}
+	defer tx.Rollback()
+
+	for ; version < cacheSchemaVersion; version++ {
+		if migration := sqliteMigrations[version]; len(migration) != 0 {
+			if _, err := tx.Exec(migration); err != nil {
+				return errors.Wrapf(err, "failed to migrate cache from schema version %d", version)
+			}
+		}
+		app.Logger.WithField("version", version+1).Info("Migrated cache")
+	}
+
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		return errors.Wrap(err, "failed to create cache tables")
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", cacheSchemaVersion)); err != nil {
+		return errors.Wrap(err, "failed to write cache schema version")
+	}
+
+	return errors.Wrap(tx.Commit(), "failed to commit cache transaction")
+}
+
+// Save writes the WAL into the database file, the entries are already stored when they are added
+func (s *sqliteCache) Save(app *App) error {
+	if s.db == nil {
+		return errors.New("cache database not opened")
+	}
+
+	if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return errors.Wrap(err, "failed to save cache database")
+	}
+
+	return nil
+}
+
+// Close closes the database
+func (s *sqliteCache) Close() error {
+	if s.db == nil {
+		return nil
+	}
+
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// Init has nothing to initialize, the tables are created by Open
+func (s *sqliteCache) Init() {}
+
+// CleanUp removes past broadcasts and entries older than the TTL of their data type
+func (s *sqliteCache) CleanUp(app *App) {
+	if s.db == nil {
+		return
+	}
+
+	now := time.Now()
+	ttl := app.Config.Options.CacheTTL
+	var expired int64
+
+	err := s.update(func(tx *sql.Tx) error {
+		for _, table := range []struct {
+			name string
+			ttl  time.Duration
+		}{{"schedules", ttl.Schedules}, {"programs", ttl.Programs}, {"metadata", ttl.Metadata}} {
+			// A TTL of 0 never expires, past broadcasts are always removed
+			var where []string
+			var args []interface{}
+			if table.ttl > 0 {
+				where = append(where, "cached <= ?")
+				args = append(args, now.Add(-table.ttl).Unix())
+			}
+			if table.name == "schedules" {
+				where = append(where, "air_date_time <= ?")
+				args = append(args, now.Unix())
+			}
+			if len(where) == 0 {
+				continue
+			}
+
+			res, err := tx.Exec("DELETE FROM "+table.name+" WHERE "+strings.Join(where, " OR "), args...)
+			if err != nil {
+				return err
+			}
+			n, _ := res.RowsAffected()
+			expired += n
+		}
+
+		// Programs which no cached schedule refers to are removed once they were not used for the retention
+		if retention := app.Config.Options.ProgramRetention; retention > 0 {
+			res, err := tx.Exec("DELETE FROM programs WHERE used <= ? AND program_id NOT IN (SELECT program_id FROM schedules)", now.Add(-retention).Unix())
+			if err != nil {
+				return err
+			}
+			n, _ := res.RowsAffected()
+			expired += n
+		}
+
+		// People are kept as long as a program refers to them
+		return prunePeopleSQLite(tx)
+	})
+	if err != nil {
+		app.Logger.WithError(err).Error("Failed to clean up cache")
+		return
+	}
+
+	app.Logger.WithField("expired", expired).Info("Cleaned up cache")
+}
+
+// AddStations adds station data to the cache
+func (s *sqliteCache) AddStations(ctx context.Context, data *[]byte, lineup string, app *App) error {
+	tmp := newCache()
+	if err := tmp.AddStations(ctx, data, lineup, app); err != nil {
+		return err
+	}
+	s.stats.added(data)
+
+	return s.store(tmp)
+}
+
+// LineupUnchanged reports whether the station map of a lineup has not been modified since the last download
+// and all configured channels of the lineup are cached
+func (s *sqliteCache) LineupUnchanged(lineup, modified string, app *App) bool {
+	var cached string
+	if err := s.db.QueryRow("SELECT modified FROM lineups WHERE lineup = ?", lineup).Scan(&cached); err != nil {
+		return false
+	}
+
+	if len(modified) == 0 || cached != modified {
+		return false
+	}
+
+	for _, id := range app.Config.GetChannelList(lineup) {
+		var exists int
+		if err := s.db.QueryRow("SELECT 1 FROM channels WHERE station_id = ?", id).Scan(&exists); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SetLineupModified stores the modified timestamp of a downloaded lineup
+func (s *sqliteCache) SetLineupModified(lineup, modified string) {
+	s.db.Exec("INSERT OR REPLACE INTO lineups (lineup, modified) VALUES (?, ?)", lineup, modified)
+}
+
+// RemoveChannels removes channels from the cache which are no longer configured
+func (s *sqliteCache) RemoveChannels(app *App) {
+	channelIDs := app.Config.GetChannelList("")
+
+	err := s.update(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT station_id FROM channels")
+		if err != nil {
+			return err
+		}
+
+		var remove []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			if ContainsString(channelIDs, id) == -1 {
+				remove = append(remove, id)
+			}
+		}
+		rows.Close()
+
+		for _, id := range remove {
+			if _, err := tx.Exec("DELETE FROM channels WHERE station_id = ?", id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		app.Logger.WithError(err).Error("Failed to remove channels from cache")
+	}
+}
+
+// AddSchedule adds schedule data to the cache, a downloaded day replaces the cached broadcasts of the same time
+func (s *sqliteCache) AddSchedule(ctx context.Context, data *[]byte, app *App) error {
+	tmp := newCache()
+	if err := tmp.AddSchedule(ctx, data, app); err != nil {
+		return err
+	}
+	s.stats.added(data)
+
+	return s.store(tmp)
+}
+
+// AddProgram adds program data to the cache
+func (s *sqliteCache) AddProgram(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error {
+	defer wg.Done()
+
+	tmp := newCache()
+	var local sync.WaitGroup
+	local.Add(1)
+	if err := tmp.AddProgram(ctx, data, &local, app); err != nil {
+		return err
+	}
+	s.stats.added(data)
+
+	return s.store(tmp)
+}
+
+// GetAllProgramIDs returns the sorted IDs of the programs of the cached schedules
+func (s *sqliteCache) GetAllProgramIDs() []string {
+	return sortedKeys(s.scheduledPrograms())
+}
+
+// GetRequiredProgramIDs returns the programs of the cached schedules which are not cached or changed since
+func (s *sqliteCache) GetRequiredProgramIDs() []string {
+	return requiredProgramIDs(s)
+}
+
+// GetRequiredMetaIDs returns the root IDs of the scheduled programs with artwork which is not cached
+func (s *sqliteCache) GetRequiredMetaIDs() []string {
+	return requiredMetaIDs(s)
+}
+
+// scheduledPrograms returns the MD5 of Schedules Direct by program ID of the cached schedules
+func (s *sqliteCache) scheduledPrograms() map[string]string {
+	scheduled := make(map[string]string)
+	s.query("SELECT program_id, data FROM schedules", func(key string, entry G2GCache) { scheduled[key] = entry.Md5 })
+	return scheduled
+}
+
+// programStates returns the state of the cached programs of ids, programs which can't be read are not cached
+func (s *sqliteCache) programStates(ids []string) map[string]programState {
+	states := make(map[string]programState)
+	for _, id := range ids {
+		var data []byte
+		var state programState
+		if s.db.QueryRow("SELECT data FROM programs WHERE program_id = ?", id).Scan(&data) == nil && json.Unmarshal(data, &state) == nil {
+			states[id] = state
+		}
+	}
+	return states
+}
+
+// cachedMetadata returns which of ids have cached artwork
+func (s *sqliteCache) cachedMetadata(ids []string) map[string]bool {
+	cached := make(map[string]bool)
+	for _, id := range ids {
+		var exists int
+		cached[id] = s.db.QueryRow("SELECT 1 FROM metadata WHERE program_id = ?", id).Scan(&exists) == nil
+	}
+	return cached
+}
+
+// MissingPrograms returns the program IDs which are not in the cache
+func (s *sqliteCache) MissingPrograms(ids []string) (missing []string) {
+	for _, id := range ids {
+		var exists int
+		if err := s.db.QueryRow("SELECT 1 FROM programs WHERE program_id = ?", id).Scan(&exists); err != nil {
+			missing = append(missing, id)
+		}
+	}
+
+	return
+}
+
+// UseSeriesProgram caches the generic series program for an episode which could not be downloaded
+func (s *sqliteCache) UseSeriesProgram(episodeID, seriesID string) bool {
+	res, err := s.db.Exec(`INSERT OR REPLACE INTO programs (program_id, cached, used, data)
+		SELECT ?, cached, used, data FROM programs WHERE program_id = ?`, episodeID, seriesID)
+	if err != nil {
+		return false
+	}
+
+	n, _ := res.RowsAffected()
+	return n != 0
+}
+
+// AddMetadata adds metadata to the cache
+func (s *sqliteCache) AddMetadata(ctx context.Context, data *[]byte, wg *sync.WaitGroup, app *App) error {
+	defer wg.Done()
+
+	tmp := newCache()
+	var local sync.WaitGroup
+	local.Add(1)
+	if err := tmp.AddMetadata(ctx, data, &local, app); err != nil {
+		return err
+	}
+	s.stats.added(data)
+
+	return s.store(tmp)
+}
+
+// Export returns all entries of the database
+func (s *sqliteCache) Export(app *App) (*cache, error) {
+	if s.db == nil {
+		return nil, errors.New("cache database not opened")
+	}
+
+	c := newCache()
+	for query, add := range map[string]func(key string, entry G2GCache){
+		"SELECT station_id, data FROM channels":                                     func(key string, entry G2GCache) { c.Channel[key] = entry },
+		"SELECT station_id, data FROM schedules ORDER BY station_id, air_date_time": func(key string, entry G2GCache) { c.Schedule[key] = append(c.Schedule[key], entry) },
+		"SELECT program_id, data FROM programs":                                     func(key string, entry G2GCache) { c.Program[key] = entry },
+		"SELECT program_id, data FROM metadata":                                     func(key string, entry G2GCache) { c.Metadata[key] = entry },
+	} {
+		if err := s.query(query, add); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := s.db.Query("SELECT lineup, modified FROM lineups")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cache")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lineup, modified string
+		if err := rows.Scan(&lineup, &modified); err != nil {
+			return nil, errors.Wrap(err, "failed to read cache")
+		}
+		c.Lineup[lineup] = modified
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read cache")
+	}
+
+	people, err := s.db.Query("SELECT person_id, name, name_id FROM people")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cache")
+	}
+	defer people.Close()
+
+	for people.Next() {
+		var id string
+		var person Person
+		if err := people.Scan(&id, &person.Name, &person.NameID); err != nil {
+			return nil, errors.Wrap(err, "failed to read cache")
+		}
+		c.Person[id] = person
+	}
+
+	return c, errors.Wrap(people.Err(), "failed to read cache")
+}
+
+// Import adds all entries of c to the database, entries with the same key are replaced
+func (s *sqliteCache) Import(c *cache, app *App) error {
+	return s.store(c)
+}
+
+// GetChannels returns the cached channels
+func (s *sqliteCache) GetChannels(app *App) []G2GCache {
+	var channels []G2GCache
+	if s.db == nil {
+		return channels
+	}
+
+	if err := s.query("SELECT station_id, data FROM channels", func(key string, entry G2GCache) { channels = append(channels, entry) }); err != nil {
+		app.Logger.WithError(err).Error("Failed to read channels from cache")
+	}
+	return channels
+}
+
+// GetSchedule returns the cached broadcasts of the station sorted by the air time
+func (s *sqliteCache) GetSchedule(stationID string, app *App) []G2GCache {
+	var schedule []G2GCache
+	if s.db == nil {
+		return schedule
+	}
+
+	err := s.query("SELECT station_id, data FROM schedules WHERE station_id = ? ORDER BY air_date_time", func(key string, entry G2GCache) {
+		schedule = append(schedule, entry)
+	}, stationID)
+	if err != nil {
+		app.Logger.WithError(err).WithField("stationID", stationID).Error("Failed to read schedule from cache")
+	}
+	return schedule
+}
+
+// Get data from cache, the entries of the program are loaded into a cache of their own
+// which formats them like the JSON cache
+func (s *sqliteCache) GetTitle(id, lang string, app *App) []Title {
+	return s.lookup(id, app).GetTitle(id, lang, app)
+}
+
+func (s *sqliteCache) GetSubTitle(id, lang string, app *App) SubTitle {
+	return s.lookup(id, app).GetSubTitle(id, lang, app)
+}
+
+func (s *sqliteCache) GetDescs(id, subTitle string, app *App) []Desc {
+	return s.lookup(id, app).GetDescs(id, subTitle, app)
+}
+
+func (s *sqliteCache) GetCredits(id string, app *App) Credits {
+	return s.lookup(id, app).GetCredits(id, app)
+}
+
+func (s *sqliteCache) GetCategory(id string, app *App) []Category {
+	return s.lookup(id, app).GetCategory(id, app)
+}
+
+func (s *sqliteCache) GetKeywords(id string, app *App) []Keyword {
+	return s.lookup(id, app).GetKeywords(id, app)
+}
+
+func (s *sqliteCache) GetContentAdvisory(id string, app *App) []string {
+	return s.lookup(id, app).GetContentAdvisory(id, app)
+}
+
+func (s *sqliteCache) GetEpisodeNum(id string, app *App) []EpisodeNum {
+	return s.lookup(id, app).GetEpisodeNum(id, app)
+}
+
+func (s *sqliteCache) GetIcon(id string, app *App) []Icon {
+	return s.lookup(id, app).GetIcon(id, app)
+}
+
+func (s *sqliteCache) GetRating(id, countryCode string, app *App) []Rating {
+	return s.lookup(id, app).GetRating(id, countryCode, app)
+}
+
+func (s *sqliteCache) GetStarRating(id string, app *App) []StarRating {
+	return s.lookup(id, app).GetStarRating(id, app)
+}
+
+func (s *sqliteCache) GetPreviouslyShown(id string, app *App) *PreviouslyShown {
+	return s.lookup(id, app).GetPreviouslyShown(id, app)
+}
+
+// GetStats returns the number of entries per data type and the lookup counters
+func (s *sqliteCache) GetStats() map[string]interface{} {
+	stats := make(map[string]interface{})
+	if s.db == nil {
+		return stats
+	}
+
+	for key, query := range map[string]string{
+		"channels":   "SELECT COUNT(*) FROM channels",
+		"programs":   "SELECT COUNT(*) FROM programs",
+		"metadata":   "SELECT COUNT(*) FROM metadata",
+		"schedule":   "SELECT COUNT(DISTINCT station_id) FROM schedules",
+		"broadcasts": "SELECT COUNT(*) FROM schedules",
+		"people":     "SELECT COUNT(*) FROM people",
+		"version":    "PRAGMA user_version",
+	} {
+		var n int
+		if err := s.db.QueryRow(query).Scan(&n); err == nil {
+			stats[key] = n
+		}
+	}
+
+	s.stats.report(stats)
+	return stats
+}
+
+// Inspect returns the cached entries of a program, series or station ID by data type
+func (s *sqliteCache) Inspect(id string, app *App) map[string]interface{} {
+	c := newCache()
+	for _, key := range []string{id, truncateID(id)} {
+		found := s.lookup(key, app)
+		for k, v := range found.Program {
+			c.Program[k] = v
+		}
+		for k, v := range found.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+
+	var data []byte
+	if err := s.db.QueryRow("SELECT data FROM channels WHERE station_id = ?", id).Scan(&data); err == nil {
+		var channel G2GCache
+		if json.Unmarshal(data, &channel) == nil {
+			c.Channel[id] = channel
+		}
+	}
+
+	rows, err := s.db.Query("SELECT data FROM schedules WHERE station_id = ? ORDER BY air_date_time", id)
+	if err != nil {
+		app.Logger.WithError(err).WithField("stationID", id).Error("Failed to read cache")
+		return c.Inspect(id, app)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var broadcast G2GCache
+		if rows.Scan(&data) == nil && json.Unmarshal(data, &broadcast) == nil {
+			c.Schedule[id] = append(c.Schedule[id], broadcast)
+		}
+	}
+
+	return c.Inspect(id, app)
+}
+
+// lookup returns a cache with the program and the metadata of id
+func (s *sqliteCache) lookup(id string, app *App) *cache {
+	c := newCache()
+
+	for table, entries := range map[string]map[string]G2GCache{"programs": c.Program, "metadata": c.Metadata} {
+		var data []byte
+		err := s.db.QueryRow("SELECT data FROM "+table+" WHERE program_id = ?", id).Scan(&data)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				app.Logger.WithError(err).WithFields(logrus.Fields{"table": table, "programID": id}).Error("Failed to read cache")
+			}
+			continue
+		}
+
+		var entry G2GCache
+		if err := json.Unmarshal(data, &entry); err != nil {
+			app.Logger.WithError(err).WithFields(logrus.Fields{"table": table, "programID": id}).Error("Failed to unmarshal cache entry")
+			continue
+		}
+		entries[id] = entry
+	}
+
+	if err := s.people(c); err != nil {
+		app.Logger.WithError(err).WithField("programID", id).Error("Failed to read cast and crew")
+	}
+
+	s.stats.lookup(len(c.Program) != 0 || len(c.Metadata) != 0)
+	return c
+}
+
+// people loads the cast and crew of the programs of c into its person store
+func (s *sqliteCache) people(c *cache) error {
+	ids := make(map[string]bool)
+	for _, program := range c.Program {
+		program.personIDs(ids)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(ids))
+	for id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := s.db.Query("SELECT person_id, name, name_id FROM people WHERE person_id IN (?"+strings.Repeat(", ?", len(args)-1)+")", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var person Person
+		if err := rows.Scan(&id, &person.Name, &person.NameID); err != nil {
+			return err
+		}
+		c.Person[id] = person
+	}
+	return rows.Err()
+}
+
+// prunePeopleSQLite removes the people no cached program refers to
+func prunePeopleSQLite(tx *sql.Tx) error {
+	used := make(map[string]bool)
+	rows, err := tx.Query("SELECT data FROM programs")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var data []byte
+		var program G2GCache
+		if rows.Scan(&data) == nil && json.Unmarshal(data, &program) == nil {
+			program.personIDs(used)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var unused []string
+	rows, err = tx.Query("SELECT person_id FROM people")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil && !used[id] {
+			unused = append(unused, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range unused {
+		if _, err := tx.Exec("DELETE FROM people WHERE person_id = ?", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store writes all entries of c in one transaction
+func (s *sqliteCache) store(c *cache) error {
+	return s.update(func(tx *sql.Tx) error {
+		for id, channel := range c.Channel {
+			if err := upsert(tx, "INSERT OR REPLACE INTO channels (station_id, data) VALUES (?, ?)", channel, id); err != nil {
+				return err
+			}
+		}
+		for stationID, schedule := range c.Schedule {
+			for _, broadcast := range schedule {
+				err := upsert(tx, "INSERT OR REPLACE INTO schedules (station_id, air_date_time, program_id, cached, data) VALUES (?, ?, ?, ?, ?)",
+					broadcast, stationID, broadcast.AirDateTime.Unix(), broadcast.ProgramID, broadcast.Cached.Unix())
+				if err != nil {
+					return err
+				}
+
+				// A downloaded schedule uses the program
+				if _, err := tx.Exec("UPDATE programs SET used = MAX(used, ?) WHERE program_id = ?", broadcast.Cached.Unix(), broadcast.ProgramID); err != nil {
+					return err
+				}
+			}
+		}
+		for id, program := range c.Program {
+			err := upsert(tx, "INSERT OR REPLACE INTO programs (program_id, cached, used, data) VALUES (?, ?, ?, ?)",
+				program, id, program.Cached.Unix(), max(program.Used.Unix(), program.Cached.Unix()))
+			if err != nil {
+				return err
+			}
+		}
+		for id, metadata := range c.Metadata {
+			if err := upsert(tx, "INSERT OR REPLACE INTO metadata (program_id, cached, data) VALUES (?, ?, ?)", metadata, id, metadata.Cached.Unix()); err != nil {
+				return err
+			}
+		}
+		for lineup, modified := range c.Lineup {
+			if _, err := tx.Exec("INSERT OR REPLACE INTO lineups (lineup, modified) VALUES (?, ?)", lineup, modified); err != nil {
+				return err
+			}
+		}
+		for id, person := range c.Person {
+			if _, err := tx.Exec("INSERT OR REPLACE INTO people (person_id, name, name_id) VALUES (?, ?, ?)", id, person.Name, person.NameID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// query calls add with the key and the entry of every row, the query selects the key and the data column
+func (s *sqliteCache) query(query string, add func(key string, entry G2GCache), args ...interface{}) error {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to read cache")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var data []byte
+		if err := rows.Scan(&key, &data); err != nil {
+			return errors.Wrap(err, "failed to read cache")
+		}
+
+		var entry G2GCache
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return errors.Wrap(err, "failed to unmarshal cache entry")
+		}
+		add(key, entry)
+	}
+
+	return errors.Wrap(rows.Err(), "failed to read cache")
+}
+
+// update runs fn in a transaction
+func (s *sqliteCache) update(fn func(tx *sql.Tx) error) error {
+	if s.db == nil {
+		return errors.New("cache database not opened")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin cache transaction")
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "failed to update cache")
+	}
+
+	return errors.Wrap(tx.Commit(), "failed to commit cache transaction")
+}
+
+// upsert stores entry as JSON, query takes the data as last argument
+func upsert(tx *sql.Tx, query string, entry G2GCache, args ...interface{}) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cache entry")
+	}
+
+	_, err = tx.Exec(query, append(args, data)...)
+	return err
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCacheBackend(t *testing.T) {
+	cases := []struct {
+		file    string
+		backend string
+		want    string
+	}{
+		{"guide_cache.json", "", "json"},
+		{"guide_cache.db", "", "sqlite"},
+		{"guide_cache.sqlite", "", "sqlite"},
+		{"guide_cache.db", "json", "json"},
+		{"guide_cache.json", "sqlite", "sqlite"},
+		{"guide_cache.bolt", "", "bolt"},
+		{"redis://localhost:6379/0", "", "redis"},
+	}
+	for _, tc := range cases {
+		var c config
+		c.Files.Cache = tc.file
+		c.Options.CacheBackend = tc.backend
+		if got := cacheBackend(c); got != tc.want {
+			t.Errorf("cacheBackend(%q, %q) = %q, want %q", tc.file, tc.backend, got, tc.want)
+		}
+	}
+}
+
+func TestSQLiteCache(t *testing.T) {
+	testCacheStore(t, filepath.Join(t.TempDir(), "guide_cache.db"), func() CacheStore { return &sqliteCache{} })
+}
+
+// testCacheStore adds entries to a store of the backend, reopens the file and reads them again
+func testCacheStore(t *testing.T, file string, newStore func() CacheStore) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	app := &App{Logger: logger, Config: config{}}
+	app.Config.Files.Cache = file
+	app.Config.Options.CacheTTL.Schedules = defaultScheduleTTL
+	app.Config.Options.CacheTTL.Programs = defaultProgramTTL
+	app.Config.Options.CacheTTL.Metadata = defaultMetadataTTL
+	app.Config.Station = []channel{{Name: "KABC", ID: "10001", Lineup: "USA-OTA-90210"}}
+
+	s := newStore()
+	if err := s.Open(app); err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+
+	ctx := context.Background()
+	stations := []byte(`{"stations":[{"stationID":"10001","name":"KABC","callsign":"KABC"},{"stationID":"10002","name":"KCBS"}]}`)
+	if err := s.AddStations(ctx, &stations, "USA-OTA-90210", app); err != nil {
+		t.Fatalf("Failed to add stations: %v", err)
+	}
+	s.SetLineupModified("USA-OTA-90210", "2024-01-01T00:00:00Z")
+	if !s.LineupUnchanged("USA-OTA-90210", "2024-01-01T00:00:00Z", app) {
+		t.Error("LineupUnchanged() = false for the cached lineup")
+	}
+
+	airDateTime := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	schedule := []byte(`[{"stationID":"10001","programs":[{"programID":"EP012345670042","airDateTime":"` + airDateTime + `","duration":1800}]}]`)
+	if err := s.AddSchedule(ctx, &schedule, app); err != nil {
+		t.Fatalf("Failed to add schedule: %v", err)
+	}
+	if required := s.GetRequiredProgramIDs(); len(required) != 1 || required[0] != "EP012345670042" {
+		t.Errorf("GetRequiredProgramIDs() = %v, want [EP012345670042]", required)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	programs := []byte(`[{"programID":"SH012345670000","titles":[{"title120":"Series"}],"showType":"Series","hasImageArtwork":true,"cast":[{"personId":"123","name":"Jane Doe","characterName":"Herself","role":"Actor","billingOrder":"01"}]}]`)
+	if err := s.AddProgram(ctx, &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+	metadata := []byte(`[{"programID":"EP01234567","data":[{"uri":"p123.jpg","width":"240","height":"360","aspect":"2x3","category":"Poster Art"}]}]`)
+	if err := s.AddMetadata(ctx, &metadata, &wg, app); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+	wg.Wait()
+
+	if missing := s.MissingPrograms([]string{"EP012345670042", "SH012345670000"}); len(missing) != 1 || missing[0] != "EP012345670042" {
+		t.Errorf("MissingPrograms() = %v, want [EP012345670042]", missing)
+	}
+	if !s.UseSeriesProgram("EP012345670042", "SH012345670000") {
+		t.Error("Episode did not get the series program")
+	}
+	if required := s.GetRequiredProgramIDs(); len(required) != 0 {
+		t.Errorf("GetRequiredProgramIDs() = %v, want the episode with the series program as cached", required)
+	}
+	if required := s.GetRequiredMetaIDs(); len(required) != 0 {
+		t.Errorf("GetRequiredMetaIDs() = %v, want none for the cached artwork", required)
+	}
+
+	// The entries are stored in the database, a new cache of the same file reads them
+	if err := s.Save(app); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+	s.(io.Closer).Close()
+
+	reopened := newStore()
+	if err := reopened.Open(app); err != nil {
+		t.Fatalf("Failed to reopen cache: %v", err)
+	}
+	defer reopened.(io.Closer).Close()
+
+	if title := reopened.GetTitle("EP012345670042", "en", app); len(title) != 1 || title[0].Value != "Series" {
+		t.Errorf("GetTitle() = %+v, want Series", title)
+	}
+	app.Config.Options.Credits = true
+	if credits := reopened.GetCredits("EP012345670042", app); len(credits.Actor) != 1 || credits.Actor[0].Value != "Jane Doe" {
+		t.Errorf("GetCredits() = %+v, want the actor of the person store", credits)
+	}
+	app.Config.Options.PosterAspect = "2x3"
+	if icons := reopened.GetIcon("EP01234567", app); len(icons) != 1 || icons[0].Width != 240 {
+		t.Errorf("GetIcon() = %+v, want the poster", icons)
+	}
+
+	if channels := reopened.GetChannels(app); len(channels) == 0 || channels[0].StationID != "10001" {
+		t.Errorf("GetChannels() = %+v, want KABC", channels)
+	}
+	if schedule := reopened.GetSchedule("10001", app); len(schedule) != 1 || schedule[0].ProgramID != "EP012345670042" {
+		t.Errorf("GetSchedule() = %+v, want 1 broadcast", schedule)
+	}
+
+	entries := reopened.Inspect("EP012345670042", app)
+	if _, ok := entries["program"]; !ok {
+		t.Errorf("Inspect() = %v, want the program", entries)
+	}
+	if _, ok := entries["metadata"]; !ok {
+		t.Errorf("Inspect() = %v, want the series artwork", entries)
+	}
+	if schedule, ok := reopened.Inspect("10001", app)["schedule"].([]G2GCache); !ok || len(schedule) != 1 {
+		t.Errorf("Inspect() schedule = %v, want 1 broadcast", schedule)
+	}
+	if stats := reopened.GetStats(); fmt.Sprint(stats["programs"], stats["broadcasts"]) != "2 1" || stats["hits"] == int64(0) {
+		t.Errorf("GetStats() = %v, want 2 programs, 1 broadcast and the hits of the lookups", stats)
+	}
+
+	// Broadcasts downloaded before the schedule TTL are removed
+	reopened.CleanUp(app)
+	if _, ok := reopened.Inspect("10001", app)["schedule"]; !ok {
+		t.Error("CleanUp() removed a broadcast within the schedule TTL")
+	}
+	app.Config.Options.CacheTTL.Schedules = time.Nanosecond
+	reopened.CleanUp(app)
+	if schedule, ok := reopened.Inspect("10001", app)["schedule"]; ok {
+		t.Errorf("CleanUp() kept the expired broadcasts %v", schedule)
+	}
+
+	// KCBS is not configured
+	reopened.RemoveChannels(app)
+	app.Config.Station = append(app.Config.Station, channel{Name: "KCBS", ID: "10002", Lineup: "USA-OTA-90210"})
+	if reopened.LineupUnchanged("USA-OTA-90210", "2024-01-01T00:00:00Z", app) {
+		t.Error("RemoveChannels() kept a channel which is not configured")
+	}
+}
@@ -1,10 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
 )
 
 func TestIsValidImageID(t *testing.T) {
@@ -53,11 +65,79 @@ func TestBufferPoolReuse(t *testing.T) {
 	bufferPool.Put(buf2)
 }
 
-// Note: Full integration tests for GetImageUrl would require refactoring for dependency injection of httpClient and file system.
-
-// Placeholder for GetImageUrl tests (requires refactoring for testability)
 func TestGetImageUrl(t *testing.T) {
-	t.Skip("GetImageUrl requires dependency injection for HTTP and file system to be testable")
+	image := bytes.Repeat([]byte{0xff}, 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/image/p123.jpg" || r.URL.Query().Get("token") != "token" {
+			t.Errorf("Unexpected image request %q", r.URL)
+		}
+		w.Write(image)
+	}))
+	defer srv.Close()
+
+	app := &App{Logger: logrus.New(), Config: config{}}
+	app.Config.Options.ImagesPath = t.TempDir() + "/"
+	app.Images = schedulesdirect.New(srv.URL, srv.Client().Transport)
+	app.Images.SetToken("token")
+
+	if err := app.GetImageUrl("p123.jpg", "p123.jpg"); err != nil {
+		t.Fatalf("Failed to download image: %v", err)
+	}
+
+	data, err := os.ReadFile(app.Config.Options.ImagesPath + "p123.jpg")
+	if err != nil || !bytes.Equal(data, image) {
+		t.Errorf("Image not saved: %v", err)
+	}
+}
+
+func TestLogoIcon(t *testing.T) {
+	logo := bytes.Repeat([]byte{0xff}, 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing/s10021_h3_aa.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(logo)
+	}))
+	defer srv.Close()
+
+	app := &App{Logger: logrus.New(), Config: config{}}
+	app.Logger.SetOutput(io.Discard)
+	app.Config.Options.ImagesPath = t.TempDir() + "/"
+	app.Config.Options.Hostname = "localhost:8080"
+	app.Images = schedulesdirect.New(srv.URL, srv.Client().Transport)
+
+	uri := srv.URL + "/logos/s10021_h3_aa.png"
+	if icon := logoIcon(uri, 360, 270, app); icon.Src != uri {
+		t.Errorf("logoIcon() = %+v, want the Schedules Direct URL without the local logos option", icon)
+	}
+
+	app.Config.Options.LocalLogos = true
+	if icon := logoIcon(uri, 360, 270, app); icon != (Icon{Src: "http://localhost:8080/images/s10021_h3_aa.png", Width: 360, Height: 270}) {
+		t.Errorf("logoIcon() = %+v, want the local URL", icon)
+	}
+	if _, err := os.Stat(app.Config.Options.ImagesPath + "s10021_h3_aa.png"); err != nil {
+		t.Errorf("Logo not saved: %v", err)
+	}
+
+	// Failed downloads keep the Schedules Direct URL
+	os.Remove(app.Config.Options.ImagesPath + "s10021_h3_aa.png")
+	if missing := srv.URL + "/missing/s10021_h3_aa.png"; logoIcon(missing, 360, 270, app).Src != missing {
+		t.Error("logoIcon() changed the URL of a failed download")
+	}
+}
+
+func TestImageName(t *testing.T) {
+	cases := map[string]string{
+		"p123.jpg":  "p123.jpg",
+		"/p123.jpg": "p123.jpg",
+		"https://example.com/assets/p456.jpg?x=1": "p456.jpg",
+	}
+	for uri, want := range cases {
+		if got := imageName(uri); got != want {
+			t.Errorf("imageName(%q) = %q, want %q", uri, got, want)
+		}
+	}
 }
 
 func TestCacheInitAndCleanUp(t *testing.T) {
@@ -83,3 +163,791 @@ func TestCacheOpenAndSave(t *testing.T) {
 		t.Errorf("Failed to open cache: %v", err)
 	}
 }
+
+func TestCacheCompression(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	app.Config.Files.Cache = filepath.Join(t.TempDir(), "guide_cache.json")
+
+	for _, compressed := range []bool{true, false} {
+		app.Config.Options.CompressCache = compressed
+
+		c := newCache()
+		c.Program["SH012345670000"] = G2GCache{ShowType: "Series"}
+		if err := c.Save(app); err != nil {
+			t.Fatalf("Failed to save cache: %v", err)
+		}
+
+		data, err := os.ReadFile(app.Config.Files.Cache)
+		if err != nil {
+			t.Fatalf("Failed to read cache file: %v", err)
+		}
+		if isGzip(data) != compressed {
+			t.Errorf("Cache file compressed = %v, want %v", isGzip(data), compressed)
+		}
+
+		opened := &cache{}
+		if err := opened.Open(app); err != nil {
+			t.Fatalf("Failed to open cache: %v", err)
+		}
+		if opened.Program["SH012345670000"].ShowType != "Series" {
+			t.Errorf("Program not loaded from the cache file (compressed %v)", compressed)
+		}
+	}
+}
+
+func TestCacheCorrupt(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	app := &App{Logger: logger, Config: config{}}
+	app.Config.Files.Cache = filepath.Join(t.TempDir(), "guide_cache.json")
+
+	var compressed bytes.Buffer
+	c := newCache()
+	c.Program["SH012345670000"] = G2GCache{ShowType: "Series"}
+	if err := c.encode(&compressed, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"truncated JSON": []byte(`{"Version":3,"Program":{"SH012345670000":{"showT`),
+		"truncated gzip": compressed.Bytes()[:compressed.Len()/2],
+		"empty file":     {},
+	} {
+		if err := os.WriteFile(app.Config.Files.Cache, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		opened := &cache{}
+		if err := opened.Open(app); err != nil {
+			t.Fatalf("Open() of a %s = %v, want a new cache", name, err)
+		}
+		if len(opened.Program) != 0 || opened.Version != cacheSchemaVersion {
+			t.Errorf("Open() of a %s kept %d programs and version %d", name, len(opened.Program), opened.Version)
+		}
+		if _, err := os.Stat(app.Config.Files.Cache); !os.IsNotExist(err) {
+			t.Errorf("The %s was not moved aside", name)
+		}
+
+		moved, _ := filepath.Glob(app.Config.Files.Cache + ".corrupt-*")
+		for _, file := range moved {
+			os.Remove(file)
+		}
+		if len(moved) != 1 {
+			t.Errorf("Moved files of a %s = %v, want one", name, moved)
+		}
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	app.Config.Options.CacheTTL.Schedules = defaultScheduleTTL
+	app.Config.Options.CacheTTL.Programs = defaultProgramTTL
+	app.Config.Options.CacheTTL.Metadata = 0
+
+	now := time.Now()
+	c := newCache()
+	c.Schedule["10001"] = []G2GCache{
+		{ProgramID: "EP012345670042", AirDateTime: now.Add(time.Hour), Cached: now},
+		{ProgramID: "EP012345670043", AirDateTime: now.Add(2 * time.Hour), Cached: now.Add(-2 * defaultScheduleTTL)},
+		{ProgramID: "EP012345670041", AirDateTime: now.Add(-time.Hour), Cached: now},
+	}
+	c.Program["EP012345670042"] = G2GCache{Cached: now.Add(-time.Hour)}
+	c.Program["EP012345670043"] = G2GCache{Cached: now.Add(-2 * defaultProgramTTL)}
+	c.Metadata["EP01234567"] = G2GCache{Cached: now.Add(-365 * 24 * time.Hour)}
+
+	c.CleanUp(app)
+
+	if len(c.Schedule["10001"]) != 1 || c.Schedule["10001"][0].ProgramID != "EP012345670042" {
+		t.Errorf("Schedule = %+v, want the future broadcast of the last download", c.Schedule["10001"])
+	}
+	if _, ok := c.Program["EP012345670043"]; ok || len(c.Program) != 1 {
+		t.Errorf("Programs = %v, want the program expired by the program TTL removed", c.Program)
+	}
+	if _, ok := c.Metadata["EP01234567"]; !ok {
+		t.Error("Metadata without TTL expired")
+	}
+}
+
+func TestCacheExpirationMigration(t *testing.T) {
+	c := &config{File: filepath.Join(t.TempDir(), "test")}
+	if err := c.updateNewOptions([]byte("Options:\n    Cache Expiration: 48h0m0s\n")); err != nil {
+		t.Fatalf("updateNewOptions() failed: %v", err)
+	}
+
+	if c.Options.CacheTTL.Schedules != 48*time.Hour || c.Options.CacheTTL.Programs != defaultProgramTTL {
+		t.Errorf("CacheTTL = %+v, want the cache expiration as TTL of the schedules", c.Options.CacheTTL)
+	}
+	data, err := os.ReadFile(c.File + ".yaml")
+	if err != nil {
+		t.Fatalf("Failed to read configuration: %v", err)
+	}
+	if bytes.Contains(data, []byte("Cache Expiration")) {
+		t.Error("Saved configuration contains the cache expiration option")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	app := &App{Logger: logger, Config: config{}}
+
+	now := time.Now()
+	c := newCache()
+	c.Program["EP012345670001"] = G2GCache{Size: 100, Used: now.Add(-3 * time.Hour)}
+	c.Program["EP012345670002"] = G2GCache{Size: 100, Used: now.Add(-2 * time.Hour)}
+	c.Program["EP012345670003"] = G2GCache{Size: 100, Used: now.Add(-1 * time.Hour)}
+	c.Metadata["EP01234567"] = G2GCache{Size: 100, Used: now.Add(-4 * time.Hour)}
+
+	// The oldest program is still scheduled, so is the artwork of its series
+	c.Schedule["10001"] = []G2GCache{{ProgramID: "EP012345670001", AirDateTime: now.Add(time.Hour)}}
+
+	c.evict(app, 300)
+
+	if len(c.Program) != 2 || len(c.Metadata) != 1 {
+		t.Fatalf("Evicted %d programs and %d metadata, want 1 program", 3-len(c.Program), 1-len(c.Metadata))
+	}
+	if _, ok := c.Program["EP012345670002"]; ok {
+		t.Error("The least recently used program which is not scheduled was kept")
+	}
+
+	c.evict(app, 0)
+	if _, ok := c.Program["EP012345670001"]; !ok || len(c.Program) != 1 {
+		t.Errorf("Programs = %v, want only the scheduled program", c.Program)
+	}
+}
+
+func TestAddScheduleReplacesBroadcasts(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	for _, programID := range []string{"EP012345670042", "EP012345670043"} {
+		schedule := []byte(`[{"stationID":"10001","programs":[{"programID":"` + programID + `","airDateTime":"2030-01-01T20:00:00Z","duration":1800}]}]`)
+		if err := c.AddSchedule(context.Background(), &schedule, app); err != nil {
+			t.Fatalf("Failed to add schedule: %v", err)
+		}
+	}
+
+	if len(c.Schedule["10001"]) != 1 || c.Schedule["10001"][0].ProgramID != "EP012345670043" {
+		t.Errorf("Schedule = %+v, want the broadcast of the last download", c.Schedule["10001"])
+	}
+}
+
+func TestAddSchedulePremiere(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	schedule := []byte(`[{"stationID":"10001","programs":[{"programID":"EP012345670042","airDateTime":"2030-01-01T20:00:00Z","duration":1800,"premiere":true,"isPremiereOrFinale":"Season Premiere"}]}]`)
+	if err := c.AddSchedule(context.Background(), &schedule, app); err != nil {
+		t.Fatalf("Failed to add schedule: %v", err)
+	}
+
+	if broadcast := c.Schedule["10001"][0]; !broadcast.Premiere || broadcast.PremiereFinale != "Season Premiere" {
+		t.Errorf("Schedule = %+v, want the season premiere", broadcast)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	programs := []byte(`[{"programID":"SH012345670000","titles":[{"title120":"Series"}]}]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+
+	c.GetTitle("SH012345670000", "en", app)
+	c.GetCategory("SH012345670000", app)
+	c.GetTitle("EP012345670042", "en", app)
+	c.GetIcon("SH01234567", app)
+
+	stats := c.GetStats()
+	if stats["hits"] != int64(2) || stats["misses"] != int64(2) || stats["size"] != int64(len(programs)) {
+		t.Errorf("GetStats() = %v, want 2 hits, 2 misses and %d bytes", stats, len(programs))
+	}
+}
+
+func TestAddMetadataStream(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	// Errors of single programs are skipped, the other entries are added
+	metadata := []byte(`[
+		{"programID":"EP01234567","data":[{"uri":"p123.jpg","width":"240","height":"360","aspect":"2x3","category":"Poster Art"}]},
+		{"programID":"EP07654321","data":{"code":5000,"message":"No image"}},
+		{"programID":"SH01234567","data":[]}
+	]`)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	if err := c.AddMetadata(context.Background(), &metadata, &wg, app); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+	if len(c.Metadata) != 2 || len(c.Metadata["EP01234567"].Data) != 1 {
+		t.Errorf("Metadata = %v, want the 2 entries without the error", c.Metadata)
+	}
+
+	// Unchanged artwork keeps its entry with a new download time, changed artwork replaces it
+	poster := c.Metadata["EP01234567"]
+	poster.Cached = time.Now().Add(-time.Hour)
+	c.Metadata["EP01234567"] = poster
+	changed := c.Metadata["SH01234567"].Md5
+
+	refresh := []byte(`[
+		{"programID":"EP01234567","data":[{"uri":"p123.jpg","width":"240","height":"360","aspect":"2x3","category":"Poster Art"}]},
+		{"programID":"SH01234567","data":[{"uri":"p456.jpg","width":"240","height":"360","aspect":"2x3","category":"Banner"}]}
+	]`)
+	wg.Add(1)
+	if err := c.AddMetadata(context.Background(), &refresh, &wg, app); err != nil {
+		t.Fatalf("Failed to refresh metadata: %v", err)
+	}
+	if m := c.Metadata["EP01234567"]; m.Md5 != poster.Md5 || !m.Cached.After(poster.Cached) {
+		t.Errorf("Unchanged artwork = %+v, want MD5 %s and a new download time", m, poster.Md5)
+	}
+	if m := c.Metadata["SH01234567"]; m.Md5 == changed || len(m.Data) != 1 {
+		t.Errorf("Changed artwork = %+v, want the new artwork", m)
+	}
+
+	invalid := []byte(`{"programID":"EP01234567"}`)
+	if err := c.AddMetadata(context.Background(), &invalid, &wg, app); err == nil {
+		t.Error("AddMetadata() of an object instead of an array succeeded")
+	}
+}
+
+func TestAddStationsChannelNumber(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	app.Config.Station = []channel{{ID: "10001", Lineup: "USA-OTA-90210"}, {ID: "10002", Lineup: "USA-OTA-90210"}}
+	c := newCache()
+
+	stations := []byte(`{"map":[{"stationID":"10001","atscMajor":7,"atscMinor":1},{"stationID":"10002","channel":"702"}],` +
+		`"stations":[{"stationID":"10001","callsign":"KABC"},{"stationID":"10002","callsign":"KABC"}]}`)
+	if err := c.AddStations(context.Background(), &stations, "USA-OTA-90210", app); err != nil {
+		t.Fatalf("Failed to add stations: %v", err)
+	}
+	if c.Channel["10001"].ChannelNumber != "7.1" || c.Channel["10002"].ChannelNumber != "702" {
+		t.Errorf("Channels = %+v, want the channel numbers of the lineup", c.Channel)
+	}
+}
+
+func TestGetStarRating(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	programs := []byte(`[{"programID":"MV000000010000","titles":[{"title120":"Movie"}],"movie":{"year":"2004","qualityRating":[{"ratingsBody":"Gracenote","rating":"3.5","minRating":"1","maxRating":"4","increment":".5"}]}}]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+
+	if sr := c.GetStarRating("MV000000010000", app); len(sr) != 1 || sr[0].Value != "3.5/4" || sr[0].System != "Gracenote" {
+		t.Errorf("GetStarRating() = %+v, want 3.5/4 of Gracenote", sr)
+	}
+}
+
+func TestGetKeywords(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	programs := []byte(`[{"programID":"MV000000010000","titles":[{"title120":"Movie"}],"keyWords":{"Theme":["Heist"],"Mood":["Suspenseful","Heist"],"Time Period":["21st century"]}}]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+
+	if kw := c.GetKeywords("MV000000010000", app); len(kw) != 0 {
+		t.Errorf("GetKeywords() = %+v without the keywords option", kw)
+	}
+	app.Config.Options.Keywords = true
+	if kw := c.GetKeywords("MV000000010000", app); fmt.Sprint(kw) != "[{Suspenseful en} {Heist en} {21st century en}]" {
+		t.Errorf("GetKeywords() = %v, want the keywords sorted by group without duplicates", kw)
+	}
+}
+
+func TestGetContentAdvisory(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	programs := []byte(`[{"programID":"MV000000010000","titles":[{"title120":"Movie"}],"contentAdvisory":["Violence","Adult Language"]}]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+
+	if advisories := c.GetContentAdvisory("MV000000010000", app); len(advisories) != 0 {
+		t.Errorf("GetContentAdvisory() = %v without the content advisories option", advisories)
+	}
+	app.Config.Options.Rating.ContentAdvisory = "rating"
+	if advisories := c.GetContentAdvisory("MV000000010000", app); fmt.Sprint(advisories) != "[Violence Adult Language]" {
+		t.Errorf("GetContentAdvisory() = %v, want the advisories of the program", advisories)
+	}
+}
+
+func TestGetSportsEvent(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	programs := []byte(`[{"programID":"EP000000010042","titles":[{"title120":"NBA Basketball"}],"genres":["Basketball"],"eventDetails":{"venue100":"Crypto.com Arena","teams":[{"name":"Los Angeles Lakers","isHome":true},{"name":"Boston Celtics"}]}}]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+
+	subTitle := c.GetSubTitle("EP000000010042", "en", app)
+	if subTitle.Value != "Boston Celtics at Los Angeles Lakers" {
+		t.Errorf("GetSubTitle() = %q, want the teams", subTitle.Value)
+	}
+	if desc := c.GetDescs("EP000000010042", subTitle.Value, app); len(desc) != 1 || desc[0].Value != "Venue: Crypto.com Arena" {
+		t.Errorf("GetDescs() = %+v, want the venue", desc)
+	}
+	if ca := c.GetCategory("EP000000010042", app); fmt.Sprint(ca) != "[{Basketball en} {Sports event en}]" {
+		t.Errorf("GetCategory() = %v, want the sports event category", ca)
+	}
+}
+
+func TestGetCategoryMapping(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	programs := []byte(`[{"programID":"EP000000010042","titles":[{"title120":"Evening News"}],"genres":["News","Newsmagazine","Weather"]}]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+
+	cases := []struct {
+		profile string
+		mapping map[string]string
+		want    string
+	}{
+		{"none", nil, "[{News en} {Newsmagazine en} {Weather en}]"},
+		{"plex", nil, "[{News en} {Weather en}]"},
+		{"tvheadend", nil, "[{News / Current affairs en} {News magazine en} {News / Weather report en}]"},
+		{"plex", map[string]string{"Weather": "", "News": "Nachrichten"}, "[{Nachrichten en} {News en}]"},
+	}
+	for _, tc := range cases {
+		app.Config.Options.CategoryProfile = tc.profile
+		app.Config.Options.CategoryMapping = tc.mapping
+		if ca := c.GetCategory("EP000000010042", app); fmt.Sprint(ca) != tc.want {
+			t.Errorf("GetCategory() with profile %s and mapping %v = %v, want %s", tc.profile, tc.mapping, ca, tc.want)
+		}
+	}
+}
+
+func TestSelectBroadcastFlags(t *testing.T) {
+	option := map[string][]string{"live-new": {"live"}}
+	cases := []struct {
+		schedule G2GCache
+		kind     string
+		want     []string
+	}{
+		{G2GCache{LiveTapeDelay: "Live", New: true}, "live-new", []string{"live"}},
+		{G2GCache{LiveTapeDelay: "Live"}, "live", []string{"live", "previously-shown"}},
+		{G2GCache{LiveTapeDelay: "Tape", PremiereFinale: "Season Premiere"}, "tape-new", []string{"new", "premiere"}},
+		{G2GCache{}, "other", []string{"previously-shown"}},
+	}
+	for _, c := range cases {
+		if kind := broadcastKind(c.schedule); kind != c.kind {
+			t.Errorf("broadcastKind(%+v) = %q, want %q", c.schedule, kind, c.kind)
+		}
+		if flags := selectBroadcastFlags(c.schedule, option); len(flags) != len(c.want) {
+			t.Errorf("selectBroadcastFlags(%+v) = %v, want %v", c.schedule, flags, c.want)
+		} else {
+			for _, flag := range c.want {
+				if !flags[flag] {
+					t.Errorf("selectBroadcastFlags(%+v) = %v, want %v", c.schedule, flags, c.want)
+				}
+			}
+		}
+	}
+}
+
+func TestGetTitlePlaceholder(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	if title := c.GetTitle("EP012345670042", "de", app); fmt.Sprint(title) != "[{No EPG Info en}]" {
+		t.Errorf("GetTitle() = %v, want the default placeholder", title)
+	}
+	app.Config.Options.Placeholder.Title = "Keine Daten"
+	app.Config.Options.Placeholder.Lang = "de"
+	if title := c.GetTitle("EP012345670042", "de", app); fmt.Sprint(title) != "[{Keine Daten de}]" {
+		t.Errorf("GetTitle() = %v, want the placeholder of the configuration", title)
+	}
+}
+
+func TestGetEpisodeNum(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	c := newCache()
+
+	programs := []byte(`[
+		{"programID":"EP012345670042","titles":[{"title120":"Series"}],"originalAirDate":"2006-09-18","metadata":[{"Gracenote":{"season":4,"episode":1}}]},
+		{"programID":"EP012345670043","titles":[{"title120":"Series"}],"metadata":[{"Gracenote":{"season":4}}]}
+	]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+
+	cases := []struct {
+		id      string
+		systems []string
+		want    string
+	}{
+		{"EP012345670042", nil, "[{3.0. xmltv_ns} {S4 E1 onscreen} {EP01234567.0042 dd_progid} {SH012345670000 series-id} {2006-09-18 original-air-date}]"},
+		{"EP012345670043", nil, "[{3.. xmltv_ns} {S4 onscreen} {EP01234567.0043 dd_progid} {SH012345670000 series-id}]"},
+		{"EP012345670042", []string{"dd_progid", "xmltv_ns"}, "[{EP01234567.0042 dd_progid} {3.0. xmltv_ns}]"},
+		{"EP012345670042", []string{"series-id", "episode-id"}, "[{SH012345670000 series-id} {EP012345670042 episode-id}]"},
+	}
+	for _, tc := range cases {
+		app.Config.Options.EpisodeNumSystems = tc.systems
+		if ep := c.GetEpisodeNum(tc.id, app); fmt.Sprint(ep) != tc.want {
+			t.Errorf("GetEpisodeNum(%s) with systems %v = %v, want %s", tc.id, tc.systems, ep, tc.want)
+		}
+	}
+}
+
+func TestGetIconPolicy(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	app.Config.Options.Hostname = "localhost:8080"
+	app.Config.Options.ArtworkPolicies = defaultArtworkPolicies()
+	c := newCache()
+
+	metadata := []byte(`[
+		{"programID":"MV00000001","data":[
+			{"uri":"a.jpg","width":"240","height":"360","aspect":"2x3","category":"Box Art"},
+			{"uri":"b.jpg","width":"480","height":"720","aspect":"2x3","category":"VOD Art"},
+			{"uri":"c.jpg","width":"120","height":"180","aspect":"2x3","category":"Poster Art"},
+			{"uri":"d.jpg","width":"960","height":"540","aspect":"16x9","category":"Iconic"}
+		]},
+		{"programID":"EP01234567","data":[
+			{"uri":"e.jpg","width":"240","height":"360","aspect":"2x3","category":"Poster Art"},
+			{"uri":"f.jpg","width":"960","height":"540","aspect":"16x9","category":"Banner-L1"}
+		]},
+		{"programID":"EP012345670042","data":[
+			{"uri":"g.jpg","width":"960","height":"540","aspect":"16x9","category":"Banner-L1"},
+			{"uri":"h.jpg","width":"960","height":"540","aspect":"16x9","category":"Iconic"}
+		]}
+	]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddMetadata(context.Background(), &metadata, &wg, app); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+
+	if icons := c.GetIcon("MV00000001", app); len(icons) != 1 || icons[0].Src != "http://localhost:8080/images/c.jpg" {
+		t.Errorf("GetIcon() of the movie = %+v, want the poster", icons)
+	}
+	if icons := c.GetIcon("EP01234567", app); len(icons) != 1 || icons[0].Src != "http://localhost:8080/images/f.jpg" || icons[0].Width != 960 {
+		t.Errorf("GetIcon() of the series = %+v, want the 16x9 banner", icons)
+	}
+	if icons := c.GetIcon("EP012345670042", app); len(icons) != 1 || icons[0].Src != "http://localhost:8080/images/h.jpg" {
+		t.Errorf("GetIcon() of the episode = %+v, want the still", icons)
+	}
+}
+
+func TestRequiredIDs(t *testing.T) {
+	c := newCache()
+	c.Schedule["10001"] = []G2GCache{
+		{ProgramID: "EP012345670001", Md5: "a"},
+		{ProgramID: "EP012345670002", Md5: "b"},
+		{ProgramID: "EP012345670003", Md5: "c"},
+		{ProgramID: "MV000000010000", Md5: "d"},
+		{ProgramID: "SH076543210000", Md5: "e"},
+	}
+	c.Program["EP012345670001"] = G2GCache{ProgramID: "EP012345670001", Md5: "a", HasSeriesArtwork: true, HasEpisodeArtwork: true}
+	c.Program["EP012345670002"] = G2GCache{ProgramID: "EP012345670002", Md5: "old", HasSeriesArtwork: true}
+	c.Program["EP012345670003"] = G2GCache{ProgramID: "SH012345670000", Md5: "f", HasSeriesArtwork: true}
+	c.Program["MV000000010000"] = G2GCache{HasImageArtwork: true}
+	c.Program["SH076543210000"] = G2GCache{ProgramID: "SH076543210000", Md5: "e"}
+	c.Metadata["MV00000001"] = G2GCache{}
+
+	if all := c.GetAllProgramIDs(); len(all) != 5 || all[0] != "EP012345670001" {
+		t.Errorf("GetAllProgramIDs() = %v, want the 5 scheduled programs sorted", all)
+	}
+
+	// Changed programs are downloaded again, series programs of episodes and programs without MD5 are kept
+	if required := c.GetRequiredProgramIDs(); fmt.Sprint(required) != "[EP012345670002]" {
+		t.Errorf("GetRequiredProgramIDs() = %v, want the changed episode", required)
+	}
+
+	// The episodes share the artwork of the series, episodes with their own artwork need it as well, programs without artwork need none
+	if required := c.GetRequiredMetaIDs(); fmt.Sprint(required) != "[EP01234567 EP012345670001]" {
+		t.Errorf("GetRequiredMetaIDs() = %v, want the series and the episode artwork", required)
+	}
+}
+
+func TestPersonStore(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	app.Config.Options.Credits = true
+	app.Config.Options.CacheTTL.Programs = defaultProgramTTL
+	c := newCache()
+
+	programs := []byte(`[
+		{"programID":"EP012345670042","titles":[{"title120":"Series"}],"cast":[{"personId":"123","nameId":"456","name":"Jane Doe","characterName":"Herself","role":"Actor"}],"crew":[{"personId":"789","name":"John Roe","role":"Director"}]},
+		{"programID":"EP012345670043","titles":[{"title120":"Series"}],"cast":[{"personId":"123","nameId":"456","name":"Jane Doe","characterName":"Herself","role":"Actor"}]}
+	]`)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+
+	if len(c.Person) != 2 || c.Program["EP012345670043"].Cast[0].Name != "" {
+		t.Fatalf("People = %v, want cast and crew stored once without names in the programs", c.Person)
+	}
+	credits := c.GetCredits("EP012345670042", app)
+	if len(credits.Actor) != 1 || credits.Actor[0].Value != "Jane Doe" || credits.Actor[0].Role != "Herself" ||
+		len(credits.Director) != 1 || credits.Director[0].Value != "John Roe" {
+		t.Errorf("GetCredits() = %+v, want the names of the person store", credits)
+	}
+	if len(credits.Actor[0].Image) != 0 {
+		t.Errorf("Actor images = %+v, want none without the cast images option", credits.Actor[0].Image)
+	}
+
+	app.Config.Options.CastImages = true
+	app.Config.Options.Hostname = "localhost:8080"
+	credits = c.GetCredits("EP012345670042", app)
+	if want := (Image{Value: "http://localhost:8080/images/celebrity/123", Type: "person"}); len(credits.Actor[0].Image) != 1 || credits.Actor[0].Image[0] != want {
+		t.Errorf("Actor images = %+v, want %+v", credits.Actor[0].Image, want)
+	}
+
+	// The director is only referred to by the expired program
+	p := c.Program["EP012345670042"]
+	p.Cached = time.Now().Add(-2 * defaultProgramTTL)
+	c.Program["EP012345670042"] = p
+	c.CleanUp(app)
+	if _, ok := c.Person["789"]; ok || len(c.Person) != 1 {
+		t.Errorf("People = %v, want only the people of cached programs", c.Person)
+	}
+}
+
+func TestUseSeriesProgram(t *testing.T) {
+	c := &cache{}
+	c.Init()
+
+	seriesID, ok := seriesProgramID("EP012345670042")
+	if !ok || seriesID != "SH012345670000" {
+		t.Fatalf("seriesProgramID() = %q, %v, want SH012345670000, true", seriesID, ok)
+	}
+	if _, ok := seriesProgramID("MV012345670000"); ok {
+		t.Error("Movies have no series program")
+	}
+
+	if c.UseSeriesProgram("EP012345670042", seriesID) {
+		t.Error("UseSeriesProgram() without cached series program")
+	}
+
+	c.Program[seriesID] = G2GCache{ShowType: "Series"}
+	if missing := c.MissingPrograms([]string{"EP012345670042", seriesID}); len(missing) != 1 || missing[0] != "EP012345670042" {
+		t.Errorf("MissingPrograms() = %v, want [EP012345670042]", missing)
+	}
+
+	if !c.UseSeriesProgram("EP012345670042", seriesID) || c.Program["EP012345670042"].ShowType != "Series" {
+		t.Error("Episode did not get the series program")
+	}
+}
+
+func TestCacheExportImport(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+
+	now := time.Now().Truncate(time.Second)
+	src := &App{Logger: logger, Config: config{}}
+	src.Config.Files.Cache = filepath.Join(dir, "guide_cache.json")
+	src.Cache = newCache()
+	src.Cache.Import(&cache{
+		Channel:  map[string]G2GCache{"10001": {Callsign: "KABC"}},
+		Schedule: map[string][]G2GCache{"10001": {{ProgramID: "EP012345670042", AirDateTime: now.Add(time.Hour), Cached: now}}},
+		Program:  map[string]G2GCache{"EP012345670042": {ShowType: "Series", Cached: now}},
+		Metadata: map[string]G2GCache{"EP01234567": {Cached: now}},
+		Lineup:   map[string]string{"USA-OTA-90210": "2024-01-01T00:00:00Z"},
+	}, src)
+
+	export := filepath.Join(dir, "export.json.gz")
+	if err := src.exportCache(export); err != nil {
+		t.Fatalf("Failed to export cache: %v", err)
+	}
+
+	// The export of one backend is imported into the others and exported again
+	for i, dst := range []CacheStore{&sqliteCache{}, &boltCache{}} {
+		app := &App{Logger: logger, Config: config{}, Cache: dst}
+		app.Config.Files.Cache = filepath.Join(dir, fmt.Sprintf("guide_cache_%d.db", i))
+		if err := dst.Open(app); err != nil {
+			t.Fatalf("Failed to open cache: %v", err)
+		}
+
+		file, err := os.Open(export)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = app.importCache(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("Failed to import into %T: %v", dst, err)
+		}
+
+		c, err := dst.Export(app)
+		dst.(io.Closer).Close()
+		if err != nil {
+			t.Fatalf("Failed to export %T: %v", dst, err)
+		}
+		if c.Channel["10001"].Callsign != "KABC" || c.Program["EP012345670042"].ShowType != "Series" ||
+			len(c.Schedule["10001"]) != 1 || len(c.Metadata) != 1 || c.Lineup["USA-OTA-90210"] == "" {
+			t.Errorf("%T export = %+v, want the imported entries", dst, c)
+		}
+	}
+}
+
+func TestCacheMigrate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	now := time.Now().Truncate(time.Second)
+	app := &App{Logger: logger, Config: config{}}
+	app.Config.Files.Cache = filepath.Join(t.TempDir(), "guide_cache.json")
+	app.Config.Options.CacheTTL.Programs = defaultProgramTTL
+	app.Cache = newCache()
+	app.Cache.Import(&cache{
+		Channel:  map[string]G2GCache{"10001": {Callsign: "KABC"}},
+		Schedule: map[string][]G2GCache{"10001": {{ProgramID: "EP012345670042", AirDateTime: now.Add(time.Hour), Cached: now}}},
+		Program:  map[string]G2GCache{"EP012345670042": {ShowType: "Series", Cached: now, Cast: []CastMember{{PersonID: "123", Role: "Actor"}}}},
+		Person:   map[string]Person{"123": {Name: "Jane Doe"}},
+	}, app)
+	if err := app.Cache.Save(app); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := migrationTarget(app.Config.Files.Cache, "sqlite")
+	if err != nil || filepath.Base(dst) != "guide_cache.db" {
+		t.Fatalf("migrationTarget() = %q, %v, want guide_cache.db", dst, err)
+	}
+
+	var progress strings.Builder
+	if err := app.copyCache(&progress, "json", "sqlite", dst); err != nil {
+		t.Fatalf("Failed to migrate cache: %v", err)
+	}
+	if !strings.Contains(progress.String(), "programs  1/1") {
+		t.Errorf("Progress = %q, want the migrated programs", progress.String())
+	}
+
+	migrated := &App{Logger: logger, Config: config{}}
+	migrated.Config.Files.Cache = dst
+	s := &sqliteCache{}
+	if err := s.Open(migrated); err != nil {
+		t.Fatalf("Failed to open migrated cache: %v", err)
+	}
+	defer s.Close()
+
+	c, err := s.Export(migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Channel["10001"].Callsign != "KABC" || len(c.Schedule["10001"]) != 1 || c.Person["123"].Name != "Jane Doe" ||
+		c.Program["EP012345670042"].ShowType != "Series" {
+		t.Errorf("Migrated cache = %+v, want the entries of the JSON cache", c)
+	}
+
+	if err := app.copyCache(io.Discard, "json", "json", app.Config.Files.Cache); err == nil {
+		t.Error("copyCache() into the cache itself succeeded")
+	}
+}
+
+func TestProgramRetention(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+
+	for i, store := range []CacheStore{&cache{}, &sqliteCache{}, &boltCache{}} {
+		app := &App{Logger: logger, Config: config{}}
+		app.Config.Files.Cache = filepath.Join(dir, fmt.Sprintf("guide_cache_%d.db", i))
+		app.Config.Options.CacheTTL.Schedules = defaultScheduleTTL
+		app.Config.Options.CacheTTL.Programs = defaultProgramTTL
+		if err := store.Open(app); err != nil {
+			t.Fatalf("Failed to open %T: %v", store, err)
+		}
+
+		ctx := context.Background()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		programs := []byte(`[{"programID":"EP012345670042","titles":[{"title120":"Episode"}]},{"programID":"MV012345670000","titles":[{"title120":"Movie"}]}]`)
+		if err := store.AddProgram(ctx, &programs, &wg, app); err != nil {
+			t.Fatalf("Failed to add programs: %v", err)
+		}
+		airDateTime := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		schedule := []byte(`[{"stationID":"10001","programs":[{"programID":"EP012345670042","airDateTime":"` + airDateTime + `","duration":1800}]}]`)
+		if err := store.AddSchedule(ctx, &schedule, app); err != nil {
+			t.Fatalf("Failed to add schedule: %v", err)
+		}
+
+		// Without retention unreferenced programs are kept until their TTL
+		store.CleanUp(app)
+		if missing := store.MissingPrograms([]string{"EP012345670042", "MV012345670000"}); len(missing) != 0 {
+			t.Errorf("%T CleanUp() without retention removed %v", store, missing)
+		}
+
+		app.Config.Options.ProgramRetention = time.Nanosecond
+		store.CleanUp(app)
+		if missing := store.MissingPrograms([]string{"EP012345670042", "MV012345670000"}); len(missing) != 1 || missing[0] != "MV012345670000" {
+			t.Errorf("%T CleanUp() removed %v, want only the unreferenced movie", store, missing)
+		}
+
+		if closer, ok := store.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+func TestCacheConcurrentAdd(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	app := &App{Logger: logger, Config: config{}}
+	app.Config.Options.Credits = true
+	c := newCache()
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		app.Config.Station = append(app.Config.Station, channel{Name: "Station", ID: "1000" + strconv.Itoa(i), Lineup: "USA-OTA-90210"})
+	}
+
+	// Every data type has its own lock, the race detector checks the maps of the parallel downloads
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("EP0123456700%02d", i)
+		programs := []byte(`[{"programID":"` + id + `","titles":[{"title120":"Episode"}],"cast":[{"personId":"123","name":"Jane Doe","role":"Actor"}]}]`)
+		metadata := []byte(`[{"programID":"` + id[:10] + `","data":[]}]`)
+		schedule := []byte(`[{"stationID":"10001","programs":[{"programID":"` + id + `","airDateTime":"2030-01-01T2` + strconv.Itoa(i%4) + `:00:00Z"}]}]`)
+		stations := []byte(`{"stations":[{"stationID":"1000` + strconv.Itoa(i) + `","name":"Station"}]}`)
+
+		wg.Add(6)
+		go c.AddProgram(ctx, &programs, &wg, app)
+		go c.AddMetadata(ctx, &metadata, &wg, app)
+		go func() {
+			defer wg.Done()
+			c.AddSchedule(ctx, &schedule, app)
+		}()
+		go func() {
+			defer wg.Done()
+			c.AddStations(ctx, &stations, "USA-OTA-90210", app)
+		}()
+		go func() {
+			defer wg.Done()
+			c.GetCredits(id, app)
+		}()
+		go func() {
+			defer wg.Done()
+			c.GetStats()
+		}()
+	}
+	wg.Wait()
+
+	if len(c.Program) != 10 || len(c.Channel) != 10 || len(c.Schedule["10001"]) != 4 {
+		t.Errorf("Cache has %d programs, %d channels and %d broadcasts, want 10, 10 and 4", len(c.Program), len(c.Channel), len(c.Schedule["10001"]))
+	}
+}
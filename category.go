@@ -0,0 +1,87 @@
+package main
+
+// categoryProfiles maps the genres of Schedules Direct to the categories a media server recognizes.
+// Genres without an entry are passed through.
+var categoryProfiles = map[string]map[string]string{
+	// Plex and Emby detect movies, sports, news and kids programs by these categories
+	"plex": {
+		"Movie":            "Movie",
+		"Sports event":     "Sports",
+		"Sports non-event": "Sports",
+		"Sports talk":      "Sports",
+		"News":             "News",
+		"Newsmagazine":     "News",
+		"Children":         "Kids",
+	},
+	"emby": {
+		"Movie":            "Movie",
+		"Sports event":     "Sports",
+		"Sports non-event": "Sports",
+		"Sports talk":      "Sports",
+		"News":             "News",
+		"Newsmagazine":     "News",
+		"Children":         "Children",
+	},
+	// TVHeadend maps the categories to the content genres of DVB, EN 300 468
+	"tvheadend": {
+		"Movie":            "Movie / Drama",
+		"Drama":            "Movie / Drama",
+		"Crime drama":      "Detective / Thriller",
+		"Mystery":          "Detective / Thriller",
+		"Suspense":         "Detective / Thriller",
+		"Action":           "Adventure / Western / War",
+		"Adventure":        "Adventure / Western / War",
+		"Western":          "Adventure / Western / War",
+		"War":              "Adventure / Western / War",
+		"Science fiction":  "Science fiction / Fantasy / Horror",
+		"Fantasy":          "Science fiction / Fantasy / Horror",
+		"Horror":           "Science fiction / Fantasy / Horror",
+		"Comedy":           "Comedy",
+		"Sitcom":           "Comedy",
+		"Soap":             "Soap / Melodrama / Folklore",
+		"Romance":          "Romance",
+		"News":             "News / Current affairs",
+		"Newsmagazine":     "News magazine",
+		"Weather":          "News / Weather report",
+		"Documentary":      "Documentary",
+		"Talk":             "Discussion / Interview / Debate",
+		"Game show":        "Game show / Quiz / Contest",
+		"Reality":          "Show / Game show",
+		"Variety":          "Variety show",
+		"Sports event":     "Sports",
+		"Sports non-event": "Sports magazines",
+		"Sports talk":      "Sports magazines",
+		"Children":         "Children's / Youth programs",
+		"Animated":         "Cartoons / Puppets",
+		"Educational":      "Education / Science / Factual topics",
+		"Science":          "Education / Science / Factual topics",
+		"Music":            "Music / Ballet / Dance",
+		"Art":              "Arts / Culture (without music)",
+		"Religious":        "Religion",
+		"Cooking":          "Cooking",
+		"Travel":           "Tourism / Travel",
+		"Shopping":         "Advertisement / Shopping",
+	},
+}
+
+// mapCategories maps genres by the user mapping and the category profile, the user mapping has precedence.
+// A genre mapped to an empty category is removed, several genres mapped to the same category give one category.
+func mapCategories(genres []string, profile string, mapping map[string]string) (categories []string) {
+	seen := make(map[string]bool)
+	for _, genre := range genres {
+		category, ok := mapping[genre]
+		if !ok {
+			if category, ok = categoryProfiles[profile][genre]; !ok {
+				category = genre
+			}
+		}
+
+		if len(category) == 0 || seen[category] {
+			continue
+		}
+		seen[category] = true
+		categories = append(categories, category)
+	}
+
+	return
+}
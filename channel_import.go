@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+	"github.com/yourusername/guide2go/web/handlers"
+)
+
+// importLineup are the stations of a subscribed lineup a channel import is matched against
+type importLineup struct {
+	lineup   string
+	stations []schedulesdirect.Station
+}
+
+// findStation returns the station of an import entry: the station ID or else the callsign, ignoring the case.
+// Without a lineup of the entry the first subscribed lineup with the station is used.
+func findStation(entry handlers.ChannelImportEntry, lineups []importLineup) (channel, bool) {
+	for _, byCallsign := range []bool{false, true} {
+		for _, l := range lineups {
+			if len(entry.Lineup) != 0 && !strings.EqualFold(l.lineup, entry.Lineup) {
+				continue
+			}
+			for _, s := range l.stations {
+				if (!byCallsign && s.StationID == entry.Station) || (byCallsign && strings.EqualFold(s.Callsign, entry.Station)) {
+					return channel{Name: s.Name, ID: s.StationID, Lineup: l.lineup}, true
+				}
+			}
+		}
+	}
+	return channel{}, false
+}
+
+// importChannels adds the stations of the entries to the channels of the configuration, replace removes the other channels.
+// The channels which stay keep their settings, e.g. the time zone.
+func importChannels(c *config, entries []handlers.ChannelImportEntry, lineups []importLineup, replace bool) (*handlers.ChannelImport, error) {
+	current := make(map[string]channel)
+	for _, ch := range c.Station {
+		current[ch.ID] = ch
+	}
+
+	result := &handlers.ChannelImport{}
+	imported := make(map[string]bool)
+	var stations []channel
+	for _, entry := range entries {
+		r := handlers.ChannelImportResult{Entry: entry.Station, Status: handlers.ChannelNotFound}
+		if len(entry.Lineup) != 0 {
+			r.Entry = fmt.Sprintf("%s (%s)", entry.Station, entry.Lineup)
+		}
+
+		if ch, ok := findStation(entry, lineups); ok {
+			r.StationID, r.Name, r.Lineup = ch.ID, ch.Name, ch.Lineup
+			if old, ok := current[ch.ID]; ok {
+				r.Status, r.Lineup = handlers.ChannelExists, old.Lineup
+				ch = old
+			} else if imported[ch.ID] {
+				r.Status = handlers.ChannelExists
+			} else {
+				r.Status = handlers.ChannelAdded
+				result.Added++
+			}
+			if !imported[ch.ID] {
+				imported[ch.ID] = true
+				stations = append(stations, ch)
+			}
+		}
+		result.Results = append(result.Results, r)
+	}
+
+	if replace {
+		if len(stations) == 0 {
+			return result, errors.New("no station of the list is in the subscribed lineups, the channels are kept")
+		}
+		result.Removed = len(current) - (len(stations) - result.Added)
+		c.Station = stations
+	} else {
+		for _, ch := range stations {
+			if _, ok := current[ch.ID]; !ok {
+				c.AddChannel(&ch)
+			}
+		}
+	}
+	c.GetChannels()
+	result.Channels = len(c.Station)
+	return result, nil
+}
+
+// ImportStations adds the stations of a channel list of the web UI to the configuration, each matched against the subscribed lineups
+func (app *App) ImportStations(entries []handlers.ChannelImportEntry, replace bool) (*handlers.ChannelImport, error) {
+	sd, err := app.webSD()
+	if err != nil {
+		return nil, err
+	}
+
+	var lineups []importLineup
+	for _, l := range sd.Resp.Status.Lineups {
+		sd.Req.Parameter = fmt.Sprintf("/%s", l.Lineup)
+		if err := sd.Channels(); err != nil {
+			return nil, errors.Wrapf(err, "failed to get channels of lineup %s", l.Lineup)
+		}
+		lineups = append(lineups, importLineup{lineup: l.Lineup, stations: sd.Resp.Channels.Stations})
+	}
+
+	result, err := importChannels(&app.Config, entries, lineups, replace)
+	if err != nil {
+		return nil, err
+	}
+
+	app.Logger.WithFields(logrus.Fields{
+		"entries":  len(entries),
+		"added":    result.Added,
+		"removed":  result.Removed,
+		"channels": result.Channels,
+	}).Info("Channels imported in the web UI")
+
+	return result, app.Config.Save()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+	"github.com/yourusername/guide2go/web/handlers"
+)
+
+func TestImportChannels(t *testing.T) {
+	lineups := []importLineup{
+		{lineup: "USA-OTA-10001", stations: []schedulesdirect.Station{
+			{StationID: "10021", Name: "WABC", Callsign: "WABC"},
+			{StationID: "10022", Name: "WCBS", Callsign: "WCBS"},
+		}},
+		{lineup: "USA-NY31587-X", stations: []schedulesdirect.Station{
+			{StationID: "10021", Name: "WABC", Callsign: "WABC"},
+			{StationID: "10179", Name: "ESPN", Callsign: "ESPN"},
+		}},
+	}
+	entries := []handlers.ChannelImportEntry{
+		{Station: "wcbs"},
+		{Station: "10021", Lineup: "USA-NY31587-X"},
+		{Station: "10179"},
+		{Station: "10179"},
+		{Station: "CNN"},
+	}
+
+	var c config
+	c.Station = []channel{{Name: "WCBS", ID: "10022", Lineup: "USA-OTA-10001", Timezone: "America/New_York"}}
+	result, err := importChannels(&c, entries, lineups, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []handlers.ChannelImportResult{
+		{Entry: "wcbs", Status: handlers.ChannelExists, StationID: "10022", Name: "WCBS", Lineup: "USA-OTA-10001"},
+		{Entry: "10021 (USA-NY31587-X)", Status: handlers.ChannelAdded, StationID: "10021", Name: "WABC", Lineup: "USA-NY31587-X"},
+		{Entry: "10179", Status: handlers.ChannelAdded, StationID: "10179", Name: "ESPN", Lineup: "USA-NY31587-X"},
+		{Entry: "10179", Status: handlers.ChannelExists, StationID: "10179", Name: "ESPN", Lineup: "USA-NY31587-X"},
+		{Entry: "CNN", Status: handlers.ChannelNotFound},
+	}
+	for i, r := range result.Results {
+		if r != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, r, want[i])
+		}
+	}
+	if result.Added != 2 || result.Channels != 3 || c.Station[0].Timezone != "America/New_York" {
+		t.Errorf("import added %d of %d channels: %+v", result.Added, result.Channels, c.Station)
+	}
+
+	result, err = importChannels(&c, []handlers.ChannelImportEntry{{Station: "WCBS"}, {Station: "WABC"}}, lineups, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 0 || result.Removed != 1 || len(c.Station) != 2 || c.Station[1].Lineup != "USA-NY31587-X" {
+		t.Errorf("replace added %d and removed %d channels: %+v", result.Added, result.Removed, c.Station)
+	}
+
+	if _, err := importChannels(&c, []handlers.ChannelImportEntry{{Station: "CNN"}}, lineups, true); err == nil || len(c.Station) != 2 {
+		t.Errorf("replace without a matching station = %v, kept %d channels", err, len(c.Station))
+	}
+}
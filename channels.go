@@ -63,16 +63,18 @@ func (e *Entry) manageChannels(app *App, sd *SD) (err error) {
 	}
 
 	sd.Req.Parameter = fmt.Sprintf("/%s", entry.Lineup)
-	sd.Req.Type = "GET"
 
-	err = sd.Lineups()
+	err = sd.Channels()
+	if err != nil {
+		return
+	}
 
 	entry.headline()
 	var channelNames []string
 	var existing string
 	var addAll, removeAll bool
 
-	for _, station := range sd.Resp.Status.Lineups[0].Stations {
+	for _, station := range sd.Resp.Channels.Stations {
 		channelNames = append(channelNames, station.Name)
 	}
 
@@ -82,7 +84,7 @@ func (e *Entry) manageChannels(app *App, sd *SD) (err error) {
 
 	for _, cName := range channelNames {
 
-		for _, station := range sd.Resp.Status.Lineups[0].Stations {
+		for _, station := range sd.Resp.Channels.Stations {
 
 			if cName == station.Name {
 
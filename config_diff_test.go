@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	var a []string
+	for i := 1; i <= 20; i++ {
+		a = append(a, fmt.Sprintf("line %d", i))
+	}
+	b := append([]string{}, a...)
+	b[1] = "changed 2"
+	b = append(b[:15], b[16:]...) // Removes line 16
+
+	diff, changes := unifiedDiff("a.yaml", "b.yaml", strings.Join(a, "\n")+"\n", strings.Join(b, "\n")+"\n")
+	want := `--- a.yaml
++++ b.yaml
+@@ -1,5 +1,5 @@
+ line 1
+-line 2
++changed 2
+ line 3
+ line 4
+ line 5
+@@ -13,7 +13,6 @@
+ line 13
+ line 14
+ line 15
+-line 16
+ line 17
+ line 18
+ line 19
+`
+	if diff != want || changes != 3 {
+		t.Errorf("unifiedDiff() = %d changes\n%s\nwant 3 changes\n%s", changes, diff, want)
+	}
+
+	if diff, changes := unifiedDiff("a", "b", "x\ny\n", "x\ny\n"); len(diff) != 0 || changes != 0 {
+		t.Errorf("unifiedDiff() of equal texts = %q, %d", diff, changes)
+	}
+}
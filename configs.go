@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// webConfigs are the configuration files managed by the web UI, the pages show the selected one.
+// The login, the API key and TLS always come from the configuration file the web UI was started with.
+type webConfigs struct {
+	paths []string // Files and directories of -web-configs
+
+	mu       sync.Mutex
+	selected string
+}
+
+// configFiles returns the configuration files of the web UI: the file of -config first, then the configuration files
+// in its directory and in the files and directories of -web-configs. Without the web UI it is only the file of -config.
+func (app *App) configFiles() []string {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = filepath.Clean(name)
+		if !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+
+	if len(app.Config2) != 0 {
+		add(app.Config2)
+	}
+	if app.configs == nil {
+		return files
+	}
+
+	var dirs []string
+	if len(app.Config2) != 0 {
+		dirs = append(dirs, filepath.Dir(app.Config2))
+	}
+	for _, p := range app.configs.paths {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			dirs = append(dirs, p)
+		} else {
+			add(p)
+		}
+	}
+
+	var found []string
+	for _, dir := range dirs {
+		names, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		for _, name := range names {
+			if isConfigFile(name) {
+				found = append(found, filepath.Clean(name))
+			}
+		}
+	}
+	sort.Strings(found)
+	for _, name := range found {
+		add(name)
+	}
+	return files
+}
+
+// isConfigFile reports whether the YAML file is a configuration file of guide2go
+func isConfigFile(name string) bool {
+	data, err := os.ReadFile(name)
+	return err == nil && bytes.Contains(data, []byte("Account:")) && bytes.Contains(data, []byte("Files:"))
+}
+
+// selectedConfig returns the configuration file the pages of the web UI show, -config until another one is selected
+func (app *App) selectedConfig() string {
+	if app.configs == nil {
+		return app.Config2
+	}
+
+	app.configs.mu.Lock()
+	defer app.configs.mu.Unlock()
+	if len(app.configs.selected) == 0 {
+		return app.Config2
+	}
+	return app.configs.selected
+}
+
+// selectConfig selects a configuration file of the web UI
+func (app *App) selectConfig(name string) error {
+	if app.configs == nil {
+		return errors.New("configuration files can only be selected in the web UI")
+	}
+	if err := app.checkConfigFile(name); err != nil {
+		return err
+	}
+
+	app.configs.mu.Lock()
+	defer app.configs.mu.Unlock()
+	app.configs.selected = filepath.Clean(name)
+	return nil
+}
+
+// checkConfigFile returns an error unless name is one of the configuration files of the web UI
+func (app *App) checkConfigFile(name string) error {
+	for _, file := range app.configFiles() {
+		if file == filepath.Clean(name) {
+			return nil
+		}
+	}
+	return errors.Errorf("unknown configuration file %s", name)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfigFiles(t *testing.T) {
+	dir, more := t.TempDir(), t.TempDir()
+	write := func(name, data string) string {
+		if err := os.WriteFile(name, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	config := "Account:\n    Username: user\nFiles:\n    Cache: cache.json\n"
+	usOTA := write(filepath.Join(dir, "us-ota.yaml"), config)
+	cable := write(filepath.Join(dir, "cable.yaml"), config)
+	write(filepath.Join(dir, "compose.yaml"), "services:\n")
+	extra := write(filepath.Join(more, "eu.yaml"), config)
+
+	app := newApp()
+	app.Config2 = usOTA
+	if files := app.configFiles(); !reflect.DeepEqual(files, []string{usOTA}) {
+		t.Errorf("configFiles() = %v without the web UI, want only -config", files)
+	}
+
+	app.configs = &webConfigs{paths: []string{more, cable}}
+	if files := app.configFiles(); !reflect.DeepEqual(files, []string{usOTA, cable, extra}) {
+		t.Errorf("configFiles() = %v, want -config first and the configuration files without duplicates", files)
+	}
+
+	if app.selectedConfig() != usOTA {
+		t.Errorf("selectedConfig() = %s, want -config", app.selectedConfig())
+	}
+	if err := app.selectConfig(filepath.Join(dir, "compose.yaml")); err == nil {
+		t.Error("selected a file which is no configuration file")
+	}
+	if err := app.selectConfig(extra); err != nil || app.selectedConfig() != extra {
+		t.Errorf("selectConfig(%s) = %v, selected %s", extra, err, app.selectedConfig())
+	}
+}
+
+func TestWebLanguage(t *testing.T) {
+	c := config{File: filepath.Join(t.TempDir(), "test")}
+	c.InitConfig()
+	c.Options.Language = "de"
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newApp()
+	app.Config2 = c.File + ".yaml"
+	app.language = &webLanguage{}
+	if lang := app.Language(); lang != "de" {
+		t.Fatalf("Language() = %s, want de", lang)
+	}
+
+	// The language is kept until the file changes
+	app.language.lang = "cached"
+	if lang := app.Language(); lang != "cached" {
+		t.Errorf("Language() = %s, want the cached language", lang)
+	}
+	c.Options.Language = "en"
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(time.Minute)
+	if err := os.Chtimes(app.Config2, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	if lang := app.Language(); lang != "en" {
+		t.Errorf("Language() = %s, want en of the changed file", lang)
+	}
+}
+
+func TestWebConfig(t *testing.T) {
+	c := config{File: filepath.Join(t.TempDir(), "test")}
+	c.InitConfig()
+	c.Account.Password = "secret"
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	app := newApp()
+	app.Config2 = c.File + ".yaml"
+
+	// The handlers of the web UI read the configuration concurrently without the app
+	var wg sync.WaitGroup
+	loaded := make([][]byte, 4)
+	for i := range loaded {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := app.LoadConfig()
+			if err != nil {
+				t.Error(err)
+			}
+			loaded[i] = data
+		}()
+	}
+	wg.Wait()
+
+	if strings.Contains(string(loaded[0]), "secret") {
+		t.Error("LoadConfig() returned the password")
+	}
+	if preview, err := app.PreviewConfig(loaded[0]); err != nil || preview.Changes != 0 {
+		t.Errorf("PreviewConfig() = %+v, %v, want no changes against the saved configuration", preview, err)
+	}
+	if err := app.SaveConfig(loaded[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(app.Config.File) != 0 {
+		t.Errorf("Config of the app = %s, want it unchanged", app.Config.File)
+	}
+	saved, err := app.readConfig(context.Background(), app.Config2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Account.Password != "secret" {
+		t.Errorf("Password = %q, want the password kept by the empty one of the web UI", saved.Account.Password)
+	}
+}
+
+func TestWebCacheStats(t *testing.T) {
+	c := config{File: filepath.Join(t.TempDir(), "test")}
+	c.InitConfig()
+	c.Files.Cache = c.File + "_cache.db"
+	c.Options.CacheTTL.Schedules = 6 * time.Hour
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The web app never opens the configuration, the statistics are those of the selected one
+	app := newApp()
+	app.Config2 = c.File + ".yaml"
+	app.cacheCounters = &cacheCounters{}
+	stats, err := app.CacheStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Backend != "sqlite" || stats.TTL.Schedules != "6h0m0s" {
+		t.Errorf("CacheStats() = %+v, want the sqlite backend and the TTL of the configuration", stats)
+	}
+
+	app.cacheCounters.set(map[string]interface{}{"hits": int64(3), "misses": int64(1), "size": int64(42)})
+	if stats, err = app.CacheStats(); err != nil || stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("CacheStats() = %+v, %v, want the counters of the last update", stats, err)
+	}
+}
@@ -5,13 +5,17 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/yourusername/guide2go/pkg/i18n"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
 	"gopkg.in/yaml.v3"
 )
 
@@ -29,6 +33,7 @@ func (app *App) Configure(filename string) error {
 		app.Logger.WithError(err).Error("Failed to open configuration")
 		return errors.Wrap(err, "failed to open configuration")
 	}
+	i18n.SetLanguage(app.Config.Options.Language)
 
 	sd.Init(app)
 
@@ -272,21 +277,92 @@ func (c *config) InitConfig() {
 
 	// Options
 	c.Options.PosterAspect = "landscape"
+	c.Options.ArtworkPolicies = defaultArtworkPolicies()
 	c.Options.Schedule = 7
 	c.Options.SubtitleIntoDescription = true
 	c.Options.Credits = true
+	c.Options.CastImages = false
+	c.Options.Keywords = false
+	c.Options.EpisodeNumSystems = slices.Clone(episodeNumSystems)
+	c.Options.CategoryProfile = "none"
+	c.Options.CategoryMapping = map[string]string{}
 	c.Options.TVShowImages = false
 	c.Options.ImagesPath = "${images_path}"
 	c.Options.ProxyImages = false
+	c.Options.LocalLogos = false
 	c.Options.Hostname = "localhost:8080"
-	c.Options.CacheExpiration = 24 * time.Hour
+	c.Options.CompressXMLTV = "none"
+	c.Options.XMLTVPerLineup = false
+	c.Options.M3UStreamURL = ""
+	c.Options.ValidateXMLTV = "none"
+	c.Options.ChannelID = "callsign"
+	c.Options.Timezone = ""
+	c.Options.CacheBackend = ""
+	c.Options.CompressCache = true
+	c.Options.CacheLockTimeout = 0
+	c.Options.ProgramRetention = 0
+	c.Options.UpdateSchedule = defaultUpdateSchedule
+	c.Options.UpdateScheduleEnabled = false
 	c.Options.SDDownloadErrors = false
+	c.Options.SDBaseURL = schedulesdirect.DefaultBaseURL
+	c.Options.SDImageURL = ""
+	c.Options.SDOfflineCache = true
+	c.Options.ExpiryWarning = defaultExpiryWarning
+	c.Options.StatusInterval = defaultStatusInterval
+	c.Options.Language = i18n.English
+
+	// Timeouts
+	c.Options.Timeouts.Status = schedulesdirect.DefaultStatusTimeout
+	c.Options.Timeouts.Default = schedulesdirect.DefaultRequestTimeout
+	c.Options.Timeouts.Schedule = schedulesdirect.DefaultScheduleTimeout
+	c.Options.Timeouts.Program = schedulesdirect.DefaultProgramTimeout
+	c.Options.Timeouts.Update = 0
+
+	// Cache TTL
+	c.Options.CacheTTL.Schedules = defaultScheduleTTL
+	c.Options.CacheTTL.Programs = defaultProgramTTL
+	c.Options.CacheTTL.Metadata = defaultMetadataTTL
+
+	// Cache autosave
+	c.Options.CacheAutosave.Batches = 0
+	c.Options.CacheAutosave.Interval = defaultAutosaveInterval
+
+	// Web UI login
+	c.Options.WebLogin.Username = ""
+	c.Options.WebLogin.Password = ""
+	c.Options.WebLogin.SessionTimeout = defaultSessionTimeout
+	c.Options.WebLogin.ReadOnly.Username = ""
+	c.Options.WebLogin.ReadOnly.Password = ""
+	c.Options.APIKey = hex.EncodeToString(token)
+
+	// TLS
+	c.Options.TLS.Certificate = ""
+	c.Options.TLS.Key = ""
+	c.Options.TLS.SelfSigned = false
+
+	// Title marks
+	c.Options.TitleMarks.Live = defaultLiveMark
+	c.Options.TitleMarks.New = defaultNewMark
+	c.Options.TitleMarks.Placement = "suffix"
+
+	// New, live, premiere and previously shown flags
+	c.Options.BroadcastFlags = defaultBroadcastFlags()
+
+	// Placeholder programs
+	c.Options.Placeholder.Title = defaultPlaceholderTitle
+	c.Options.Placeholder.Lang = "en"
+	c.Options.Placeholder.FillGaps = false
+	c.Options.Placeholder.GapTitle = defaultGapTitle
 
 	// Rating
 	c.Options.Rating.Guidelines = true
 	c.Options.Rating.MaxEntries = 1
 	c.Options.Rating.Countries = []string{}
 	c.Options.Rating.CountryCodeAsSystem = false
+	c.Options.Rating.ContentAdvisory = "none"
+
+	// Outputs
+	c.Outputs = []xmltvOutput{}
 }
 
 // validate performs validation on the configuration
@@ -318,11 +394,135 @@ func (c *config) validate() error {
 		return errors.New("invalid poster aspect")
 	}
 
+	// Validate episode number systems
+	for _, system := range c.Options.EpisodeNumSystems {
+		if !slices.Contains(episodeNumSystems, system) && !slices.Contains(optionalEpisodeNumSystems, system) {
+			return errors.Errorf("invalid episode number system %s", system)
+		}
+	}
+
+	// Validate category profile
+	switch c.Options.CategoryProfile {
+	case "", "none", "plex", "emby", "tvheadend":
+		// Valid values
+	default:
+		return errors.New("invalid category profile")
+	}
+
+	// Validate XMLTV validation mode
+	switch c.Options.ValidateXMLTV {
+	case "", "none", "warn", "strict":
+		// Valid values
+	default:
+		return errors.New("invalid XMLTV validation mode")
+	}
+
+	// Validate language, empty is English
+	if len(c.Options.Language) != 0 && !i18n.Supported(c.Options.Language) {
+		return errors.Errorf("unsupported language %q, supported are %s", c.Options.Language, strings.Join(i18n.Languages(), ", "))
+	}
+
+	// Validate title marks placement
+	switch c.Options.TitleMarks.Placement {
+	case "", "suffix", "prefix", "sub-title", "none":
+		// Valid values
+	default:
+		return errors.New("invalid title marks placement")
+	}
+
+	// Validate broadcast flags
+	for kind, flags := range c.Options.BroadcastFlags {
+		if _, ok := defaultBroadcastFlags()[kind]; !ok {
+			return errors.Errorf("invalid broadcast %s of the broadcast flags", kind)
+		}
+		for _, flag := range flags {
+			if !slices.Contains(broadcastFlags, flag) {
+				return errors.Errorf("invalid flag %s of the broadcast %s", flag, kind)
+			}
+		}
+	}
+
+	// Validate content advisories
+	switch c.Options.Rating.ContentAdvisory {
+	case "", "none", "rating", "description":
+		// Valid values
+	default:
+		return errors.New("invalid content advisories option")
+	}
+
+	// Validate artwork policies
+	for programType := range c.Options.ArtworkPolicies {
+		switch programType {
+		case "movie", "episode", "series", "sports":
+			// Valid values
+		default:
+			return errors.Errorf("invalid program type %s of the artwork policies", programType)
+		}
+	}
+
+	// Validate update schedule
+	if len(c.Options.UpdateSchedule) != 0 {
+		if _, err := parseCron(c.Options.UpdateSchedule); err != nil {
+			return err
+		}
+	}
+
+	// Validate web UI login, a username without password would accept an empty one
+	if len(c.Options.WebLogin.Username) != 0 {
+		if len(c.Options.WebLogin.Password) == 0 {
+			return errors.New("web UI login requires a password")
+		}
+		if c.Options.WebLogin.SessionTimeout <= 0 {
+			return errors.New("web UI session timeout must be positive")
+		}
+	}
+	if r := c.Options.WebLogin.ReadOnly; len(r.Username) != 0 {
+		if len(c.Options.WebLogin.Username) == 0 {
+			return errors.New("web UI read-only user requires the web UI login")
+		}
+		if len(r.Password) == 0 {
+			return errors.New("web UI read-only user requires a password")
+		}
+		if r.Username == c.Options.WebLogin.Username {
+			return errors.New("web UI read-only user must differ from the admin")
+		}
+	}
+
+	// Validate TLS, the certificate needs its key
+	if (len(c.Options.TLS.Certificate) == 0) != (len(c.Options.TLS.Key) == 0) {
+		return errors.New("TLS requires a certificate and a key")
+	}
+
 	// Validate rating entries
 	if c.Options.Rating.MaxEntries < 0 || c.Options.Rating.MaxEntries > 10 {
 		return errors.New("rating max entries must be between 0 and 10")
 	}
 
+	// Validate outputs, every output needs its own file
+	files := map[string]bool{c.Files.XMLTV: true}
+	for _, output := range c.Outputs {
+		if output.Name == "" || output.File == "" {
+			return errors.New("outputs require a name and a file")
+		}
+		if !slices.Contains([]string{"", "xmltv", "json", "csv", "tsv"}, output.Format) {
+			return errors.Errorf("invalid format %s of output %s", output.Format, output.Name)
+		}
+		if files[output.File] {
+			return errors.Errorf("file %s of output %s is already written", output.File, output.Name)
+		}
+		files[output.File] = true
+	}
+
+	// Validate time zones, they are IANA names like Europe/Berlin
+	if _, err := time.LoadLocation(c.Options.Timezone); err != nil {
+		return errors.Wrap(err, "invalid time zone")
+	}
+	for _, channel := range c.Station {
+		if _, err := time.LoadLocation(channel.Timezone); err != nil {
+			return errors.Wrapf(err, "invalid time zone of station %s", channel.ID)
+		}
+	}
+
 	return nil
 }
 
@@ -331,12 +531,60 @@ func (c *config) updateNewOptions(data []byte) error {
 	var updated bool
 
 	// Check and update new options
+	if !bytes.Contains(data, []byte("Artwork by program type")) {
+		updated = true
+		c.Options.ArtworkPolicies = defaultArtworkPolicies()
+		logger.Info("Added artwork by program type option")
+	}
+
 	if !bytes.Contains(data, []byte("credits tag")) {
 		updated = true
 		c.Options.Credits = true
 		logger.Info("Added credits tag option")
 	}
 
+	if !bytes.Contains(data, []byte("keyword tags")) {
+		updated = true
+		c.Options.Keywords = false
+		logger.Info("Added keyword tags option")
+	}
+
+	if !bytes.Contains(data, []byte("Episode number systems")) {
+		updated = true
+		c.Options.EpisodeNumSystems = slices.Clone(episodeNumSystems)
+		logger.Info("Added episode number systems option")
+	}
+
+	if !bytes.Contains(data, []byte("Category profile")) {
+		updated = true
+		c.Options.CategoryProfile = "none"
+		c.Options.CategoryMapping = map[string]string{}
+		logger.Info("Added category mapping options")
+	}
+
+	if !bytes.Contains(data, []byte("Title marks")) {
+		updated = true
+		c.Options.TitleMarks.Live = defaultLiveMark
+		c.Options.TitleMarks.New = defaultNewMark
+		c.Options.TitleMarks.Placement = "suffix"
+		logger.Info("Added title marks option")
+	}
+
+	if !bytes.Contains(data, []byte("Flags by broadcast")) {
+		updated = true
+		c.Options.BroadcastFlags = defaultBroadcastFlags()
+		logger.Info("Added broadcast flags option")
+	}
+
+	if !bytes.Contains(data, []byte("Placeholder programs")) {
+		updated = true
+		c.Options.Placeholder.Title = defaultPlaceholderTitle
+		c.Options.Placeholder.Lang = "en"
+		c.Options.Placeholder.FillGaps = false
+		c.Options.Placeholder.GapTitle = defaultGapTitle
+		logger.Info("Added placeholder programs option")
+	}
+
 	if !bytes.Contains(data, []byte("Rating:")) {
 		updated = true
 		c.Options.Rating.Guidelines = true
@@ -346,6 +594,12 @@ func (c *config) updateNewOptions(data []byte) error {
 		logger.Info("Added rating options")
 	}
 
+	if !bytes.Contains(data, []byte("Content advisories")) {
+		updated = true
+		c.Options.Rating.ContentAdvisory = "none"
+		logger.Info("Added content advisories option")
+	}
+
 	if !bytes.Contains(data, []byte("Local Images Cache:")) {
 		updated = true
 		c.Options.TVShowImages = false
@@ -364,6 +618,18 @@ func (c *config) updateNewOptions(data []byte) error {
 		logger.Info("Added proxy images option")
 	}
 
+	if !bytes.Contains(data, []byte("Download channel logos into the images path")) {
+		updated = true
+		c.Options.LocalLogos = false
+		logger.Info("Added channel logos option")
+	}
+
+	if !bytes.Contains(data, []byte("Insert cast images into credits")) {
+		updated = true
+		c.Options.CastImages = false
+		logger.Info("Added cast images option")
+	}
+
 	if !bytes.Contains(data, []byte("Hostname")) {
 		updated = true
 		c.Options.Hostname = "localhost:8080"
@@ -376,10 +642,193 @@ func (c *config) updateNewOptions(data []byte) error {
 		logger.Info("Added SD download errors option")
 	}
 
-	if !bytes.Contains(data, []byte("Cache Expiration")) {
+	if !bytes.Contains(data, []byte("Cache TTL")) {
+		updated = true
+		c.Options.CacheTTL.Schedules = defaultScheduleTTL
+		c.Options.CacheTTL.Programs = defaultProgramTTL
+		c.Options.CacheTTL.Metadata = defaultMetadataTTL
+		logger.Info("Added cache TTL option")
+	}
+
+	// Cache Expiration of older versions becomes the TTL of the schedules, saving the configuration removes it
+	if bytes.Contains(data, []byte("Cache Expiration")) {
+		var previous struct {
+			Options struct {
+				CacheExpiration time.Duration `yaml:"Cache Expiration"`
+			} `yaml:"Options"`
+		}
+		if err := yaml.Unmarshal(data, &previous); err != nil {
+			return errors.Wrap(err, "failed to parse cache expiration option")
+		}
+
+		updated = true
+		if expiration := previous.Options.CacheExpiration; expiration > 0 && !bytes.Contains(data, []byte("Cache TTL")) {
+			c.Options.CacheTTL.Schedules = expiration
+			logger.WithField("expiration", expiration).Info("Replaced cache expiration option by the cache TTL of the schedules")
+		} else {
+			logger.Info("Removed cache expiration option")
+		}
+	}
+
+	if !bytes.Contains(data, []byte("Compress XMLTV")) {
+		updated = true
+		c.Options.CompressXMLTV = "none"
+		logger.Info("Added compress XMLTV option")
+	}
+
+	if !bytes.Contains(data, []byte("XMLTV file per lineup")) {
+		updated = true
+		c.Options.XMLTVPerLineup = false
+		logger.Info("Added XMLTV file per lineup option")
+	}
+
+	if !bytes.Contains(data, []byte("Validate XMLTV")) {
+		updated = true
+		c.Options.ValidateXMLTV = "none"
+		logger.Info("Added validate XMLTV option")
+	}
+
+	if !bytes.Contains(data, []byte("Outputs:")) {
+		updated = true
+		c.Outputs = []xmltvOutput{}
+		logger.Info("Added outputs option")
+	}
+
+	if !bytes.Contains(data, []byte("XMLTV channel ID")) {
+		updated = true
+		c.Options.ChannelID = "callsign"
+		logger.Info("Added XMLTV channel ID option")
+	}
+
+	if !bytes.Contains(data, []byte("Time zone of the programmes")) {
+		updated = true
+		c.Options.Timezone = ""
+		logger.Info("Added time zone option")
+	}
+
+	if !bytes.Contains(data, []byte("Cache Backend")) {
+		updated = true
+		c.Options.CacheBackend = ""
+		logger.Info("Added cache backend option")
+	}
+
+	if !bytes.Contains(data, []byte("Compress Cache")) {
+		updated = true
+		c.Options.CompressCache = true
+		logger.Info("Added compress cache option")
+	}
+
+	if !bytes.Contains(data, []byte("Cache Lock Timeout")) {
+		updated = true
+		c.Options.CacheLockTimeout = 0
+		logger.Info("Added cache lock timeout option")
+	}
+
+	if !bytes.Contains(data, []byte("Program Retention")) {
+		updated = true
+		c.Options.ProgramRetention = 0
+		logger.Info("Added program retention option")
+	}
+
+	if !bytes.Contains(data, []byte("Update Schedule:")) {
+		updated = true
+		c.Options.UpdateSchedule = defaultUpdateSchedule
+		logger.Info("Added update schedule option")
+	}
+
+	if !bytes.Contains(data, []byte("Update Schedule Enabled")) {
+		updated = true
+		c.Options.UpdateScheduleEnabled = false
+		logger.Info("Added update schedule enabled option")
+	}
+
+	if !bytes.Contains(data, []byte("Warn days before the account expires")) {
+		updated = true
+		c.Options.ExpiryWarning = defaultExpiryWarning
+		logger.Info("Added account expiry warning option")
+	}
+
+	if !bytes.Contains(data, []byte("Poll the Schedules Direct status")) {
+		updated = true
+		c.Options.StatusInterval = defaultStatusInterval
+		logger.Info("Added Schedules Direct status interval option")
+	}
+
+	if !bytes.Contains(data, []byte("Language of the menus")) {
 		updated = true
-		c.Options.CacheExpiration = 24 * time.Hour
-		logger.Info("Added cache expiration option")
+		c.Options.Language = i18n.English
+		logger.Info("Added language option")
+	}
+
+	if !bytes.Contains(data, []byte("Web UI login")) {
+		updated = true
+		c.Options.WebLogin.Username = ""
+		c.Options.WebLogin.Password = ""
+		c.Options.WebLogin.SessionTimeout = defaultSessionTimeout
+		logger.Info("Added web UI login option")
+	}
+
+	if !bytes.Contains(data, []byte("Read-only user")) {
+		updated = true
+		c.Options.WebLogin.ReadOnly.Username = ""
+		c.Options.WebLogin.ReadOnly.Password = ""
+		logger.Info("Added web UI read-only user option")
+	}
+
+	// Existing configurations keep /run without a key, a generated key would break their cronjobs
+	if !bytes.Contains(data, []byte("API Key")) {
+		updated = true
+		c.Options.APIKey = ""
+		logger.Info("Added API key option")
+	}
+
+	if !bytes.Contains(data, []byte("M3U playlist stream URL")) {
+		updated = true
+		c.Options.M3UStreamURL = ""
+		logger.Info("Added M3U playlist option")
+	}
+
+	if !bytes.Contains(data, []byte("TLS of the image server")) {
+		updated = true
+		c.Options.TLS.Certificate = ""
+		c.Options.TLS.Key = ""
+		c.Options.TLS.SelfSigned = false
+		logger.Info("Added TLS option")
+	}
+
+	if !bytes.Contains(data, []byte("Cache Autosave")) {
+		updated = true
+		c.Options.CacheAutosave.Batches = 0
+		c.Options.CacheAutosave.Interval = defaultAutosaveInterval
+		logger.Info("Added cache autosave option")
+	}
+
+	if !bytes.Contains(data, []byte("Schedules Direct API URL")) {
+		updated = true
+		c.Options.SDBaseURL = schedulesdirect.DefaultBaseURL
+		logger.Info("Added Schedules Direct API URL option")
+	}
+
+	if !bytes.Contains(data, []byte("Schedules Direct image URL")) {
+		updated = true
+		c.Options.SDImageURL = ""
+		logger.Info("Added Schedules Direct image URL option")
+	}
+
+	if !bytes.Contains(data, []byte("Schedules Direct is offline")) {
+		updated = true
+		c.Options.SDOfflineCache = true
+		logger.Info("Added offline cache option")
+	}
+
+	if !bytes.Contains(data, []byte("Timeouts:")) {
+		updated = true
+		c.Options.Timeouts.Status = schedulesdirect.DefaultStatusTimeout
+		c.Options.Timeouts.Default = schedulesdirect.DefaultRequestTimeout
+		c.Options.Timeouts.Schedule = schedulesdirect.DefaultScheduleTimeout
+		c.Options.Timeouts.Program = schedulesdirect.DefaultProgramTimeout
+		c.Options.Timeouts.Update = 0
+		logger.Info("Added timeouts option")
 	}
 
 	if updated {
@@ -410,6 +859,33 @@ func (c *config) GetChannelList(lineup string) (list []string) {
 	return
 }
 
+// GetLineups returns the lineups of the configured stations in the order of the configuration
+func (c *config) GetLineups() (lineups []string) {
+	for _, channel := range c.Station {
+		if !slices.Contains(lineups, channel.Lineup) {
+			lineups = append(lineups, channel.Lineup)
+		}
+	}
+	return
+}
+
+// GetLocation returns the time zone of the programmes of a station, the time zone of the station overrides the option
+func (c *config) GetLocation(id string) *time.Location {
+	name := c.Options.Timezone
+	for _, channel := range c.Station {
+		if id == channel.ID && len(channel.Timezone) != 0 {
+			name = channel.Timezone
+		}
+	}
+
+	// The time zones are validated when the configuration is opened
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 func (c *config) GetLineupCountry(id string) (countryCode string) {
 
 	for _, channel := range c.Station {
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+	from := time.Date(2030, 1, 1, 12, 30, 0, 0, time.UTC) // Tuesday
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"0 4 * * *", time.Date(2030, 1, 2, 4, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2030, 1, 1, 12, 45, 0, 0, time.UTC)},
+		{"30 12 * * *", time.Date(2030, 1, 2, 12, 30, 0, 0, time.UTC)},
+		{"0 3 * * 0", time.Date(2030, 1, 6, 3, 0, 0, 0, time.UTC)},
+		{"0 3 * * 7", time.Date(2030, 1, 6, 3, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * 5", time.Date(2030, 1, 4, 0, 0, 0, 0, time.UTC)}, // The first Friday is before the 15th
+		{"0 6-8/2 1 3 *", time.Date(2030, 3, 1, 6, 0, 0, 0, time.UTC)},
+		{"5/10 * * * *", time.Date(2030, 1, 1, 12, 35, 0, 0, time.UTC)}, // 5-59/10
+		{"0 22/5 * * *", time.Date(2030, 1, 1, 22, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		schedule, err := parseCron(c.spec)
+		if err != nil {
+			t.Fatalf("parseCron(%q) failed: %v", c.spec, err)
+		}
+		if next := schedule.next(from); !next.Equal(c.next) {
+			t.Errorf("next(%q) = %v, want %v", c.spec, next, c.next)
+		}
+		if !schedule.matches(c.next) {
+			t.Errorf("%q doesn't match its next run %v", c.spec, c.next)
+		}
+	}
+
+	for _, spec := range []string{"", "0 4 * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("parseCron(%q) accepted an invalid expression", spec)
+		}
+	}
+}
@@ -3,17 +3,18 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
 	"golang.org/x/time/rate"
 )
 
@@ -30,7 +31,44 @@ var (
 
 // Update updates data from Schedules Direct and creates the XMLTV file
 func (app *App) Update(ctx context.Context, sd *SD, filename string) error {
+	// The run history and the logs of the updates are kept next to the configuration file
+	app.Config.File = strings.TrimSuffix(filename, filepath.Ext(filename))
+	runs, _ := loadRuns(app)
+	run := runRecord{ID: nextRunID(runs), Started: time.Now()}
+	if err := app.runLog.start(runLogFile(app, run.ID)); err != nil {
+		app.Logger.WithError(err).Warn("Failed to create the log file of the update")
+	}
+	defer app.runLog.stop()
+
 	app.Logger.WithField("filename", filename).Info("Starting data update")
+
+	err := app.update(ctx, sd, filename, true)
+	if err := saveLastRun(app, run.Started, err); err != nil {
+		app.Logger.WithError(err).Warn("Failed to save the result of the update")
+	}
+
+	run.Finished = time.Now()
+	run.Programs = sd.downloaded
+	if err != nil {
+		run.Error = err.Error()
+	} else if _, info, ok := statXMLTV(&app.Config); ok {
+		run.FileSize = info.Size()
+	}
+	if err := saveRun(app, run); err != nil {
+		app.Logger.WithError(err).Warn("Failed to save the update in the run history")
+	}
+	return err
+}
+
+// Warm downloads the stations, schedules and programs of a configuration into the cache without creating the XMLTV file.
+// The cache can be filled off-peak, the next update only downloads what changed since.
+func (app *App) Warm(ctx context.Context, sd *SD, filename string) error {
+	app.Logger.WithField("filename", filename).Info("Starting cache warming")
+	return app.update(ctx, sd, filename, false)
+}
+
+// update downloads the data from Schedules Direct into the cache and creates the XMLTV file if xmltv is set
+func (app *App) update(ctx context.Context, sd *SD, filename string, xmltv bool) error {
 	app.Config.File = strings.TrimSuffix(filename, filepath.Ext(filename))
 	if _, err := os.ReadFile(fmt.Sprintf("%s.yaml", app.Config.File)); err != nil {
 		app.Logger.WithError(err).Error("Failed to read configuration file")
@@ -40,44 +78,102 @@ func (app *App) Update(ctx context.Context, sd *SD, filename string) error {
 		app.Logger.WithError(err).Error("Failed to open configuration")
 		return errors.Wrap(err, "failed to open configuration")
 	}
-	if err := sd.Init(); err != nil {
+	app.selectCacheStore()
+
+	// A concurrent update of the same cache would overwrite the entries of this one
+	unlock, err := app.lockCache(ctx)
+	if err != nil {
+		app.Logger.WithError(err).Error("Failed to lock cache")
+		return err
+	}
+	defer unlock()
+
+	if err := sd.Init(app); err != nil {
 		app.Logger.WithError(err).Error("Failed to initialize SD client")
 		return errors.Wrap(err, "failed to initialize SD client")
 	}
-	if len(sd.Token) == 0 {
+
+	// Overall deadline of the update, cancels all pending requests once it is exceeded
+	if deadline := app.Config.Options.Timeouts.Update; deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+	sd.SetContext(ctx)
+
+	// Warming without Schedules Direct has nothing to do
+	var offline bool
+	sd.progress.start("Logging in", 0)
+	if len(sd.client.Token()) == 0 {
 		if err := sd.Login(); err != nil {
-			app.Logger.WithError(err).Error("Failed to login to Schedules Direct")
-			return errors.Wrap(err, "failed to login to Schedules Direct")
+			if !xmltv || !app.useCacheWhenOffline(err) {
+				app.Logger.WithError(err).Error("Failed to login to Schedules Direct")
+				return errors.Wrap(err, "failed to login to Schedules Direct")
+			}
+			offline = true
 		}
 	}
-	if err := sd.GetData(ctx); err != nil {
-		app.Logger.WithError(err).Error("Failed to get data from Schedules Direct")
-		return errors.Wrap(err, "failed to get data from Schedules Direct")
+	if !offline {
+		if err := sd.GetData(ctx); err != nil {
+			// The token was rejected and a new login failed as well, the next run must not reuse it
+			if errors.Is(err, schedulesdirect.ErrTokenInvalid) {
+				removeToken(app)
+			}
+			if !xmltv || !app.useCacheWhenOffline(err) {
+				app.Logger.WithError(err).Error("Failed to get data from Schedules Direct")
+				return errors.Wrap(err, "failed to get data from Schedules Direct")
+			}
+		}
 	}
 	runtime.GC()
-	if err := app.CreateXMLTV(ctx, filename); err != nil {
-		app.Logger.WithError(err).Error("Failed to create XMLTV file")
-		return errors.Wrap(err, "failed to create XMLTV file")
+	if xmltv {
+		sd.progress.start("Generating XMLTV", 0)
+		if err := app.CreateXMLTV(ctx, filename); err != nil {
+			app.Logger.WithError(err).Error("Failed to create XMLTV file")
+			return errors.Wrap(err, "failed to create XMLTV file")
+		}
+	} else {
+		stats := app.Cache.GetStats()
+		app.Logger.WithFields(logrus.Fields{
+			"channels":   stats["channels"],
+			"broadcasts": stats["broadcasts"],
+			"programs":   stats["programs"],
+			"metadata":   stats["metadata"],
+		}).Info("Warmed cache")
 	}
 	app.Cache.CleanUp(app)
 	runtime.GC()
 	return nil
 }
 
+// useCacheWhenOffline reports whether the XMLTV file is created from the existing cache after err
+func (app *App) useCacheWhenOffline(err error) bool {
+	if !errors.Is(err, schedulesdirect.ErrOffline) || !app.Config.Options.SDOfflineCache {
+		return false
+	}
+
+	app.Logger.WithError(err).Warn("Schedules Direct is offline, creating XMLTV file from the cache")
+	return true
+}
+
 // GetData fetches and processes data from Schedules Direct
 func (sd *SD) GetData(ctx context.Context) error {
-	logger := logger.WithField("operation", "GetData")
+	app := sd.app
 
 	// Open and initialize cache
 	if err := app.Cache.Open(app); err != nil {
 		return errors.Wrap(err, "failed to open cache")
 	}
 	app.Cache.Init()
+	sd.autosave = newAutosave(app)
 
 	// Get account status
 	if err := sd.Status(); err != nil {
 		return errors.Wrap(err, "failed to get account status")
 	}
+	if err := sd.Resp.Status.Online(); err != nil {
+		return err
+	}
 
 	// Process lineups
 	if err := sd.processLineups(ctx); err != nil {
@@ -102,118 +198,138 @@ func (sd *SD) GetData(ctx context.Context) error {
 	return nil
 }
 
+// inParallel runs job for n items with at most maxConcurrentRequests jobs in flight and returns the first error
+func inParallel(ctx context.Context, n int, job func(i int) error) error {
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	sem := make(chan struct{}, maxConcurrentRequests)
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := job(i); err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
 // processLineups processes all lineups from Schedules Direct
 func (sd *SD) processLineups(ctx context.Context) error {
-	logger := logger.WithField("operation", "processLineups")
+	app := sd.app
+	logger := app.Logger.WithField("operation", "processLineups")
 
-	// Reset channel cache
-	app.Cache.Channel = make(map[string]G2GCache)
+	// Remove channels which are no longer configured
+	app.Cache.RemoveChannels(app)
 
-	// Get lineups from status
+	// Station map has not changed since the last run
 	var lineups []string
+	var modified = make(map[string]string)
 	for _, l := range sd.Resp.Status.Lineups {
+		if app.Cache.LineupUnchanged(l.Lineup, l.Modified, app) {
+			continue
+		}
 		lineups = append(lineups, l.Lineup)
+		modified[l.Lineup] = l.Modified
 	}
 
-	// Process each lineup
-	for _, id := range lineups {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			sd.Req.Parameter = fmt.Sprintf("/%s", id)
-			sd.Req.Type = "GET"
+	err := inParallel(ctx, len(lineups), func(i int) error {
+		id := lineups[i]
 
-			if err := sd.Lineups(); err != nil {
-				logger.WithError(err).WithField("lineup", id).Error("Failed to get lineup")
-				continue
-			}
+		body, err := sd.client.StationMap(ctx, id)
+		if err != nil {
+			logger.WithError(err).WithField("lineup", id).Error("Failed to get lineup")
+			return nil
+		}
 
-			if err := app.Cache.AddStations(ctx, &sd.Resp.Body, id); err != nil {
-				logger.WithError(err).WithField("lineup", id).Error("Failed to add stations")
-				continue
-			}
+		if err := app.Cache.AddStations(ctx, &body, id, app); err != nil {
+			logger.WithError(err).WithField("lineup", id).Error("Failed to add stations")
+			return nil
 		}
+
+		app.Cache.SetLineupModified(id, modified[id])
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	logger.WithFields(logrus.Fields{
+		"lineups": len(sd.Resp.Status.Lineups),
+		"skipped": len(sd.Resp.Status.Lineups) - len(lineups),
+	}).Info("Processed lineups")
+
 	return nil
 }
 
 // processSchedules processes schedules for all channels
 func (sd *SD) processSchedules(ctx context.Context) error {
-	logger := logger.WithField("operation", "processSchedules")
+	app := sd.app
+	logger := app.Logger.WithField("operation", "processSchedules")
 
 	// Prepare schedule dates
-	days := make([]string, Config.Options.Schedule)
-	for i := 0; i < Config.Options.Schedule; i++ {
+	days := make([]string, app.Config.Options.Schedule)
+	for i := 0; i < app.Config.Options.Schedule; i++ {
 		days[i] = time.Now().Add(time.Hour * time.Duration(24*i)).Format("2006-01-02")
 	}
 
-	logger.WithField("days", Config.Options.Schedule).Info("Downloading schedules")
-
-	// Process channels in batches
-	var wg sync.WaitGroup
-	errChan := make(chan error, 1)
-
-	for i := 0; i < len(Config.Station); i += batchSize {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			end := i + batchSize
-			if end > len(Config.Station) {
-				end = len(Config.Station)
-			}
+	logger.WithField("days", app.Config.Options.Schedule).Info("Downloading schedules")
 
-			// Prepare batch
-			channels := make([]interface{}, 0, end-i)
-			for _, channel := range Config.Station[i:end] {
-				channel.Date = days
-				channels = append(channels, channel)
-			}
-
-			// Marshal batch data
-			data, err := json.Marshal(channels)
-			if err != nil {
-				return errors.Wrap(err, "failed to marshal channel data")
-			}
-			sd.Req.Data = data
-
-			// Get schedule data
-			if err := sd.Schedule(); err != nil {
-				logger.WithError(err).WithField("batch", i/batchSize).Error("Failed to get schedule")
-				continue
-			}
+	// Split channels into batches, every batch is downloaded with its own request
+	var batches [][]schedulesdirect.ScheduleRequest
+	for i := 0; i < len(app.Config.Station); i += batchSize {
+		end := i + batchSize
+		if end > len(app.Config.Station) {
+			end = len(app.Config.Station)
+		}
 
-			// Process schedule data
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				if err := app.Cache.AddSchedule(ctx, &sd.Resp.Body); err != nil {
-					select {
-					case errChan <- errors.Wrap(err, "failed to add schedule"):
-					default:
-					}
-				}
-			}()
+		batch := make([]schedulesdirect.ScheduleRequest, 0, end-i)
+		for _, channel := range app.Config.Station[i:end] {
+			batch = append(batch, schedulesdirect.ScheduleRequest{StationID: channel.ID, Date: days})
 		}
+		batches = append(batches, batch)
 	}
 
-	// Wait for all goroutines to complete
-	wg.Wait()
-	close(errChan)
+	sd.progress.start("Downloading schedules", len(batches))
+	return inParallel(ctx, len(batches), func(i int) error {
+		defer sd.progress.advance()
 
-	// Check for errors
-	if err := <-errChan; err != nil {
-		return err
-	}
+		body, err := sd.client.Schedules(ctx, batches[i])
+		if err != nil {
+			logger.WithError(err).WithField("batch", i).Error("Failed to get schedule")
+			return nil
+		}
 
-	return nil
+		if err := app.Cache.AddSchedule(ctx, &body, app); err != nil {
+			return errors.Wrap(err, "failed to add schedule")
+		}
+		sd.autosave.batchDone()
+		return nil
+	})
 }
 
 // processProgramsAndMetadata processes programs and metadata
 func (sd *SD) processProgramsAndMetadata(ctx context.Context) error {
-	logger := logger.WithField("operation", "processProgramsAndMetadata")
+	app := sd.app
+	logger := app.Logger.WithField("operation", "processProgramsAndMetadata")
 
 	// Get program IDs
 	programIDs := app.Cache.GetRequiredProgramIDs()
@@ -232,84 +348,22 @@ func (sd *SD) processProgramsAndMetadata(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
-			// Configure request based on type
-			switch t {
-			case "metadata":
-				sd.Req.URL = fmt.Sprintf("%smetadata/programs", sd.BaseURL)
-				sd.Req.Call = "metadata"
-				programIDs = app.Cache.GetRequiredMetaIDs()
-				logger.WithField("count", len(programIDs)).Info("Downloading metadata")
-			case "programs":
-				sd.Req.URL = fmt.Sprintf("%sprograms", sd.BaseURL)
-				sd.Req.Call = "programs"
-				logger.WithField("count", len(programIDs)).Info("Downloading programs")
-			}
-
-			// Process in batches
-			batchSize := metadataBatchSize
-			if t == "programs" {
-				batchSize = batchSize
-			}
+		}
 
-			var wg sync.WaitGroup
-			errChan := make(chan error, 1)
-
-			for i := 0; i < len(programIDs); i += batchSize {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				default:
-					end := i + batchSize
-					if end > len(programIDs) {
-						end = len(programIDs)
-					}
-
-					// Prepare batch
-					programs := make([]interface{}, 0, end-i)
-					for _, p := range programIDs[i:end] {
-						programs = append(programs, p)
-					}
-
-					// Marshal batch data
-					data, err := json.Marshal(programs)
-					if err != nil {
-						return errors.Wrap(err, "failed to marshal program data")
-					}
-					sd.Req.Data = data
-
-					// Get program data
-					if err := sd.Program(); err != nil {
-						logger.WithError(err).WithField("batch", i/batchSize).Error("Failed to get programs")
-						continue
-					}
-
-					// Process program data
-					wg.Add(1)
-					go func() {
-						defer wg.Done()
-						var err error
-						switch t {
-						case "metadata":
-							err = app.Cache.AddMetadata(ctx, &sd.Resp.Body, &wg)
-						case "programs":
-							err = app.Cache.AddProgram(ctx, &sd.Resp.Body, &wg)
-						}
-						if err != nil {
-							select {
-							case errChan <- errors.Wrap(err, "failed to add program data"):
-							default:
-							}
-						}
-					}()
-				}
-			}
+		switch t {
+		case "metadata":
+			programIDs = app.Cache.GetRequiredMetaIDs()
+			logger.WithField("count", len(programIDs)).Info("Downloading metadata")
+		case "programs":
+			logger.WithField("count", len(programIDs)).Info("Downloading programs")
+		}
 
-			// Wait for all goroutines to complete
-			wg.Wait()
-			close(errChan)
+		if err := sd.downloadPrograms(ctx, t, programIDs); err != nil {
+			return err
+		}
 
-			// Check for errors
-			if err := <-errChan; err != nil {
+		if t == "programs" {
+			if err := sd.processSeriesFallback(ctx, programIDs); err != nil {
 				return err
 			}
 		}
@@ -317,3 +371,97 @@ func (sd *SD) processProgramsAndMetadata(ctx context.Context) error {
 
 	return nil
 }
+
+// downloadPrograms downloads programs or metadata in batches and adds them to the cache
+func (sd *SD) downloadPrograms(ctx context.Context, t string, programIDs []string) error {
+	app := sd.app
+	logger := app.Logger.WithField("operation", "downloadPrograms")
+
+	// Configure request based on type
+	size := batchSize
+	download := sd.client.Programs
+	add := app.Cache.AddProgram
+
+	if t == "metadata" {
+		size = metadataBatchSize
+		download = sd.client.Metadata
+		add = app.Cache.AddMetadata
+	}
+
+	// Split program IDs into batches, every batch is downloaded with its own request
+	var batches [][]string
+	for i := 0; i < len(programIDs); i += size {
+		end := i + size
+		if end > len(programIDs) {
+			end = len(programIDs)
+		}
+		batches = append(batches, programIDs[i:end])
+	}
+
+	if t == "programs" {
+		sd.downloaded += len(programIDs)
+	}
+
+	sd.progress.start("Downloading "+t, len(batches))
+	return inParallel(ctx, len(batches), func(i int) error {
+		defer sd.progress.advance()
+
+		body, err := download(ctx, batches[i])
+		if err != nil {
+			logger.WithError(err).WithField("batch", i).Errorf("Failed to get %s", t)
+			return nil
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		if err := add(ctx, &body, &wg, app); err != nil {
+			return errors.Wrapf(err, "failed to add %s", t)
+		}
+		sd.autosave.batchDone()
+		return nil
+	})
+}
+
+// processSeriesFallback downloads the generic series program (SH) of every episode (EP) which could not be downloaded,
+// so the XMLTV file gets at least the title and description of the series
+func (sd *SD) processSeriesFallback(ctx context.Context, programIDs []string) error {
+	app := sd.app
+
+	var episodes = make(map[string]string)
+	var series []string
+
+	for _, id := range app.Cache.MissingPrograms(programIDs) {
+		seriesID, ok := seriesProgramID(id)
+		if !ok {
+			continue
+		}
+		episodes[id] = seriesID
+		series = append(series, seriesID)
+	}
+
+	if len(episodes) == 0 {
+		return nil
+	}
+
+	// Episodes of the same series share the series program
+	slices.Sort(series)
+	series = slices.Compact(series)
+
+	if err := sd.downloadPrograms(ctx, "programs", app.Cache.MissingPrograms(series)); err != nil {
+		return err
+	}
+
+	var used int
+	for episodeID, seriesID := range episodes {
+		if app.Cache.UseSeriesProgram(episodeID, seriesID) {
+			used++
+		}
+	}
+
+	app.Logger.WithFields(logrus.Fields{
+		"episodes": len(episodes),
+		"fallback": used,
+	}).Info("Used series programs for missing episodes")
+
+	return nil
+}
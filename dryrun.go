@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+	"github.com/yourusername/guide2go/web/handlers"
+)
+
+// DryRun returns what an update of the selected configuration would download for the web UI, nothing is downloaded.
+// Only the account status is requested. The programs and the artwork are counted from the cached schedules,
+// the schedules an update downloads first can add more.
+func (app *App) DryRun(ctx context.Context) (*handlers.DryRun, error) {
+	// The plan opens the configuration and the cache in a copy of the app, the web UI keeps its configuration
+	plan := *app
+	plan.Cache = &cache{}
+	if err := plan.openConfig(ctx); err != nil {
+		return nil, err
+	}
+	_, token := loadToken(&plan)
+
+	sd, err := plan.webSD()
+	if err != nil {
+		return nil, err
+	}
+
+	closeCache, err := plan.openCache()
+	if err != nil {
+		return nil, err
+	}
+	defer closeCache()
+
+	run := planUpdate(&plan, sd.Resp.Status.Lineups, time.Now())
+	if !token {
+		run.Requests.Login = 1
+	}
+	run.APICalls = run.Requests.Login + run.Requests.Status + run.Requests.Lineups + run.Requests.Schedules + run.Requests.Programs + run.Requests.Metadata
+	return run, nil
+}
+
+// planUpdate returns the downloads of an update with the lineups of the account and the opened cache, without the login
+func planUpdate(app *App, lineups []schedulesdirect.StatusLineup, now time.Time) *handlers.DryRun {
+	c := app.Cache
+	run := &handlers.DryRun{
+		ScheduleDays: app.Config.Options.Schedule,
+		Channels:     len(app.Config.Station),
+		Requests:     handlers.DryRunRequests{Status: 1},
+	}
+
+	for _, l := range lineups {
+		refresh := !c.LineupUnchanged(l.Lineup, l.Modified, app)
+		run.Lineups = append(run.Lineups, handlers.DryRunLineup{Lineup: l.Lineup, Name: l.Name, Refresh: refresh})
+		if refresh {
+			run.Requests.Lineups++
+		}
+	}
+
+	for i := 0; i < run.ScheduleDays; i++ {
+		run.Dates = append(run.Dates, now.Add(time.Hour*time.Duration(24*i)).Format("2006-01-02"))
+	}
+	for _, station := range app.Config.Station {
+		if schedule, _ := c.Inspect(station.ID, app)["schedule"].([]G2GCache); len(schedule) == 0 {
+			run.NewChannels++
+		}
+	}
+
+	run.Programs = len(c.GetRequiredProgramIDs())
+	run.Metadata = len(c.GetRequiredMetaIDs())
+	run.Requests.Schedules = batches(run.Channels, batchSize)
+	run.Requests.Programs = batches(run.Programs, batchSize)
+	run.Requests.Metadata = batches(run.Metadata, metadataBatchSize)
+	return run
+}
+
+// batches returns the number of requests of n IDs in batches of size
+func batches(n, size int) int {
+	return (n + size - 1) / size
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+)
+
+func TestPlanUpdate(t *testing.T) {
+	c := newCache()
+	c.Lineup = map[string]string{"USA-OTA-10001": "2026-10-01T12:00:00Z"}
+	c.Channel["10021"] = G2GCache{}
+	c.Channel["10022"] = G2GCache{}
+	c.Schedule["10021"] = []G2GCache{{ProgramID: "EP012345670001", Md5: "a"}}
+
+	app := &App{Cache: c}
+	app.Config.Options.Schedule = 3
+	app.Config.Station = []channel{
+		{Name: "WABC", ID: "10021", Lineup: "USA-OTA-10001"},
+		{Name: "WCBS", ID: "10022", Lineup: "USA-OTA-10001"},
+		{Name: "ESPN", ID: "10179", Lineup: "USA-NY31587-X"},
+	}
+
+	lineups := []schedulesdirect.StatusLineup{
+		{Lineup: "USA-OTA-10001", Modified: "2026-10-01T12:00:00Z"},
+		{Lineup: "USA-NY31587-X", Modified: "2026-10-02T12:00:00Z"},
+	}
+	run := planUpdate(app, lineups, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC))
+
+	if len(run.Lineups) != 2 || run.Lineups[0].Refresh || !run.Lineups[1].Refresh {
+		t.Errorf("lineups = %+v, want only USA-NY31587-X refreshed", run.Lineups)
+	}
+	if len(run.Dates) != 3 || run.Dates[2] != "2026-10-17" {
+		t.Errorf("dates = %v, want 3 days from 2026-10-15", run.Dates)
+	}
+	if run.Channels != 3 || run.NewChannels != 2 || run.Programs != 1 {
+		t.Errorf("channels = %d, new channels = %d, programs = %d, want 3, 2 and 1", run.Channels, run.NewChannels, run.Programs)
+	}
+	if r := run.Requests; r.Status != 1 || r.Lineups != 1 || r.Schedules != 1 || r.Programs != 1 || r.Metadata != 0 {
+		t.Errorf("requests = %+v", r)
+	}
+}
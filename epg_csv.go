@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// csvHeader are the columns of the CSV guide
+var csvHeader = []string{"channel", "start", "stop", "title", "episode", "genre"}
+
+// csvEPG writes the programmes of the guide as flat CSV or TSV table for spreadsheets and BI tools, one row per programme.
+// The channels are not written, the rows refer to them by the XMLTV channel ID.
+type csvEPG struct {
+	w *csv.Writer
+}
+
+// newCSVEPG returns the CSV writer of the guide, tabs separate the columns of TSV
+func newCSVEPG(w io.Writer, tabs bool) *csvEPG {
+	c := &csvEPG{w: csv.NewWriter(w)}
+	if tabs {
+		c.w.Comma = '\t'
+	}
+	return c
+}
+
+// begin writes the header before the channels
+func (c *csvEPG) begin(name string) error {
+	if name == "channels" {
+		return c.w.Write(csvHeader)
+	}
+	return nil
+}
+
+// end flushes the rows
+func (c *csvEPG) end() error {
+	c.w.Flush()
+	return errors.Wrap(c.w.Error(), "failed to write CSV guide")
+}
+
+// encode writes the row of a programme, channels have no rows
+func (c *csvEPG) encode(v interface{}) error {
+	element, ok := v.(Programme)
+	if !ok {
+		return nil
+	}
+
+	programme, err := newJSONProgramme(element)
+	if err != nil {
+		return err
+	}
+
+	// The episode on screen is the most readable, otherwise the first episode number
+	episode := programme.Episode["onscreen"]
+	if len(episode) == 0 && len(element.EpisodeNums) != 0 {
+		episode = element.EpisodeNums[0].Value
+	}
+
+	return c.w.Write([]string{
+		programme.Channel,
+		programme.Start.Format(time.RFC3339),
+		programme.Stop.Format(time.RFC3339),
+		programme.Title,
+		episode,
+		strings.Join(programme.Categories, ", "),
+	})
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// epgWriter writes the guide in another format than XMLTV, channels first, then the programmes
+type epgWriter interface {
+	begin(name string) error    // Starts the list name, channels or programmes
+	encode(v interface{}) error // Writes a ChannelXML or Programme into the current list
+	end() error                 // Ends the guide
+}
+
+// jsonEPG writes the guide as JSON document {"channels": [...], "programmes": [...]} instead of XMLTV.
+// Like the XMLTV encoder it writes one channel or programme after the other, the guide is never held in memory.
+type jsonEPG struct {
+	w       io.Writer
+	entries int // Entries of the current list
+}
+
+// jsonChannel is a channel of the JSON guide
+type jsonChannel struct {
+	ID    string   `json:"id"`
+	Names []string `json:"names"`
+	Icon  string   `json:"icon,omitempty"`
+}
+
+// jsonProgramme is a programme of the JSON guide, the XMLTV elements are flattened to plain values
+type jsonProgramme struct {
+	Channel     string            `json:"channel"`
+	Start       time.Time         `json:"start"`
+	Stop        time.Time         `json:"stop"`
+	Title       string            `json:"title"`
+	SubTitle    string            `json:"subTitle,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Length      int               `json:"length,omitempty"` // Minutes
+	Categories  []string          `json:"categories,omitempty"`
+	Keywords    []string          `json:"keywords,omitempty"`
+	Episode     map[string]string `json:"episode,omitempty"` // Episode numbers by system, e.g. onscreen: S4 E1
+	Icon        string            `json:"icon,omitempty"`
+	Actors      []string          `json:"actors,omitempty"`
+	Directors   []string          `json:"directors,omitempty"`
+	Ratings     map[string]string `json:"ratings,omitempty"` // Ratings by system
+	StarRating  string            `json:"starRating,omitempty"`
+	Quality     string            `json:"quality,omitempty"`
+	Subtitles   []string          `json:"subtitles,omitempty"`
+	Premiere    string            `json:"premiere,omitempty"`
+	Finale      string            `json:"finale,omitempty"`
+	New         bool              `json:"new,omitempty"`
+	Live        bool              `json:"live,omitempty"`
+}
+
+// begin closes the previous list and starts the list name, channels or programmes
+func (j *jsonEPG) begin(name string) error {
+	prefix := "{"
+	if name != "channels" {
+		prefix = "\n],"
+	}
+	j.entries = 0
+
+	_, err := io.WriteString(j.w, prefix+`"`+name+`":[`)
+	return err
+}
+
+// end closes the last list and the document
+func (j *jsonEPG) end() error {
+	_, err := io.WriteString(j.w, "\n]}\n")
+	return err
+}
+
+// encode writes a channel or programme into the current list
+func (j *jsonEPG) encode(v interface{}) error {
+	switch element := v.(type) {
+	case ChannelXML:
+		v = newJSONChannel(element)
+	case Programme:
+		programme, err := newJSONProgramme(element)
+		if err != nil {
+			return err
+		}
+		v = programme
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal JSON guide entry")
+	}
+
+	separator := "\n"
+	if j.entries != 0 {
+		separator = ",\n"
+	}
+	j.entries++
+
+	if _, err := io.WriteString(j.w, separator); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+// newJSONChannel returns the JSON channel of an XMLTV channel
+func newJSONChannel(c ChannelXML) jsonChannel {
+	channel := jsonChannel{ID: c.ID, Icon: c.Icon.Src}
+	for _, name := range c.DisplayName {
+		channel.Names = append(channel.Names, name.Value)
+	}
+	return channel
+}
+
+// newJSONProgramme returns the JSON programme of an XMLTV programme, the times keep the offset of the time zone option
+func newJSONProgramme(p Programme) (programme jsonProgramme, err error) {
+	if programme.Start, err = time.Parse(xmltvTimeLayout, p.Start); err != nil {
+		return programme, errors.Wrap(err, "invalid start time")
+	}
+	if programme.Stop, err = time.Parse(xmltvTimeLayout, p.Stop); err != nil {
+		return programme, errors.Wrap(err, "invalid stop time")
+	}
+
+	programme.Channel = p.Channel
+	if len(p.Title) != 0 {
+		programme.Title = p.Title[0].Value
+	}
+	programme.SubTitle = p.SubTitle.Value
+	if len(p.Desc) != 0 {
+		programme.Description = p.Desc[0].Value
+	}
+	programme.Language = p.Language
+	if p.Length != nil {
+		programme.Length = p.Length.Value
+	}
+
+	for _, category := range p.Categorys {
+		programme.Categories = append(programme.Categories, category.Value)
+	}
+	for _, keyword := range p.Keywords {
+		programme.Keywords = append(programme.Keywords, keyword.Value)
+	}
+	for _, episode := range p.EpisodeNums {
+		if programme.Episode == nil {
+			programme.Episode = make(map[string]string)
+		}
+		programme.Episode[episode.System] = episode.Value
+	}
+	if len(p.Icon) != 0 {
+		programme.Icon = p.Icon[0].Src
+	}
+
+	for _, actor := range p.Credits.Actor {
+		programme.Actors = append(programme.Actors, actor.Value)
+	}
+	for _, director := range p.Credits.Director {
+		programme.Directors = append(programme.Directors, director.Value)
+	}
+	for _, rating := range p.Rating {
+		if programme.Ratings == nil {
+			programme.Ratings = make(map[string]string)
+		}
+		programme.Ratings[rating.System] = rating.Value
+	}
+	if len(p.StarRating) != 0 {
+		programme.StarRating = p.StarRating[0].Value
+	}
+
+	programme.Quality = p.Video.Quality
+	for _, subtitles := range p.Subtitles {
+		programme.Subtitles = append(programme.Subtitles, subtitles.Type)
+	}
+	if p.Premiere != nil {
+		programme.Premiere = p.Premiere.Value
+		if len(programme.Premiere) == 0 {
+			programme.Premiere = "Premiere"
+		}
+	}
+	if p.LastChance != nil {
+		programme.Finale = p.LastChance.Value
+	}
+	programme.New = p.New != nil
+	programme.Live = p.Live != nil
+
+	return programme, nil
+}
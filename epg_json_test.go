@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONEPG(t *testing.T) {
+	var buf bytes.Buffer
+	j := &jsonEPG{w: &buf}
+
+	programme := Programme{
+		Channel:     "KABC",
+		Start:       "20300101200000 -0500",
+		Stop:        "20300101203000 -0500",
+		Title:       []Title{{Value: "News", Lang: "en"}},
+		EpisodeNums: []EpisodeNum{{Value: "S4 E1", System: "onscreen"}},
+		New:         &New{},
+	}
+	steps := []func() error{
+		func() error { return j.begin("channels") },
+		func() error {
+			return j.encode(ChannelXML{ID: "KABC", DisplayName: []DisplayName{{Value: "KABC"}, {Value: "7 KABC"}}})
+		},
+		func() error { return j.begin("programmes") },
+		func() error { return j.encode(programme) },
+		func() error { return j.encode(programme) },
+		j.end,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("Failed to write JSON guide: %v", err)
+		}
+	}
+
+	var guide struct {
+		Channels   []jsonChannel   `json:"channels"`
+		Programmes []jsonProgramme `json:"programmes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &guide); err != nil {
+		t.Fatalf("Invalid JSON guide %s: %v", buf.String(), err)
+	}
+	if len(guide.Channels) != 1 || len(guide.Channels[0].Names) != 2 || len(guide.Programmes) != 2 {
+		t.Fatalf("Guide = %+v, want 1 channel and 2 programmes", guide)
+	}
+
+	p := guide.Programmes[0]
+	if p.Title != "News" || !p.New || p.Episode["onscreen"] != "S4 E1" || p.Stop.Sub(p.Start).Minutes() != 30 {
+		t.Errorf("Programme = %+v, want the values of the XMLTV programme", p)
+	}
+	if _, offset := p.Start.Zone(); offset != -5*60*60 {
+		t.Errorf("Start = %v, want the offset of the XMLTV time", p.Start)
+	}
+}
+
+func TestCSVEPG(t *testing.T) {
+	var buf bytes.Buffer
+	c := newCSVEPG(&buf, true)
+
+	programme := Programme{
+		Channel:     "KABC",
+		Start:       "20300101200000 +0000",
+		Stop:        "20300101203000 +0000",
+		Title:       []Title{{Value: "Series, Part 1", Lang: "en"}},
+		EpisodeNums: []EpisodeNum{{Value: "3.0.", System: "xmltv_ns"}, {Value: "S4 E1", System: "onscreen"}},
+		Categorys:   []Category{{Value: "Sitcom"}, {Value: "Comedy"}},
+	}
+	for _, err := range []error{c.begin("channels"), c.encode(ChannelXML{ID: "KABC"}), c.begin("programmes"), c.encode(programme), c.end()} {
+		if err != nil {
+			t.Fatalf("Failed to write CSV guide: %v", err)
+		}
+	}
+
+	want := "channel\tstart\tstop\ttitle\tepisode\tgenre\nKABC\t2030-01-01T20:00:00Z\t2030-01-01T20:30:00Z\tSeries, Part 1\tS4 E1\tSitcom, Comedy\n"
+	if buf.String() != want {
+		t.Errorf("CSV guide = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestM3UEPG(t *testing.T) {
+	var buf bytes.Buffer
+	m := &m3uEPG{w: &buf, streamURL: "http://tuner:5004/auto/v{number}?station={stationID}&callsign={callsign}"}
+
+	steps := []func() error{
+		func() error { return m.begin("channels") },
+		func() error {
+			return m.encode(ChannelXML{
+				ID:          "KABC",
+				DisplayName: []DisplayName{{Value: "KABC"}, {Value: `KABC "Eyewitness"`}, {Value: "7.1 KABC"}, {Value: "7.1"}},
+				Icon:        Icon{Src: "http://localhost:8080/images/kabc.png"},
+				StationID:   "10021",
+				Number:      "7.1",
+			})
+		},
+		func() error {
+			return m.encode(ChannelXML{ID: "KNBC", DisplayName: []DisplayName{{Value: "KNBC"}}, StationID: "10022"})
+		},
+		func() error { return m.begin("programmes") },
+		func() error { return m.encode(Programme{Channel: "KABC"}) },
+		m.end,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("Failed to write M3U playlist: %v", err)
+		}
+	}
+
+	want := "#EXTM3U\n" +
+		`#EXTINF:-1 tvg-id="KABC" tvg-name="KABC 'Eyewitness'" tvg-chno="7.1" tvg-logo="http://localhost:8080/images/kabc.png",KABC "Eyewitness"` + "\n" +
+		"http://tuner:5004/auto/v7.1?station=10021&callsign=KABC\n" +
+		`#EXTINF:-1 tvg-id="KNBC" tvg-name="KNBC",KNBC` + "\n" +
+		"http://tuner:5004/auto/v?station=10022&callsign=KNBC\n"
+	if buf.String() != want {
+		t.Errorf("Playlist =\n%s\nwant\n%s", buf.String(), want)
+	}
+	if name := playlistFilename("/data/guide.xml"); name != "/data/guide.m3u" {
+		t.Errorf("playlistFilename() = %s, want /data/guide.m3u", name)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// m3uEPG writes the channels of the guide as M3U playlist, the tvg-id of an entry is the XMLTV channel ID.
+// IPTV front-ends match the playlist and the guide by it, the programmes are not written.
+type m3uEPG struct {
+	w         io.Writer
+	streamURL string // URL of the streams, {number}, {stationID} and {callsign} are replaced by the values of the channel
+}
+
+// playlistFilename returns the M3U playlist next to the XMLTV file, e.g. guide.m3u for guide.xml
+func playlistFilename(xmltv string) string {
+	return strings.TrimSuffix(xmltv, filepath.Ext(xmltv)) + ".m3u"
+}
+
+// begin writes the header before the channels
+func (m *m3uEPG) begin(name string) error {
+	if name != "channels" {
+		return nil
+	}
+	_, err := io.WriteString(m.w, "#EXTM3U\n")
+	return errors.Wrap(err, "failed to write M3U playlist")
+}
+
+// encode writes the entry of a channel, programmes have no entries
+func (m *m3uEPG) encode(v interface{}) error {
+	channel, ok := v.(ChannelXML)
+	if !ok {
+		return nil
+	}
+
+	var callsign, name string
+	if len(channel.DisplayName) != 0 {
+		callsign = channel.DisplayName[0].Value
+		name = callsign
+	}
+	if len(channel.DisplayName) > 1 && len(channel.DisplayName[1].Value) != 0 {
+		name = channel.DisplayName[1].Value
+	}
+
+	url := strings.NewReplacer(
+		"{number}", channel.Number,
+		"{stationID}", channel.StationID,
+		"{callsign}", callsign,
+	).Replace(m.streamURL)
+
+	// Quotes would end the attribute values
+	attr := strings.NewReplacer(`"`, "'").Replace
+	entry := fmt.Sprintf("#EXTINF:-1 tvg-id=\"%s\" tvg-name=\"%s\"", attr(channel.ID), attr(name))
+	if len(channel.Number) != 0 {
+		entry += fmt.Sprintf(" tvg-chno=\"%s\"", attr(channel.Number))
+	}
+	if len(channel.Icon.Src) != 0 {
+		entry += fmt.Sprintf(" tvg-logo=\"%s\"", attr(channel.Icon.Src))
+	}
+
+	_, err := fmt.Fprintf(m.w, "%s,%s\n%s\n", entry, name, url)
+	return errors.Wrap(err, "failed to write M3U playlist")
+}
+
+// end finishes the playlist, every entry is already complete
+func (m *m3uEPG) end() error {
+	return nil
+}
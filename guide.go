@@ -0,0 +1,22 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// scheduleWindow returns the broadcasts of a schedule which run between from and to, sorted by start time
+func scheduleWindow(schedule []G2GCache, from, to time.Time) []G2GCache {
+	var window []G2GCache
+	for _, s := range schedule {
+		stop := s.AirDateTime.Add(time.Duration(s.Duration) * time.Second)
+		if s.AirDateTime.Before(to) && stop.After(from) {
+			window = append(window, s)
+		}
+	}
+
+	sort.SliceStable(window, func(i, j int) bool {
+		return window[i].AirDateTime.Before(window[j].AirDateTime)
+	})
+	return window
+}
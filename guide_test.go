@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleWindow(t *testing.T) {
+	from := time.Date(2030, 1, 1, 18, 0, 0, 0, time.UTC)
+	broadcast := func(id string, start time.Time, minutes int) G2GCache {
+		return G2GCache{ProgramID: id, AirDateTime: start, Duration: minutes * 60}
+	}
+
+	schedule := []G2GCache{
+		broadcast("EP3", from.Add(time.Hour), 30),
+		broadcast("EP1", from.Add(-time.Hour), 60),      // Ends at the start of the window
+		broadcast("EP2", from.Add(-30*time.Minute), 60), // Runs into the window
+		broadcast("EP4", from.Add(3*time.Hour), 60),     // Starts at the end of the window
+		broadcast("EP5", from.Add(2*time.Hour+time.Minute), 30),
+	}
+
+	window := scheduleWindow(schedule, from, from.Add(3*time.Hour))
+	var ids []string
+	for _, s := range window {
+		ids = append(ids, s.ProgramID)
+	}
+	if len(ids) != 3 || ids[0] != "EP2" || ids[1] != "EP3" || ids[2] != "EP5" {
+		t.Errorf("scheduleWindow() = %v, want [EP2 EP3 EP5]", ids)
+	}
+}
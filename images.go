@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// minImageSize is the size of a valid image, smaller files are failed downloads
+const minImageSize = 500
+
+// States of the files in the images path
+const (
+	imageReferenced = "referenced"
+	imageOrphaned   = "orphaned" // No artwork or logo of the cache refers to it
+	imageFailed     = "failed"   // Referenced, but the download failed
+)
+
+// cachedImage is a file in the images path
+type cachedImage struct {
+	name     string
+	uri      string // Artwork or logo URI, empty for orphaned images
+	size     int64
+	modified time.Time
+	status   string
+}
+
+// imageReferences returns the URI of every image name the cache refers to: the artwork of the metadata and the channel logos
+func imageReferences(c *cache) map[string]string {
+	refs := make(map[string]string)
+	add := func(uri string) {
+		if len(uri) != 0 {
+			refs[imageName(uri)] = uri
+		}
+	}
+
+	for _, m := range c.Metadata {
+		for _, d := range m.Data {
+			add(d.URI)
+		}
+	}
+	for _, channel := range c.Channel {
+		add(channel.Logo.URL)
+		for _, logo := range channel.StationLogo {
+			add(logo.URL)
+		}
+	}
+	return refs
+}
+
+// scanImages returns the files of the images path sorted by name with their state by the references of the cache
+func scanImages(dir string, refs map[string]string) ([]cachedImage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read images path")
+	}
+
+	var images []cachedImage
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		img := cachedImage{name: entry.Name(), uri: refs[entry.Name()], size: info.Size(), modified: info.ModTime()}
+		switch {
+		case len(img.uri) == 0:
+			img.status = imageOrphaned
+		case img.size < minImageSize:
+			img.status = imageFailed
+		default:
+			img.status = imageReferenced
+		}
+		images = append(images, img)
+	}
+
+	sort.Slice(images, func(i, j int) bool { return images[i].name < images[j].name })
+	return images, nil
+}
+
+// purgeImages removes the orphaned images and returns their number and size
+func purgeImages(dir string, images []cachedImage) (int, int64, error) {
+	var removed int
+	var size int64
+	for _, img := range images {
+		if img.status != imageOrphaned {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, img.name)); err != nil && !os.IsNotExist(err) {
+			return removed, size, errors.Wrapf(err, "failed to remove image %s", img.name)
+		}
+		removed++
+		size += img.size
+	}
+	return removed, size, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanImages(t *testing.T) {
+	c := &cache{Channel: make(map[string]G2GCache), Metadata: make(map[string]G2GCache)}
+	c.Metadata["EP01234567"] = G2GCache{Data: []Data{{URI: "assets/p123.jpg"}, {URI: "https://example.com/image/p456.jpg"}}}
+	var channel G2GCache
+	channel.Logo.URL = "https://example.com/logos/s10021.png"
+	c.Channel["10021"] = channel
+
+	refs := imageReferences(c)
+	if refs["p456.jpg"] != "https://example.com/image/p456.jpg" || refs["s10021.png"] == "" {
+		t.Fatalf("imageReferences() = %v", refs)
+	}
+
+	dir := t.TempDir()
+	write := func(name string, size int) {
+		if err := os.WriteFile(filepath.Join(dir, name), bytes.Repeat([]byte{1}, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("p456.jpg", minImageSize)
+	write("s10021.png", 10) // Failed download
+	write("p789.jpg", minImageSize)
+
+	images, err := scanImages(dir, refs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"p456.jpg": imageReferenced, "s10021.png": imageFailed, "p789.jpg": imageOrphaned}
+	if len(images) != len(want) {
+		t.Fatalf("scanImages() returned %d images, want %d", len(images), len(want))
+	}
+	for _, img := range images {
+		if img.status != want[img.name] {
+			t.Errorf("status of %s = %s, want %s", img.name, img.status, want[img.name])
+		}
+	}
+
+	removed, size, err := purgeImages(dir, images)
+	if err != nil || removed != 1 || size != minImageSize {
+		t.Errorf("purgeImages() = %d, %d, %v, want 1, %d", removed, size, err, minImageSize)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "p789.jpg")); !os.IsNotExist(err) {
+		t.Error("orphaned image was not removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "p456.jpg")); err != nil {
+		t.Error("referenced image was removed")
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxJobs is the number of finished jobs which are kept for the job list, queued jobs are always kept
+const maxJobs = 20
+
+// jobEventBuffer is the number of finished jobs buffered per subscriber, a slow subscriber misses the following ones
+const jobEventBuffer = 16
+
+// Job states
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// job is an update started by /run or the web UI, the update reports its steps with start and advance
+type job struct {
+	mu sync.Mutex
+
+	id       int
+	config   string // Configuration file of the update
+	state    string
+	step     string // e.g. Downloading schedules
+	done     int    // Finished parts of the step, e.g. 3 of 12 batches
+	total    int    // 0 if the step has no parts
+	err      error
+	queued   time.Time
+	started  time.Time
+	finished time.Time
+}
+
+// jobStatus is a copy of the state of a job
+type jobStatus struct {
+	ID       int
+	Config   string
+	State    string
+	Step     string
+	Done     int
+	Total    int
+	Error    string
+	Queued   time.Time
+	Started  time.Time
+	Finished time.Time
+}
+
+// start begins the next step of the job with total parts
+func (j *job) start(step string, total int) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.step, j.done, j.total = step, 0, total
+}
+
+// advance finishes a part of the current step
+func (j *job) advance() {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.done < j.total {
+		j.done++
+	}
+}
+
+// setState changes the state of the job, err is the result of a finished job
+func (j *job) setState(state string, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.state = state
+	switch state {
+	case jobRunning:
+		j.started = time.Now()
+	case jobDone, jobFailed:
+		j.finished = time.Now()
+		j.err = err
+	}
+}
+
+// status returns a copy of the state of the job
+func (j *job) status() jobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s := jobStatus{
+		ID:       j.id,
+		Config:   j.config,
+		State:    j.state,
+		Step:     j.step,
+		Done:     j.done,
+		Total:    j.total,
+		Queued:   j.queued,
+		Started:  j.started,
+		Finished: j.finished,
+	}
+	if j.err != nil {
+		s.Error = j.err.Error()
+	}
+	return s
+}
+
+// jobManager runs the jobs one after the other, a second update of the same cache would wait for the cache lock anyway
+type jobManager struct {
+	run func(ctx context.Context, j *job) error
+
+	mu          sync.Mutex
+	jobs        []*job        // Newest first, the queued and the running job are never removed
+	wake        chan struct{} // Signals the worker that a job is queued
+	once        sync.Once
+	nextID      int
+	subscribers map[chan jobStatus]struct{}
+}
+
+// newJobManager returns a job manager whose jobs call run
+func newJobManager(run func(ctx context.Context, j *job) error) *jobManager {
+	return &jobManager{run: run, wake: make(chan struct{}, 1), subscribers: make(map[chan jobStatus]struct{})}
+}
+
+// queue adds a job updating the configuration file, a job of the file which is still queued is returned instead of a second one
+func (m *jobManager) queue(config string) *job {
+	m.once.Do(func() { go m.worker() })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, j := range m.jobs {
+		if s := j.status(); s.State == jobQueued && s.Config == config {
+			return j
+		}
+	}
+
+	m.nextID++
+	j := &job{id: m.nextID, config: config, state: jobQueued, queued: time.Now()}
+	m.jobs = append([]*job{j}, m.jobs...)
+	m.trim()
+
+	// The worker takes all queued jobs once it wakes up, one signal is enough
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+	return j
+}
+
+// trim removes the oldest finished jobs beyond maxJobs
+func (m *jobManager) trim() {
+	var jobs []*job
+	finished := 0
+	for _, j := range m.jobs {
+		if s := j.status().State; s == jobDone || s == jobFailed {
+			if finished++; finished > maxJobs {
+				continue
+			}
+		}
+		jobs = append(jobs, j)
+	}
+	m.jobs = jobs
+}
+
+// next starts the oldest queued job, nil if none is queued
+func (m *jobManager) next() *job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		if j := m.jobs[i]; j.status().State == jobQueued {
+			j.setState(jobRunning, nil)
+			return j
+		}
+	}
+	return nil
+}
+
+// worker runs the queued jobs
+func (m *jobManager) worker() {
+	for range m.wake {
+		for j := m.next(); j != nil; j = m.next() {
+			if err := m.run(context.Background(), j); err != nil {
+				j.setState(jobFailed, err)
+			} else {
+				j.setState(jobDone, nil)
+			}
+			m.publish(j.status())
+		}
+	}
+}
+
+// subscribe returns the jobs which finish until unsubscribe is called
+func (m *jobManager) subscribe() (<-chan jobStatus, func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := make(chan jobStatus, jobEventBuffer)
+	m.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish passes a finished job to the subscribers without blocking the worker
+func (m *jobManager) publish(s jobStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ch := range m.subscribers {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+}
+
+// list returns the state of the jobs, newest first
+func (m *jobManager) list() []jobStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]jobStatus, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		list = append(list, j.status())
+	}
+	return list
+}
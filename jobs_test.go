@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestJobManager(t *testing.T) {
+	release := make(chan struct{})
+	m := newJobManager(func(ctx context.Context, j *job) error {
+		j.start("Downloading schedules", 2)
+		j.advance()
+		<-release
+		if j.id == 2 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	first := m.queue("us.yaml")
+	waitJob(t, first, func(s jobStatus) bool { return s.State == jobRunning && s.Done == 1 })
+
+	// A queued job of the same configuration is returned instead of a second one
+	second := m.queue("us.yaml")
+	if m.queue("us.yaml") != second || len(m.list()) != 2 {
+		t.Fatalf("Jobs = %+v, want one running and one queued job", m.list())
+	}
+	third := m.queue("cable.yaml")
+	if third == second || len(m.list()) != 3 {
+		t.Fatalf("Jobs = %+v, want a queued job of every configuration", m.list())
+	}
+
+	release <- struct{}{}
+	release <- struct{}{}
+	release <- struct{}{}
+	waitJob(t, third, func(s jobStatus) bool { return s.State == jobDone })
+
+	list := m.list()
+	if list[0].Config != "cable.yaml" || list[1].ID != 2 || list[1].Error != "failed" || list[2].State != jobDone || list[2].Step != "Downloading schedules" || list[2].Total != 2 {
+		t.Errorf("Jobs = %+v, want the job of the other configuration, the failed job and the done job", list)
+	}
+}
+
+func TestJobManagerQueueMany(t *testing.T) {
+	release := make(chan struct{})
+	m := newJobManager(func(ctx context.Context, j *job) error {
+		<-release
+		return nil
+	})
+	jobs, unsubscribe := m.subscribe()
+	defer unsubscribe()
+
+	// Queueing doesn't wait for the worker, every queued job is listed
+	queued := make(chan []*job)
+	go func() {
+		var list []*job
+		for i := 0; i <= 2*maxJobs; i++ {
+			list = append(list, m.queue(fmt.Sprintf("%d.yaml", i)))
+		}
+		queued <- list
+	}()
+
+	var list []*job
+	select {
+	case list = <-queued:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Queueing more than maxJobs jobs blocked")
+	}
+	if n := len(m.list()); n != 2*maxJobs+1 {
+		t.Fatalf("Jobs = %d, want all %d queued jobs", n, 2*maxJobs+1)
+	}
+	if last := list[len(list)-1]; m.queue(last.config) != last {
+		t.Error("Second job of a queued configuration")
+	}
+
+	for range list {
+		release <- struct{}{}
+		<-jobs
+	}
+	waitJob(t, list[len(list)-1], func(s jobStatus) bool { return s.State == jobDone })
+
+	// Finished jobs are removed beyond maxJobs
+	m.queue("new.yaml")
+	if l := m.list(); len(l) != maxJobs+1 || l[0].Config != "new.yaml" || l[maxJobs].Config != fmt.Sprintf("%d.yaml", maxJobs+1) {
+		t.Errorf("Jobs = %+v, want the new job and the %d newest finished jobs", l, maxJobs)
+	}
+	release <- struct{}{}
+}
+
+// waitJob waits until the state of the job fulfills ok
+func waitJob(t *testing.T, j *job, ok func(jobStatus) bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if ok(j.status()) {
+			return
+		}
+	}
+	t.Fatalf("Job = %+v, timed out waiting for its state", j.status())
+}
+
+func TestJobManagerSubscribe(t *testing.T) {
+	m := newJobManager(func(ctx context.Context, j *job) error {
+		return errors.New("failed")
+	})
+
+	jobs, unsubscribe := m.subscribe()
+	j := m.queue("us.yaml")
+
+	select {
+	case s := <-jobs:
+		if s.ID != j.id || s.State != jobFailed || s.Error != "failed" {
+			t.Errorf("Finished job = %+v, want the failed job", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No finished job received")
+	}
+
+	unsubscribe()
+	if _, ok := <-jobs; ok {
+		t.Error("Channel is open after unsubscribe")
+	}
+}
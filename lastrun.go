@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lastRun is the result of the last update, it is kept next to the configuration file for the dashboard of the web UI
+type lastRun struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// lastRunFile returns the file of the last update of the configuration
+func lastRunFile(app *App) string {
+	return app.Config.File + ".lastrun"
+}
+
+// saveLastRun writes the result of an update, err is nil for a successful update
+func saveLastRun(app *App, started time.Time, err error) error {
+	run := lastRun{Started: started, Finished: time.Now()}
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal last run")
+	}
+
+	if err := os.WriteFile(lastRunFile(app), data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write last run file")
+	}
+
+	return nil
+}
+
+// loadLastRun returns the result of the last update, false if the configuration has never been updated
+func loadLastRun(app *App) (lastRun, bool) {
+	var run lastRun
+
+	data, err := os.ReadFile(lastRunFile(app))
+	if err != nil {
+		return run, false
+	}
+
+	if err := json.Unmarshal(data, &run); err != nil {
+		app.Logger.WithError(err).Warn("Ignoring unreadable last run file")
+		return run, false
+	}
+
+	return run, true
+}
@@ -1 +1,74 @@
 package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+)
+
+// AddLineup adds a lineup to the Schedules Direct account without the interactive menu
+func (app *App) AddLineup(ctx context.Context, lineup string) error {
+	return app.changeLineup(ctx, lineup, "PUT")
+}
+
+// RemoveLineup removes a lineup from the Schedules Direct account without the interactive menu
+func (app *App) RemoveLineup(ctx context.Context, lineup string) error {
+	return app.changeLineup(ctx, lineup, "DELETE")
+}
+
+func (app *App) changeLineup(ctx context.Context, lineup, method string) error {
+	if len(lineup) == 0 {
+		return errors.New("lineup ID is required")
+	}
+
+	if err := app.openConfig(ctx); err != nil {
+		return err
+	}
+
+	var sd SD
+	if err := sd.Init(app); err != nil {
+		return errors.Wrap(err, "failed to initialize SD client")
+	}
+	if err := sd.Login(); err != nil {
+		return errors.Wrap(err, "failed to login to Schedules Direct")
+	}
+	if err := sd.Status(); err != nil {
+		return errors.Wrap(err, "failed to get Schedules Direct status")
+	}
+
+	var subscribed bool
+	for _, l := range sd.Resp.Status.Lineups {
+		if l.Lineup == lineup {
+			subscribed = true
+		}
+	}
+
+	log := app.Logger.WithFields(logrus.Fields{"lineup": lineup, "method": method})
+
+	switch {
+	case method == "PUT" && subscribed:
+		log.Info("Lineup is already in the account")
+		return nil
+	case method == "DELETE" && !subscribed:
+		return schedulesdirect.NewAPIError(2102, fmt.Sprintf("lineup %s is not in the account", lineup))
+	}
+
+	sd.Req.Parameter = fmt.Sprintf("/%s", lineup)
+	sd.Req.Type = method
+
+	var err error
+	if method == "DELETE" {
+		err = sd.Delete()
+	} else {
+		err = sd.Lineups()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to change lineup")
+	}
+
+	log.Info("Lineup changed")
+	return nil
+}
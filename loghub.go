@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	logHubHistory = 100 // Entries a new subscriber receives first
+	logHubBuffer  = 256 // Entries buffered per subscriber, a slow subscriber misses the following ones
+)
+
+// logHub is a logrus hook which passes the log entries to the live log of the web UI.
+// Subscribers never block the logger, entries are dropped for subscribers which don't keep up.
+type logHub struct {
+	formatter logrus.Formatter
+
+	mu          sync.Mutex
+	recent      []string
+	subscribers map[chan string]struct{}
+}
+
+func newLogHub() *logHub {
+	return &logHub{
+		formatter:   &logrus.TextFormatter{DisableColors: true, FullTimestamp: true},
+		subscribers: make(map[chan string]struct{}),
+	}
+}
+
+// Levels returns all levels, the level of the logger selects the entries
+func (h *logHub) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire passes the formatted entry to all subscribers
+func (h *logHub) Fire(entry *logrus.Entry) error {
+	data, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	line := strings.TrimSuffix(string(data), "\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.recent = append(h.recent, line)
+	if len(h.recent) > logHubHistory {
+		h.recent = h.recent[len(h.recent)-logHubHistory:]
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the recent and all following log entries until unsubscribe is called
+func (h *logHub) Subscribe() (<-chan string, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan string, logHubBuffer+logHubHistory)
+	for _, line := range h.recent {
+		ch <- line
+	}
+	h.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
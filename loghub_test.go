@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogHub(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	hub := newLogHub()
+	logger.AddHook(hub)
+
+	logger.Info("Before subscribing")
+	lines, unsubscribe := hub.Subscribe()
+	logger.WithField("lineup", "USA-OTA-90210").Warn("After subscribing")
+
+	for _, want := range []string{`msg="Before subscribing"`, `msg="After subscribing" lineup=USA-OTA-90210`} {
+		if line := <-lines; !strings.Contains(line, want) {
+			t.Errorf("Log line = %q, want %q", line, want)
+		}
+	}
+
+	unsubscribe()
+	unsubscribe()
+	logger.Info("After unsubscribing")
+	if _, ok := <-lines; ok {
+		t.Error("Unsubscribed channel received a log line")
+	}
+
+	// A subscriber which doesn't read never blocks the logger
+	hub.Subscribe()
+	for i := 0; i < 2*logHubBuffer; i++ {
+		logger.Info("Filling the buffer")
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
 	"github.com/yourusername/guide2go/web/handlers"
 )
 
@@ -28,6 +30,38 @@ type App struct {
 	Cache   CacheStore
 	SD      SchedulesDirectClient
 	Token   string
+
+	// Images resolves and downloads artwork with the token of the Schedules Direct client
+	Images *schedulesdirect.Client
+
+	// Transport is used for the requests to Schedules Direct, nil uses http.DefaultTransport.
+	// Tests and deployments behind a proxy can replace it.
+	Transport http.RoundTripper
+
+	// DebugHTTP logs every Schedules Direct request, DebugHTTPDir additionally receives the request and response bodies
+	DebugHTTP    bool
+	DebugHTTPDir string
+
+	// logs passes the log entries to the live log of the web UI, nil without the web UI
+	logs *logHub
+
+	// jobs runs the updates started by /run and the web UI
+	jobs *jobManager
+
+	// runLog writes the log entries of an update into its log file of the run history
+	runLog *runLog
+
+	// configs are the configuration files of the web UI, nil without the web UI
+	configs *webConfigs
+
+	// sdStatus is the Schedules Direct status polled for the dashboard, nil without the web UI
+	sdStatus *sdStatusPoll
+
+	// language is the language of the web UI kept until the configuration file changes, nil reads it every time
+	language *webLanguage
+
+	// cacheCounters are the cache lookup counters of the last update for the web UI, nil without the web UI
+	cacheCounters *cacheCounters
 }
 
 func newApp() *App {
@@ -35,11 +69,15 @@ func newApp() *App {
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetOutput(os.Stdout)
 	logger.SetLevel(logrus.InfoLevel)
-	return &App{
+	app := &App{
 		Logger: logger,
 		Cache:  &cache{},
 		SD:     &SD{},
 	}
+	app.jobs = newJobManager(app.runUpdate)
+	app.runLog = newRunLog()
+	logger.AddHook(app.runLog)
+	return app
 }
 
 func main() {
@@ -60,10 +98,17 @@ func main() {
 	var configure = flag.String("configure", "", "Create or modify the configuration file [filename.yaml]")
 	var config = flag.String("config", "", "Get data from Schedules Direct with configuration file [filename.yaml]")
 	var webPort = flag.String("web-port", "", "Start web UI on the specified port (e.g. 8080)")
+	var webConfigs = flag.String("web-configs", "", "Additional configuration files and directories of the web UI, comma separated, requires -config")
+	var addLineup = flag.String("add-lineup", "", "Add a lineup to the Schedules Direct account, requires -config [lineup ID]")
+	var removeLineup = flag.String("remove-lineup", "", "Remove a lineup from the Schedules Direct account, requires -config [lineup ID]")
+	var debugHTTP = flag.Bool("debug-http", false, "Log every Schedules Direct request")
+	var debugHTTPDir = flag.String("debug-http-dir", "", "Write Schedules Direct request and response bodies into this directory, implies -debug-http")
 	var h = flag.Bool("h", false, "Show help")
 
 	flag.Parse()
 	app.Config2 = *config
+	app.DebugHTTP = *debugHTTP || len(*debugHTTPDir) != 0
+	app.DebugHTTPDir = *debugHTTPDir
 
 	app.Logger.WithFields(logrus.Fields{
 		"version": Version,
@@ -77,10 +122,55 @@ func main() {
 	}
 
 	if *webPort != "" {
-		app.StartWebServer(*webPort)
+		var paths []string
+		if len(*webConfigs) != 0 {
+			if len(*config) == 0 {
+				app.Logger.Fatal("-web-configs requires -config")
+			}
+			paths = strings.Split(*webConfigs, ",")
+		}
+		app.StartWebServer(*webPort, paths)
 		return
 	}
 
+	if len(*addLineup) != 0 || len(*removeLineup) != 0 {
+		if len(*config) == 0 {
+			app.Logger.Fatal("-add-lineup and -remove-lineup require -config")
+		}
+		if len(*addLineup) != 0 {
+			if err := app.AddLineup(ctx, *addLineup); err != nil {
+				app.Logger.WithError(err).Fatal("Failed to add lineup")
+			}
+		}
+		if len(*removeLineup) != 0 {
+			if err := app.RemoveLineup(ctx, *removeLineup); err != nil {
+				app.Logger.WithError(err).Fatal("Failed to remove lineup")
+			}
+		}
+		os.Exit(0)
+	}
+
+	if flag.Arg(0) == "cache" {
+		if len(*config) == 0 {
+			app.Logger.Fatal("cache requires -config")
+		}
+		if err := app.CacheCommand(ctx, os.Stdout, flag.Args()[1:]); err != nil {
+			app.Logger.WithError(err).Fatal("Cache command failed")
+		}
+		os.Exit(0)
+	}
+
+	if flag.Arg(0) == "warm" {
+		if len(*config) == 0 {
+			app.Logger.Fatal("warm requires -config")
+		}
+		var sd SD
+		if err := app.Warm(ctx, &sd, *config); err != nil {
+			app.Logger.WithError(err).Fatal("Failed to warm cache")
+		}
+		os.Exit(0)
+	}
+
 	if len(*configure) != 0 {
 		if err := app.Configure(*configure); err != nil {
 			app.Logger.WithError(err).Fatal("Failed to configure application")
@@ -106,12 +196,33 @@ func (app *App) ShowErr(err error) {
 	app.Logger.WithError(err).Error("Application error")
 }
 
-// StartWebServer starts the web UI server on the given port
-func (app *App) StartWebServer(port string) {
+// StartWebServer starts the web UI server on the given port, configs are the additional configuration files and directories
+func (app *App) StartWebServer(port string, configs []string) {
+	app.configs = &webConfigs{paths: configs}
+	app.logs = newLogHub()
+	app.Logger.AddHook(app.logs)
+	app.sdStatus = &sdStatusPoll{}
+	app.language = &webLanguage{}
+	app.cacheCounters = &cacheCounters{}
+	go app.runScheduler(context.Background())
+	go app.runSDStatusPoller(context.Background())
+
+	if login, err := app.WebLogin(); err == nil && len(login.Username) == 0 {
+		app.Logger.Warn("Web UI login is disabled, everyone who can reach the port can change the configuration")
+	}
+
+	srv := &http.Server{Addr: ":" + port}
+	if c, err := app.readConfig(context.Background(), app.Config2); err == nil {
+		if srv.TLSConfig, err = app.tlsConfig(c); err != nil {
+			app.Logger.WithError(err).Fatal("Failed to configure TLS")
+		}
+	}
+
 	r := mux.NewRouter()
-	handlers.RegisterRoutes(r)
-	app.Logger.WithField("port", port).Info("Web UI server started")
-	if err := http.ListenAndServe(":"+port, r); err != nil {
+	handlers.RegisterRoutes(r, app)
+	srv.Handler = r
+	app.Logger.WithFields(logrus.Fields{"port": port, "tls": srv.TLSConfig != nil}).Info("Web UI server started")
+	if err := listenAndServe(srv); err != nil {
 		app.Logger.WithError(err).Fatal("Web server error")
 	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 )
 
 func (e *Entry) headline() {
@@ -54,43 +55,7 @@ func (e *Entry) addLineup(sd *SD) (err error) {
 	entry.Value = getMsg(0200)
 	menu.Entry[index] = entry
 
-	for _, lineup := range sd.Resp.Countries.NorthAmerica {
-
-		index++
-		entry.Key = index
-		entry.Value = fmt.Sprintf("%s [%s]", lineup.FullName, lineup.PostalCodeExample)
-		entry.Country = lineup.FullName
-		entry.Postalcode = lineup.PostalCode
-		entry.ShortName = lineup.ShortName
-		menu.Entry[index] = entry
-
-	}
-
-	for _, lineup := range sd.Resp.Countries.Europe {
-
-		index++
-		entry.Key = index
-		entry.Value = fmt.Sprintf("%s [%s]", lineup.FullName, lineup.PostalCodeExample)
-		entry.Country = lineup.FullName
-		entry.Postalcode = lineup.PostalCode
-		entry.ShortName = lineup.ShortName
-		menu.Entry[index] = entry
-
-	}
-
-	for _, lineup := range sd.Resp.Countries.LatinAmerica {
-
-		index++
-		entry.Key = index
-		entry.Value = fmt.Sprintf("%s [%s]", lineup.FullName, lineup.PostalCodeExample)
-		entry.Country = lineup.FullName
-		entry.Postalcode = lineup.PostalCode
-		entry.ShortName = lineup.ShortName
-		menu.Entry[index] = entry
-
-	}
-
-	for _, lineup := range sd.Resp.Countries.Caribbean {
+	for _, lineup := range sd.Resp.Countries.Regions() {
 
 		index++
 		entry.Key = index
@@ -120,9 +85,7 @@ func (e *Entry) addLineup(sd *SD) (err error) {
 		fmt.Print(fmt.Sprintf("%s: ", getMsg(0202)))
 		fmt.Scanln(&postalcode)
 
-		sd.Req.Parameter = fmt.Sprintf("?country=%s&postalcode=%s", entry.ShortName, postalcode)
-
-		err = sd.Headends()
+		err = sd.Headends(entry.ShortName, postalcode)
 
 		if err == nil {
 			break
@@ -167,6 +130,26 @@ func (e *Entry) addLineup(sd *SD) (err error) {
 
 	}
 
+	// Preview the stations before using one of the limited lineup changes
+	err = sd.Preview(entry.Lineup)
+	if err != nil {
+		return
+	}
+
+	fmt.Println()
+	for _, station := range sd.Resp.Preview {
+		fmt.Println(fmt.Sprintf("%6s  %-10s %s", station.Channel, station.Callsign, station.Name))
+	}
+	fmt.Println()
+
+	var confirm string
+	fmt.Print(fmt.Sprintf("%s [%s]: ", getMsg(0205), entry.Lineup))
+	fmt.Scanln(&confirm)
+
+	if strings.ToLower(confirm) != "y" {
+		return
+	}
+
 	sd.Req.Parameter = fmt.Sprintf("/%s", entry.Lineup)
 	sd.Req.Type = "PUT"
 
@@ -214,9 +197,8 @@ func (e *Entry) removeLineup(sd *SD) (err error) {
 	}
 
 	sd.Req.Parameter = fmt.Sprintf("/%s", entry.Lineup)
-	sd.Req.Type = "DELETE"
 
-	err = sd.Lineups()
+	err = sd.Delete()
 
 	return
 }
@@ -0,0 +1,94 @@
+// Package i18n translates the messages of the terminal menus and the web UI.
+//
+// The English text of a message is its ID. The bundles in locales/ map it to the translation of a language,
+// messages without a translation stay English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// English is the language of the messages themselves, it needs no bundle
+const English = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// bundles are the translations by language, they are read once from locales/
+var bundles = loadBundles()
+
+// language is the language of T, the menus set it from the configuration
+var language = struct {
+	sync.RWMutex
+	lang string
+}{lang: English}
+
+// loadBundles reads the embedded bundles, an invalid bundle is a bug of the build and panics
+func loadBundles() map[string]map[string]string {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	bundles := make(map[string]map[string]string)
+	for _, file := range files {
+		data, err := locales.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic("invalid message bundle " + file.Name() + ": " + err.Error())
+		}
+		bundles[strings.TrimSuffix(file.Name(), ".json")] = messages
+	}
+	return bundles
+}
+
+// Languages returns the supported languages, English first
+func Languages() []string {
+	var langs []string
+	for lang := range bundles {
+		if lang != English {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return append([]string{English}, langs...)
+}
+
+// Supported reports whether there is a bundle of the language
+func Supported(lang string) bool {
+	_, ok := bundles[lang]
+	return ok || lang == English
+}
+
+// Translate returns the message in the language, the message itself for English, an unknown language or a missing translation
+func Translate(lang, msg string) string {
+	if translation, ok := bundles[lang][msg]; ok && len(translation) != 0 {
+		return translation
+	}
+	return msg
+}
+
+// SetLanguage sets the language of T, an unknown language is English
+func SetLanguage(lang string) {
+	if !Supported(lang) {
+		lang = English
+	}
+	language.Lock()
+	defer language.Unlock()
+	language.lang = lang
+}
+
+// T returns the message in the language of SetLanguage
+func T(msg string) string {
+	language.RLock()
+	defer language.RUnlock()
+	return Translate(language.lang, msg)
+}
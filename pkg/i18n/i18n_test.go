@@ -0,0 +1,61 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("de", "Logout"); got != "Abmelden" {
+		t.Errorf("Translate(de, Logout) = %q, want Abmelden", got)
+	}
+	for _, lang := range []string{English, "xx"} {
+		if got := Translate(lang, "Logout"); got != "Logout" {
+			t.Errorf("Translate(%s, Logout) = %q, want the message", lang, got)
+		}
+	}
+	if got := Translate("de", "Unknown message"); got != "Unknown message" {
+		t.Errorf("missing translation = %q, want the message", got)
+	}
+
+	SetLanguage("de")
+	defer SetLanguage(English)
+	if got := T("Cancel"); got != "Abbrechen" {
+		t.Errorf("T(Cancel) = %q, want Abbrechen", got)
+	}
+}
+
+// TestBundles checks that every bundle translates the messages of the web UI templates and the menus
+func TestBundles(t *testing.T) {
+	patterns := map[string]*regexp.Regexp{
+		"../../web/templates/*.html": regexp.MustCompile(`\bt "([^"]+)"`),
+		"../../screen.go":            regexp.MustCompile(`msg = "([^"]+)"`),
+	}
+
+	messages := make(map[string]bool)
+	for pattern, re := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no files of %s: %v", pattern, err)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, match := range re.FindAllStringSubmatch(string(data), -1) {
+				messages[match[1]] = true
+			}
+		}
+	}
+
+	for lang, bundle := range bundles {
+		for msg := range messages {
+			if _, ok := bundle[msg]; !ok {
+				t.Errorf("bundle %s has no translation of %q", lang, msg)
+			}
+		}
+	}
+}
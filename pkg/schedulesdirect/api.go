@@ -0,0 +1,136 @@
+package schedulesdirect
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// Login requests a new token for the Credentials of the client
+func (c *Client) Login(ctx context.Context) (*LoginResponse, error) {
+	data, err := json.Marshal(c.Credentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal login data")
+	}
+
+	var resp LoginResponse
+	if err := c.sendJSON(ctx, request{call: "login", method: "POST", path: "token", data: data}, &resp); err != nil {
+		return nil, err
+	}
+
+	c.SetToken(resp.Token)
+	if c.OnToken != nil {
+		c.OnToken(resp.Token)
+	}
+	return &resp, nil
+}
+
+// Status returns the account and system status
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var resp Status
+	if err := c.sendJSON(ctx, request{call: "status", method: "GET", path: "status"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Countries returns the countries for which Schedules Direct has lineups
+func (c *Client) Countries(ctx context.Context) (*Countries, error) {
+	var resp Countries
+	if err := c.sendJSON(ctx, request{call: "countries", method: "GET", path: "available/countries"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Headends returns the headends and their lineups for a postal code
+func (c *Client) Headends(ctx context.Context, country, postalCode string) ([]Headend, error) {
+	query := url.Values{}
+	query.Set("country", country)
+	query.Set("postalcode", postalCode)
+
+	var resp []Headend
+	if err := c.sendJSON(ctx, request{call: "headends", method: "GET", path: "headends?" + query.Encode()}, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Preview returns the stations of a lineup without adding it to the account
+func (c *Client) Preview(ctx context.Context, lineup string) ([]LineupPreview, error) {
+	var resp []LineupPreview
+	if err := c.sendJSON(ctx, request{call: "preview", method: "GET", path: "lineups/preview/" + url.PathEscape(lineup)}, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AddLineup adds a lineup to the account
+func (c *Client) AddLineup(ctx context.Context, lineup string) (*LineupChange, error) {
+	return c.changeLineup(ctx, "PUT", lineup)
+}
+
+// DeleteLineup removes a lineup from the account
+func (c *Client) DeleteLineup(ctx context.Context, lineup string) (*LineupChange, error) {
+	return c.changeLineup(ctx, "DELETE", lineup)
+}
+
+func (c *Client) changeLineup(ctx context.Context, method, lineup string) (*LineupChange, error) {
+	var resp LineupChange
+	if err := c.sendJSON(ctx, request{call: "lineups", method: method, path: "lineups/" + url.PathEscape(lineup)}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Channels returns the channel map and the stations of a lineup
+func (c *Client) Channels(ctx context.Context, lineup string) (*LineupMap, error) {
+	var resp LineupMap
+	if err := c.sendJSON(ctx, request{call: "lineups", method: "GET", path: "lineups/" + url.PathEscape(lineup)}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// StationMap returns the station map of a lineup as JSON, it is decoded by the caller
+func (c *Client) StationMap(ctx context.Context, lineup string) ([]byte, error) {
+	return c.sendRaw(ctx, request{call: "lineups", method: "GET", path: "lineups/" + url.PathEscape(lineup)})
+}
+
+// Schedules returns the schedules of the requested stations and dates as JSON list, it is decoded by the caller
+func (c *Client) Schedules(ctx context.Context, stations []ScheduleRequest) ([]byte, error) {
+	data, err := json.Marshal(stations)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal schedule request")
+	}
+	return c.sendRaw(ctx, request{call: "schedule", method: "POST", path: "schedules", data: data, compression: true})
+}
+
+// Programs returns the programs as JSON list, it is decoded by the caller
+func (c *Client) Programs(ctx context.Context, programIDs []string) ([]byte, error) {
+	data, err := json.Marshal(programIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal program request")
+	}
+	return c.sendRaw(ctx, request{call: "programs", method: "POST", path: "programs", data: data, compression: true})
+}
+
+// Metadata returns the artwork of the programs as JSON list, it is decoded by the caller
+func (c *Client) Metadata(ctx context.Context, programIDs []string) ([]byte, error) {
+	data, err := json.Marshal(programIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal metadata request")
+	}
+	return c.sendRaw(ctx, request{call: "metadata", method: "POST", path: "metadata/programs", data: data, compression: true})
+}
+
+// CelebrityImages returns the headshots of a cast or crew member by the person ID of the credits
+func (c *Client) CelebrityImages(ctx context.Context, personID string) ([]CelebrityImage, error) {
+	var resp []CelebrityImage
+	if err := c.sendJSON(ctx, request{call: "metadata", method: "GET", path: "metadata/celebrity/" + url.PathEscape(personID)}, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
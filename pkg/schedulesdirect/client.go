@@ -0,0 +1,465 @@
+// Package schedulesdirect is a client for the Schedules Direct JSON API.
+//
+// The client handles rate limiting, retries, compressed responses and logs in again
+// if the token expires during a long run. All methods are safe for concurrent use.
+package schedulesdirect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultBaseURL is the Schedules Direct JSON API endpoint
+	DefaultBaseURL = "https://json.schedulesdirect.org/20141201/"
+
+	// Default timeouts of a single request attempt
+	DefaultStatusTimeout   = 10 * time.Second
+	DefaultRequestTimeout  = 30 * time.Second
+	DefaultScheduleTimeout = 2 * time.Minute
+	DefaultProgramTimeout  = 5 * time.Minute
+
+	maxRetries = 3
+	retryDelay = 2 * time.Second
+	maxBackoff = 30 * time.Second
+
+	// maxRetryAfter caps the wait requested by a Retry-After header
+	maxRetryAfter = 5 * time.Minute
+	// minRequestInterval is the slowest request rate the adaptive rate limiting falls back to
+	minRequestInterval = 5 * time.Second
+)
+
+// Timeouts of a single request attempt per call type, zero values use the defaults
+type Timeouts struct {
+	Status   time.Duration
+	Default  time.Duration
+	Schedule time.Duration
+	Program  time.Duration
+}
+
+// Client is a Schedules Direct API client
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	UserAgent  string
+	Logger     logrus.FieldLogger
+	Timeouts   Timeouts
+
+	// ImageBaseURL resolves relative artwork URIs, empty uses the image path of BaseURL
+	ImageBaseURL string
+
+	// Credentials are used by Login and to log in again after the token expired
+	Credentials Credentials
+
+	// OnToken is called with the new token after every successful login, e.g. to persist it
+	OnToken func(token string)
+
+	// Debug logs every request, DebugDir additionally receives the request and response bodies
+	Debug    bool
+	DebugDir string
+
+	limiter   *rate.Limiter
+	tokenMu   sync.RWMutex
+	refreshMu sync.Mutex
+	token     string
+}
+
+// request holds the state of a single request, requests in flight at the same time don't share any fields
+type request struct {
+	call        string
+	method      string
+	path        string
+	data        []byte
+	compression bool
+}
+
+// New creates a client for the API at baseURL, an empty baseURL uses DefaultBaseURL.
+// A nil transport uses http.DefaultTransport.
+func New(baseURL string, transport http.RoundTripper) *Client {
+	if len(baseURL) == 0 {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/") + "/",
+		// Timeouts are set per request, depending on the call type
+		HTTPClient: &http.Client{Transport: transport},
+		UserAgent:  "guide2go",
+		Logger:     logrus.StandardLogger(),
+		limiter:    rate.NewLimiter(rate.Every(100*time.Millisecond), 1),
+	}
+}
+
+// Token returns the current token
+func (c *Client) Token() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the token used by all following requests, e.g. with a persisted token
+func (c *Client) SetToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.token = token
+}
+
+// renewToken logs in again after Schedules Direct rejected token.
+// If another request already replaced the token in the meantime, the new one is used without a login.
+func (c *Client) renewToken(ctx context.Context, token string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if c.Token() != token {
+		return nil
+	}
+
+	c.Logger.Info("Schedules Direct token rejected, logging in again")
+	_, err := c.Login(ctx)
+	return err
+}
+
+// timeout returns the timeout of a single request attempt for the call type
+func (c *Client) timeout(call string) time.Duration {
+	var timeout, fallback time.Duration
+
+	switch call {
+	case "login", "status":
+		timeout, fallback = c.Timeouts.Status, DefaultStatusTimeout
+	case "schedule":
+		timeout, fallback = c.Timeouts.Schedule, DefaultScheduleTimeout
+	case "programs", "metadata":
+		timeout, fallback = c.Timeouts.Program, DefaultProgramTimeout
+	default:
+		timeout, fallback = c.Timeouts.Default, DefaultRequestTimeout
+	}
+
+	if timeout <= 0 {
+		return fallback
+	}
+	return timeout
+}
+
+// sendJSON sends r and decodes the response into v.
+// Responses which don't match v are decoded as error object with code and message.
+func (c *Client) sendJSON(ctx context.Context, r request, v interface{}) error {
+	_, err := c.send(ctx, r, func(body []byte) error {
+		var status ResponseStatus
+		if err := json.Unmarshal(body, v); err != nil {
+			if json.Unmarshal(body, &status) != nil || status.Code == 0 {
+				return errors.Wrapf(err, "failed to unmarshal %s response", r.call)
+			}
+			return newAPIError(status)
+		}
+
+		// Objects carry the code next to the data
+		if json.Unmarshal(body, &status) == nil && status.Code != 0 {
+			return newAPIError(status)
+		}
+		return nil
+	})
+	return err
+}
+
+// sendRaw sends r and returns the response body which is decoded by the caller
+func (c *Client) sendRaw(ctx context.Context, r request) ([]byte, error) {
+	return c.send(ctx, r, func(body []byte) error {
+		return checkResponse(r.call, body)
+	})
+}
+
+// send sends the HTTP request to Schedules Direct with retries and rate limiting.
+// check validates the response body, its error decides whether the request is repeated.
+func (c *Client) send(ctx context.Context, r request, check func(body []byte) error) ([]byte, error) {
+	var lastErr error
+	var refreshed bool
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		// Wait for rate limiter
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, errors.Wrap(err, "rate limiter error")
+		}
+
+		// Create request, the timeout covers sending the request and reading the response
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout(r.call))
+		req, err := http.NewRequestWithContext(reqCtx, r.method, c.BaseURL+r.path, bytes.NewBuffer(r.data))
+		if err != nil {
+			cancel()
+			return nil, errors.Wrap(err, "failed to create request")
+		}
+
+		// Set headers
+		if r.compression {
+			req.Header.Set("Accept-Encoding", "deflate,gzip")
+		}
+		token := c.Token()
+		req.Header.Set("Token", token)
+		req.Header.Set("User-Agent", c.UserAgent)
+		req.Header.Set("X-Custom-Header", c.UserAgent)
+		req.Header.Set("Content-Type", "application/json")
+
+		// Send request
+		start := time.Now()
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			cancel()
+			c.trace(r, attempt, start, 0, nil, err)
+			if ctx.Err() != nil {
+				return nil, errors.Wrap(ctx.Err(), "request cancelled")
+			}
+			lastErr = errors.Wrap(err, "request failed")
+			if err := sleep(ctx, backoff(attempt)); err != nil {
+				return nil, errors.Wrap(err, "request cancelled")
+			}
+			continue
+		}
+
+		// Read response
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		if err == nil {
+			body, err = decodeBody(resp.Header.Get("Content-Encoding"), body)
+		}
+		c.trace(r, attempt, start, resp.StatusCode, body, err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.Wrap(ctx.Err(), "request cancelled")
+			}
+			lastErr = errors.Wrap(err, "failed to read response")
+			if err := sleep(ctx, backoff(attempt)); err != nil {
+				return nil, errors.Wrap(err, "request cancelled")
+			}
+			continue
+		}
+
+		// Throttled by the server, wait as long as requested and slow down for the rest of the run
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			lastErr = errors.Errorf("throttled by Schedules Direct (HTTP %d)", resp.StatusCode)
+			c.slowDown()
+			if err := sleep(ctx, retryAfter(resp.Header.Get("Retry-After"), backoff(attempt))); err != nil {
+				return nil, errors.Wrap(err, "request cancelled")
+			}
+			continue
+		}
+
+		// Process response based on call type
+		if err := check(body); err != nil {
+			lastErr = err
+			// Expired token during a long run, log in again and repeat the request once
+			if errors.Is(err, ErrTokenInvalid) && r.call != "login" && !refreshed && len(c.Credentials.Username) != 0 {
+				refreshed = true
+				if err := c.renewToken(ctx, token); err != nil {
+					return nil, errors.Wrap(err, "failed to refresh token")
+				}
+				attempt--
+				continue
+			}
+			if errors.Is(err, ErrRateLimited) {
+				c.slowDown()
+				if err := sleep(ctx, backoff(attempt)); err != nil {
+					return nil, errors.Wrap(err, "request cancelled")
+				}
+				continue
+			}
+			if isRetryableError(err) {
+				if err := sleep(ctx, backoff(attempt)); err != nil {
+					return nil, errors.Wrap(err, "request cancelled")
+				}
+				continue
+			}
+			return nil, err
+		}
+
+		return body, nil
+	}
+
+	return nil, errors.Wrap(lastErr, "all retry attempts failed")
+}
+
+// trace logs the metadata of a request if debugging is enabled and optionally dumps the bodies to files
+func (c *Client) trace(r request, attempt int, start time.Time, status int, body []byte, err error) {
+	if !c.Debug {
+		return
+	}
+
+	entry := c.Logger.WithFields(logrus.Fields{
+		"call":          r.call,
+		"method":        r.method,
+		"url":           c.BaseURL + r.path,
+		"status":        status,
+		"duration":      time.Since(start),
+		"request_bytes": len(r.data),
+		"reply_bytes":   len(body),
+		"retry":         attempt,
+	})
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Info("SD HTTP trace")
+
+	if len(c.DebugDir) == 0 {
+		return
+	}
+
+	if err := os.MkdirAll(c.DebugDir, 0755); err != nil {
+		c.Logger.WithError(err).Warn("Failed to create HTTP debug directory")
+		return
+	}
+
+	name := fmt.Sprintf("%s_%s_%d", start.Format("20060102T150405.000"), r.call, attempt)
+
+	// The login request contains the password hash, never write it to disk
+	if len(r.data) != 0 && r.call != "login" {
+		os.WriteFile(filepath.Join(c.DebugDir, name+"_request.json"), r.data, 0600)
+	}
+	if len(body) != 0 {
+		os.WriteFile(filepath.Join(c.DebugDir, name+"_response.json"), body, 0600)
+	}
+}
+
+// slowDown halves the request rate of the rate limiter for the remaining requests of the client
+func (c *Client) slowDown() {
+	limit := c.limiter.Limit() / 2
+	if min := rate.Every(minRequestInterval); limit < min {
+		limit = min
+	}
+	c.limiter.SetLimit(limit)
+}
+
+// checkResponse checks a response which is decoded by the caller for Schedules Direct errors
+func checkResponse(call string, body []byte) error {
+	var status ResponseStatus
+
+	switch call {
+	case "schedule", "programs", "metadata":
+		if err := checkListResponse(body, &status); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal %s response", call)
+		}
+
+	default:
+		// The station map of a lineup and other objects carry a code only in case of an error
+		if err := json.Unmarshal(body, &status); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal %s response", call)
+		}
+	}
+
+	if status.Code != 0 {
+		return newAPIError(status)
+	}
+
+	return nil
+}
+
+// checkListResponse reads the error of a response that returns a JSON list on success
+func checkListResponse(body []byte, status *ResponseStatus) error {
+	body = bytes.TrimSpace(body)
+	if len(body) != 0 && body[0] == '[' {
+		return nil
+	}
+
+	if err := json.Unmarshal(body, status); err != nil {
+		return err
+	}
+	if status.Code == 0 {
+		return errors.New("unexpected response")
+	}
+
+	return nil
+}
+
+// decodeBody decompresses a gzip or deflate encoded response body
+func decodeBody(encoding string, body []byte) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+
+	switch strings.ToLower(encoding) {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return body, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decompress %s response", encoding)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	return data, errors.Wrapf(err, "failed to decompress %s response", encoding)
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff calculates exponential backoff duration
+func backoff(attempt int) time.Duration {
+	duration := retryDelay * time.Duration(1<<uint(attempt))
+	if duration > maxBackoff {
+		duration = maxBackoff
+	}
+	return duration
+}
+
+// retryAfter parses a Retry-After header given in seconds or as HTTP date.
+// The fallback is used if the header is missing or invalid.
+func retryAfter(value string, fallback time.Duration) time.Duration {
+	var wait time.Duration
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	}
+
+	if wait <= 0 {
+		return fallback
+	}
+	if wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return wait
+}
+
+// isRetryableError determines if an error should trigger a retry
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// Check for Schedules Direct errors
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable
+	}
+
+	// Check for network errors
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,346 @@
+package schedulesdirect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	c := New(srv.URL, srv.Client().Transport)
+	c.Logger = logger
+	return c
+}
+
+func TestRetryAfter(t *testing.T) {
+	fallback := 2 * time.Second
+	cases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", fallback},
+		{"invalid", fallback},
+		{"0", fallback},
+		{"10", 10 * time.Second},
+		{"3600", maxRetryAfter},
+	}
+	for _, c := range cases {
+		if got := retryAfter(c.value, fallback); got != c.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", c.value, got, c.want)
+		}
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	cases := []struct {
+		code        int
+		name        string
+		retryable   bool
+		token       bool
+		rateLimited bool
+		offline     bool
+	}{
+		{4001, "ACCOUNT_EXPIRED", false, false, false, false},
+		{4003, "INVALID_USER", false, true, false, false},
+		{1004, "TOKEN_MISSING", false, true, false, false},
+		{4004, "ACCOUNT_LOCKOUT", false, false, false, false},
+		{5002, "MAX_IMAGE_DOWNLOADS", true, false, true, false},
+		{3000, "SERVICE_OFFLINE", true, false, false, true},
+		{1234, "UNKNOWN", false, false, false, false},
+	}
+	for _, c := range cases {
+		var err error = newAPIError(ResponseStatus{Code: c.code, Message: "message"})
+		apiErr := err.(*APIError)
+		if apiErr.Name != c.name || apiErr.Retryable != c.retryable {
+			t.Errorf("newAPIError(%d) = %s retryable %v, want %s retryable %v", c.code, apiErr.Name, apiErr.Retryable, c.name, c.retryable)
+		}
+		if got := errors.Is(err, ErrTokenInvalid); got != c.token {
+			t.Errorf("errors.Is(%d, ErrTokenInvalid) = %v, want %v", c.code, got, c.token)
+		}
+		if got := errors.Is(err, ErrRateLimited); got != c.rateLimited {
+			t.Errorf("errors.Is(%d, ErrRateLimited) = %v, want %v", c.code, got, c.rateLimited)
+		}
+		if got := errors.Is(err, ErrOffline); got != c.offline {
+			t.Errorf("errors.Is(%d, ErrOffline) = %v, want %v", c.code, got, c.offline)
+		}
+		if got := isRetryableError(err); got != c.retryable {
+			t.Errorf("isRetryableError(%d) = %v, want %v", c.code, got, c.retryable)
+		}
+	}
+}
+
+func TestCountries(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/available/countries" {
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+		w.Write([]byte(`{"North America":[{"fullName":"United States","shortName":"USA","postalCodeExample":"60030"}]}`))
+	})
+
+	resp, err := c.Countries(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get countries: %v", err)
+	}
+
+	countries := resp.Regions()
+	if len(countries) != 1 || countries[0].ShortName != "USA" {
+		t.Errorf("Unexpected countries %+v", countries)
+	}
+}
+
+func TestHeadendsError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":2051,"message":"Invalid postal code"}`))
+	})
+
+	_, err := c.Headends(context.Background(), "USA", "invalid")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 2051 {
+		t.Errorf("Headends() error = %v, want API error 2051", err)
+	}
+}
+
+func TestChannels(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/lineups/USA-OTA-90210" {
+			t.Errorf("Unexpected request %s %q", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{
+			"map":[{"stationID":"10001","uhfVhf":7},{"stationID":"10002","atscMajor":7,"atscMinor":1}],
+			"stations":[{"stationID":"10001","name":"KABC","callsign":"KABC","broadcastLanguage":["en"]}],
+			"metadata":{"lineup":"USA-OTA-90210","modified":"2024-01-01T00:00:00Z","transport":"Antenna"}
+		}`))
+	})
+
+	resp, err := c.Channels(context.Background(), "USA-OTA-90210")
+	if err != nil {
+		t.Fatalf("Failed to get channels: %v", err)
+	}
+
+	if len(resp.Stations) != 1 || resp.Stations[0].Callsign != "KABC" || resp.Metadata.Transport != "Antenna" {
+		t.Errorf("Unexpected channels %+v", resp)
+	}
+	if got := resp.Channel("10001"); got != "7" {
+		t.Errorf("Channel(10001) = %q, want 7", got)
+	}
+	if got := resp.Channel("10002"); got != "7.1" {
+		t.Errorf("Channel(10002) = %q, want 7.1", got)
+	}
+}
+
+func TestDeleteLineup(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || r.URL.Path != "/lineups/USA-OTA-90210" {
+			t.Errorf("Unexpected request %s %q", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"response":"OK","code":0,"message":"Deleted lineup.","changesRemaining":5}`))
+	})
+
+	resp, err := c.DeleteLineup(context.Background(), "USA-OTA-90210")
+	if err != nil {
+		t.Fatalf("Failed to delete lineup: %v", err)
+	}
+	if resp.ChangesRemaining != 5 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
+
+func TestDeleteLineupError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response":"INVALID_LINEUP_DELETE","code":2102,"message":"Lineup not in account."}`))
+	})
+
+	_, err := c.DeleteLineup(context.Background(), "USA-OTA-90210")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 2102 {
+		t.Errorf("DeleteLineup() error = %v, want API error 2102", err)
+	}
+}
+
+func TestImage(t *testing.T) {
+	var tokens []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.URL.Query().Get("token")+"/"+r.Header.Get("Token"))
+		w.Write([]byte("image"))
+	})
+	c.SetToken("secret")
+
+	if got, want := c.ImageURL("p123.jpg"), c.BaseURL+"image/p123.jpg"; got != want {
+		t.Errorf("ImageURL() = %q, want %q", got, want)
+	}
+	if got := c.ImageURL("https://example.com/p123.jpg"); got != "https://example.com/p123.jpg" {
+		t.Errorf("ImageURL() changed an absolute URL: %q", got)
+	}
+
+	resp, err := c.Image(context.Background(), "p123.jpg")
+	if err != nil {
+		t.Fatalf("Failed to get image: %v", err)
+	}
+	resp.Body.Close()
+
+	// Absolute URLs of the API host keep receiving the token with a separate image server
+	c.ImageBaseURL = "https://images.example.com/"
+	resp, err = c.Image(context.Background(), c.BaseURL+"p123.jpg")
+	if err != nil {
+		t.Fatalf("Failed to get image: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(tokens) != 2 || tokens[0] != "secret/secret" || tokens[1] != "secret/secret" {
+		t.Errorf("Unexpected tokens %q", tokens)
+	}
+	if c.isImageHost("cdn.example.com") {
+		t.Error("isImageHost() accepted a foreign host")
+	}
+}
+
+func TestImageError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":5002,"message":"Maximum image downloads for trial account exceeded"}`))
+	})
+
+	_, err := c.Image(context.Background(), "p123.jpg")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Image() error = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	var logins int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			atomic.AddInt32(&logins, 1)
+			w.Write([]byte(`{"code":0,"message":"OK","token":"new"}`))
+		case r.Header.Get("Token") != "new":
+			w.Write([]byte(`{"code":4006,"message":"Token expired"}`))
+		default:
+			w.Write([]byte(`{"code":0,"account":{"maxLineups":4}}`))
+		}
+	})
+	c.Credentials = Credentials{Username: "user", Password: "hash"}
+	c.SetToken("old")
+
+	var persisted string
+	c.OnToken = func(token string) { persisted = token }
+
+	status, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if status.Account.MaxLineups != 4 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if logins != 1 || persisted != "new" || c.Token() != "new" {
+		t.Errorf("logins = %d, persisted = %q, token = %q, want 1 login with the new token", logins, persisted, c.Token())
+	}
+}
+
+func TestStatusOnline(t *testing.T) {
+	var s Status
+	s.SystemStatus = []SystemStatus{{Status: "Online"}}
+	if err := s.Online(); err != nil {
+		t.Errorf("Online() = %v, want nil", err)
+	}
+
+	s.SystemStatus = []SystemStatus{{Status: "Offline", Message: "Maintenance"}}
+	if err := s.Online(); !errors.Is(err, ErrOffline) {
+		t.Errorf("Online() = %v, want ErrOffline", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	c := New("", nil)
+	c.Timeouts.Program = time.Minute
+
+	cases := []struct {
+		call string
+		want time.Duration
+	}{
+		{"login", DefaultStatusTimeout},
+		{"status", DefaultStatusTimeout},
+		{"lineups", DefaultRequestTimeout},
+		{"schedule", DefaultScheduleTimeout},
+		{"programs", time.Minute},
+		{"metadata", time.Minute},
+	}
+	for _, tc := range cases {
+		if got := c.timeout(tc.call); got != tc.want {
+			t.Errorf("timeout(%q) = %v, want %v", tc.call, got, tc.want)
+		}
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	cases := []struct {
+		call    string
+		body    string
+		wantErr bool
+	}{
+		{"schedule", `[{"stationID":"10001"}]`, false},
+		{"programs", `{"code":4003,"message":"Invalid token"}`, true},
+		{"lineups", `{"map":[],"stations":[]}`, false},
+		{"lineups", `{"code":2100,"message":"Lineup not found"}`, true},
+		{"lineups", `invalid`, true},
+	}
+	for _, c := range cases {
+		if err := checkResponse(c.call, []byte(c.body)); (err != nil) != c.wantErr {
+			t.Errorf("checkResponse(%q, %s) error = %v, wantErr %v", c.call, c.body, err, c.wantErr)
+		}
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`[{"programID":"EP000000010001"}]`))
+	zw.Close()
+
+	data, err := decodeBody("gzip", buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decode gzip body: %v", err)
+	}
+	if string(data) != `[{"programID":"EP000000010001"}]` {
+		t.Errorf("Unexpected body %q", data)
+	}
+
+	data, err = decodeBody("", []byte("plain"))
+	if err != nil || string(data) != "plain" {
+		t.Errorf("decodeBody() changed an uncompressed body: %q, %v", data, err)
+	}
+}
+
+func TestCelebrityImages(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata/celebrity/123" {
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+		w.Write([]byte(`[{"uri":"assets/p123_h3_aa.jpg","width":"270","height":"360","aspect":"3x4","category":"Photo-Headshot"}]`))
+	})
+
+	images, err := c.CelebrityImages(context.Background(), "123")
+	if err != nil {
+		t.Fatalf("Failed to get celebrity images: %v", err)
+	}
+	if len(images) != 1 || images[0].URI != "assets/p123_h3_aa.jpg" {
+		t.Errorf("Unexpected images %+v", images)
+	}
+}
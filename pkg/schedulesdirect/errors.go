@@ -0,0 +1,117 @@
+package schedulesdirect
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTokenInvalid matches API errors of a rejected or expired token
+	ErrTokenInvalid = errors.New("schedules direct token invalid or expired")
+
+	// ErrOffline matches API errors and system status reports of an offline Schedules Direct service
+	ErrOffline = errors.New("schedules direct is offline")
+
+	// ErrRateLimited matches API errors of an exceeded request quota
+	ErrRateLimited = errors.New("schedules direct request quota exceeded")
+)
+
+// errorInfo describes a documented Schedules Direct API response code
+type errorInfo struct {
+	Name      string
+	Retryable bool
+	Hint      string
+}
+
+// errorCodes maps the documented Schedules Direct response codes
+var errorCodes = map[int]errorInfo{
+	1001: {Name: "INVALID_JSON"},
+	1002: {Name: "DEFLATE_REQUIRED"},
+	1004: {Name: "TOKEN_MISSING", Hint: "Log in to Schedules Direct again"},
+	2000: {Name: "UNSUPPORTED_COMMAND"},
+	2001: {Name: "REQUIRED_ACTION_MISSING"},
+	2002: {Name: "REQUIRED_REQUEST_MISSING"},
+	2004: {Name: "REQUIRED_PARAMETER_MISSING:COUNTRY"},
+	2005: {Name: "REQUIRED_PARAMETER_MISSING:POSTALCODE"},
+	2050: {Name: "INVALID_PARAMETER:COUNTRY", Hint: "Select a country from the available countries list"},
+	2051: {Name: "INVALID_PARAMETER:POSTALCODE", Hint: "Check the postal code format of the selected country"},
+	2055: {Name: "DUPLICATE_LINEUP", Hint: "The lineup is already in the account"},
+	2100: {Name: "LINEUP_NOT_FOUND"},
+	2101: {Name: "UNKNOWN_LINEUP", Hint: "Check the lineup ID"},
+	2102: {Name: "INVALID_LINEUP_DELETE", Hint: "The lineup is not in the account"},
+	2103: {Name: "LINEUP_WRONG_FORMAT", Hint: "Lineup IDs look like USA-OTA-90210"},
+	2104: {Name: "INVALID_LINEUP"},
+	2105: {Name: "LINEUP_DELETED", Hint: "Remove the lineup and add a replacement"},
+	2106: {Name: "LINEUP_QUEUED", Retryable: true},
+	2107: {Name: "INVALID_COUNTRY"},
+	2200: {Name: "STATIONID_NOT_FOUND", Hint: "Remove the channel from the configuration"},
+	3000: {Name: "SERVICE_OFFLINE", Retryable: true, Hint: "Schedules Direct is offline, try again later"},
+	4001: {Name: "ACCOUNT_EXPIRED", Hint: "Renew the Schedules Direct subscription"},
+	4002: {Name: "INVALID_HASH", Hint: "Enter the Schedules Direct password again"},
+	4003: {Name: "INVALID_USER", Hint: "Check the Schedules Direct username and password"},
+	4004: {Name: "ACCOUNT_LOCKOUT", Hint: "Too many failed logins, wait before trying again"},
+	4005: {Name: "ACCOUNT_DISABLED", Hint: "Contact Schedules Direct support"},
+	4006: {Name: "TOKEN_EXPIRED"},
+	4009: {Name: "TOO_MANY_LOGINS", Retryable: true, Hint: "Too many logins, the token is reused for 24 hours"},
+	4100: {Name: "MAX_LINEUP_CHANGES_REACHED", Hint: "The daily limit of lineup changes is reached"},
+	4101: {Name: "MAX_LINEUPS", Hint: "Remove a lineup before adding another one"},
+	4102: {Name: "NO_LINEUPS", Hint: "Add a lineup to the account"},
+	5000: {Name: "IMAGE_NOT_FOUND"},
+	5001: {Name: "INVALID_PROGRAMID"},
+	5002: {Name: "MAX_IMAGE_DOWNLOADS", Retryable: true, Hint: "The daily image download limit is reached"},
+	5003: {Name: "MAX_IMAGE_DOWNLOADS_TRIAL", Retryable: true, Hint: "The image download limit of trial accounts is reached"},
+	6000: {Name: "PROGRAMID_QUEUED", Retryable: true},
+	6001: {Name: "FUTURE_PROGRAM"},
+	7020: {Name: "SCHEDULE_RANGE_EXCEEDED", Retryable: true, Hint: "Reduce the number of schedule days"},
+	7030: {Name: "SCHEDULE_NOT_FOUND"},
+	7100: {Name: "SCHEDULE_QUEUED", Retryable: true},
+	9999: {Name: "INTERNAL_ERROR", Retryable: true},
+}
+
+// APIError is an error response of the Schedules Direct API
+type APIError struct {
+	Code      int    `json:"code"`
+	Name      string `json:"name"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+// newAPIError creates the error for the code and message of a response
+func newAPIError(status ResponseStatus) *APIError {
+	info, ok := errorCodes[status.Code]
+	if !ok {
+		info.Name = "UNKNOWN"
+	}
+
+	return &APIError{
+		Code:      status.Code,
+		Name:      info.Name,
+		Message:   status.Message,
+		Retryable: info.Retryable,
+		Hint:      info.Hint,
+	}
+}
+
+// NewAPIError creates the error of a documented response code for checks which answer like the API without a request
+func NewAPIError(code int, message string) *APIError {
+	return newAPIError(ResponseStatus{Code: code, Message: message})
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s [SD API Error Code: %d %s]", e.Message, e.Code, e.Name)
+}
+
+// Is makes the error match ErrTokenInvalid, ErrOffline and ErrRateLimited
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrTokenInvalid:
+		// 4004 is not a token error, a new login during the lockout extends it
+		return e.Code == 1004 || e.Code == 4003 || e.Code == 4006
+	case ErrRateLimited:
+		return e.Code == 4009 || e.Code == 5002 || e.Code == 5003 || e.Code == 7020
+	case ErrOffline:
+		return e.Code == 3000
+	}
+	return false
+}
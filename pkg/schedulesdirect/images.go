@@ -0,0 +1,86 @@
+package schedulesdirect
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ImageURL resolves the URI of an artwork or logo. Schedules Direct returns relative URIs
+// for its own images and absolute URLs for images hosted elsewhere.
+func (c *Client) ImageURL(uri string) string {
+	if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+		return uri
+	}
+	return c.imageBaseURL() + strings.TrimPrefix(uri, "/")
+}
+
+// Image downloads an artwork or logo, the caller closes the body of the response.
+// The token is only sent to the Schedules Direct image server, never to other hosts.
+func (c *Client) Image(ctx context.Context, uri string) (*http.Response, error) {
+	link, err := url.Parse(c.ImageURL(uri))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid image URI %q", uri)
+	}
+
+	header := http.Header{}
+	header.Set("User-Agent", c.UserAgent)
+	if c.isImageHost(link.Host) {
+		token := c.Token()
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		header.Set("Token", token)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create image request")
+	}
+	req.Header = header
+
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, errors.Wrap(err, "rate limiter error")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch image %s", uri)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		// Schedules Direct reports e.g. an exceeded image download limit as error object
+		var status ResponseStatus
+		if json.Unmarshal(body, &status) == nil && status.Code != 0 {
+			return nil, newAPIError(status)
+		}
+		return nil, errors.Errorf("failed to fetch image %s: HTTP %d", uri, resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// imageBaseURL returns ImageBaseURL or the image path of the API
+func (c *Client) imageBaseURL() string {
+	if len(c.ImageBaseURL) != 0 {
+		return strings.TrimSuffix(c.ImageBaseURL, "/") + "/"
+	}
+	return c.BaseURL + "image/"
+}
+
+// isImageHost reports whether host serves the images of Schedules Direct
+func (c *Client) isImageHost(host string) bool {
+	for _, base := range []string{c.imageBaseURL(), c.BaseURL} {
+		if u, err := url.Parse(base); err == nil && strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
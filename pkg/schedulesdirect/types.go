@@ -0,0 +1,226 @@
+package schedulesdirect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ResponseStatus holds the code and message every Schedules Direct response carries
+type ResponseStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Credentials : Account of the token request, the password is the SHA1 hash of the password
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse : Response of the token request
+type LoginResponse struct {
+	Message  string    `json:"message"`
+	Code     int       `json:"code"`
+	ServerID string    `json:"serverID"`
+	Datetime time.Time `json:"datetime"`
+	Token    string    `json:"token"`
+}
+
+// Status : Account and system status
+type Status struct {
+	Account struct {
+		Expires    time.Time `json:"expires"`
+		MaxLineups int64     `json:"maxLineups"`
+		Messages   []Message `json:"messages"`
+	} `json:"account"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+
+	Datetime       string         `json:"datetime"`
+	LastDataUpdate string         `json:"lastDataUpdate"`
+	Lineups        []StatusLineup `json:"lineups"`
+	Notifications  []Message      `json:"notifications"`
+	ServerID       string         `json:"serverID"`
+	SystemStatus   []SystemStatus `json:"systemStatus"`
+}
+
+// StatusLineup : Lineup of the account
+type StatusLineup struct {
+	Lineup   string `json:"lineup"`
+	Modified string `json:"modified"`
+	Name     string `json:"name"`
+	URI      string `json:"uri"`
+}
+
+// SystemStatus : Status of the Schedules Direct service
+type SystemStatus struct {
+	Date    string `json:"date"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// Message : Account message or notification of the status response
+type Message struct {
+	MsgID   string `json:"msgID"`
+	Date    string `json:"date"`
+	Message string `json:"message"`
+}
+
+// Online returns ErrOffline if the system status reports that Schedules Direct is not online
+func (s *Status) Online() error {
+	for _, status := range s.SystemStatus {
+		if !strings.EqualFold(status.Status, "Online") {
+			return errors.Wrapf(ErrOffline, "%s: %s", status.Status, status.Message)
+		}
+	}
+	return nil
+}
+
+// Country : Country entry of the available countries response
+type Country struct {
+	FullName          string `json:"fullName"`
+	ShortName         string `json:"shortName"`
+	PostalCodeExample string `json:"postalCodeExample"`
+	PostalCode        string `json:"postalCode"`
+	OnePostalCode     bool   `json:"onePostalCode"`
+}
+
+// Countries : Available countries grouped by region
+type Countries struct {
+	NorthAmerica []Country `json:"North America"`
+	Europe       []Country `json:"Europe"`
+	LatinAmerica []Country `json:"Latin America"`
+	Caribbean    []Country `json:"Caribbean"`
+	Oceania      []Country `json:"Oceania"`
+}
+
+// Regions returns the countries of all regions in display order
+func (c *Countries) Regions() (list []Country) {
+	list = append(list, c.NorthAmerica...)
+	list = append(list, c.Europe...)
+	list = append(list, c.LatinAmerica...)
+	list = append(list, c.Caribbean...)
+	list = append(list, c.Oceania...)
+	return
+}
+
+// Headend : Headend with its lineups, result of a postal code lookup
+type Headend struct {
+	Headend   string `json:"headend"`
+	Transport string `json:"transport"`
+	Location  string `json:"location"`
+	Lineups   []struct {
+		Name   string `json:"name"`
+		Lineup string `json:"lineup"`
+		URI    string `json:"uri"`
+	} `json:"lineups"`
+}
+
+// LineupPreview : Station of a lineup preview
+type LineupPreview struct {
+	Channel   string `json:"channel"`
+	Name      string `json:"name"`
+	Callsign  string `json:"callsign"`
+	Affiliate string `json:"affiliate"`
+}
+
+// LineupChange : Response of adding or deleting a lineup
+type LineupChange struct {
+	Response         string `json:"response"`
+	Code             int    `json:"code"`
+	ServerID         string `json:"serverID"`
+	Message          string `json:"message"`
+	ChangesRemaining int    `json:"changesRemaining"`
+	Datetime         string `json:"datetime"`
+}
+
+// LineupMap : Channel map and stations of a lineup
+type LineupMap struct {
+	Map      []ChannelMap `json:"map"`
+	Stations []Station    `json:"stations"`
+	Metadata struct {
+		Lineup     string `json:"lineup"`
+		Modified   string `json:"modified"`
+		Transport  string `json:"transport"`
+		Modulation string `json:"modulation"`
+	} `json:"metadata"`
+}
+
+// ChannelMap : Channel number of a station in a lineup, the fields depend on the transport of the lineup
+type ChannelMap struct {
+	StationID            string `json:"stationID"`
+	Channel              string `json:"channel"`
+	UhfVhf               int    `json:"uhfVhf"`
+	AtscMajor            int    `json:"atscMajor"`
+	AtscMinor            int    `json:"atscMinor"`
+	ProviderCallsign     string `json:"providerCallsign"`
+	LogicalChannelNumber string `json:"logicalChannelNumber"`
+	MatchType            string `json:"matchType"`
+}
+
+// Station : Station of a lineup
+type Station struct {
+	StationID           string   `json:"stationID"`
+	Name                string   `json:"name"`
+	Callsign            string   `json:"callsign"`
+	Affiliate           string   `json:"affiliate"`
+	BroadcastLanguage   []string `json:"broadcastLanguage"`
+	DescriptionLanguage []string `json:"descriptionLanguage"`
+	Broadcaster         struct {
+		City       string `json:"city"`
+		State      string `json:"state"`
+		Postalcode string `json:"postalcode"`
+		Country    string `json:"country"`
+	} `json:"broadcaster"`
+	IsCommercialFree bool          `json:"isCommercialFree"`
+	Logo             StationLogo   `json:"logo"`
+	StationLogo      []StationLogo `json:"stationLogo"`
+}
+
+// StationLogo : Logo of a station
+type StationLogo struct {
+	URL      string `json:"URL"`
+	Height   int    `json:"height"`
+	Width    int    `json:"width"`
+	Md5      string `json:"md5"`
+	Source   string `json:"source"`
+	Category string `json:"category"`
+}
+
+// Channel returns the channel number of a station in the lineup
+func (l *LineupMap) Channel(stationID string) string {
+	for _, m := range l.Map {
+		if m.StationID != stationID {
+			continue
+		}
+		switch {
+		case len(m.Channel) != 0:
+			return m.Channel
+		case m.AtscMajor != 0:
+			return fmt.Sprintf("%d.%d", m.AtscMajor, m.AtscMinor)
+		case m.UhfVhf != 0:
+			return strconv.Itoa(m.UhfVhf)
+		}
+		return m.LogicalChannelNumber
+	}
+	return ""
+}
+
+// ScheduleRequest : Station and dates of a schedule request
+type ScheduleRequest struct {
+	StationID string   `json:"stationID"`
+	Date      []string `json:"date"`
+}
+
+// CelebrityImage : Headshot of a cast or crew member
+type CelebrityImage struct {
+	URI      string `json:"uri"`
+	Width    string `json:"width"`
+	Height   string `json:"height"`
+	Aspect   string `json:"aspect"`
+	Category string `json:"category"`
+}
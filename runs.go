@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxRuns is the number of updates kept in the run history, the logs of older updates are removed
+const maxRuns = 50
+
+// runRecord is an update in the run history of a configuration
+type runRecord struct {
+	ID       int       `json:"id"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Programs int       `json:"programs"`            // Programs downloaded from Schedules Direct
+	FileSize int64     `json:"file_size,omitempty"` // Size of the XMLTV file, 0 if none was created
+	Error    string    `json:"error,omitempty"`
+}
+
+// runHistoryFile returns the file of the run history of the configuration, it is kept next to the configuration file
+func runHistoryFile(app *App) string {
+	return app.Config.File + ".runs"
+}
+
+// runLogFile returns the log file of an update of the configuration
+func runLogFile(app *App, id int) string {
+	return filepath.Join(app.Config.File+".logs", strconv.Itoa(id)+".log")
+}
+
+// loadRuns returns the run history of the configuration, newest first
+func loadRuns(app *App) ([]runRecord, error) {
+	data, err := os.ReadFile(runHistoryFile(app))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read run history")
+	}
+
+	var runs []runRecord
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, errors.Wrap(err, "failed to parse run history")
+	}
+	return runs, nil
+}
+
+// nextRunID returns the ID of the next update of the run history
+func nextRunID(runs []runRecord) int {
+	if len(runs) == 0 {
+		return 1
+	}
+	return runs[0].ID + 1
+}
+
+// saveRun adds an update to the run history, the file is replaced at once so the web UI never reads half of it
+func saveRun(app *App, run runRecord) error {
+	runs, err := loadRuns(app)
+	if err != nil {
+		app.Logger.WithError(err).Warn("Starting a new run history")
+	}
+
+	runs = append([]runRecord{run}, runs...)
+	if len(runs) > maxRuns {
+		for _, old := range runs[maxRuns:] {
+			os.Remove(runLogFile(app, old.ID))
+		}
+		runs = runs[:maxRuns]
+	}
+
+	data, err := json.Marshal(runs)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal run history")
+	}
+	tmp := runHistoryFile(app) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write run history")
+	}
+	return errors.Wrap(os.Rename(tmp, runHistoryFile(app)), "failed to replace run history")
+}
+
+// statXMLTV returns the XMLTV file of the configuration, only the compressed file exists with the compression option only
+func statXMLTV(c *config) (string, os.FileInfo, bool) {
+	for _, name := range []string{c.Files.XMLTV, c.Files.XMLTV + ".gz"} {
+		if info, err := os.Stat(name); err == nil {
+			return name, info, true
+		}
+	}
+	return "", nil, false
+}
+
+// runLog is a logrus hook which writes the log entries into the log file of the running update.
+// The updates run one after the other, entries of other requests during an update are written as well.
+type runLog struct {
+	formatter logrus.Formatter
+
+	mu   sync.Mutex
+	file *os.File // nil while no update runs
+}
+
+func newRunLog() *runLog {
+	return &runLog{formatter: &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}}
+}
+
+// Levels returns all levels, the level of the logger selects the entries
+func (l *runLog) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire writes the entry into the log file of the running update
+func (l *runLog) Fire(entry *logrus.Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+
+	data, err := l.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(data)
+	return err
+}
+
+// start writes the following entries into the file, nil logs nothing
+func (l *runLog) start(name string) error {
+	if l == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return errors.Wrap(err, "failed to create run log directory")
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to create run log")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.file = file
+	return nil
+}
+
+// stop closes the log file of the update
+func (l *runLog) stop() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSaveRun(t *testing.T) {
+	app := &App{Config: config{File: filepath.Join(t.TempDir(), "test.yaml")}, Logger: logrus.New()}
+
+	for i := 0; i < maxRuns+2; i++ {
+		runs, err := loadRuns(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := nextRunID(runs)
+		if err := os.MkdirAll(filepath.Dir(runLogFile(app, id)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(runLogFile(app, id), []byte("log"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveRun(app, runRecord{ID: id, Started: time.Now(), Finished: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs, err := loadRuns(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != maxRuns || runs[0].ID != maxRuns+2 || runs[maxRuns-1].ID != 3 {
+		t.Fatalf("loadRuns() returned %d runs from %d to %d", len(runs), runs[0].ID, runs[len(runs)-1].ID)
+	}
+	for id, exists := range map[int]bool{1: false, 2: false, 3: true} {
+		if _, err := os.Stat(runLogFile(app, id)); (err == nil) != exists {
+			t.Errorf("log of run %d exists: %v, want %v", id, err == nil, exists)
+		}
+	}
+}
+
+func TestRunLog(t *testing.T) {
+	logger := logrus.New()
+	hook := newRunLog()
+	logger.AddHook(hook)
+	name := filepath.Join(t.TempDir(), "logs", "1.log")
+
+	logger.Info("before")
+	if err := hook.start(name); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("during")
+	hook.stop()
+	logger.Info("after")
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, "during") || strings.Contains(got, "before") || strings.Contains(got, "after") {
+		t.Fatalf("run log = %q", got)
+	}
+
+	var none *runLog
+	if err := none.start(name); err != nil {
+		t.Fatal(err)
+	}
+	none.stop()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultUpdateSchedule is the update schedule of new configurations, every day at 4:00
+const defaultUpdateSchedule = "0 4 * * *"
+
+// runScheduler queues an update whenever the update schedule of a configuration file is due, until ctx is done.
+// The configuration files are read every minute, changes of the web UI apply without a restart.
+func (app *App) runScheduler(ctx context.Context) {
+	for {
+		// Every check is at the start of a minute
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			for _, name := range app.configFiles() {
+				schedule, enabled, err := app.updateSchedule(ctx, name)
+				if err != nil {
+					app.Logger.WithError(err).WithField("config", name).Warn("Failed to read the update schedule")
+					continue
+				}
+
+				if enabled && schedule.matches(now) {
+					app.Logger.WithFields(logrus.Fields{"config": name, "job": app.jobs.queue(name).status().ID}).Info("Scheduled update")
+				}
+			}
+		}
+	}
+}
+
+// updateSchedule returns the update schedule of a configuration file
+func (app *App) updateSchedule(ctx context.Context, name string) (*cronSchedule, bool, error) {
+	c, err := app.readConfig(ctx, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// An empty schedule disables the updates like the enabled option
+	if len(c.Options.UpdateSchedule) == 0 {
+		return nil, false, nil
+	}
+
+	schedule, err := parseCron(c.Options.UpdateSchedule)
+	if err != nil {
+		return nil, false, err
+	}
+	return schedule, c.Options.UpdateScheduleEnabled, nil
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+
+	"github.com/yourusername/guide2go/pkg/i18n"
 )
 
+// getMsg returns the message of the code in the language of the configuration
 func getMsg(code int) (msg string) {
 
   switch code {
@@ -46,6 +49,8 @@ func getMsg(code int) (msg string) {
     msg = "Select Provider"
   case 0204:
     msg = "Select Lineup"
+  case 0205:
+    msg = "Add Lineup to the account (Y/N)"
 
   case 0300:
     msg = "Update Config File"
@@ -62,7 +67,7 @@ func getMsg(code int) (msg string) {
     msg = "Local Images Cache"
   }
 
-  return
+  return i18n.T(msg)
 }
 
 // Show : Show menu on screen
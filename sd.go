@@ -2,99 +2,83 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"io"
-	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/time/rate"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
 )
 
 const (
-	maxRetries     = 3
-	retryDelay     = 2 * time.Second
-	maxBackoff     = 30 * time.Second
-	requestTimeout = 30 * time.Second
-)
+	// tokenLifetime is how long a Schedules Direct token is reused before a new login.
+	// SD tokens are valid for 24 hours, keep a margin so a run never starts with a token about to expire.
+	tokenLifetime = 23 * time.Hour
+
+	// defaultExpiryWarning is the number of days before the expiry of the account the log and the web UI warn
+	defaultExpiryWarning = 14
 
-var (
-	// rateLimiter limits requests to Schedules Direct API
-	rateLimiter = rate.NewLimiter(rate.Every(100*time.Millisecond), 1)
+	// defaultStatusInterval is how often the web UI polls the Schedules Direct status
+	defaultStatusInterval = 15 * time.Minute
 )
 
-// SD represents the Schedules Direct API client
+// sdToken is the persisted Schedules Direct token
+type sdToken struct {
+	Username string    `json:"username"`
+	Token    string    `json:"token"`
+	Issued   time.Time `json:"issued"`
+}
+
+// SD connects the application to the Schedules Direct API client
 type SD struct {
 	BaseURL string
-	Token   string
-	client  *http.Client
+	client  *schedulesdirect.Client
+	app     *App
+
+	// ctx cancels all requests of a run, e.g. on shutdown or when the update deadline is exceeded
+	ctx context.Context
+
+	// autosave saves the cache during the downloads of a run
+	autosave *autosave
 
-	// SD Request
+	// progress receives the steps of a run started as job, nil otherwise
+	progress *job
+
+	// downloaded counts the programs requested from Schedules Direct in the run
+	downloaded int
+
+	// SD Request of the lineup calls
 	Req struct {
-		URL         string
-		Data        []byte
-		Type        string
-		Compression bool
-		Parameter   string
-		Call        string
+		Type      string
+		Parameter string
 	}
 
 	// SD Response
 	Resp struct {
+		// Body is the station map of a lineup
 		Body []byte
 
-		// Login
-		Login struct {
-			Message  string    `json:"message"`
-			Code     int       `json:"code"`
-			ServerID string    `json:"serverID"`
-			Datetime time.Time `json:"datetime"`
-			Token    string    `json:"token"`
-		}
-
-		// Status
-		Status struct {
-			Account struct {
-				Expires    time.Time     `json:"expires"`
-				MaxLineups int64         `json:"maxLineups"`
-				Messages   []interface{} `json:"messages"`
-			} `json:"account"`
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-
-			Datetime       string `json:"datetime"`
-			LastDataUpdate string `json:"lastDataUpdate"`
-			Lineups        []struct {
-				Lineup   string `json:"lineup"`
-				Modified string `json:"modified"`
-				Name     string `json:"name"`
-				URI      string `json:"uri"`
-			} `json:"lineups"`
-			Notifications []interface{} `json:"notifications"`
-			ServerID      string        `json:"serverID"`
-			SystemStatus  []struct {
-				Date    string `json:"date"`
-				Message string `json:"message"`
-				Status  string `json:"status"`
-			} `json:"systemStatus"`
-		}
-
-		// Other response fields remain unchanged...
+		Login        schedulesdirect.LoginResponse
+		Status       schedulesdirect.Status
+		Countries    schedulesdirect.Countries
+		Headend      []schedulesdirect.Headend
+		Preview      []schedulesdirect.LineupPreview
+		LineupChange schedulesdirect.LineupChange
+		Channels     schedulesdirect.LineupMap
 	}
 
 	// SD API Calls
 	Login     func() error
 	Status    func() error
 	Countries func() error
-	Headends  func() error
+	Headends  func(country, postalCode string) error
 	Lineups   func() error
+	Preview   func(lineup string) error
 	Delete    func() error
 	Channels  func() error
-	Schedule  func() error
-	Program   func() error
 }
 
 // SchedulesDirectClient defines the interface for Schedules Direct operations
@@ -108,50 +92,60 @@ type SchedulesDirectClient interface {
 
 // Init initializes the Schedules Direct client
 func (sd *SD) Init(app *App) error {
-	sd.BaseURL = "https://json.schedulesdirect.org/20141201/"
-	sd.client = &http.Client{
-		Timeout: requestTimeout,
+	sd.app = app
+
+	sd.client = schedulesdirect.New(app.Config.Options.SDBaseURL, app.Transport)
+	sd.client.UserAgent = AppName
+	sd.client.Logger = app.Logger
+	sd.client.Debug = app.DebugHTTP
+	sd.client.DebugDir = app.DebugHTTPDir
+	sd.client.Credentials = schedulesdirect.Credentials{
+		Username: app.Config.Account.Username,
+		Password: app.Config.Account.Password,
 	}
+	sd.client.Timeouts = schedulesdirect.Timeouts{
+		Status:   app.Config.Options.Timeouts.Status,
+		Default:  app.Config.Options.Timeouts.Default,
+		Schedule: app.Config.Options.Timeouts.Schedule,
+		Program:  app.Config.Options.Timeouts.Program,
+	}
+	sd.client.OnToken = func(token string) {
+		app.Token = token
+		if err := saveToken(app, token); err != nil {
+			app.Logger.WithError(err).Warn("Failed to persist Schedules Direct token")
+		}
+	}
+	sd.client.ImageBaseURL = app.Config.Options.SDImageURL
+	sd.BaseURL = sd.client.BaseURL
+	app.Images = sd.client
 
 	sd.Login = func() error {
-		sd.Req.URL = sd.BaseURL + "token"
-		sd.Req.Type = "POST"
-		sd.Req.Call = "login"
-		sd.Req.Compression = false
-		sd.Token = ""
-
-		login := app.Config.Account
-		data, err := json.MarshalIndent(login, "", "  ")
-		if err != nil {
-			return errors.Wrap(err, "failed to marshal login data")
+		// An expired persisted token is replaced by the client with a new login
+		if token, ok := loadToken(app); ok {
+			sd.client.SetToken(token)
+			app.Token = token
+			app.Logger.Debug("Reusing persisted Schedules Direct token")
+			return nil
 		}
-		sd.Req.Data = data
 
-		if err := sd.Connect(); err != nil {
-			if sd.Resp.Login.Code != 0 {
-				return errors.New(sd.Resp.Login.Message)
-			}
+		resp, err := sd.client.Login(sd.context())
+		if err != nil {
 			return err
 		}
+		sd.Resp.Login = *resp
 
 		app.Logger.WithFields(logrus.Fields{
-			"message": sd.Resp.Login.Message,
+			"message": resp.Message,
 		}).Info("Successfully logged in to Schedules Direct")
-
-		sd.Token = sd.Resp.Login.Token
 		return nil
 	}
 
 	sd.Status = func() error {
-		sd.Req.URL = sd.BaseURL + "status"
-		sd.Req.Type = "GET"
-		sd.Req.Data = nil
-		sd.Req.Call = "status"
-		sd.Req.Compression = false
-
-		if err := sd.Connect(); err != nil {
+		resp, err := sd.client.Status(sd.context())
+		if err != nil {
 			return err
 		}
+		sd.Resp.Status = *resp
 
 		app.Logger.WithFields(logrus.Fields{
 			"expires":    sd.Resp.Status.Account.Expires,
@@ -160,6 +154,13 @@ func (sd *SD) Init(app *App) error {
 			"channels":   len(app.Config.Station),
 		}).Info("Schedules Direct status")
 
+		if days, warn := expiryWarning(sd.Resp.Status.Account.Expires, app.Config.Options.ExpiryWarning, time.Now()); warn {
+			app.Logger.WithFields(logrus.Fields{
+				"expires": sd.Resp.Status.Account.Expires,
+				"days":    days,
+			}).Warn("Schedules Direct account expires soon")
+		}
+
 		for _, status := range sd.Resp.Status.SystemStatus {
 			app.Logger.WithFields(logrus.Fields{
 				"status":  status.Status,
@@ -167,137 +168,223 @@ func (sd *SD) Init(app *App) error {
 			}).Info("System status")
 		}
 
+		for _, msg := range sd.Resp.Status.Account.Messages {
+			app.Logger.WithFields(logrus.Fields{
+				"id":      msg.MsgID,
+				"date":    msg.Date,
+				"message": msg.Message,
+			}).Warn("Schedules Direct account message")
+		}
+
+		for _, msg := range sd.Resp.Status.Notifications {
+			app.Logger.WithFields(logrus.Fields{
+				"id":      msg.MsgID,
+				"date":    msg.Date,
+				"message": msg.Message,
+			}).Info("Schedules Direct notification")
+		}
+
 		return nil
 	}
 
-	// Initialize other API methods...
-	return nil
-}
-
-// Connect sends the HTTP request to Schedules Direct with retries and rate limiting
-func (sd *SD) Connect() error {
-	var lastErr error
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		// Wait for rate limiter
-		if err := rateLimiter.Wait(context.Background()); err != nil {
-			return errors.Wrap(err, "rate limiter error")
+	sd.Countries = func() error {
+		resp, err := sd.client.Countries(sd.context())
+		if err != nil {
+			return err
 		}
+		sd.Resp.Countries = *resp
 
-		// Create request
-		req, err := http.NewRequest(sd.Req.Type, sd.Req.URL, bytes.NewBuffer(sd.Req.Data))
+		app.Logger.WithField("countries", len(sd.Resp.Countries.Regions())).Debug("Received available countries")
+		return nil
+	}
+
+	sd.Headends = func(country, postalCode string) error {
+		resp, err := sd.client.Headends(sd.context(), country, postalCode)
 		if err != nil {
-			return errors.Wrap(err, "failed to create request")
+			return err
 		}
+		sd.Resp.Headend = resp
+
+		app.Logger.WithFields(logrus.Fields{
+			"country":    country,
+			"postalcode": postalCode,
+			"headends":   len(sd.Resp.Headend),
+		}).Debug("Received headends")
+		return nil
+	}
+
+	// Lineups gets the station map (GET), adds (PUT) or deletes (DELETE) the lineup in sd.Req.Parameter
+	sd.Lineups = func() error {
+		lineup := strings.TrimPrefix(sd.Req.Parameter, "/")
 
-		// Set headers
-		if sd.Req.Compression {
-			req.Header.Set("Accept-Encoding", "deflate,gzip")
+		switch sd.Req.Type {
+		case "PUT":
+			resp, err := sd.client.AddLineup(sd.context(), lineup)
+			if err != nil {
+				return err
+			}
+			sd.Resp.LineupChange = *resp
+
+			app.Logger.WithFields(logrus.Fields{
+				"message":          resp.Message,
+				"changesRemaining": resp.ChangesRemaining,
+			}).Info("Lineup changed")
+			return nil
+
+		case "DELETE":
+			return sd.Delete()
 		}
-		req.Header.Set("Token", sd.Token)
-		req.Header.Set("User-Agent", AppName)
-		req.Header.Set("X-Custom-Header", AppName)
-		req.Header.Set("Content-Type", "application/json")
 
-		// Send request
-		resp, err := sd.client.Do(req)
+		body, err := sd.client.StationMap(sd.context(), lineup)
 		if err != nil {
-			lastErr = errors.Wrap(err, "request failed")
-			time.Sleep(backoff(attempt))
-			continue
+			return err
 		}
+		sd.Resp.Body = body
+		return nil
+	}
+
+	// Delete removes the lineup in sd.Req.Parameter from the account
+	sd.Delete = func() error {
+		lineup := strings.TrimPrefix(sd.Req.Parameter, "/")
 
-		// Read response
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+		resp, err := sd.client.DeleteLineup(sd.context(), lineup)
 		if err != nil {
-			lastErr = errors.Wrap(err, "failed to read response")
-			time.Sleep(backoff(attempt))
-			continue
+			return err
 		}
+		sd.Resp.LineupChange = *resp
 
-		sd.Resp.Body = body
+		app.Logger.WithFields(logrus.Fields{
+			"lineup":           lineup,
+			"message":          resp.Message,
+			"changesRemaining": resp.ChangesRemaining,
+		}).Info("Lineup deleted")
+		return nil
+	}
 
-		// Process response based on call type
-		if err := sd.processResponse(); err != nil {
-			lastErr = err
-			if isRetryableError(err) {
-				time.Sleep(backoff(attempt))
-				continue
-			}
+	// Channels gets the channel map and the stations of the lineup in sd.Req.Parameter
+	sd.Channels = func() error {
+		lineup := strings.TrimPrefix(sd.Req.Parameter, "/")
+
+		resp, err := sd.client.Channels(sd.context(), lineup)
+		if err != nil {
+			return err
+		}
+		sd.Resp.Channels = *resp
+
+		app.Logger.WithFields(logrus.Fields{
+			"lineup":   lineup,
+			"stations": len(resp.Stations),
+		}).Debug("Received channels")
+		return nil
+	}
+
+	sd.Preview = func(lineup string) error {
+		resp, err := sd.client.Preview(sd.context(), lineup)
+		if err != nil {
 			return err
 		}
+		sd.Resp.Preview = resp
 
+		app.Logger.WithFields(logrus.Fields{
+			"lineup":   lineup,
+			"stations": len(sd.Resp.Preview),
+		}).Debug("Received lineup preview")
 		return nil
 	}
 
-	return errors.Wrap(lastErr, "all retry attempts failed")
+	// Initialize other API methods...
+	return nil
 }
 
-// processResponse processes the API response based on the call type
-func (sd *SD) processResponse() error {
-	var sdStatus SDStatus
+// SetContext sets the context which cancels all following requests
+func (sd *SD) SetContext(ctx context.Context) {
+	sd.ctx = ctx
+}
 
-	switch sd.Req.Call {
-	case "login":
-		if err := json.Unmarshal(sd.Resp.Body, &sd.Resp.Login); err != nil {
-			return errors.Wrap(err, "failed to unmarshal login response")
-		}
-		sdStatus.Code = sd.Resp.Login.Code
-		sdStatus.Message = sd.Resp.Login.Message
+// context returns the context of the run
+func (sd *SD) context() context.Context {
+	if sd.ctx == nil {
+		return context.Background()
+	}
+	return sd.ctx
+}
 
-	case "status":
-		if err := json.Unmarshal(sd.Resp.Body, &sd.Resp.Status); err != nil {
-			return errors.Wrap(err, "failed to unmarshal status response")
-		}
-		sdStatus.Code = sd.Resp.Status.Code
-		sdStatus.Message = sd.Resp.Status.Message
+// tokenFile returns the path of the persisted token, stored next to the cache file.
+// A Redis cache has no file, the token is stored next to the configuration file.
+func tokenFile(app *App) string {
+	if len(app.Config.Files.Cache) == 0 {
+		return ""
+	}
+	if isRedisURL(app.Config.Files.Cache) {
+		return app.Config.File + ".token"
+	}
+	return app.Config.Files.Cache + ".token"
+}
 
-	// Add other cases...
+// loadToken returns the persisted token if it belongs to the configured account and is still valid
+func loadToken(app *App) (string, bool) {
+	file := tokenFile(app)
+	if len(file) == 0 {
+		return "", false
+	}
 
-	default:
-		return errors.New("unknown API call type")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
 	}
 
-	// Check for API errors
-	if sdStatus.Code != 0 {
-		return errors.New(sdStatus.Message)
+	var t sdToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		app.Logger.WithError(err).Warn("Ignoring unreadable token file")
+		return "", false
 	}
 
-	return nil
-}
+	if t.Username != app.Config.Account.Username || len(t.Token) == 0 {
+		return "", false
+	}
 
-// backoff calculates exponential backoff duration
-func backoff(attempt int) time.Duration {
-	duration := retryDelay * time.Duration(1<<uint(attempt))
-	if duration > maxBackoff {
-		duration = maxBackoff
+	if time.Since(t.Issued) > tokenLifetime {
+		return "", false
 	}
-	return duration
+
+	return t.Token, true
 }
 
-// isRetryableError determines if an error should trigger a retry
-func isRetryableError(err error) bool {
-	if err == nil {
-		return false
+// saveToken persists the token with its issue time
+func saveToken(app *App, token string) error {
+	file := tokenFile(app)
+	if len(file) == 0 {
+		return nil
 	}
 
-	// Check for network errors
-	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
-		return true
+	data, err := json.Marshal(sdToken{
+		Username: app.Config.Account.Username,
+		Token:    token,
+		Issued:   time.Now(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal token")
 	}
 
-	// Check for HTTP errors
-	var httpErr *http.Response
-	if errors.As(err, &httpErr) {
-		switch httpErr.StatusCode {
-		case http.StatusTooManyRequests,
-			http.StatusInternalServerError,
-			http.StatusBadGateway,
-			http.StatusServiceUnavailable,
-			http.StatusGatewayTimeout:
-			return true
-		}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return errors.Wrap(err, "failed to write token file")
 	}
 
-	return false
+	return nil
+}
+
+// removeToken deletes the persisted token so the next login authenticates again, an update removes it after a token error
+func removeToken(app *App) {
+	if file := tokenFile(app); len(file) != 0 {
+		os.Remove(file)
+	}
+}
+
+// expiryWarning returns the full days until the account expires and whether they are within the warning days, an expired account is warned as well
+func expiryWarning(expires time.Time, warningDays int, now time.Time) (int, bool) {
+	if expires.IsZero() {
+		return 0, false
+	}
+	left := expires.Sub(now)
+	return int(left / (24 * time.Hour)), warningDays > 0 && left < time.Duration(warningDays)*24*time.Hour
 }
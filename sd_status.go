@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+	"github.com/yourusername/guide2go/web/handlers"
+)
+
+// maxAnnouncements is the number of account messages and notifications the status panel shows
+const maxAnnouncements = 10
+
+// sdStatusPoll is the last Schedules Direct status polled for the web UI
+type sdStatusPoll struct {
+	mu     sync.Mutex
+	status *handlers.SDStatus
+}
+
+// get returns the last polled status, nil before the first poll
+func (p *sdStatusPoll) get() *handlers.SDStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// set stores a polled status and returns the one before
+func (p *sdStatusPoll) set(status *handlers.SDStatus) *handlers.SDStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	last := p.status
+	p.status = status
+	return last
+}
+
+// sdStatus converts the status response for the web UI, err is the error of the poll
+func sdStatus(s *schedulesdirect.Status, err error, now time.Time) *handlers.SDStatus {
+	status := &handlers.SDStatus{Checked: now}
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	status.Online = s.Online() == nil
+	status.LastDataUpdate = s.LastDataUpdate
+	for _, st := range s.SystemStatus {
+		status.SystemStatus = append(status.SystemStatus, handlers.SystemStatus{Date: st.Date, Status: st.Status, Message: st.Message})
+	}
+	for _, m := range append(s.Account.Messages, s.Notifications...) {
+		status.Announcements = append(status.Announcements, handlers.Message{ID: m.MsgID, Date: m.Date, Message: m.Message})
+	}
+
+	// The dates are ISO 8601 in UTC, they sort as strings
+	sort.SliceStable(status.Announcements, func(i, j int) bool {
+		return status.Announcements[i].Date > status.Announcements[j].Date
+	})
+	if len(status.Announcements) > maxAnnouncements {
+		status.Announcements = status.Announcements[:maxAnnouncements]
+	}
+	return status
+}
+
+// pollSDStatus requests the Schedules Direct status without the log entries of an update
+func (app *App) pollSDStatus(ctx context.Context) *handlers.SDStatus {
+	x := *app
+	status, err := func() (*schedulesdirect.Status, error) {
+		if err := x.openConfig(ctx); err != nil {
+			return nil, err
+		}
+
+		sd := &SD{}
+		if err := sd.Init(&x); err != nil {
+			return nil, errors.Wrap(err, "failed to initialize SD client")
+		}
+		if err := sd.Login(); err != nil {
+			return nil, errors.Wrap(err, "failed to login to Schedules Direct")
+		}
+		return sd.client.Status(ctx)
+	}()
+	return sdStatus(status, err, time.Now())
+}
+
+// refreshSDStatus polls the status and logs when Schedules Direct goes offline or comes back
+func (app *App) refreshSDStatus(ctx context.Context) *handlers.SDStatus {
+	status := app.pollSDStatus(ctx)
+	last := app.sdStatus.set(status)
+
+	switch {
+	case len(status.Error) != 0:
+		app.Logger.WithError(errors.New(status.Error)).Debug("Failed to poll the Schedules Direct status")
+	case !status.Online && (last == nil || last.Online):
+		for _, st := range status.SystemStatus {
+			app.Logger.WithFields(logrus.Fields{"status": st.Status, "message": st.Message}).Warn("Schedules Direct reports a problem")
+		}
+	case status.Online && last != nil && len(last.Error) == 0 && !last.Online:
+		app.Logger.Info("Schedules Direct is online again")
+	}
+	return status
+}
+
+// runSDStatusPoller polls the Schedules Direct status at the interval of the configuration until ctx ends,
+// the interval is read again after every poll. 0 only polls when the status is requested.
+func (app *App) runSDStatusPoller(ctx context.Context) {
+	for {
+		interval := time.Minute
+		if c, err := app.readConfig(ctx, app.Config2); err == nil && c.Options.StatusInterval > 0 {
+			app.refreshSDStatus(ctx)
+			interval = c.Options.StatusInterval
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// SDStatus returns the last polled Schedules Direct status, it is polled now if the poller has none
+func (app *App) SDStatus() (*handlers.SDStatus, error) {
+	if app.sdStatus == nil {
+		return nil, errors.New("Schedules Direct status is only polled by the web UI")
+	}
+	if status := app.sdStatus.get(); status != nil {
+		c, err := app.readConfig(context.Background(), app.Config2)
+		if err != nil {
+			return nil, err
+		}
+		if c.Options.StatusInterval > 0 {
+			return status, nil
+		}
+	}
+	return app.refreshSDStatus(context.Background()), nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+)
+
+func TestTokenPersistence(t *testing.T) {
+	app := &App{Logger: logrus.New(), Config: config{}}
+	app.Config.Account.Username = "user"
+	app.Config.Files.Cache = filepath.Join(t.TempDir(), "cache.json")
+
+	if _, ok := loadToken(app); ok {
+		t.Fatal("Expected no token before saving")
+	}
+
+	if err := saveToken(app, "abc"); err != nil {
+		t.Fatalf("Failed to save token: %v", err)
+	}
+
+	token, ok := loadToken(app)
+	if !ok || token != "abc" {
+		t.Errorf("loadToken() = %q, %v, want %q, true", token, ok, "abc")
+	}
+
+	app.Config.Account.Username = "other"
+	if _, ok := loadToken(app); ok {
+		t.Error("Token of another account must not be reused")
+	}
+
+	removeToken(app)
+	if _, err := os.Stat(tokenFile(app)); !os.IsNotExist(err) {
+		t.Error("Token file was not removed")
+	}
+}
+
+func TestCountriesWithMockServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/available/countries" {
+			t.Errorf("Unexpected request path %q", r.URL.Path)
+		}
+		w.Write([]byte(`{"North America":[{"fullName":"United States","shortName":"USA","postalCodeExample":"60030"}]}`))
+	}))
+	defer srv.Close()
+
+	app := &App{Logger: logrus.New(), Config: config{}, Transport: srv.Client().Transport}
+	app.Config.Options.SDBaseURL = srv.URL
+
+	var sd SD
+	if err := sd.Init(app); err != nil {
+		t.Fatalf("Failed to initialize SD client: %v", err)
+	}
+	if err := sd.Countries(); err != nil {
+		t.Fatalf("Failed to get countries: %v", err)
+	}
+
+	countries := sd.Resp.Countries.Regions()
+	if len(countries) != 1 || countries[0].ShortName != "USA" {
+		t.Errorf("Unexpected countries %+v", countries)
+	}
+}
+
+func TestDeleteAndChannels(t *testing.T) {
+	var deleted bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "DELETE":
+			deleted = true
+			w.Write([]byte(`{"code":0,"message":"Deleted lineup.","changesRemaining":5}`))
+		default:
+			w.Write([]byte(`{"map":[{"stationID":"10001","channel":"7"}],"stations":[{"stationID":"10001","name":"KABC"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	app := &App{Logger: logrus.New(), Config: config{}, Transport: srv.Client().Transport}
+	app.Config.Options.SDBaseURL = srv.URL
+
+	var sd SD
+	if err := sd.Init(app); err != nil {
+		t.Fatalf("Failed to initialize SD client: %v", err)
+	}
+
+	sd.Req.Parameter = "/USA-OTA-90210"
+	if err := sd.Channels(); err != nil {
+		t.Fatalf("Failed to get channels: %v", err)
+	}
+	if len(sd.Resp.Channels.Stations) != 1 || sd.Resp.Channels.Channel("10001") != "7" {
+		t.Errorf("Unexpected channels %+v", sd.Resp.Channels)
+	}
+
+	if err := sd.Delete(); err != nil {
+		t.Fatalf("Failed to delete lineup: %v", err)
+	}
+	if !deleted || sd.Resp.LineupChange.ChangesRemaining != 5 {
+		t.Errorf("Lineup was not deleted: %+v", sd.Resp.LineupChange)
+	}
+}
+
+func TestExpiryWarning(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expires time.Time
+		days    int
+		left    int
+		warn    bool
+	}{
+		{now.Add(30 * 24 * time.Hour), 14, 30, false},
+		{now.Add(10*24*time.Hour + time.Hour), 14, 10, true},
+		{now.Add(10 * 24 * time.Hour), 0, 10, false},
+		{now.Add(-2 * 24 * time.Hour), 14, -2, true},
+		{time.Time{}, 14, 0, false},
+	}
+	for _, tt := range tests {
+		left, warn := expiryWarning(tt.expires, tt.days, now)
+		if left != tt.left || warn != tt.warn {
+			t.Errorf("expiryWarning(%v, %d) = %d, %v, want %d, %v", tt.expires, tt.days, left, warn, tt.left, tt.warn)
+		}
+	}
+}
+
+func TestSDStatus(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	var s schedulesdirect.Status
+	s.LastDataUpdate = "2026-10-15T10:00:00Z"
+	s.SystemStatus = []schedulesdirect.SystemStatus{{Date: "2026-10-15T09:00:00Z", Status: "Offline", Message: "Maintenance"}}
+	s.Account.Messages = []schedulesdirect.Message{{MsgID: "1", Date: "2026-10-01T00:00:00Z", Message: "old"}}
+	s.Notifications = []schedulesdirect.Message{{MsgID: "2", Date: "2026-10-14T00:00:00Z", Message: "new"}}
+
+	status := sdStatus(&s, nil, now)
+	if status.Online || status.LastDataUpdate != s.LastDataUpdate || !status.Checked.Equal(now) {
+		t.Errorf("sdStatus = %+v, want offline with the last data update", status)
+	}
+	if len(status.Announcements) != 2 || status.Announcements[0].ID != "2" {
+		t.Errorf("announcements = %+v, want the notification first", status.Announcements)
+	}
+
+	s.SystemStatus[0].Status = "Online"
+	if status := sdStatus(&s, nil, now); !status.Online {
+		t.Error("sdStatus of an online system = offline")
+	}
+	if status := sdStatus(nil, errors.New("timeout"), now); status.Online || status.Error != "timeout" {
+		t.Errorf("sdStatus of a failed poll = %+v, want the error", status)
+	}
+}
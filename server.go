@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -17,6 +19,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/ulule/limiter/v3"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
+	"github.com/yourusername/guide2go/web/handlers"
 )
 
 var requestCount uint64
@@ -95,12 +98,18 @@ func (app *App) Server(ctx context.Context) error {
 		})
 	})
 
+	if app.Config.Options.CastImages {
+		r.HandleFunc("/images/celebrity/{id}", app.proxyCelebrityImages)
+	}
 	if app.Config.Options.ProxyImages {
 		r.HandleFunc("/images/{id}", app.proxyImages)
-	} else if app.Config.Options.TVShowImages {
+	} else if app.Config.Options.TVShowImages || app.Config.Options.LocalLogos {
 		r.PathPrefix("/images/").Handler(http.StripPrefix("/images/", fs))
 	}
-	r.HandleFunc("/run", app.run)
+	apiKey := app.Config.Options.APIKey
+	r.HandleFunc("/run", app.requireAPIKey(apiKey, app.run))
+	r.HandleFunc("/xmltv", app.requireAPIKey(apiKey, app.xmltvDownload))
+	r.HandleFunc("/playlist.m3u", app.requireAPIKey(apiKey, app.playlistDownload))
 	r.HandleFunc("/health", app.healthCheck)
 	r.HandleFunc("/metrics", app.metricsHandler)
 
@@ -113,13 +122,22 @@ func (app *App) Server(ctx context.Context) error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	tlsConfig, err := app.tlsConfig(&app.Config)
+	if err != nil {
+		return err
+	}
+	srv.TLSConfig = tlsConfig
+
 	// Start server in a goroutine
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := listenAndServe(srv); err != nil && err != http.ErrServerClosed {
 			app.Logger.WithError(err).Fatal("Server error")
 		}
 	}()
 
+	// The image server keeps running, it updates the guide by the update schedule
+	go app.runScheduler(ctx)
+
 	// Wait for context cancellation
 	<-ctx.Done()
 
@@ -134,6 +152,14 @@ func (app *App) Server(ctx context.Context) error {
 	return nil
 }
 
+// listenAndServe serves HTTPS if the server has a TLS configuration, otherwise HTTP
+func listenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
 // validateImagePath ensures the image path is within the allowed directory and safe
 func validateImagePath(basePath, name string) error {
 	cleanPath := filepath.Clean(filepath.Join(basePath, name))
@@ -157,20 +183,68 @@ func (app *App) proxyImages(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid image ID", http.StatusBadRequest)
 		return
 	}
-	url := "https://json.schedulesdirect.org/20141201/image/" + id
+
+	// Downloaded channel logos are served from the images path
+	if app.Config.Options.LocalLogos {
+		logo := filepath.Join(app.Config.Options.ImagesPath, id)
+		if info, err := os.Stat(logo); err == nil && info.Mode().IsRegular() {
+			http.ServeFile(w, r, logo)
+			return
+		}
+	}
+
+	if app.Images == nil {
+		http.Error(w, "Schedules Direct client is not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
 	app.Logger.WithFields(logrus.Fields{
 		"image_id": id,
-		"url":      url,
+		"url":      app.Images.ImageURL(id),
 	}).Debug("Proxying image request")
 
-	req, err := http.NewRequest("GET", url, nil)
+	app.copyImage(w, r, id)
+}
+
+// proxyCelebrityImages proxies the largest headshot of a cast member, the person ID is resolved when the image is requested
+func (app *App) proxyCelebrityImages(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !isValidImageID(id) {
+		http.Error(w, "Invalid person ID", http.StatusBadRequest)
+		return
+	}
+	if app.Images == nil {
+		http.Error(w, "Schedules Direct client is not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
+	images, err := app.Images.CelebrityImages(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+		app.Logger.WithError(err).WithField("person_id", id).Warn("Failed to fetch cast images")
+		http.Error(w, "Failed to fetch cast images", http.StatusBadGateway)
 		return
 	}
-	req.Header.Set("Authorization", "Bearer "+app.Token)
-	resp, err := httpClient.Do(req)
+
+	var uri string
+	var largest int
+	for _, image := range images {
+		if width, _ := strconv.Atoi(image.Width); len(uri) == 0 || width > largest {
+			uri, largest = image.URI, width
+		}
+	}
+	if len(uri) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	app.copyImage(w, r, uri)
+}
+
+// copyImage copies the response of a Schedules Direct image into w
+func (app *App) copyImage(w http.ResponseWriter, r *http.Request, uri string) {
+	resp, err := app.Images.Image(r.Context(), uri)
 	if err != nil {
+		app.Logger.WithError(err).WithField("image_id", uri).Warn("Failed to fetch image")
 		http.Error(w, "Failed to fetch image", http.StatusBadGateway)
 		return
 	}
@@ -184,14 +258,86 @@ func (app *App) proxyImages(w http.ResponseWriter, r *http.Request) {
 	io.Copy(w, resp.Body)
 }
 
+// requireAPIKey lets only requests with the API key through, an empty key disables the check
+func (app *App) requireAPIKey(key string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(key) != 0 && !handlers.CheckAPIKey(r, key) {
+			app.Logger.WithFields(logrus.Fields{"path": r.URL.Path, "remote_ip": r.RemoteAddr}).Warn("Request without valid API key")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// xmltvDownload sends the XMLTV file, the compressed one if only that is written
+func (app *App) xmltvDownload(w http.ResponseWriter, r *http.Request) {
+	name := app.Config.Files.XMLTV
+	if app.Config.Options.CompressXMLTV == "only" {
+		name += ".gz"
+	}
+	if _, err := os.Stat(name); err != nil {
+		http.Error(w, "XMLTV file not found", http.StatusNotFound)
+		return
+	}
+	http.ServeFile(w, r, name)
+}
+
+// playlistDownload sends the M3U playlist of the XMLTV file
+func (app *App) playlistDownload(w http.ResponseWriter, r *http.Request) {
+	name := playlistFilename(app.Config.Files.XMLTV)
+	if _, err := os.Stat(name); err != nil {
+		http.Error(w, "M3U playlist not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/x-mpegurl")
+	http.ServeFile(w, r, name)
+}
+
+// run queues an update, the progress is shown by /api/jobs of the web UI
 func (app *App) run(w http.ResponseWriter, r *http.Request) {
-	var sd SD
-	go func() {
-		if err := sd.Update(app.Config2); err != nil {
-			app.Logger.WithError(err).Error("Failed to update EPG data")
+	app.jobs.queue(app.Config2)
+	fmt.Fprint(w, "Grabbing EPG")
+}
+
+// runUpdate is the job of an update started by /run or the web UI.
+// It runs with an application of its own, the configuration, the cache store and the Schedules Direct client
+// are not shared with the web UI.
+func (app *App) runUpdate(ctx context.Context, j *job) error {
+	c, err := app.readConfig(ctx, j.config)
+	if err != nil {
+		app.Logger.WithError(err).WithField("config", j.config).Error("Failed to update EPG data")
+		return err
+	}
+
+	run := &App{
+		Config:       *c,
+		Config2:      app.Config2,
+		Logger:       app.Logger,
+		Cache:        &cache{},
+		Transport:    app.Transport,
+		DebugHTTP:    app.DebugHTTP,
+		DebugHTTPDir: app.DebugHTTPDir,
+		logs:         app.logs,
+		jobs:         app.jobs,
+		runLog:       app.runLog,
+		configs:      app.configs,
+		sdStatus:     app.sdStatus,
+	}
+	// The update opens a cache store of its own, it is closed when the job is done
+	defer func() {
+		app.cacheCounters.set(run.Cache.GetStats())
+		if closer, ok := run.Cache.(io.Closer); ok {
+			closer.Close()
 		}
 	}()
-	fmt.Fprint(w, "Grabbing EPG")
+
+	sd := SD{progress: j}
+	if err := run.Update(ctx, &sd, j.config); err != nil {
+		app.Logger.WithError(err).WithField("config", j.config).Error("Failed to update EPG data")
+		return err
+	}
+	return nil
 }
 
 func (app *App) healthCheck(w http.ResponseWriter, r *http.Request) {
@@ -213,5 +359,32 @@ func (app *App) metricsHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "# HELP guide2go_errors_total Total HTTP errors\n")
 	fmt.Fprintf(w, "# TYPE guide2go_errors_total counter\n")
 	fmt.Fprintf(w, "guide2go_errors_total %d\n", atomic.LoadUint64(&errorCount))
+
+	if app.Cache != nil {
+		stats := app.Cache.GetStats()
+		writeMetric(w, "guide2go_cache_hits_total", "Cache lookups of programs and artwork which found the entry", "counter", stats["hits"])
+		writeMetric(w, "guide2go_cache_misses_total", "Cache lookups of programs and artwork which missed the entry", "counter", stats["misses"])
+		writeMetric(w, "guide2go_cache_added_bytes_total", "Size of the downloaded data added to the cache", "counter", stats["size"])
+
+		fmt.Fprintf(w, "# HELP guide2go_cache_entries Cached entries by data type\n")
+		fmt.Fprintf(w, "# TYPE guide2go_cache_entries gauge\n")
+		for _, t := range []string{"channels", "broadcasts", "programs", "metadata"} {
+			if n, ok := stats[t]; ok {
+				fmt.Fprintf(w, "guide2go_cache_entries{type=%q} %v\n", t, n)
+			}
+		}
+	}
+
 	app.Logger.WithField("endpoint", "/metrics").Info("Metrics requested")
 }
+
+// writeMetric writes a metric without labels in the Prometheus text format, missing values are skipped
+func writeMetric(w io.Writer, name, help, typ string, value interface{}) {
+	if value == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	fmt.Fprintf(w, "%s %v\n", name, value)
+}
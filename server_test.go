@@ -4,14 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
 	"github.com/gorilla/mux"
 )
 
 func TestIsValidImageID(t *testing.T) {
 	cases := []struct {
-		id     string
-		valid  bool
+		id    string
+		valid bool
 	}{
 		{"abc-123_foo.bar", true},
 		{"../etc/passwd", false},
@@ -89,4 +91,65 @@ func TestProxyImagesHandler_InvalidID(t *testing.T) {
 	if rw.Code != http.StatusBadRequest {
 		t.Errorf("Expected 400 Bad Request, got %d", rw.Code)
 	}
-} 
\ No newline at end of file
+}
+
+func TestMetricsHandler(t *testing.T) {
+	app := newApp()
+	app.Cache.(*cache).Init()
+	app.Cache.(*cache).Program["SH012345670000"] = G2GCache{}
+	app.Cache.GetTitle("SH012345670000", "en", app)
+	app.Cache.GetTitle("EP012345670042", "en", app)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rw := httptest.NewRecorder()
+	app.metricsHandler(rw, req)
+
+	for _, metric := range []string{
+		"guide2go_cache_hits_total 1\n",
+		"guide2go_cache_misses_total 1\n",
+		`guide2go_cache_entries{type="programs"} 1` + "\n",
+	} {
+		if !strings.Contains(rw.Body.String(), metric) {
+			t.Errorf("Metrics do not contain %q:\n%s", metric, rw.Body.String())
+		}
+	}
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	app := newApp()
+	next := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+	handler := app.requireAPIKey("secret", next)
+
+	cases := []struct {
+		name   string
+		header map[string]string
+		target string
+		code   int
+	}{
+		{"missing", nil, "/run", http.StatusUnauthorized},
+		{"wrong", map[string]string{"X-API-Key": "guess"}, "/run", http.StatusUnauthorized},
+		{"header", map[string]string{"X-API-Key": "secret"}, "/run", http.StatusNoContent},
+		{"bearer", map[string]string{"Authorization": "Bearer secret"}, "/run", http.StatusNoContent},
+		{"query", nil, "/run?token=secret", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.target, nil)
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		rw := httptest.NewRecorder()
+		handler(rw, req)
+		if rw.Code != c.code {
+			t.Errorf("%s: expected %d, got %d", c.name, c.code, rw.Code)
+		}
+	}
+
+	// Without a key every request passes
+	rw := httptest.NewRecorder()
+	app.requireAPIKey("", next)(rw, httptest.NewRequest("GET", "/run", nil))
+	if rw.Code != http.StatusNoContent {
+		t.Errorf("expected %d without API key, got %d", http.StatusNoContent, rw.Code)
+	}
+}
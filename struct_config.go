@@ -20,27 +20,101 @@ type config struct {
 	} `yaml:"Files" json:"files"`
 
 	Options struct {
-		PosterAspect            string        `yaml:"Poster Aspect" json:"poster_aspect" validate:"oneof=portrait landscape square"`
-		Schedule                int           `yaml:"Schedule Days" json:"schedule_days" validate:"min=1,max=14"`
-		SubtitleIntoDescription bool          `yaml:"Subtitle into Description" json:"subtitle_into_description"`
-		Credits                 bool          `yaml:"Insert credits tag into XML file" json:"credits"`
-		TVShowImages            bool          `yaml:"Local Images Cache" json:"tv_show_images"`
-		ImagesPath              string        `yaml:"Images Path" json:"images_path" validate:"required"`
-		ProxyImages             bool          `yaml:"Proxy Images" json:"proxy_images"`
-		Hostname                string        `yaml:"Hostname" json:"hostname" validate:"required,hostname_port"`
-		CacheExpiration         time.Duration `yaml:"Cache Expiration" json:"cache_expiration" validate:"min=1h,max=168h"` // 1 hour to 1 week
+		PosterAspect            string                   `yaml:"Poster Aspect" json:"poster_aspect" validate:"oneof=portrait landscape square"`
+		ArtworkPolicies         map[string]artworkPolicy `yaml:"Artwork by program type. movie/episode/series/sports" json:"artwork_policies"` // Overrides the poster aspect
+		Schedule                int                      `yaml:"Schedule Days" json:"schedule_days" validate:"min=1,max=14"`
+		SubtitleIntoDescription bool                     `yaml:"Subtitle into Description" json:"subtitle_into_description"`
+		Credits                 bool                     `yaml:"Insert credits tag into XML file" json:"credits"`
+		CastImages              bool                     `yaml:"Insert cast images into credits" json:"cast_images"`
+		Keywords                bool                     `yaml:"Insert keyword tags into XML file" json:"keywords"`
+		CategoryProfile         string                   `yaml:"Category profile. none/plex/emby/tvheadend" json:"category_profile" validate:"omitempty,oneof=none plex emby tvheadend"`
+		CategoryMapping         map[string]string        `yaml:"Category mapping. Genre: category. Empty removes the genre" json:"category_mapping"`
+		TVShowImages            bool                     `yaml:"Local Images Cache" json:"tv_show_images"`
+		ImagesPath              string                   `yaml:"Images Path" json:"images_path" validate:"required"`
+		ProxyImages             bool                     `yaml:"Proxy Images" json:"proxy_images"`
+		LocalLogos              bool                     `yaml:"Download channel logos into the images path" json:"local_logos"`
+		Hostname                string                   `yaml:"Hostname" json:"hostname" validate:"required,hostname_port"`
+		CompressXMLTV           string                   `yaml:"Compress XMLTV. none/also/only" json:"compress_xmltv" validate:"omitempty,oneof=none also only"`
+		XMLTVPerLineup          bool                     `yaml:"Additional XMLTV file per lineup" json:"xmltv_per_lineup"`
+		M3UStreamURL            string                   `yaml:"M3U playlist stream URL. Empty for no playlist" json:"m3u_stream_url"` // {number}, {stationID} and {callsign} are replaced
+		ValidateXMLTV           string                   `yaml:"Validate XMLTV. none/warn/strict" json:"validate_xmltv" validate:"omitempty,oneof=none warn strict"`
+		ChannelID               string                   `yaml:"XMLTV channel ID. callsign/stationID/callsign.stationID/number" json:"channel_id" validate:"omitempty,oneof=callsign stationID callsign.stationID number"`
+		Timezone                string                   `yaml:"Time zone of the programmes. Empty for UTC" json:"timezone" validate:"omitempty,timezone"`
+		CacheBackend            string                   `yaml:"Cache Backend" json:"cache_backend" validate:"omitempty,oneof=json sqlite bolt redis"`
+		CompressCache           bool                     `yaml:"Compress Cache" json:"compress_cache"`
+		CacheLockTimeout        time.Duration            `yaml:"Cache Lock Timeout. 0 fails at once" json:"cache_lock_timeout" validate:"min=0"`
+		ProgramRetention        time.Duration            `yaml:"Program Retention. 0 keeps them until the TTL" json:"program_retention" validate:"min=0"`
+		UpdateSchedule          string                   `yaml:"Update Schedule" json:"update_schedule"` // Cron expression of the updates of the web UI and the image server
+		UpdateScheduleEnabled   bool                     `yaml:"Update Schedule Enabled" json:"update_schedule_enabled"`
+
+		WebLogin struct {
+			Username       string        `yaml:"Username" json:"username"`
+			Password       string        `yaml:"Password" json:"password"`
+			SessionTimeout time.Duration `yaml:"Session Timeout" json:"session_timeout" validate:"min=0"`
+			ReadOnly       struct {
+				Username string `yaml:"Username" json:"username"`
+				Password string `yaml:"Password" json:"password"`
+			} `yaml:"Read-only user. Empty username disables it" json:"read_only"` // Sees the pages, changes nothing
+		} `yaml:"Web UI login. Empty username disables it" json:"web_login"`
+		TLS struct {
+			Certificate string `yaml:"Certificate" json:"certificate"`
+			Key         string `yaml:"Key" json:"key"`
+			SelfSigned  bool   `yaml:"Create a self-signed certificate if it is missing" json:"self_signed"`
+		} `yaml:"TLS of the image server and the web UI. Empty certificate for HTTP" json:"tls"`
+		APIKey string `yaml:"API Key. Empty disables it" json:"api_key"` // Required by /run, /xmltv, /playlist.m3u and, with the web UI login, the API of the web UI
+
+		EpisodeNumSystems []string            `yaml:"Episode number systems. xmltv_ns/onscreen/dd_progid/series-id/episode-id/original-air-date" json:"episode_num_systems" validate:"dive,oneof=xmltv_ns onscreen dd_progid series-id episode-id original-air-date"`
+		TitleMarks        titleMarks          `yaml:"Title marks of live and new programs" json:"title_marks"`
+		BroadcastFlags    map[string][]string `yaml:"Flags by broadcast. live/tape/delay/other. -new for new broadcasts" json:"broadcast_flags"`
+		Placeholder       placeholder         `yaml:"Placeholder programs" json:"placeholder"`
 
 		Rating struct {
 			Guidelines          bool     `yaml:"Insert rating tag into XML file" json:"guidelines"`
 			MaxEntries          int      `yaml:"Maximum rating entries. 0 for all entries" json:"max_entries" validate:"min=0,max=10"`
 			Countries           []string `yaml:"Preferred countries. ISO 3166-1 alpha-3 country code. Leave empty for all systems" json:"countries" validate:"dive,iso3166_1_alpha3"`
 			CountryCodeAsSystem bool     `yaml:"Use country code as rating system" json:"country_code_as_system"`
+			ContentAdvisory     string   `yaml:"Content advisories. none/rating/description" json:"content_advisory" validate:"omitempty,oneof=none rating description"`
 		} `yaml:"Rating" json:"rating"`
 
-		SDDownloadErrors bool `yaml:"Show download errors from Schedules Direct in the log" json:"sd_download_errors"`
+		SDDownloadErrors bool          `yaml:"Show download errors from Schedules Direct in the log" json:"sd_download_errors"`
+		SDBaseURL        string        `yaml:"Schedules Direct API URL" json:"sd_base_url" validate:"omitempty,url"`
+		SDImageURL       string        `yaml:"Schedules Direct image URL" json:"sd_image_url" validate:"omitempty,url"`
+		SDOfflineCache   bool          `yaml:"Use cache if Schedules Direct is offline" json:"sd_offline_cache"`
+		ExpiryWarning    int           `yaml:"Warn days before the account expires. 0 disables it" json:"expiry_warning" validate:"min=0"`
+		Language         string        `yaml:"Language of the menus and the web UI. en or de" json:"language" validate:"omitempty,oneof=en de"`
+		StatusInterval   time.Duration `yaml:"Poll the Schedules Direct status of the web UI every. 0 polls when the dashboard is opened" json:"status_interval" validate:"min=0"`
+
+		Timeouts struct {
+			Status   time.Duration `yaml:"Login and status requests" json:"status" validate:"min=0"`
+			Default  time.Duration `yaml:"Lineup requests" json:"default" validate:"min=0"`
+			Schedule time.Duration `yaml:"Schedule requests" json:"schedule" validate:"min=0"`
+			Program  time.Duration `yaml:"Program and metadata requests" json:"program" validate:"min=0"`
+			Update   time.Duration `yaml:"Update deadline. 0 for no deadline" json:"update" validate:"min=0"`
+		} `yaml:"Timeouts" json:"timeouts"`
+
+		CacheTTL struct {
+			Schedules time.Duration `yaml:"Schedules" json:"schedules" validate:"min=0"`
+			Programs  time.Duration `yaml:"Programs" json:"programs" validate:"min=0"`
+			Metadata  time.Duration `yaml:"Artwork metadata" json:"metadata" validate:"min=0"`
+		} `yaml:"Cache TTL. 0 for no expiration" json:"cache_ttl"`
+
+		CacheAutosave struct {
+			Batches  int           `yaml:"Every N batches" json:"batches" validate:"min=0"`
+			Interval time.Duration `yaml:"Interval" json:"interval" validate:"min=0"`
+		} `yaml:"Cache Autosave. 0 disables" json:"cache_autosave"`
 	} `yaml:"Options" json:"options"`
 
-	Station []channel `yaml:"Station" json:"station" validate:"dive"`
+	Outputs []xmltvOutput `yaml:"Outputs" json:"outputs" validate:"dive"` // Additional XMLTV files with a part of the channels
+	Station []channel     `yaml:"Station" json:"station" validate:"dive"`
+}
+
+// xmltvOutput is an additional XMLTV file with the channels of the include list without those of the exclude list
+type xmltvOutput struct {
+	Name    string   `yaml:"Name" json:"name" validate:"required"`
+	File    string   `yaml:"File" json:"file" validate:"required"`
+	Format  string   `yaml:"Format. xmltv/json/csv/tsv" json:"format" validate:"omitempty,oneof=xmltv json csv tsv"`
+	Include []string `yaml:"Include. Station IDs or callsigns. Empty for all" json:"include"`
+	Exclude []string `yaml:"Exclude. Station IDs or callsigns" json:"exclude"`
 }
 
 // Channel represents a TV channel configuration
@@ -49,10 +123,26 @@ type channel struct {
 	DisplayName []DisplayName `yaml:"-" json:"display_name" xml:"display-name"`
 	ID          string        `yaml:"ID" json:"station_id" xml:"id,attr" validate:"required"`
 	Lineup      string        `yaml:"Lineup" json:"lineup" validate:"required"`
+	Timezone    string        `yaml:"Timezone,omitempty" json:"timezone,omitempty" validate:"omitempty,timezone"` // Overrides the time zone option
 	Date        []string      `yaml:"-" json:"date"`
 	Icon        Icon          `yaml:"-" json:"icon" xml:"icon"`
 }
 
+// titleMarks are the marks of live and new programs, e.g. the suffix " ᴺᵉʷ" of the title
+type titleMarks struct {
+	Live      string `yaml:"Live" json:"live"`
+	New       string `yaml:"New" json:"new"`
+	Placement string `yaml:"Placement. suffix/prefix/sub-title/none" json:"placement" validate:"omitempty,oneof=suffix prefix sub-title none"`
+}
+
+// placeholder are the programs of broadcasts missing in the cache and of gaps in the schedule
+type placeholder struct {
+	Title    string `yaml:"Title of missing programs" json:"title"`
+	Lang     string `yaml:"Language" json:"lang"`
+	FillGaps bool   `yaml:"Fill gaps in the schedule" json:"fill_gaps"`
+	GapTitle string `yaml:"Title of gaps" json:"gap_title"`
+}
+
 // DisplayName represents a channel's display name in different languages (canonical definition)
 type DisplayName struct {
 	Lang  string `xml:"lang,attr,omitempty" json:"lang,omitempty"`
@@ -18,7 +18,10 @@ type Programme struct {
 	Credits Credits `xml:"credits,omitempty"`
 
 	Categorys   []Category   `xml:"category,omitempty"`
+	Keywords    []Keyword    `xml:"keyword,omitempty"`
 	Language    string       `xml:"language,omitempty"`
+	Length      *Length      `xml:"length,omitempty"`
+	Country     []Country    `xml:"country,omitempty"`
 	EpisodeNums []EpisodeNum `xml:"episode-num,omitempty"`
 
 	//Icon
@@ -26,11 +29,15 @@ type Programme struct {
 	Video Video  `xml:"video"`
 	Audio Audio  `xml:"audio"`
 
-	Rating []Rating `xml:"rating,omitempty"`
+	Rating     []Rating     `xml:"rating,omitempty"`
+	StarRating []StarRating `xml:"star-rating,omitempty"`
 
 	PreviouslyShown *PreviouslyShown `xml:"previously-shown,omitempty"`
+	Premiere        *Premiere        `xml:"premiere,omitempty"`
+	LastChance      *LastChance      `xml:"last-chance,omitempty"`
 	New             *New             `xml:"new"`
 	Live            *Live            `xml:"live"`
+	Subtitles       []Subtitles      `xml:"subtitles,omitempty"`
 }
 
 // ChannelXML : Channel
@@ -38,6 +45,8 @@ type ChannelXML struct {
 	ID          string        `xml:"id,attr"`
 	DisplayName []DisplayName `xml:"display-name"`
 	Icon        Icon          `xml:"icon"`
+	StationID   string        `xml:"-"` // For the M3U playlist
+	Number      string        `xml:"-"`
 }
 
 // Title : Title
@@ -80,8 +89,15 @@ type Presenter struct {
 }
 
 type Actor struct {
+	Value string  `xml:",chardata"`
+	Role  string  `xml:"role,attr,omitempty"`
+	Image []Image `xml:"image,omitempty"`
+}
+
+// Image : Image of a credit, e.g. the headshot of an actor
+type Image struct {
 	Value string `xml:",chardata"`
-	Role  string `xml:"role,attr,omitempty"`
+	Type  string `xml:"type,attr,omitempty"`
 }
 
 type Writer struct {
@@ -93,6 +109,21 @@ type Category struct {
 	Lang  string `xml:"lang,attr"`
 }
 
+// Keyword : Keyword
+type Keyword struct {
+	Value string `xml:",chardata"`
+	Lang  string `xml:"lang,attr"`
+}
+
+type Length struct {
+	Value int    `xml:",chardata"`
+	Units string `xml:"units,attr"`
+}
+
+type Country struct {
+	Value string `xml:",chardata"`
+}
+
 type EpisodeNum struct {
 	Value  string `xml:",chardata"`
 	System string `xml:"system,attr"`
@@ -110,6 +141,12 @@ type Rating struct {
 	Icon   []Icon `xml:"icon",omitempty`
 }
 
+// StarRating : Star rating of a movie, e.g. 3/4
+type StarRating struct {
+	System string `xml:"system,attr,omitempty"`
+	Value  string `xml:"value"`
+}
+
 type Video struct {
 	Present string `xml:"present,omitempty"`
 	Colour  string `xml:"colour,omitempty"`
@@ -125,6 +162,20 @@ type PreviouslyShown struct {
 	Start string `xml:"start,attr,omitempty"`
 }
 
+type Premiere struct {
+	Value string `xml:",chardata"`
+	Lang  string `xml:"lang,attr,omitempty"`
+}
+
+type LastChance struct {
+	Value string `xml:",chardata"`
+	Lang  string `xml:"lang,attr,omitempty"`
+}
+
+type Subtitles struct {
+	Type string `xml:"type,attr,omitempty"`
+}
+
 type New struct {
 	Value string `xml:",chardata"`
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// selfSignedValidity is the validity of a created self-signed certificate
+const selfSignedValidity = 10 * 365 * 24 * time.Hour
+
+// serverURL returns the URL of a path of the image server, https if it is served with TLS
+func (c *config) serverURL(path string) string {
+	if len(c.Options.TLS.Certificate) != 0 {
+		return "https://" + c.Options.Hostname + path
+	}
+	return "http://" + c.Options.Hostname + path
+}
+
+// tlsConfig returns the TLS configuration of the HTTP servers for the configuration c, nil serves plain HTTP.
+// A missing certificate is created first if the self-signed option is enabled.
+func (app *App) tlsConfig(c *config) (*tls.Config, error) {
+	t := c.Options.TLS
+	if len(t.Certificate) == 0 {
+		return nil, nil
+	}
+
+	if t.SelfSigned {
+		if _, err := os.Stat(t.Certificate); os.IsNotExist(err) {
+			host, _, err := net.SplitHostPort(c.Options.Hostname)
+			if err != nil {
+				host = c.Options.Hostname
+			}
+			if err := createCertificate(t.Certificate, t.Key, host); err != nil {
+				return nil, errors.Wrap(err, "failed to create self-signed certificate")
+			}
+			app.Logger.WithField("certificate", t.Certificate).Info("Created self-signed certificate")
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.Certificate, t.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load TLS certificate")
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// createCertificate writes a self-signed certificate for host and localhost with its private key
+func createCertificate(certFile, keyFile, host string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate key")
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return errors.Wrap(err, "failed to generate serial number")
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: host, Organization: []string{"guide2go"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else if len(host) != 0 && host != "localhost" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return errors.Wrap(err, "failed to create certificate")
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal key")
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return errors.Wrap(err, "failed to write key")
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return errors.Wrap(err, "failed to write certificate")
+	}
+	return nil
+}
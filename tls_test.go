@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSConfig(t *testing.T) {
+	app := newApp()
+	var c config
+	c.Options.Hostname = "192.168.1.10:8080"
+
+	if cfg, err := app.tlsConfig(&c); err != nil || cfg != nil {
+		t.Fatalf("expected plain HTTP without certificate, got %v, %v", cfg, err)
+	}
+	if url := c.serverURL("/images/a.jpg"); url != "http://192.168.1.10:8080/images/a.jpg" {
+		t.Errorf("unexpected URL %s", url)
+	}
+
+	dir := t.TempDir()
+	c.Options.TLS.Certificate = filepath.Join(dir, "guide2go.crt")
+	c.Options.TLS.Key = filepath.Join(dir, "guide2go.key")
+	if _, err := app.tlsConfig(&c); err == nil {
+		t.Fatal("expected an error for a missing certificate")
+	}
+
+	c.Options.TLS.SelfSigned = true
+	cfg, err := app.tlsConfig(&c)
+	if err != nil {
+		t.Fatalf("failed to create self-signed certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifyHostname("192.168.1.10"); err != nil {
+		t.Errorf("certificate doesn't match the hostname: %v", err)
+	}
+	if info, err := os.Stat(c.Options.TLS.Key); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected private key with mode 0600, got %v, %v", info, err)
+	}
+
+	// An existing certificate is loaded instead of created again
+	again, err := app.tlsConfig(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again.Certificates[0].Certificate[0]) != string(cfg.Certificates[0].Certificate[0]) {
+		t.Error("expected the existing certificate")
+	}
+	if url := c.serverURL("/images/a.jpg"); url != "https://192.168.1.10:8080/images/a.jpg" {
+		t.Errorf("unexpected URL %s", url)
+	}
+}
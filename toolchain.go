@@ -30,6 +30,14 @@ func ContainsString(slice []string, e string) int {
   return -1
 }
 
+// seriesProgramID : Get the generic series program ID (SH) of an episode program ID (EP)
+func seriesProgramID(id string) (string, bool) {
+  if len(id) != 14 || !strings.HasPrefix(id, "EP") {
+    return "", false
+  }
+  return "SH" + id[2:10] + "0000", true
+}
+
 func gUnzip(data []byte) (res []byte, err error) {
 
   b := bytes.NewBuffer(data)
@@ -0,0 +1,931 @@
+// Package main provides Guide2Go, a tool to generate XMLTV files from Schedules Direct JSON API.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/guide2go/pkg/i18n"
+	"github.com/yourusername/guide2go/web/handlers"
+	"gopkg.in/yaml.v3"
+)
+
+// openConfig loads the configuration file the application was started with
+func (app *App) openConfig(ctx context.Context) error {
+	name := app.selectedConfig()
+	if len(name) == 0 {
+		return errors.New("no configuration file, start with -config [filename.yaml]")
+	}
+
+	app.Config.File = strings.TrimSuffix(name, filepath.Ext(name))
+	if err := app.Config.Open(ctx); err != nil {
+		return errors.Wrap(err, "failed to open configuration")
+	}
+
+	return nil
+}
+
+// defaultSessionTimeout is the session timeout of the web UI login of new configurations
+const defaultSessionTimeout = 24 * time.Hour
+
+// readConfig loads a configuration file into its own configuration, a running update keeps the configuration of the application
+func (app *App) readConfig(ctx context.Context, name string) (*config, error) {
+	if len(name) == 0 {
+		return nil, errors.New("no configuration file, start with -config [filename.yaml]")
+	}
+
+	var c config
+	c.File = strings.TrimSuffix(name, filepath.Ext(name))
+	if err := c.Open(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to open configuration")
+	}
+	return &c, nil
+}
+
+// WebLogin returns the login of the web UI from the configuration file of -config,
+// it is read with every request so a changed password applies at once
+func (app *App) WebLogin() (*handlers.WebLogin, error) {
+	c, err := app.readConfig(context.Background(), app.Config2)
+	if err != nil {
+		return nil, err
+	}
+
+	l := c.Options.WebLogin
+	return &handlers.WebLogin{
+		Username:         l.Username,
+		Password:         l.Password,
+		SessionTimeout:   l.SessionTimeout,
+		ReadOnlyUsername: l.ReadOnly.Username,
+		ReadOnlyPassword: l.ReadOnly.Password,
+		APIKey:           c.Options.APIKey,
+	}, nil
+}
+
+// webLanguage is the language of the web UI, it is read again once the configuration file changed
+type webLanguage struct {
+	mu       sync.Mutex
+	file     string
+	modified time.Time
+	lang     string
+}
+
+// Language returns the language of the web UI from the configuration file of -config, English if it cannot be read.
+// Every page asks for it, the configuration is only parsed again after the file changed.
+func (app *App) Language() string {
+	file := strings.TrimSuffix(app.Config2, filepath.Ext(app.Config2)) + ".yaml"
+	info, err := os.Stat(file)
+	if app.language == nil || err != nil {
+		return app.readLanguage()
+	}
+
+	l := app.language
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != file || !l.modified.Equal(info.ModTime()) {
+		l.file, l.modified, l.lang = file, info.ModTime(), app.readLanguage()
+	}
+	return l.lang
+}
+
+// readLanguage reads the language of the web UI from the configuration file of -config
+func (app *App) readLanguage() string {
+	c, err := app.readConfig(context.Background(), app.Config2)
+	if err != nil || len(c.Options.Language) == 0 {
+		return i18n.English
+	}
+	return c.Options.Language
+}
+
+// AccountStatus returns the Schedules Direct account status for the web UI
+func (app *App) AccountStatus() (*handlers.AccountStatus, error) {
+	sd, err := app.webSD()
+	if err != nil {
+		return nil, err
+	}
+
+	s := sd.Resp.Status
+	status := &handlers.AccountStatus{
+		Expires:        s.Account.Expires,
+		MaxLineups:     s.Account.MaxLineups,
+		LastDataUpdate: s.LastDataUpdate,
+	}
+	status.DaysLeft, status.ExpiryWarning = expiryWarning(s.Account.Expires, app.Config.Options.ExpiryWarning, time.Now())
+
+	for _, l := range s.Lineups {
+		status.Lineups = append(status.Lineups, handlers.Lineup{Lineup: l.Lineup, Name: l.Name, Modified: l.Modified})
+	}
+	for _, m := range s.Account.Messages {
+		status.Messages = append(status.Messages, handlers.Message{ID: m.MsgID, Date: m.Date, Message: m.Message})
+	}
+	for _, m := range s.Notifications {
+		status.Notifications = append(status.Notifications, handlers.Message{ID: m.MsgID, Date: m.Date, Message: m.Message})
+	}
+	for _, st := range s.SystemStatus {
+		status.SystemStatus = append(status.SystemStatus, handlers.SystemStatus{Date: st.Date, Status: st.Status, Message: st.Message})
+	}
+
+	return status, nil
+}
+
+// LoadConfig returns the configuration as JSON for the web UI, the passwords and the API key are never sent to the browser
+func (app *App) LoadConfig() ([]byte, error) {
+	c, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	c.Account.Password = ""
+	c.Options.WebLogin.Password = ""
+	c.Options.WebLogin.ReadOnly.Password = ""
+	c.Options.APIKey = ""
+	data, err := json.Marshal(c)
+	return data, errors.Wrap(err, "failed to marshal configuration")
+}
+
+// SaveConfig validates the configuration of the web UI and saves it, empty passwords and API key keep the current ones
+func (app *App) SaveConfig(data []byte) error {
+	current, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return err
+	}
+	c, err := proposedConfig(current, data)
+	if err != nil {
+		return err
+	}
+	return c.Save()
+}
+
+// PreviewConfig returns the changes the configuration of the web UI would make as YAML diff, nothing is saved.
+// The passwords and the API key only show whether they change.
+func (app *App) PreviewConfig(data []byte) (*handlers.ConfigPreview, error) {
+	saved, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return nil, err
+	}
+	c, err := proposedConfig(saved, data)
+	if err != nil {
+		return nil, err
+	}
+
+	current := *saved
+	hideSecret(&current.Account.Password, &c.Account.Password)
+	hideSecret(&current.Options.WebLogin.Password, &c.Options.WebLogin.Password)
+	hideSecret(&current.Options.WebLogin.ReadOnly.Password, &c.Options.WebLogin.ReadOnly.Password)
+	hideSecret(&current.Options.APIKey, &c.Options.APIKey)
+
+	a, err := yaml.Marshal(&current)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal configuration")
+	}
+	b, err := yaml.Marshal(&c)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal configuration")
+	}
+
+	name := filepath.Base(current.File) + ".yaml"
+	preview := &handlers.ConfigPreview{Stations: len(c.Station)}
+	preview.Diff, preview.Changes = unifiedDiff(name, name+" (new)", string(a), string(b))
+
+	kept := make(map[string]bool)
+	for _, s := range c.Station {
+		kept[s.Lineup+"/"+s.ID] = true
+	}
+	for _, s := range current.Station {
+		if !kept[s.Lineup+"/"+s.ID] {
+			preview.RemovedStations++
+		}
+	}
+	return preview, nil
+}
+
+// hideSecret replaces a password of the configurations in the diff of the preview
+func hideSecret(current, proposed *string) {
+	switch {
+	case *current != *proposed:
+		*current, *proposed = "(hidden)", "(hidden, changed)"
+	case len(*current) != 0:
+		*current, *proposed = "(hidden)", "(hidden)"
+	}
+}
+
+// proposedConfig returns the configuration of the web UI as it replaces current, empty passwords and API key keep the current ones
+func proposedConfig(current *config, data []byte) (config, error) {
+	var c config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return config{}, errors.Wrap(err, "failed to parse configuration")
+	}
+
+	c.File = current.File
+	if len(c.Account.Password) == 0 {
+		c.Account.Password = current.Account.Password
+	}
+	if len(c.Options.WebLogin.Password) == 0 {
+		c.Options.WebLogin.Password = current.Options.WebLogin.Password
+	}
+	if len(c.Options.WebLogin.ReadOnly.Password) == 0 {
+		c.Options.WebLogin.ReadOnly.Password = current.Options.WebLogin.ReadOnly.Password
+	}
+	if len(c.Options.APIKey) == 0 {
+		c.Options.APIKey = current.Options.APIKey
+	}
+	if err := c.validate(); err != nil {
+		return config{}, errors.Wrap(err, "invalid configuration")
+	}
+	return c, nil
+}
+
+// Countries returns the countries with Schedules Direct lineups for the lineup search of the web UI
+func (app *App) Countries() ([]handlers.Country, error) {
+	sd, err := app.webSD()
+	if err != nil {
+		return nil, err
+	}
+	if err := sd.Countries(); err != nil {
+		return nil, errors.Wrap(err, "failed to get countries")
+	}
+
+	var countries []handlers.Country
+	for _, c := range sd.Resp.Countries.Regions() {
+		country := handlers.Country{Name: c.FullName, Code: c.ShortName, PostalCodeExample: c.PostalCodeExample}
+		if c.OnePostalCode {
+			country.PostalCode = c.PostalCode
+		}
+		countries = append(countries, country)
+	}
+	return countries, nil
+}
+
+// Headends returns the headends and lineups of a postal code for the lineup search of the web UI
+func (app *App) Headends(country, postalCode string) ([]handlers.Headend, error) {
+	sd, err := app.webSD()
+	if err != nil {
+		return nil, err
+	}
+	if err := sd.Headends(country, postalCode); err != nil {
+		return nil, errors.Wrap(err, "failed to get headends")
+	}
+
+	var headends []handlers.Headend
+	for _, h := range sd.Resp.Headend {
+		headend := handlers.Headend{Headend: h.Headend, Transport: h.Transport, Location: h.Location}
+		for _, l := range h.Lineups {
+			headend.Lineups = append(headend.Lineups, handlers.Lineup{Lineup: l.Lineup, Name: l.Name})
+		}
+		headends = append(headends, headend)
+	}
+	return headends, nil
+}
+
+// Stations returns the stations of the subscribed lineups sorted by name for the channel manager of the web UI
+func (app *App) Stations() ([]handlers.LineupStations, error) {
+	sd, err := app.webSD()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &sd.app.Config
+	c.GetChannels()
+
+	var lineups []handlers.LineupStations
+	for _, l := range sd.Resp.Status.Lineups {
+		sd.Req.Parameter = fmt.Sprintf("/%s", l.Lineup)
+		if err := sd.Channels(); err != nil {
+			return nil, errors.Wrapf(err, "failed to get channels of lineup %s", l.Lineup)
+		}
+
+		lineup := handlers.LineupStations{Lineup: l.Lineup, Name: l.Name}
+		for _, station := range sd.Resp.Channels.Stations {
+			lineup.Stations = append(lineup.Stations, handlers.Station{
+				StationID: station.StationID,
+				Name:      station.Name,
+				Callsign:  station.Callsign,
+				Channel:   sd.Resp.Channels.Channel(station.StationID),
+				Languages: station.BroadcastLanguage,
+				Selected:  ContainsString(c.ChannelIDs, station.StationID) != -1,
+			})
+		}
+		sort.Slice(lineup.Stations, func(i, j int) bool {
+			return lineup.Stations[i].Name < lineup.Stations[j].Name
+		})
+
+		lineups = append(lineups, lineup)
+	}
+
+	return lineups, nil
+}
+
+// SelectStations adds and removes stations of a lineup in the configuration for the channel manager of the web UI
+func (app *App) SelectStations(change handlers.StationChange) error {
+	sd, err := app.webSD()
+	if err != nil {
+		return err
+	}
+
+	sd.Req.Parameter = fmt.Sprintf("/%s", change.Lineup)
+	if err := sd.Channels(); err != nil {
+		return errors.Wrapf(err, "failed to get channels of lineup %s", change.Lineup)
+	}
+
+	names := make(map[string]string)
+	for _, station := range sd.Resp.Channels.Stations {
+		names[station.StationID] = station.Name
+	}
+
+	c := &sd.app.Config
+	c.GetChannels()
+	for _, id := range change.Add {
+		name, ok := names[id]
+		if !ok {
+			return errors.Errorf("station %s is not in lineup %s", id, change.Lineup)
+		}
+		if ContainsString(c.ChannelIDs, id) == -1 {
+			c.AddChannel(&channel{Name: name, ID: id, Lineup: change.Lineup})
+			c.ChannelIDs = append(c.ChannelIDs, id)
+		}
+	}
+	for _, id := range change.Remove {
+		c.RemoveChannel(&channel{ID: id})
+	}
+
+	app.Logger.WithFields(logrus.Fields{
+		"lineup":  change.Lineup,
+		"added":   len(change.Add),
+		"removed": len(change.Remove),
+	}).Info("Channels changed in the web UI")
+
+	return c.Save()
+}
+
+// webSD returns a Schedules Direct client with the account status of the configuration for the web UI.
+// The client has its own copy of the app, the configuration of the client is sd.app.Config.
+func (app *App) webSD() (*SD, error) {
+	web := *app
+	if err := web.openConfig(context.Background()); err != nil {
+		return nil, err
+	}
+
+	sd := &SD{}
+	if err := sd.Init(&web); err != nil {
+		return nil, errors.Wrap(err, "failed to initialize SD client")
+	}
+	if err := sd.Login(); err != nil {
+		return nil, errors.Wrap(err, "failed to login to Schedules Direct")
+	}
+	if err := sd.Status(); err != nil {
+		return nil, errors.Wrap(err, "failed to get account status")
+	}
+	return sd, nil
+}
+
+// GuideStatus returns the configured channels, the last update and the XMLTV file for the dashboard of the web UI
+func (app *App) GuideStatus() (*handlers.GuideStatus, error) {
+	c, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	status := &handlers.GuideStatus{Channels: len(c.Station)}
+	if run, ok := loadLastRun(&App{Config: *c, Logger: app.Logger}); ok {
+		status.LastRun = &handlers.LastRun{Started: run.Started, Finished: run.Finished, Error: run.Error}
+	}
+
+	if name, info, ok := statXMLTV(c); ok {
+		status.XMLTV = &handlers.XMLTVFile{Name: name, Size: info.Size(), Modified: info.ModTime()}
+	}
+
+	return status, nil
+}
+
+// SubscribeLogs returns the log entries for the live log of the web UI until unsubscribe is called, none without the web server
+func (app *App) SubscribeLogs() (<-chan string, func()) {
+	if app.logs == nil {
+		lines := make(chan string)
+		close(lines)
+		return lines, func() {}
+	}
+	return app.logs.Subscribe()
+}
+
+// SubscribeJobs returns the updates which finish for the notifications of the web UI until unsubscribe is called
+func (app *App) SubscribeJobs() (<-chan handlers.Job, func()) {
+	statuses, unsubscribe := app.jobs.subscribe()
+	jobs := make(chan handlers.Job)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(jobs)
+		for s := range statuses {
+			select {
+			case jobs <- webJob(s):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return jobs, func() {
+		unsubscribe()
+		close(done)
+	}
+}
+
+// StartUpdate queues an update of a configuration file for the web UI, empty for the selected one
+func (app *App) StartUpdate(config string) (handlers.Job, error) {
+	if len(config) == 0 {
+		config = app.selectedConfig()
+	} else if err := app.checkConfigFile(config); err != nil {
+		return handlers.Job{}, err
+	}
+	return webJob(app.jobs.queue(config).status()), nil
+}
+
+// Configs returns the configuration files of the web UI
+func (app *App) Configs() []handlers.ConfigFile {
+	selected := app.selectedConfig()
+
+	var configs []handlers.ConfigFile
+	for _, name := range app.configFiles() {
+		configs = append(configs, handlers.ConfigFile{Name: configName(name), Path: name, Selected: name == selected})
+	}
+	return configs
+}
+
+// SelectConfig selects the configuration file the pages of the web UI show
+func (app *App) SelectConfig(path string) error {
+	if err := app.selectConfig(path); err != nil {
+		return err
+	}
+	app.Logger.WithField("config", path).Info("Selected configuration file")
+	return nil
+}
+
+// UpdateSchedule returns the update schedule with the next scheduled update for the web UI
+func (app *App) UpdateSchedule() (*handlers.UpdateSchedule, error) {
+	c, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &handlers.UpdateSchedule{Schedule: c.Options.UpdateSchedule, Enabled: c.Options.UpdateScheduleEnabled}
+	if s.Enabled && len(s.Schedule) != 0 {
+		schedule, err := parseCron(s.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		s.Next = schedule.next(time.Now())
+	}
+	return s, nil
+}
+
+// SetUpdateSchedule changes the update schedule of the configuration for the web UI, the scheduler applies it within a minute
+func (app *App) SetUpdateSchedule(s handlers.UpdateSchedule) error {
+	c, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return err
+	}
+
+	c.Options.UpdateSchedule = s.Schedule
+	c.Options.UpdateScheduleEnabled = s.Enabled
+	if err := c.validate(); err != nil {
+		return errors.Wrap(err, "invalid update schedule")
+	}
+	return c.Save()
+}
+
+// Jobs returns the updates, newest first, for the web UI
+func (app *App) Jobs() []handlers.Job {
+	var jobs []handlers.Job
+	for _, status := range app.jobs.list() {
+		jobs = append(jobs, webJob(status))
+	}
+	return jobs
+}
+
+// Runs returns the run history of the selected configuration file, newest first, for the web UI
+func (app *App) Runs() ([]handlers.Run, error) {
+	c, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return nil, err
+	}
+	history := App{Config: *c}
+	records, err := loadRuns(&history)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []handlers.Run{}
+	for _, r := range records {
+		_, err := os.Stat(runLogFile(&history, r.ID))
+		runs = append(runs, handlers.Run{
+			ID:       r.ID,
+			Started:  r.Started,
+			Finished: r.Finished,
+			Duration: r.Finished.Sub(r.Started).Seconds(),
+			Programs: r.Programs,
+			FileSize: r.FileSize,
+			Error:    r.Error,
+			Log:      err == nil,
+		})
+	}
+	return runs, nil
+}
+
+// RunLog returns the log file of an update of the selected configuration file for the web UI
+func (app *App) RunLog(id int) (string, error) {
+	c, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return "", err
+	}
+	history := App{Config: *c}
+
+	name := runLogFile(&history, id)
+	if _, err := os.Stat(name); err != nil {
+		return "", errors.Errorf("no log of update %d", id)
+	}
+	return name, nil
+}
+
+// configName returns the name of a configuration file shown in the web UI
+func configName(name string) string {
+	return strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+}
+
+// webJob converts the state of a job for the web UI
+func webJob(s jobStatus) handlers.Job {
+	return handlers.Job{
+		ID:       s.ID,
+		Config:   configName(s.Config),
+		State:    s.State,
+		Step:     s.Step,
+		Done:     s.Done,
+		Total:    s.Total,
+		Error:    s.Error,
+		Queued:   s.Queued,
+		Started:  s.Started,
+		Finished: s.Finished,
+	}
+}
+
+// cacheCounters are the lookup counters of the cache of the last finished update
+type cacheCounters struct {
+	mu     sync.Mutex
+	hits   interface{}
+	misses interface{}
+	size   interface{}
+}
+
+// set keeps the counters of the statistics of a cache
+func (c *cacheCounters) set(stats map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hits, c.misses, c.size = stats["hits"], stats["misses"], stats["size"]
+}
+
+// report replaces the counters of stats with the kept ones, a web UI without a finished update keeps them
+func (c *cacheCounters) report(stats map[string]interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hits == nil {
+		return
+	}
+	stats["hits"], stats["misses"], stats["size"] = c.hits, c.misses, c.size
+}
+
+// CacheStats returns the cached entries of the selected configuration and the lookup counters of the last update for the web UI.
+// The cache is opened with a copy of the application like the export.
+func (app *App) CacheStats() (*handlers.CacheStats, error) {
+	view := *app
+	view.Cache = &cache{}
+	if err := view.openConfig(context.Background()); err != nil {
+		return nil, err
+	}
+	closeCache, err := view.openCache()
+	if err != nil {
+		return nil, err
+	}
+	defer closeCache()
+
+	stats := view.Cache.GetStats()
+	app.cacheCounters.report(stats)
+	ttl := view.Config.Options.CacheTTL
+	return &handlers.CacheStats{
+		Backend:    cacheBackend(view.Config),
+		Version:    statValue(stats["version"]),
+		Channels:   statValue(stats["channels"]),
+		Broadcasts: statValue(stats["broadcasts"]),
+		Programs:   statValue(stats["programs"]),
+		Metadata:   statValue(stats["metadata"]),
+		People:     statValue(stats["people"]),
+		EntrySize:  statValue(stats["entry size"]),
+		Hits:       statValue(stats["hits"]),
+		Misses:     statValue(stats["misses"]),
+		AddedBytes: statValue(stats["size"]),
+		TTL: handlers.CacheTTL{
+			Schedules: ttl.Schedules.String(),
+			Programs:  ttl.Programs.String(),
+			Metadata:  ttl.Metadata.String(),
+		},
+	}, nil
+}
+
+// ExportCache writes all entries of the cache to w as a gzip compressed JSON cache file for the web UI.
+// The cache is opened with a copy of the application, a running update keeps its cache store.
+func (app *App) ExportCache(w io.Writer) error {
+	export := *app
+	if err := export.openConfig(context.Background()); err != nil {
+		return err
+	}
+
+	closeCache, err := export.openCache()
+	if err != nil {
+		return err
+	}
+	defer closeCache()
+
+	return export.writeCache(w, true)
+}
+
+// Grid returns the broadcasts of the configured channels between from and from plus hours for the guide grid of the web UI.
+// The cache is opened with a copy of the application like the export.
+func (app *App) Grid(from time.Time, hours int) (*handlers.Grid, error) {
+	grid := *app
+	if err := grid.openConfig(context.Background()); err != nil {
+		return nil, err
+	}
+	closeCache, err := grid.openCache()
+	if err != nil {
+		return nil, err
+	}
+	defer closeCache()
+
+	to := from.Add(time.Duration(hours) * time.Hour)
+	g := &handlers.Grid{From: from, To: to}
+	for _, station := range grid.Config.Station {
+		entries := grid.Cache.Inspect(station.ID, &grid)
+		channel, _ := entries["channel"].(G2GCache)
+		schedule, _ := entries["schedule"].([]G2GCache)
+
+		lang := "en"
+		if len(channel.BroadcastLanguage) > 0 {
+			lang = channel.BroadcastLanguage[0]
+		}
+
+		c := handlers.GridChannel{StationID: station.ID, Name: station.Name, Number: channel.ChannelNumber}
+		for _, s := range scheduleWindow(schedule, from, to) {
+			c.Broadcasts = append(c.Broadcasts, handlers.Broadcast{
+				ProgramID: s.ProgramID,
+				Title:     grid.Cache.GetTitle(s.ProgramID, lang, &grid)[0].Value,
+				Start:     s.AirDateTime,
+				Stop:      s.AirDateTime.Add(time.Duration(s.Duration) * time.Second),
+				New:       s.New,
+				Live:      strings.EqualFold(s.LiveTapeDelay, "live"),
+			})
+		}
+		g.Channels = append(g.Channels, c)
+	}
+	return g, nil
+}
+
+// Program returns the details of a cached program for the guide grid of the web UI, as they are written into the XMLTV file
+func (app *App) Program(programID string) (*handlers.Program, error) {
+	program := *app
+	if err := program.openConfig(context.Background()); err != nil {
+		return nil, err
+	}
+	closeCache, err := program.openCache()
+	if err != nil {
+		return nil, err
+	}
+	defer closeCache()
+
+	if _, ok := program.Cache.Inspect(programID, &program)["program"]; !ok {
+		return nil, errors.Errorf("program %s is not cached", programID)
+	}
+
+	c := program.Cache
+	p := &handlers.Program{
+		ProgramID: programID,
+		Title:     c.GetTitle(programID, "en", &program)[0].Value,
+		SubTitle:  c.GetSubTitle(programID, "en", &program).Value,
+	}
+	if descs := c.GetDescs(programID, p.SubTitle, &program); len(descs) != 0 {
+		p.Description = descs[0].Value
+	}
+	for _, category := range c.GetCategory(programID, &program) {
+		p.Categories = append(p.Categories, category.Value)
+	}
+	for _, ep := range c.GetEpisodeNum(programID, &program) {
+		switch ep.System {
+		case "onscreen":
+			p.Episode = ep.Value
+		case "original-air-date":
+			p.OriginalAirDate = ep.Value
+		}
+	}
+	for _, actor := range c.GetCredits(programID, &program).Actor {
+		p.Cast = append(p.Cast, actor.Value)
+	}
+	return p, nil
+}
+
+// ImportCache adds the entries of a cache export from r to the cache for the web UI, it fails while an update runs
+func (app *App) ImportCache(ctx context.Context, r io.Reader) error {
+	imp := *app
+	if err := imp.openConfig(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := imp.lockCache(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	closeCache, err := imp.openCache()
+	if err != nil {
+		return err
+	}
+	defer closeCache()
+
+	return imp.importCache(r)
+}
+
+// ImageCache returns the disk usage of the images path and the images with the status, empty for all, from offset on for the web UI.
+// The status of an image comes from the artwork and logos of the cache, it is opened with a copy of the application like the export.
+func (app *App) ImageCache(status string, offset, limit int) (*handlers.ImageCache, error) {
+	img := *app
+	if err := img.openConfig(context.Background()); err != nil {
+		return nil, err
+	}
+	dir, images, err := img.cachedImages()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &handlers.ImageCache{Path: dir, Images: []handlers.CachedImage{}}
+	for _, i := range images {
+		result.Files++
+		result.Size += i.size
+		switch i.status {
+		case imageOrphaned:
+			result.Orphaned++
+			result.OrphanedSize += i.size
+		case imageFailed:
+			result.Failed++
+		}
+
+		if len(status) != 0 && i.status != status {
+			continue
+		}
+		result.Total++
+		if result.Total > offset && len(result.Images) < limit {
+			result.Images = append(result.Images, handlers.CachedImage{Name: i.name, Size: i.size, Modified: i.modified, Status: i.status})
+		}
+	}
+	return result, nil
+}
+
+// ImagePath returns the file of an image in the images path for the thumbnails of the web UI
+func (app *App) ImagePath(name string) (string, error) {
+	if !isValidImageID(name) {
+		return "", errors.Errorf("invalid image name %s", name)
+	}
+	c, err := app.readConfig(context.Background(), app.selectedConfig())
+	if err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(c.Options.ImagesPath, name)
+	if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+		return "", errors.Errorf("image %s not found", name)
+	}
+	return file, nil
+}
+
+// PurgeImages removes the orphaned images of the images path for the web UI, it fails while an update runs
+func (app *App) PurgeImages(ctx context.Context) (*handlers.ImageResult, error) {
+	purge := *app
+	if err := purge.openConfig(ctx); err != nil {
+		return nil, err
+	}
+	unlock, err := purge.lockCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	dir, images, err := purge.cachedImages()
+	if err != nil {
+		return nil, err
+	}
+	removed, size, err := purgeImages(dir, images)
+	if err != nil {
+		return nil, err
+	}
+
+	app.Logger.WithFields(logrus.Fields{
+		"removed": removed,
+		"size":    size,
+	}).Info("Orphaned images purged in the web UI")
+	return &handlers.ImageResult{Count: removed, Size: size}, nil
+}
+
+// RedownloadImages downloads the failed images of the images path again for the web UI, it fails while an update runs
+func (app *App) RedownloadImages(ctx context.Context) (*handlers.ImageResult, error) {
+	download := *app
+	if err := download.openConfig(ctx); err != nil {
+		return nil, err
+	}
+	unlock, err := download.lockCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	_, images, err := download.cachedImages()
+	if err != nil {
+		return nil, err
+	}
+
+	sd := &SD{}
+	if err := sd.Init(&download); err != nil {
+		return nil, errors.Wrap(err, "failed to initialize SD client")
+	}
+	if err := sd.Login(); err != nil {
+		return nil, errors.Wrap(err, "failed to login to Schedules Direct")
+	}
+
+	result := &handlers.ImageResult{}
+	for _, i := range images {
+		if i.status != imageFailed {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := download.GetImageUrl(i.uri, i.name); err != nil {
+			app.Logger.WithError(err).WithField("name", i.name).Warn("Failed to download image again")
+			result.Failed++
+			continue
+		}
+		result.Count++
+	}
+
+	app.Logger.WithFields(logrus.Fields{
+		"downloaded": result.Count,
+		"failed":     result.Failed,
+	}).Info("Failed images downloaded again in the web UI")
+	return result, nil
+}
+
+// cachedImages returns the images path of the configuration with the status of its images by the references of the cache
+func (app *App) cachedImages() (string, []cachedImage, error) {
+	dir := app.Config.Options.ImagesPath
+	if len(dir) == 0 {
+		return "", nil, errors.New("no images path configured")
+	}
+
+	closeCache, err := app.openCache()
+	if err != nil {
+		return "", nil, err
+	}
+	defer closeCache()
+
+	c, err := app.Cache.Export(app)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to read cache")
+	}
+	images, err := scanImages(dir, imageReferences(c))
+	return dir, images, err
+}
+
+// statValue converts a counter of GetStats, the backends count with int or int64
+func statValue(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	}
+	return 0
+}
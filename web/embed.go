@@ -0,0 +1,15 @@
+// Package web contains the templates and static files of the web UI, they are embedded into the binary
+// so the web UI works from any working directory.
+package web
+
+import "embed"
+
+// Templates are the pages of the web UI in templates/
+//
+//go:embed templates/*.html
+var Templates embed.FS
+
+// Static are the style sheets and scripts of the web UI in static/
+//
+//go:embed static
+var Static embed.FS
@@ -0,0 +1,41 @@
+package handlers
+
+// The request and response bodies of the API which are not backend types, the OpenAPI specification is built from them
+
+// Result is the response of an API call which changes something
+type Result struct {
+	Message string `json:"message"`
+	Lineup  string `json:"lineup,omitempty"`
+}
+
+// ErrorResponse is the response of a failed API call
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// LineupRequest adds a lineup to the account
+type LineupRequest struct {
+	Lineup string `json:"lineup"`
+}
+
+// JobRequest starts an update, an empty configuration file updates the selected one
+type JobRequest struct {
+	Config string `json:"config,omitempty"`
+}
+
+// ConfigSelection selects the configuration file the pages show
+type ConfigSelection struct {
+	Path string `json:"path"`
+}
+
+// CacheStatsResponse are the cache statistics with the hit ratio of the lookups in percent
+type CacheStatsResponse struct {
+	*CacheStats
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// Event is a message of the events WebSocket, the type job is sent when an update finishes
+type Event struct {
+	Type string `json:"type"`
+	Job  *Job   `json:"job,omitempty"`
+}
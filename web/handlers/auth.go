@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionCookie is the name of the cookie with the session token
+const sessionCookie = "guide2go_session"
+
+// csrfCookie is the name of the cookie with the CSRF token, the pages read it and send it with every change.
+// A page of another site can neither read the cookie nor the token.
+const csrfCookie = "guide2go_csrf"
+
+// roleCookie is the name of the cookie with the role of the browser, the pages hide the controls the role may not use
+const roleCookie = "guide2go_role"
+
+// Role is what a user of the web UI may do
+type Role string
+
+// Roles of the web UI: admins change the configuration, channels and schedule and start updates,
+// read-only users only see the dashboard, the guide data and the run history
+const (
+	RoleAdmin    Role = "admin"
+	RoleReadOnly Role = "read-only"
+)
+
+// adminPages are shown to admins only, even read: the configuration contains the account
+var adminPages = map[string]bool{
+	"/config":     true,
+	"/api/config": true,
+}
+
+// allowed reports whether the role may send the request, the login and the logout are open to every role
+func (role Role) allowed(r *http.Request) bool {
+	if role == RoleAdmin || r.URL.Path == "/login" || r.URL.Path == "/logout" {
+		return true
+	}
+	return safeMethod(r.Method) && !adminPages[r.URL.Path]
+}
+
+// Header and form field of the CSRF token
+const (
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+)
+
+// session is a logged in browser
+type session struct {
+	expires time.Time
+	csrf    string // CSRF token of the session
+	role    Role
+}
+
+// sessions are the logged in browsers of the web UI, the tokens only live in memory and end with a restart
+type sessions struct {
+	mu       sync.Mutex
+	sessions map[string]session // By token
+}
+
+// newSessions returns an empty session store
+func newSessions() *sessions {
+	return &sessions{sessions: make(map[string]session)}
+}
+
+// randomToken returns a random token for a cookie
+func randomToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// create starts a session of the role which ends after timeout and returns its token
+func (s *sessions) create(timeout time.Duration, role Role) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Expired sessions are removed with every login, the store stays as small as the number of browsers
+	now := time.Now()
+	for t, session := range s.sessions {
+		if now.After(session.expires) {
+			delete(s.sessions, t)
+		}
+	}
+
+	s.sessions[token] = session{expires: now.Add(timeout), csrf: csrf, role: role}
+	return token, nil
+}
+
+// get returns the session of the token, false if the session has ended or does not exist
+func (s *sessions) get(token string) (session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok || !time.Now().Before(session.expires) {
+		return session, false
+	}
+	return session, true
+}
+
+// remove ends the session of the token
+func (s *sessions) remove(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+}
+
+// secureRequest reports whether the browser connected with HTTPS, directly or through a proxy which terminates TLS.
+// The cookies are only sent over HTTPS then.
+func secureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// setCookie sets a cookie of the web UI, a negative maxAge removes it. The session cookie is hidden from the scripts of the pages.
+func setCookie(w http.ResponseWriter, r *http.Request, name, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   secureRequest(r),
+		HttpOnly: name == sessionCookie,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// safeMethod reports whether the method does not change anything, these requests need no CSRF token
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// checkCSRF reports whether the request carries the CSRF token in the header or, for forms, in the form field
+func checkCSRF(r *http.Request, token string) bool {
+	sent := r.Header.Get(csrfHeader)
+	if len(sent) == 0 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		sent = r.PostFormValue(csrfField)
+	}
+	return len(sent) != 0 && equal(sent, token) == 1
+}
+
+// checkPassword compares the credentials with the admin and the read-only user in constant time and returns the role of the user
+func checkPassword(login *WebLogin, username, password string) (Role, bool) {
+	admin := equal(username, login.Username) & equal(password, login.Password)
+	readOnly := equal(username, login.ReadOnlyUsername) & equal(password, login.ReadOnlyPassword)
+	switch {
+	case admin == 1:
+		return RoleAdmin, true
+	case readOnly == 1 && len(login.ReadOnlyUsername) != 0:
+		return RoleReadOnly, true
+	}
+	return "", false
+}
+
+// CheckAPIKey reports whether the request has the API key in the X-API-Key header, as bearer token
+// or in the token query parameter. An empty key never matches.
+func CheckAPIKey(r *http.Request, key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+
+	token := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); len(token) == 0 && strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if len(token) == 0 {
+		token = r.URL.Query().Get("token")
+	}
+	return equal(token, key) == 1
+}
+
+// equal compares a and b in constant time and returns 1 if they are equal, the hashes hide the length of b
+func equal(a, b string) int {
+	hashA, hashB := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(hashA[:], hashB[:])
+}
+
+// authMiddleware lets only logged in browsers through while the login is configured, the API also accepts the API key.
+// The login page and the static files are always served, pages redirect to the login and the API answers 401.
+//
+// Changes of the browsers need the CSRF token: the token of the session, or of the server without a session.
+// Requests with the API key need none, and without the login neither do clients without the Origin header of a browser, e.g. curl.
+// The API key and the browsers without the login are admins, the sessions have the role of their user.
+func (h *handler) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		login, err := h.backend.WebLogin()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		api := strings.HasPrefix(r.URL.Path, "/api/")
+		if api && CheckAPIKey(r, login.APIKey) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		csrf, role, authorized := h.csrf, RoleAdmin, r.URL.Path == "/login" || len(login.Username) == 0
+		if len(login.Username) != 0 {
+			role = RoleReadOnly
+		}
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			if session, ok := h.sessions.get(cookie.Value); ok {
+				csrf, role, authorized = session.csrf, session.role, true
+			}
+		}
+		if !authorized {
+			if api {
+				writeError(w, http.StatusUnauthorized, errors.New("login required"))
+				return
+			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		if !safeMethod(r.Method) && (len(login.Username) != 0 || len(r.Header.Get("Origin")) != 0) && !checkCSRF(r, csrf) {
+			writeError(w, http.StatusForbidden, errors.New("invalid CSRF token, reload the page"))
+			return
+		}
+		// The pages get the token, the login and the logout change it themselves
+		if cookie, err := r.Cookie(csrfCookie); safeMethod(r.Method) && (err != nil || cookie.Value != csrf) {
+			setCookie(w, r, csrfCookie, csrf, 0)
+		}
+		if cookie, err := r.Cookie(roleCookie); safeMethod(r.Method) && (err != nil || cookie.Value != string(role)) {
+			setCookie(w, r, roleCookie, string(role), 0)
+		}
+
+		if !role.allowed(r) {
+			if api {
+				writeError(w, http.StatusForbidden, errors.New("read-only users cannot change anything"))
+				return
+			}
+			http.Error(w, "only admins may open this page", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loginHandler renders the login page with GET and starts a session with POST
+func (h *handler) loginHandler(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Username string
+		Error    string
+	}
+
+	login, err := h.backend.WebLogin()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(login.Username) == 0 {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		data.Username = r.PostFormValue("username")
+		if role, ok := checkPassword(login, data.Username, r.PostFormValue("password")); ok {
+			// A new session for every login, a token set before the login is never used by it
+			if cookie, err := r.Cookie(sessionCookie); err == nil {
+				h.sessions.remove(cookie.Value)
+			}
+			token, err := h.sessions.create(login.SessionTimeout, role)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			session, _ := h.sessions.get(token)
+			setCookie(w, r, sessionCookie, token, int(login.SessionTimeout.Seconds()))
+			setCookie(w, r, csrfCookie, session.csrf, 0)
+			setCookie(w, r, roleCookie, string(role), 0)
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		// A failed login is slowed down against guessing the password
+		time.Sleep(time.Second)
+		w.WriteHeader(http.StatusUnauthorized)
+		data.Error = "Invalid username or password"
+	}
+
+	h.render(w, r, "login.html", data)
+}
+
+// logoutHandler ends the session and returns to the login page
+func (h *handler) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		h.sessions.remove(cookie.Value)
+	}
+	setCookie(w, r, sessionCookie, "", -1)
+	setCookie(w, r, csrfCookie, h.csrf, 0)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
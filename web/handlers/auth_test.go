@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// loginBackend has the web UI login with a read-only user and the API key configured and accepts every configuration
+type loginBackend struct {
+	mockBackend
+}
+
+func (loginBackend) WebLogin() (*WebLogin, error) {
+	return &WebLogin{Username: "admin", Password: "secret", SessionTimeout: time.Hour, ReadOnlyUsername: "viewer", ReadOnlyPassword: "view", APIKey: "key"}, nil
+}
+
+func (loginBackend) SaveConfig(data []byte) error {
+	return nil
+}
+
+// cookie returns the value of the cookie the response sets
+func cookie(w *httptest.ResponseRecorder, name string) string {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestCSRF(t *testing.T) {
+	r := mux.NewRouter()
+	RegisterRoutes(r, loginBackend{})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	login := func(csrf string) *httptest.ResponseRecorder {
+		form := url.Values{"username": {"admin"}, "password": {"secret"}, csrfField: {csrf}}
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(req)
+	}
+
+	if w := login(""); w.Code != http.StatusForbidden {
+		t.Fatalf("login without CSRF token = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	page := cookie(serve(httptest.NewRequest(http.MethodGet, "/login", nil)), csrfCookie)
+	w := login(page)
+	session, csrf := cookie(w, sessionCookie), cookie(w, csrfCookie)
+	if w.Code != http.StatusSeeOther || len(session) == 0 || len(csrf) == 0 || csrf == page {
+		t.Fatalf("login = %d with session %q and CSRF token %q, want a redirect with the tokens of a new session", w.Code, session, csrf)
+	}
+
+	tests := []struct {
+		name    string
+		session bool
+		header  map[string]string
+		want    int
+	}{
+		{"session without token", true, nil, http.StatusForbidden},
+		{"session with wrong token", true, map[string]string{csrfHeader: "wrong"}, http.StatusForbidden},
+		{"session with token", true, map[string]string{csrfHeader: csrf}, http.StatusOK},
+		{"API key", false, map[string]string{"X-API-Key": "key"}, http.StatusOK},
+		{"without login", false, map[string]string{csrfHeader: csrf}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/config", strings.NewReader("{}"))
+		if tt.session {
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: session})
+		}
+		for name, value := range tt.header {
+			req.Header.Set(name, value)
+		}
+		if w := serve(req); w.Code != tt.want {
+			t.Errorf("%s: POST /api/config = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestCSRFWithoutLogin(t *testing.T) {
+	r := mux.NewRouter()
+	RegisterRoutes(r, lineupBackend{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/lineups", nil))
+	csrf := cookie(w, csrfCookie)
+	if len(csrf) == 0 {
+		t.Fatal("page sets no CSRF cookie")
+	}
+
+	// Browsers send the Origin header, a page of another site cannot read the token
+	for token, want := range map[string]int{"": http.StatusForbidden, csrf: http.StatusCreated} {
+		req := httptest.NewRequest(http.MethodPost, "/api/lineups", strings.NewReader(`{"lineup": "USA-OTA-10001"}`))
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set(csrfHeader, token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("POST /api/lineups with token %q = %d, want %d", token, w.Code, want)
+		}
+	}
+}
+
+func TestRoles(t *testing.T) {
+	r := mux.NewRouter()
+	RegisterRoutes(r, loginBackend{})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	login := func(username, password string) (string, string) {
+		page := cookie(serve(httptest.NewRequest(http.MethodGet, "/login", nil)), csrfCookie)
+		form := url.Values{"username": {username}, "password": {password}, csrfField: {page}}
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := serve(req)
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("login of %s = %d, want %d", username, w.Code, http.StatusSeeOther)
+		}
+		if role := cookie(w, roleCookie); role != map[string]string{"admin": "admin", "viewer": "read-only"}[username] {
+			t.Errorf("login of %s sets role %q", username, role)
+		}
+		return cookie(w, sessionCookie), cookie(w, csrfCookie)
+	}
+
+	tests := []struct {
+		user         string
+		method, path string
+		want         int
+	}{
+		{"viewer", http.MethodGet, "/runs", http.StatusOK},
+		{"viewer", http.MethodGet, "/config", http.StatusForbidden},
+		{"viewer", http.MethodGet, "/api/config", http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/config", http.StatusForbidden},
+		{"viewer", http.MethodPost, "/api/jobs", http.StatusForbidden},
+		{"viewer", http.MethodPost, "/logout", http.StatusSeeOther},
+		{"admin", http.MethodPost, "/api/config", http.StatusOK},
+	}
+	sessions := make(map[string][2]string)
+	for _, tt := range tests {
+		if _, ok := sessions[tt.user]; !ok {
+			session, csrf := login(tt.user, map[string]string{"admin": "secret", "viewer": "view"}[tt.user])
+			sessions[tt.user] = [2]string{session, csrf}
+		}
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: sessions[tt.user][0]})
+		req.Header.Set(csrfHeader, sessions[tt.user][1])
+		if w := serve(req); w.Code != tt.want {
+			t.Errorf("%s %s of %s = %d, want %d", tt.method, tt.path, tt.user, w.Code, tt.want)
+		}
+	}
+}
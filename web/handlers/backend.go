@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// Backend gives the web handlers access to the application.
+// It is implemented by the main package so the handlers stay testable with a mock.
+type Backend interface {
+	WebLogin() (*WebLogin, error)
+	Language() string
+	AccountStatus() (*AccountStatus, error)
+	SDStatus() (*SDStatus, error)
+	LoadConfig() ([]byte, error)
+	SaveConfig(data []byte) error
+	PreviewConfig(data []byte) (*ConfigPreview, error)
+	Countries() ([]Country, error)
+	Headends(country, postalCode string) ([]Headend, error)
+	AddLineup(ctx context.Context, lineup string) error
+	RemoveLineup(ctx context.Context, lineup string) error
+	Stations() ([]LineupStations, error)
+	SelectStations(change StationChange) error
+	ImportStations(entries []ChannelImportEntry, replace bool) (*ChannelImport, error)
+	CacheStats() (*CacheStats, error)
+	Grid(from time.Time, hours int) (*Grid, error)
+	Program(programID string) (*Program, error)
+	SearchPrograms(query string, limit int) (*ProgramSearch, error)
+	CacheEntries(id string) (map[string]interface{}, error)
+	GuideStatus() (*GuideStatus, error)
+	SubscribeLogs() (lines <-chan string, unsubscribe func())
+	SubscribeJobs() (jobs <-chan Job, unsubscribe func())
+	StartUpdate(config string) (Job, error)
+	DryRun(ctx context.Context) (*DryRun, error)
+	Configs() []ConfigFile
+	SelectConfig(path string) error
+	UpdateSchedule() (*UpdateSchedule, error)
+	SetUpdateSchedule(schedule UpdateSchedule) error
+	Jobs() []Job
+	Runs() ([]Run, error)
+	RunLog(id int) (string, error)
+	ExportCache(w io.Writer) error
+	ImportCache(ctx context.Context, r io.Reader) error
+	ImageCache(status string, offset, limit int) (*ImageCache, error)
+	ImagePath(name string) (string, error)
+	PurgeImages(ctx context.Context) (*ImageResult, error)
+	RedownloadImages(ctx context.Context) (*ImageResult, error)
+}
+
+// WebLogin are the credentials of the web UI, an empty username disables the login.
+// The read-only user is optional, the API key lets automation use the API without a session.
+type WebLogin struct {
+	Username         string
+	Password         string
+	SessionTimeout   time.Duration
+	ReadOnlyUsername string
+	ReadOnlyPassword string
+	APIKey           string
+}
+
+// AccountStatus is the Schedules Direct account status shown in the web UI
+type AccountStatus struct {
+	Expires        time.Time      `json:"expires"`
+	DaysLeft       int            `json:"days_left"`      // Full days until the account expires
+	ExpiryWarning  bool           `json:"expiry_warning"` // The account expires within the warning days of the configuration
+	MaxLineups     int64          `json:"max_lineups"`
+	Lineups        []Lineup       `json:"lineups"`
+	LastDataUpdate string         `json:"last_data_update"`
+	Messages       []Message      `json:"messages"`
+	Notifications  []Message      `json:"notifications"`
+	SystemStatus   []SystemStatus `json:"system_status"`
+}
+
+// SDStatus is the Schedules Direct system status the web UI polls, it tells whether a failed update is caused by Schedules Direct
+type SDStatus struct {
+	Checked        time.Time      `json:"checked"` // Time of the poll
+	Online         bool           `json:"online"`  // Every system status is online
+	SystemStatus   []SystemStatus `json:"system_status"`
+	LastDataUpdate string         `json:"last_data_update"`
+	Announcements  []Message      `json:"announcements"`   // Account messages and notifications, newest first
+	Error          string         `json:"error,omitempty"` // The poll failed, e.g. Schedules Direct is not reachable
+}
+
+// ConfigPreview are the changes of a configuration before it is saved
+type ConfigPreview struct {
+	Diff            string `json:"diff"`             // Unified diff of the YAML files, empty without changes
+	Changes         int    `json:"changes"`          // Removed and added lines
+	Stations        int    `json:"stations"`         // Channels of the new configuration
+	RemovedStations int    `json:"removed_stations"` // Channels of the current configuration which are missing in the new one
+}
+
+// Lineup is a lineup subscribed in the Schedules Direct account
+type Lineup struct {
+	Lineup   string `json:"lineup"`
+	Name     string `json:"name"`
+	Modified string `json:"modified"`
+}
+
+// Message is an account message or notification from Schedules Direct
+type Message struct {
+	ID      string `json:"id"`
+	Date    string `json:"date"`
+	Message string `json:"message"`
+}
+
+// SystemStatus is the status of the Schedules Direct service
+type SystemStatus struct {
+	Date    string `json:"date"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Country is a country with Schedules Direct lineups, countries with one postal code have it preset
+type Country struct {
+	Name              string `json:"name"`
+	Code              string `json:"code"`
+	PostalCodeExample string `json:"postal_code_example"`
+	PostalCode        string `json:"postal_code,omitempty"`
+}
+
+// Headend is a headend of a postal code with the lineups which can be added to the account
+type Headend struct {
+	Headend   string   `json:"headend"`
+	Transport string   `json:"transport"`
+	Location  string   `json:"location"`
+	Lineups   []Lineup `json:"lineups"`
+}
+
+// LineupStations are the stations of a subscribed lineup in the channel manager
+type LineupStations struct {
+	Lineup   string    `json:"lineup"`
+	Name     string    `json:"name"`
+	Stations []Station `json:"stations"`
+}
+
+// Station is a station of a lineup, selected stations are in the guide
+type Station struct {
+	StationID string   `json:"station_id"`
+	Name      string   `json:"name"`
+	Callsign  string   `json:"callsign"`
+	Channel   string   `json:"channel"`
+	Languages []string `json:"languages"`
+	Selected  bool     `json:"selected"`
+}
+
+// StationChange adds and removes stations of a lineup in the guide
+type StationChange struct {
+	Lineup string   `json:"lineup"`
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// ChannelImportEntry is a station of a channel import, by station ID or callsign
+type ChannelImportEntry struct {
+	Station string `json:"station"`
+	Lineup  string `json:"lineup,omitempty"` // Only this lineup, empty for all subscribed lineups
+}
+
+// States of the entries of a channel import
+const (
+	ChannelAdded    = "added"
+	ChannelExists   = "exists" // Already in the guide
+	ChannelNotFound = "not found"
+)
+
+// ChannelImport is the result of a channel import
+type ChannelImport struct {
+	Added    int                   `json:"added"`
+	Removed  int                   `json:"removed"`  // Channels of the guide which were not imported, only when replacing
+	Channels int                   `json:"channels"` // Channels of the guide after the import
+	Results  []ChannelImportResult `json:"results"`
+}
+
+// ChannelImportResult is the station an entry of a channel import was matched to
+type ChannelImportResult struct {
+	Entry     string `json:"entry"`
+	Status    string `json:"status"`
+	StationID string `json:"station_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Lineup    string `json:"lineup,omitempty"`
+}
+
+// Matches reports whether the station contains the search text in its name, callsign, station ID or channel
+func (s Station) Matches(search string) bool {
+	search = strings.ToLower(search)
+	for _, value := range []string{s.Name, s.Callsign, s.StationID, s.Channel} {
+		if strings.Contains(strings.ToLower(value), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// Job is an update started by the web UI or /run with the progress of its current step
+type Job struct {
+	ID       int       `json:"id"`
+	Config   string    `json:"config"`
+	State    string    `json:"state"` // queued, running, done or failed
+	Step     string    `json:"step,omitempty"`
+	Done     int       `json:"done"`
+	Total    int       `json:"total"` // 0 if the step has no parts
+	Error    string    `json:"error,omitempty"`
+	Queued   time.Time `json:"queued"`
+	Started  time.Time `json:"started,omitempty"`
+	Finished time.Time `json:"finished,omitempty"`
+}
+
+// Run is an update in the run history of the selected configuration file
+type Run struct {
+	ID       int       `json:"id"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Duration float64   `json:"duration"` // Seconds
+	Programs int       `json:"programs"` // Programs downloaded from Schedules Direct
+	FileSize int64     `json:"file_size,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Log      bool      `json:"log"` // The log of the update can be downloaded
+}
+
+// Grid is a part of the guide grid: the broadcasts of the configured channels between From and To
+type Grid struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Channels []GridChannel `json:"channels"`
+}
+
+// GridChannel is a row of the guide grid
+type GridChannel struct {
+	StationID  string      `json:"station_id"`
+	Name       string      `json:"name"`
+	Number     string      `json:"number,omitempty"`
+	Broadcasts []Broadcast `json:"broadcasts"`
+}
+
+// Broadcast is a broadcast of the guide grid, the program details are loaded on click
+type Broadcast struct {
+	ProgramID string    `json:"program_id"`
+	Title     string    `json:"title"`
+	Start     time.Time `json:"start"`
+	Stop      time.Time `json:"stop"`
+	New       bool      `json:"new,omitempty"`
+	Live      bool      `json:"live,omitempty"`
+}
+
+// ImageCache is the disk usage of the images path with a page of its files
+type ImageCache struct {
+	Path         string        `json:"path"`
+	Files        int           `json:"files"`
+	Size         int64         `json:"size"`
+	Orphaned     int           `json:"orphaned"`
+	OrphanedSize int64         `json:"orphaned_size"`
+	Failed       int           `json:"failed"`
+	Total        int           `json:"total"` // Files with the requested status
+	Images       []CachedImage `json:"images"`
+}
+
+// CachedImage is a file in the images path, the status is referenced, orphaned or failed
+type CachedImage struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Status   string    `json:"status"`
+}
+
+// ImageResult is the result of purging orphaned images or downloading failed images again
+type ImageResult struct {
+	Count  int   `json:"count"`
+	Size   int64 `json:"size,omitempty"`
+	Failed int   `json:"failed,omitempty"`
+}
+
+// Program are the details of a cached program as they are written into the XMLTV file
+type Program struct {
+	ProgramID       string   `json:"program_id"`
+	Title           string   `json:"title"`
+	SubTitle        string   `json:"sub_title,omitempty"`
+	Description     string   `json:"description,omitempty"`
+	Categories      []string `json:"categories,omitempty"`
+	Episode         string   `json:"episode,omitempty"`
+	OriginalAirDate string   `json:"original_air_date,omitempty"`
+	Cast            []string `json:"cast,omitempty"`
+}
+
+// ProgramSearch are the programs of the cache browser which match the query
+type ProgramSearch struct {
+	Query    string         `json:"query"`
+	Programs []ProgramMatch `json:"programs"`
+	More     bool           `json:"more"` // More programs match than the limit
+}
+
+// ProgramMatch is a cached program found by its program ID or title
+type ProgramMatch struct {
+	ProgramID string `json:"program_id"`
+	Title     string `json:"title"`
+	SubTitle  string `json:"sub_title,omitempty"`
+	ShowType  string `json:"show_type,omitempty"`
+	Scheduled bool   `json:"scheduled"` // A cached schedule contains the program
+}
+
+// ConfigFile is a configuration file managed by the web UI, the pages show the selected one
+type ConfigFile struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Selected bool   `json:"selected"`
+}
+
+// UpdateSchedule is the cron expression of the scheduled updates, Next is zero while they are disabled
+type UpdateSchedule struct {
+	Schedule string    `json:"schedule"`
+	Enabled  bool      `json:"enabled"`
+	Next     time.Time `json:"next,omitempty"`
+}
+
+// DryRun is what an update would download, counted from the account status and the cache
+type DryRun struct {
+	Lineups      []DryRunLineup `json:"lineups"`
+	ScheduleDays int            `json:"schedule_days"`
+	Dates        []string       `json:"dates"`
+	Channels     int            `json:"channels"`
+	NewChannels  int            `json:"new_channels"` // Channels without cached schedule
+	Programs     int            `json:"programs"`     // New and changed programs of the cached schedules
+	Metadata     int            `json:"metadata"`     // Programs and series with artwork which is not cached
+	Requests     DryRunRequests `json:"requests"`
+	APICalls     int            `json:"api_calls"` // Estimated requests to Schedules Direct, without the series of missing episodes and the images
+}
+
+// DryRunLineup is a lineup of the account, its channels are downloaded again if it changed
+type DryRunLineup struct {
+	Lineup  string `json:"lineup"`
+	Name    string `json:"name"`
+	Refresh bool   `json:"refresh"`
+}
+
+// DryRunRequests are the estimated requests of an update by step
+type DryRunRequests struct {
+	Login     int `json:"login"` // 0 while a persisted token is valid
+	Status    int `json:"status"`
+	Lineups   int `json:"lineups"`
+	Schedules int `json:"schedules"`
+	Programs  int `json:"programs"`
+	Metadata  int `json:"metadata"`
+}
+
+// GuideStatus is the state of the guide shown on the dashboard: the configured channels, the last update and the XMLTV file
+type GuideStatus struct {
+	Channels int        `json:"channels"`
+	LastRun  *LastRun   `json:"last_run,omitempty"` // Missing before the first update
+	XMLTV    *XMLTVFile `json:"xmltv,omitempty"`    // Missing before the first XMLTV file is created
+}
+
+// LastRun is the result of the last update, Error is empty for a successful update
+type LastRun struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// XMLTVFile is the XMLTV file of the configuration
+type XMLTVFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// Age returns the time since the XMLTV file was written
+func (f *XMLTVFile) Age() time.Duration {
+	return time.Since(f.Modified).Round(time.Minute)
+}
+
+// CacheStats is the cache usage shown in the web UI, the lookups and added bytes are counted since the start
+type CacheStats struct {
+	Backend    string   `json:"backend"`
+	Version    int64    `json:"version"`
+	Channels   int64    `json:"channels"`
+	Broadcasts int64    `json:"broadcasts"`
+	Programs   int64    `json:"programs"`
+	Metadata   int64    `json:"metadata"`
+	People     int64    `json:"people"`
+	EntrySize  int64    `json:"entry_size,omitempty"` // Size of programs and metadata, only known by the JSON cache
+	Hits       int64    `json:"hits"`
+	Misses     int64    `json:"misses"`
+	AddedBytes int64    `json:"added_bytes"`
+	TTL        CacheTTL `json:"ttl"`
+}
+
+// CacheTTL is the configured time to live of the cached entries by data type, 0s never expires
+type CacheTTL struct {
+	Schedules string `json:"schedules"`
+	Programs  string `json:"programs"`
+	Metadata  string `json:"metadata"`
+}
+
+// HitRatio returns the percentage of lookups which found the entry
+func (s *CacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) * 100 / float64(s.Hits+s.Misses)
+}
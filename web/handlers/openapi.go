@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiOperation is an endpoint of the API in the OpenAPI specification.
+// The schemas of the bodies are built from the Go types the handlers encode and decode, so they cannot drift apart.
+type apiOperation struct {
+	method, path, summary string
+	params                []apiParam  // Query parameters, the path parameters come from the path
+	request               interface{} // JSON request body, a string is the content type of another body
+	optionalRequest       bool
+	response              interface{} // JSON response body, a string is the content type of another body
+	status                int         // Status of success, 200 if 0
+}
+
+// apiParam is a query parameter of an operation
+type apiParam struct {
+	name, description string
+	kind              string // string, integer or boolean
+	required          bool
+}
+
+// config is the configuration as JSON, its schema is the configuration file
+type config map[string]interface{}
+
+// apiOperations are the endpoints of the API, a test checks them against the registered routes
+var apiOperations = []apiOperation{
+	{method: "GET", path: "/api/config", summary: "Configuration, without the passwords and the API key", response: config{}},
+	{method: "POST", path: "/api/config", summary: "Validate and save the configuration, an empty password or API key keeps the current one", request: config{}, response: Result{}},
+	{method: "POST", path: "/api/config/preview", summary: "Validate the configuration and return its changes as YAML diff, nothing is saved", request: config{}, response: ConfigPreview{}},
+	{method: "GET", path: "/api/countries", summary: "Countries with Schedules Direct lineups", response: []Country{}},
+	{method: "GET", path: "/api/headends", summary: "Headends and lineups of a postal code", response: []Headend{}, params: []apiParam{
+		{name: "country", description: "Country code, e.g. USA", kind: "string", required: true},
+		{name: "postalcode", description: "Postal code, e.g. 10001", kind: "string", required: true},
+	}},
+	{method: "GET", path: "/api/lineups", summary: "Lineups of the account", response: []Lineup{}},
+	{method: "POST", path: "/api/lineups", summary: "Add a lineup to the account", request: LineupRequest{}, response: Result{}, status: http.StatusCreated},
+	{method: "PUT", path: "/api/lineups/{lineup}", summary: "Add the lineup to the account", response: Result{}},
+	{method: "DELETE", path: "/api/lineups/{lineup}", summary: "Remove the lineup from the account", response: Result{}},
+	{method: "GET", path: "/api/stations", summary: "Stations of the subscribed lineups", response: []LineupStations{}, params: []apiParam{
+		{name: "q", description: "Search in name, callsign, station ID and channel", kind: "string"},
+	}},
+	{method: "POST", path: "/api/stations", summary: "Add and remove stations of a lineup in the guide", request: StationChange{}, response: Result{}},
+	{method: "POST", path: "/api/channels/import", summary: "Add the stations of a CSV or JSON list by station ID or callsign to the guide, each is matched against the subscribed lineups", request: "text/csv", response: ChannelImport{}, params: []apiParam{
+		{name: "replace", description: "true replaces the channels of the guide with the imported stations", kind: "boolean"},
+	}},
+	{method: "GET", path: "/api/status", summary: "Schedules Direct account status, messages and notifications", response: AccountStatus{}},
+	{method: "GET", path: "/api/sd-status", summary: "Schedules Direct system status, last data update and announcements as polled by the web UI", response: SDStatus{}},
+	{method: "GET", path: "/api/guide", summary: "Configured channels, result of the last update and the XMLTV file", response: GuideStatus{}},
+	{method: "GET", path: "/api/logs", summary: "Live log as server-sent events, starting with the last 100 entries", response: "text/event-stream"},
+	{method: "GET", path: "/api/events", summary: "WebSocket with an event for every update which finishes, the messages are JSON", response: Event{}, status: http.StatusSwitchingProtocols},
+	{method: "GET", path: "/api/jobs", summary: "Updates since the start, newest first", response: []Job{}},
+	{method: "POST", path: "/api/jobs", summary: "Start an update, a queued update of the configuration file is returned instead of a second one", request: JobRequest{}, optionalRequest: true, response: Job{}, status: http.StatusAccepted},
+	{method: "GET", path: "/api/dry-run", summary: "What an update of the selected configuration would download, counted from the account status and the cache", response: DryRun{}},
+	{method: "GET", path: "/api/runs", summary: "Run history of the selected configuration file, newest first", response: []Run{}},
+	{method: "GET", path: "/api/runs/{id}/log", summary: "Download the log of an update", response: "text/plain"},
+	{method: "GET", path: "/api/configs", summary: "Configuration files of the web UI", response: []ConfigFile{}},
+	{method: "POST", path: "/api/configs", summary: "Select the configuration file the pages show", request: ConfigSelection{}, response: []ConfigFile{}},
+	{method: "GET", path: "/api/grid", summary: "Broadcasts of the configured channels in the cache", response: Grid{}, params: []apiParam{
+		{name: "from", description: "Start as RFC 3339 time, default the current hour", kind: "string"},
+		{name: "hours", description: "Hours from the start, 1 to 24, default 3", kind: "integer"},
+	}},
+	{method: "GET", path: "/api/programs/{id}", summary: "Details of a cached program", response: Program{}},
+	{method: "GET", path: "/api/schedule", summary: "Update schedule with the next scheduled update", response: UpdateSchedule{}},
+	{method: "POST", path: "/api/schedule", summary: "Change the update schedule and switch it on or off", request: UpdateSchedule{}, response: UpdateSchedule{}},
+	{method: "GET", path: "/api/cache/stats", summary: "Cache entries, lookups, schema version and TTLs", response: CacheStatsResponse{}},
+	{method: "GET", path: "/api/cache/export", summary: "Download the cache as gzip compressed JSON", response: "application/gzip"},
+	{method: "GET", path: "/api/cache/programs", summary: "Cached programs whose program ID or title contains the query", response: ProgramSearch{}, params: []apiParam{
+		{name: "q", description: "Part of the program ID or title, case insensitive", kind: "string", required: true},
+		{name: "limit", description: "Number of programs, 1 to 200, default 50", kind: "integer"},
+	}},
+	{method: "GET", path: "/api/cache/entries/{id}", summary: "Raw cache entries of a program or station ID: program, artwork, people, channel and schedule", response: map[string]interface{}{}},
+	{method: "POST", path: "/api/cache/import", summary: "Import a cache export", request: "application/octet-stream", response: Result{}},
+	{method: "GET", path: "/api/images", summary: "Disk usage of the images path and a page of its images", response: ImageCache{}, params: []apiParam{
+		{name: "status", description: "referenced, orphaned or failed, empty for all images", kind: "string"},
+		{name: "offset", description: "First image, default 0", kind: "integer"},
+		{name: "limit", description: "Number of images, 1 to 500, default 100", kind: "integer"},
+	}},
+	{method: "POST", path: "/api/images/purge", summary: "Remove the images no artwork or channel logo of the cache refers to", response: ImageResult{}},
+	{method: "POST", path: "/api/images/redownload", summary: "Download the failed images again", response: ImageResult{}},
+	{method: "GET", path: "/api/images/{name}", summary: "An image of the images path", response: "image/*"},
+	{method: "GET", path: "/api/openapi.json", summary: "This OpenAPI specification", response: "application/json"},
+	{method: "GET", path: "/api/docs", summary: "Documentation of this specification, the requests are sent with the session of the browser", response: "text/html"},
+}
+
+// pathParams are the parameters of a path like /api/lineups/{lineup}
+var pathParams = regexp.MustCompile(`{([^}]+)}`)
+
+// openAPISpec builds the OpenAPI specification of the operations
+func openAPISpec(operations []apiOperation) map[string]interface{} {
+	s := &specBuilder{schemas: make(map[string]interface{})}
+
+	paths := make(map[string]map[string]interface{})
+	for _, op := range operations {
+		if paths[op.path] == nil {
+			paths[op.path] = make(map[string]interface{})
+		}
+
+		var params []interface{}
+		for _, m := range pathParams.FindAllStringSubmatch(op.path, -1) {
+			params = append(params, map[string]interface{}{"name": m[1], "in": "path", "required": true, "schema": map[string]string{"type": "string"}})
+		}
+		for _, p := range op.params {
+			params = append(params, map[string]interface{}{"name": p.name, "in": "query", "description": p.description, "required": p.required, "schema": map[string]string{"type": p.kind}})
+		}
+
+		status := op.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		operation := map[string]interface{}{
+			"summary": op.summary,
+			"responses": map[string]interface{}{
+				strconv.Itoa(status): map[string]interface{}{"description": http.StatusText(status), "content": s.content(op.response)},
+				"default":            map[string]interface{}{"description": "Error", "content": s.content(ErrorResponse{})},
+			},
+		}
+		if len(params) != 0 {
+			operation["parameters"] = params
+		}
+		if op.request != nil {
+			operation["requestBody"] = map[string]interface{}{"required": !op.optionalRequest, "content": s.content(op.request)}
+		}
+		paths[op.path][strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "guide2goWEB API",
+			"description": "API of the guide2go web UI. With the web UI login, the API needs the session cookie or the API key.",
+			"version":     "1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": s.schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey":  map[string]string{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearer":  map[string]string{"type": "http", "scheme": "bearer"},
+				"session": map[string]string{"type": "apiKey", "in": "cookie", "name": sessionCookie},
+			},
+		},
+		"security": []map[string][]string{{"apiKey": {}}, {"bearer": {}}, {"session": {}}},
+	}
+}
+
+// specBuilder collects the schemas of the named types
+type specBuilder struct {
+	schemas map[string]interface{}
+}
+
+// content returns the content of a body: JSON with the schema of a Go value or another content type
+func (s *specBuilder) content(body interface{}) map[string]interface{} {
+	if contentType, ok := body.(string); ok {
+		return map[string]interface{}{contentType: map[string]interface{}{}}
+	}
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": s.schema(reflect.TypeOf(body))}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of a type as it is encoded by encoding/json, named structs are referenced
+func (s *specBuilder) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if _, ok := s.schemas[t.Name()]; !ok {
+			s.schemas[t.Name()] = nil // Placeholder against recursion
+			s.schemas[t.Name()] = s.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// object returns the schema of the exported fields of a struct, fields without omitempty are required
+func (s *specBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	s.fields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) != 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fields adds the fields of a struct to the properties, embedded structs are flattened like encoding/json does
+func (s *specBuilder) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && len(tag) == 0 {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			s.fields(embedded, properties, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if len(name) == 0 {
+			name = f.Name
+		}
+		properties[name] = s.schema(f.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// openAPIHandler returns the OpenAPI specification of the API
+func (h *handler) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, openAPISpec(apiOperations))
+}
+
+// apiDocsHandler renders the documentation of the OpenAPI specification, its script is embedded like the other pages
+func (h *handler) apiDocsHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "apidocs.html", nil)
+}
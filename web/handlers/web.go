@@ -1,45 +1,923 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
 	"net/http"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gorilla/mux"
+	"github.com/yourusername/guide2go/pkg/i18n"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+	"github.com/yourusername/guide2go/web"
+)
+
+// maxCacheImportSize limits the size of an uploaded cache export
+const maxCacheImportSize = 1 << 30
+
+// maxConfigSize limits the size of a saved configuration
+const maxConfigSize = 1 << 20
+
+// maxGridHours limits the hours of the guide grid
+const maxGridHours = 24
+
+// maxImages limits the images of a page of the artwork cache
+const maxImages = 500
+
+// maxProgramMatches limits the programs of a search of the cache browser
+const maxProgramMatches = 200
+
+// eventsPingInterval keeps the events WebSocket alive through proxies
+const eventsPingInterval = 30 * time.Second
+
+// Templates cache, every page is parsed together with the layout
+var templates = map[string]*template.Template{
+	"dashboard.html": parsePage("dashboard.html"),
+	"account.html":   parsePage("account.html"),
+	"config.html":    parsePage("config.html"),
+	"lineups.html":   parsePage("lineups.html"),
+	"channels.html":  parsePage("channels.html"),
+	"logs.html":      parsePage("logs.html"),
+	"jobs.html":      parsePage("jobs.html"),
+	"runs.html":      parsePage("runs.html"),
+	"login.html":     parsePage("login.html"),
+	"grid.html":      parsePage("grid.html"),
+	"images.html":    parsePage("images.html"),
+	"cache.html":     parsePage("cache.html"),
+	"apidocs.html":   parsePage("apidocs.html"),
+}
+
+// parsePage parses a page with the layout, the parsed templates are never executed themselves but cloned by render
+func parsePage(name string) *template.Template {
+	return template.Must(template.New("layout.html").Funcs(pageFuncs(themeLight, i18n.English)).ParseFS(web.Templates, "templates/layout.html", "templates/"+name))
+}
+
+// themeCookie is the name of the cookie with the theme of the browser, the toggle of the layout sets it
+const themeCookie = "guide2go_theme"
+
+// Themes of the web UI
+const (
+	themeLight = "light"
+	themeDark  = "dark"
 )
 
-// Templates cache
-var templates = template.Must(template.ParseGlob(filepath.Join("web", "templates", "*.html")))
+// theme returns the theme of the browser, light without the cookie
+func theme(r *http.Request) string {
+	if cookie, err := r.Cookie(themeCookie); err == nil && cookie.Value == themeDark {
+		return themeDark
+	}
+	return themeLight
+}
+
+// pageFuncs are the template functions of a page: the theme and the language, which the layout sets on the html element,
+// and t, which translates a message into the language
+func pageFuncs(theme, lang string) template.FuncMap {
+	return template.FuncMap{
+		"theme": func() string { return theme },
+		"lang":  func() string { return lang },
+		"t":     func(msg string) string { return i18n.Translate(lang, msg) },
+	}
+}
+
+// handler holds the dependencies of the web handlers
+type handler struct {
+	backend  Backend
+	sessions *sessions
+	csrf     string // CSRF token of the browsers without a session
+}
 
 // RegisterRoutes sets up the web routes and static file serving
-func RegisterRoutes(r *mux.Router) {
-	r.HandleFunc("/", dashboardHandler)
-	r.HandleFunc("/config", configHandler)
-	r.HandleFunc("/api/config", configAPIHandler).Methods("GET", "POST")
+func RegisterRoutes(r *mux.Router, backend Backend) {
+	csrf, err := randomToken()
+	if err != nil {
+		panic(err) // No randomness of the system, like the templates the web UI cannot start without it
+	}
+	h := &handler{backend: backend, sessions: newSessions(), csrf: csrf}
+
+	r.Use(h.authMiddleware)
+	r.HandleFunc("/login", h.loginHandler).Methods("GET", "POST")
+	r.HandleFunc("/logout", h.logoutHandler).Methods("POST")
+	r.HandleFunc("/", h.dashboardHandler)
+	r.HandleFunc("/account", h.accountHandler)
+	r.HandleFunc("/config", h.configHandler)
+	r.HandleFunc("/lineups", h.lineupsHandler)
+	r.HandleFunc("/channels", h.channelsHandler)
+	r.HandleFunc("/logs", h.logsHandler)
+	r.HandleFunc("/jobs", h.jobsHandler)
+	r.HandleFunc("/runs", h.runsHandler)
+	r.HandleFunc("/grid", h.gridHandler)
+	r.HandleFunc("/images", h.imagesHandler)
+	r.HandleFunc("/cache", h.cacheHandler)
+	r.HandleFunc("/api/config", h.configAPIHandler).Methods("GET", "POST")
+	r.HandleFunc("/api/config/preview", h.configPreviewAPIHandler).Methods("POST")
+	r.HandleFunc("/api/countries", h.countriesAPIHandler).Methods("GET")
+	r.HandleFunc("/api/headends", h.headendsAPIHandler).Methods("GET")
+	r.HandleFunc("/api/lineups", h.lineupsAPIHandler).Methods("GET", "POST")
+	r.HandleFunc("/api/lineups/{lineup}", h.lineupAPIHandler).Methods("PUT", "DELETE")
+	r.HandleFunc("/api/stations", h.stationsAPIHandler).Methods("GET", "POST")
+	r.HandleFunc("/api/channels/import", h.channelImportAPIHandler).Methods("POST")
+	r.HandleFunc("/api/status", h.statusAPIHandler).Methods("GET")
+	r.HandleFunc("/api/sd-status", h.sdStatusAPIHandler).Methods("GET")
+	r.HandleFunc("/api/guide", h.guideAPIHandler).Methods("GET")
+	r.HandleFunc("/api/logs", h.logsAPIHandler).Methods("GET")
+	r.HandleFunc("/api/events", h.eventsAPIHandler).Methods("GET")
+	r.HandleFunc("/api/jobs", h.jobsAPIHandler).Methods("GET", "POST")
+	r.HandleFunc("/api/dry-run", h.dryRunAPIHandler).Methods("GET")
+	r.HandleFunc("/api/runs", h.runsAPIHandler).Methods("GET")
+	r.HandleFunc("/api/runs/{id}/log", h.runLogHandler).Methods("GET")
+	r.HandleFunc("/api/configs", h.configsAPIHandler).Methods("GET", "POST")
+	r.HandleFunc("/api/grid", h.gridAPIHandler).Methods("GET")
+	r.HandleFunc("/api/programs/{id}", h.programAPIHandler).Methods("GET")
+	r.HandleFunc("/api/schedule", h.scheduleAPIHandler).Methods("GET", "POST")
+	r.HandleFunc("/api/cache/stats", h.cacheStatsAPIHandler).Methods("GET")
+	r.HandleFunc("/api/cache/export", h.cacheExportHandler).Methods("GET")
+	r.HandleFunc("/api/cache/import", h.cacheImportHandler).Methods("POST")
+	r.HandleFunc("/api/cache/programs", h.cacheProgramsAPIHandler).Methods("GET")
+	r.HandleFunc("/api/cache/entries/{id}", h.cacheEntriesAPIHandler).Methods("GET")
+	r.HandleFunc("/api/images", h.imagesAPIHandler).Methods("GET")
+	r.HandleFunc("/api/images/purge", h.imagesPurgeHandler).Methods("POST")
+	r.HandleFunc("/api/images/redownload", h.imagesRedownloadHandler).Methods("POST")
+	r.HandleFunc("/api/images/{name}", h.imageHandler).Methods("GET")
+	r.HandleFunc("/api/openapi.json", h.openAPIHandler).Methods("GET")
+	r.HandleFunc("/api/docs", h.apiDocsHandler).Methods("GET")
 
-	// Serve static files
-	staticDir := http.Dir("web/static")
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(staticDir)))
+	// Serve the embedded static files
+	static, _ := fs.Sub(web.Static, "static")
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.FS(static))))
 }
 
-// dashboardHandler renders the dashboard page
-func dashboardHandler(w http.ResponseWriter, r *http.Request) {
-	err := templates.ExecuteTemplate(w, "dashboard.html", nil)
+// render executes the layout of a page with the given data in the theme of the browser and the language of the configuration
+func (h *handler) render(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	page, ok := templates[name]
+	if !ok {
+		http.Error(w, "page not found", http.StatusNotFound)
+		return
+	}
+	tmpl, err := page.Clone()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.Funcs(pageFuncs(theme(r), h.backend.Language())).ExecuteTemplate(w, "layout.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON writes v as JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+// sdErrorStatus returns the HTTP status of an error of a Schedules Direct request: the lineup errors are
+// answered as errors of the request, other errors of Schedules Direct or the connection as bad gateway
+func sdErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, schedulesdirect.ErrOffline):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, schedulesdirect.ErrRateLimited):
+		return http.StatusTooManyRequests
+	}
+
+	var apiErr *schedulesdirect.APIError
+	if !errors.As(err, &apiErr) {
+		return http.StatusBadGateway
+	}
+	switch apiErr.Code {
+	case 2100, 2101, 2102: // LINEUP_NOT_FOUND, UNKNOWN_LINEUP, INVALID_LINEUP_DELETE
+		return http.StatusNotFound
+	case 2103, 2104, 2105, 2107: // LINEUP_WRONG_FORMAT, INVALID_LINEUP, LINEUP_DELETED, INVALID_COUNTRY
+		return http.StatusBadRequest
+	case 2055, 4101: // DUPLICATE_LINEUP, MAX_LINEUPS
+		return http.StatusConflict
+	case 4100: // MAX_LINEUP_CHANGES_REACHED
+		return http.StatusTooManyRequests
+	case 4001: // ACCOUNT_EXPIRED
+		return http.StatusForbidden
 	}
+	return http.StatusBadGateway
+}
+
+// dashboardHandler renders the dashboard page
+func (h *handler) dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Status *AccountStatus
+		Cache  *CacheStats
+		Guide  *GuideStatus
+		Error  string
+	}
+
+	status, err := h.backend.AccountStatus()
+	if err != nil {
+		data.Error = err.Error()
+	}
+	data.Status = status
+	data.Cache, _ = h.backend.CacheStats()
+	data.Guide, _ = h.backend.GuideStatus()
+
+	h.render(w, r, "dashboard.html", data)
+}
+
+// accountHandler renders the Schedules Direct account status
+func (h *handler) accountHandler(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Status *AccountStatus
+		Error  string
+	}
+
+	status, err := h.backend.AccountStatus()
+	if err != nil {
+		data.Error = err.Error()
+	}
+	data.Status = status
+
+	h.render(w, r, "account.html", data)
 }
 
 // configHandler renders the config page
-func configHandler(w http.ResponseWriter, r *http.Request) {
-	err := templates.ExecuteTemplate(w, "config.html", nil)
+func (h *handler) configHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "config.html", nil)
+}
+
+// configAPIHandler returns the configuration as JSON with GET and validates and saves it with POST
+func (h *handler) configAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		data, err := h.backend.LoadConfig()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigSize))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
 	}
+	if err := h.backend.SaveConfig(data); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Result{Message: "Configuration saved"})
 }
 
-// configAPIHandler handles GET/POST for config API
-func configAPIHandler(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement config load/save logic
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"message": "Config API placeholder"}`))
-} 
\ No newline at end of file
+// configPreviewAPIHandler validates the configuration and returns its changes as YAML diff without saving it
+func (h *handler) configPreviewAPIHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	preview, err := h.backend.PreviewConfig(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// lineupsHandler renders the lineup search page
+func (h *handler) lineupsHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "lineups.html", nil)
+}
+
+// countriesAPIHandler returns the countries with Schedules Direct lineups
+func (h *handler) countriesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	countries, err := h.backend.Countries()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, countries)
+}
+
+// headendsAPIHandler returns the headends and lineups of the parameters country and postalcode
+func (h *handler) headendsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	country, postalCode := r.URL.Query().Get("country"), r.URL.Query().Get("postalcode")
+	if len(country) == 0 || len(postalCode) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("country and postal code are required"))
+		return
+	}
+
+	headends, err := h.backend.Headends(country, postalCode)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, headends)
+}
+
+// lineupsAPIHandler returns the lineups of the account with GET and adds the lineup of {"lineup": "USA-NY31587-X"} with POST
+func (h *handler) lineupsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var req LineupRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigSize)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.addLineup(w, r, req.Lineup, http.StatusCreated)
+		return
+	}
+
+	status, err := h.backend.AccountStatus()
+	if err != nil {
+		writeError(w, sdErrorStatus(err), err)
+		return
+	}
+	lineups := status.Lineups
+	if lineups == nil {
+		lineups = []Lineup{}
+	}
+	writeJSON(w, http.StatusOK, lineups)
+}
+
+// lineupAPIHandler adds the lineup to the account with PUT and removes it with DELETE
+func (h *handler) lineupAPIHandler(w http.ResponseWriter, r *http.Request) {
+	lineup := mux.Vars(r)["lineup"]
+
+	if r.Method == http.MethodDelete {
+		if err := h.backend.RemoveLineup(r.Context(), lineup); err != nil {
+			writeError(w, sdErrorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, Result{Message: "Lineup removed", Lineup: lineup})
+		return
+	}
+
+	h.addLineup(w, r, lineup, http.StatusOK)
+}
+
+// addLineup adds the lineup to the account and answers with status
+func (h *handler) addLineup(w http.ResponseWriter, r *http.Request, lineup string, status int) {
+	if len(lineup) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("lineup is required"))
+		return
+	}
+	if err := h.backend.AddLineup(r.Context(), lineup); err != nil {
+		writeError(w, sdErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, status, Result{Message: "Lineup added", Lineup: lineup})
+}
+
+// channelsHandler renders the channel manager page
+func (h *handler) channelsHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "channels.html", nil)
+}
+
+// stationsAPIHandler returns the stations of the subscribed lineups with GET, the search parameter q filters them.
+// POST adds and removes stations of a lineup in the guide.
+func (h *handler) stationsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var change StationChange
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigSize)).Decode(&change); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := h.backend.SelectStations(change); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, Result{Message: "Channels saved"})
+		return
+	}
+
+	lineups, err := h.backend.Stations()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	if search := r.URL.Query().Get("q"); len(search) != 0 {
+		for i, lineup := range lineups {
+			var stations []Station
+			for _, station := range lineup.Stations {
+				if station.Matches(search) {
+					stations = append(stations, station)
+				}
+			}
+			lineups[i].Stations = stations
+		}
+	}
+	writeJSON(w, http.StatusOK, lineups)
+}
+
+// channelImportAPIHandler adds the stations of a CSV or JSON list to the guide, with the parameter replace=true they replace its channels
+func (h *handler) channelImportAPIHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	entries, err := parseChannelImport(r.Header.Get("Content-Type"), data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.backend.ImportStations(entries, r.URL.Query().Get("replace") == "true")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// parseChannelImport returns the entries of a channel import. JSON is a list of station IDs and callsigns or of
+// {"station": "...", "lineup": "..."}, CSV has the station ID or callsign and optionally the lineup in every row.
+// Empty rows, rows starting with # and a header row are skipped.
+func parseChannelImport(contentType string, data []byte) ([]ChannelImportEntry, error) {
+	var entries []ChannelImportEntry
+	if strings.Contains(contentType, "json") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("invalid JSON channel list: %w", err)
+		}
+		for i, raw := range list {
+			var entry ChannelImportEntry
+			if err := json.Unmarshal(raw, &entry.Station); err != nil {
+				if err := json.Unmarshal(raw, &entry); err != nil {
+					return nil, fmt.Errorf("invalid entry %d of the channel list: %w", i+1, err)
+				}
+			}
+			entries = append(entries, entry)
+		}
+	} else {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		reader.Comment = '#'
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV channel list: %w", err)
+		}
+		for i, record := range records {
+			if i == 0 {
+				switch strings.ToLower(record[0]) {
+				case "station", "station_id", "stationid", "callsign":
+					continue
+				}
+			}
+			entry := ChannelImportEntry{Station: record[0]}
+			if len(record) > 1 {
+				entry.Lineup = record[1]
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	var list []ChannelImportEntry
+	for _, entry := range entries {
+		entry.Station, entry.Lineup = strings.TrimSpace(entry.Station), strings.TrimSpace(entry.Lineup)
+		if len(entry.Station) != 0 {
+			list = append(list, entry)
+		}
+	}
+	if len(list) == 0 {
+		return nil, errors.New("the channel list is empty")
+	}
+	return list, nil
+}
+
+// logsHandler renders the live log page
+func (h *handler) logsHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "logs.html", nil)
+}
+
+// logsAPIHandler streams the log entries as server-sent events until the client disconnects
+func (h *handler) logsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	lines, unsubscribe := h.backend.SubscribeLogs()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			// Every line of a multi-line entry needs its own data field
+			if _, err := io.WriteString(w, "data: "+strings.ReplaceAll(line, "\n", "\ndata: ")+"\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// eventsAPIHandler pushes an event over WebSocket for every update which finishes until the browser disconnects
+func (h *handler) eventsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer ws.close()
+
+	jobs, unsubscribe := h.backend.SubscribeJobs()
+	defer unsubscribe()
+
+	ping := time.NewTicker(eventsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ws.closed:
+			return
+		case <-ping.C:
+			if err := ws.writeFrame(wsPing, nil); err != nil {
+				return
+			}
+		case job, ok := <-jobs:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(Event{Type: "job", Job: &job})
+			if err != nil {
+				return
+			}
+			if err := ws.writeText(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// jobsHandler renders the update page
+func (h *handler) jobsHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "jobs.html", nil)
+}
+
+// jobsAPIHandler returns the updates, newest first, with GET and starts an update with POST.
+// The update is of the selected configuration file unless the body names another one.
+func (h *handler) jobsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var req JobRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigSize)).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		job, err := h.backend.StartUpdate(req.Config)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.backend.Jobs())
+}
+
+// dryRunAPIHandler returns what an update of the selected configuration would download without downloading anything
+func (h *handler) dryRunAPIHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := h.backend.DryRun(r.Context())
+	if err != nil {
+		writeError(w, sdErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// runsHandler renders the run history page
+func (h *handler) runsHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "runs.html", nil)
+}
+
+// runsAPIHandler returns the run history of the selected configuration file, newest first
+func (h *handler) runsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.backend.Runs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// runLogHandler downloads the log of an update
+func (h *handler) runLogHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid run ID"))
+		return
+	}
+	name, err := h.backend.RunLog(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="guide2go_run_%d.log"`, id))
+	http.ServeFile(w, r, name)
+}
+
+// gridHandler renders the guide grid page
+func (h *handler) gridHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "grid.html", nil)
+}
+
+// gridAPIHandler returns the broadcasts of ?from=RFC 3339 time, default the current hour, for ?hours=, default 3
+func (h *handler) gridAPIHandler(w http.ResponseWriter, r *http.Request) {
+	from := time.Now().Truncate(time.Hour)
+	if v := r.URL.Query().Get("from"); len(v) != 0 {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		from = t
+	}
+
+	hours := 3
+	if v := r.URL.Query().Get("hours"); len(v) != 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxGridHours {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("hours must be between 1 and %d", maxGridHours))
+			return
+		}
+		hours = n
+	}
+
+	grid, err := h.backend.Grid(from, hours)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, grid)
+}
+
+// programAPIHandler returns the details of a cached program
+func (h *handler) programAPIHandler(w http.ResponseWriter, r *http.Request) {
+	program, err := h.backend.Program(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, program)
+}
+
+// cacheHandler renders the cache browser
+func (h *handler) cacheHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "cache.html", nil)
+}
+
+// cacheProgramsAPIHandler returns the cached programs whose program ID or title contains the parameter q
+func (h *handler) cacheProgramsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if len(query) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("search query is required"))
+		return
+	}
+
+	limit := 50
+	if v := q.Get("limit"); len(v) != 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxProgramMatches {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxProgramMatches))
+			return
+		}
+		limit = n
+	}
+
+	result, err := h.backend.SearchPrograms(query, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// cacheEntriesAPIHandler returns the raw cache entries of a program or station ID
+func (h *handler) cacheEntriesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.backend.CacheEntries(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// configsAPIHandler returns the configuration files with GET and selects one with POST
+func (h *handler) configsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var req ConfigSelection
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigSize)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := h.backend.SelectConfig(req.Path); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, h.backend.Configs())
+}
+
+// scheduleAPIHandler returns the update schedule with the next update with GET and changes it with POST
+func (h *handler) scheduleAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var schedule UpdateSchedule
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigSize)).Decode(&schedule); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := h.backend.SetUpdateSchedule(schedule); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	schedule, err := h.backend.UpdateSchedule()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+// statusAPIHandler returns the Schedules Direct account status with messages and notifications
+func (h *handler) statusAPIHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := h.backend.AccountStatus()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// sdStatusAPIHandler returns the polled Schedules Direct system status, a failed poll is part of the status
+func (h *handler) sdStatusAPIHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := h.backend.SDStatus()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// guideAPIHandler returns the configured channels, the result of the last update and the XMLTV file
+func (h *handler) guideAPIHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := h.backend.GuideStatus()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// cacheStatsAPIHandler returns the cache statistics with the hit ratio of the lookups
+func (h *handler) cacheStatsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.backend.CacheStats()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CacheStatsResponse{stats, stats.HitRatio()})
+}
+
+// cacheExportHandler downloads all entries of the cache as a gzip compressed JSON cache file
+func (h *handler) cacheExportHandler(w http.ResponseWriter, r *http.Request) {
+	out := &responseWriter{w: w, header: func() {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", `attachment; filename="guide2go_cache.json.gz"`)
+	}}
+
+	if err := h.backend.ExportCache(out); err != nil && !out.written {
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// cacheImportHandler adds the entries of an uploaded cache export to the cache
+func (h *handler) cacheImportHandler(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxCacheImportSize)
+
+	if err := h.backend.ImportCache(r.Context(), body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Result{Message: "Cache imported"})
+}
+
+// imagesHandler renders the artwork cache page
+func (h *handler) imagesHandler(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, "images.html", nil)
+}
+
+// imagesAPIHandler returns the disk usage of the images path and the images of ?status= from ?offset= on, at most ?limit=
+func (h *handler) imagesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	status := q.Get("status")
+	switch status {
+	case "", "referenced", "orphaned", "failed":
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown status %q", status))
+		return
+	}
+
+	offset, limit := 0, 100
+	if v := q.Get("offset"); len(v) != 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("offset must not be negative"))
+			return
+		}
+		offset = n
+	}
+	if v := q.Get("limit"); len(v) != 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxImages {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxImages))
+			return
+		}
+		limit = n
+	}
+
+	images, err := h.backend.ImageCache(status, offset, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, images)
+}
+
+// imageHandler serves an image of the images path as thumbnail of the artwork cache page
+func (h *handler) imageHandler(w http.ResponseWriter, r *http.Request) {
+	name, err := h.backend.ImagePath(mux.Vars(r)["name"])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	http.ServeFile(w, r, name)
+}
+
+// imagesPurgeHandler removes the images no artwork or logo of the cache refers to
+func (h *handler) imagesPurgeHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := h.backend.PurgeImages(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// imagesRedownloadHandler downloads the failed images again
+func (h *handler) imagesRedownloadHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := h.backend.RedownloadImages(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// responseWriter sets the headers of a download with the first write,
+// an error before it can still be sent as JSON response
+type responseWriter struct {
+	w       io.Writer
+	header  func()
+	written bool
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.written {
+		rw.header()
+		rw.written = true
+	}
+	return rw.w.Write(p)
+}
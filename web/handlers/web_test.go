@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/yourusername/guide2go/pkg/schedulesdirect"
+)
+
+// mockBackend implements the backend with the web UI login disabled, other methods are not called by the test
+type mockBackend struct {
+	Backend
+}
+
+func (mockBackend) WebLogin() (*WebLogin, error) {
+	return &WebLogin{}, nil
+}
+
+func (mockBackend) Language() string {
+	return "en"
+}
+
+// TestEmbeddedFiles runs in web/handlers, the templates and static files must not be read from the working directory
+func TestEmbeddedFiles(t *testing.T) {
+	r := mux.NewRouter()
+	RegisterRoutes(r, mockBackend{})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/config", "<html"},
+		{"/static/css/style.css", ".card"},
+		{"/static/js/images.js", "/api/images"},
+		{"/api/docs", "/static/js/apidocs.js"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("GET %s = %d, want %d with %q", tt.path, w.Code, http.StatusOK, tt.want)
+		}
+	}
+}
+
+// lineupBackend answers the lineup calls like Schedules Direct with one lineup in the account
+type lineupBackend struct {
+	mockBackend
+}
+
+func (lineupBackend) AccountStatus() (*AccountStatus, error) {
+	return &AccountStatus{Lineups: []Lineup{{Lineup: "USA-NY31587-X", Name: "Cable"}}}, nil
+}
+
+func (lineupBackend) AddLineup(ctx context.Context, lineup string) error {
+	if lineup == "USA-NY31587-X" {
+		return schedulesdirect.NewAPIError(2055, "duplicate lineup")
+	}
+	return nil
+}
+
+func (lineupBackend) RemoveLineup(ctx context.Context, lineup string) error {
+	if lineup != "USA-NY31587-X" {
+		return fmt.Errorf("failed to change lineup: %w", schedulesdirect.NewAPIError(2102, "not in the account"))
+	}
+	return nil
+}
+
+func TestTheme(t *testing.T) {
+	r := mux.NewRouter()
+	RegisterRoutes(r, mockBackend{})
+
+	for cookie, want := range map[string]string{"": themeLight, themeDark: themeDark, "invalid": themeLight} {
+		req := httptest.NewRequest(http.MethodGet, "/runs", nil)
+		if len(cookie) != 0 {
+			req.AddCookie(&http.Cookie{Name: themeCookie, Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if !strings.Contains(w.Body.String(), `data-theme="`+want+`"`) {
+			t.Errorf("page with theme cookie %q is not rendered in the %s theme", cookie, want)
+		}
+	}
+}
+
+func TestLineupsAPI(t *testing.T) {
+	r := mux.NewRouter()
+	RegisterRoutes(r, lineupBackend{})
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/api/lineups", "", http.StatusOK},
+		{http.MethodPost, "/api/lineups", `{"lineup": "USA-OTA-10001"}`, http.StatusCreated},
+		{http.MethodPost, "/api/lineups", `{"lineup": "USA-NY31587-X"}`, http.StatusConflict},
+		{http.MethodPost, "/api/lineups", `{}`, http.StatusBadRequest},
+		{http.MethodDelete, "/api/lineups/USA-NY31587-X", "", http.StatusOK},
+		{http.MethodDelete, "/api/lineups/USA-OTA-10001", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.want {
+			t.Errorf("%s %s %s = %d, want %d", tt.method, tt.path, tt.body, w.Code, tt.want)
+		}
+	}
+}
+
+func TestSDErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{schedulesdirect.NewAPIError(3000, "offline"), http.StatusServiceUnavailable},
+		{schedulesdirect.NewAPIError(4101, "max lineups"), http.StatusConflict},
+		{schedulesdirect.NewAPIError(2103, "wrong format"), http.StatusBadRequest},
+		{schedulesdirect.NewAPIError(4003, "invalid user"), http.StatusBadGateway},
+		{errors.New("connection refused"), http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		if got := sdErrorStatus(tt.err); got != tt.want {
+			t.Errorf("sdErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestOpenAPISpec checks the operations of the OpenAPI specification against the registered API routes
+func TestOpenAPISpec(t *testing.T) {
+	r := mux.NewRouter()
+	RegisterRoutes(r, mockBackend{})
+
+	routes := make(map[string]bool)
+	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		if strings.HasPrefix(path, "/api/") {
+			for _, method := range methods {
+				routes[method+" "+path] = true
+			}
+		}
+		return nil
+	})
+
+	documented := make(map[string]bool)
+	for _, op := range apiOperations {
+		documented[op.method+" "+op.path] = true
+		if !routes[op.method+" "+op.path] {
+			t.Errorf("%s %s is documented but not registered", op.method, op.path)
+		}
+	}
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("%s is registered but not documented", route)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	var spec struct {
+		Paths      map[string]map[string]interface{}
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{}
+			}
+		}
+	}
+	if err := json.NewDecoder(w.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Paths["/api/lineups/{lineup}"]["delete"]; !ok {
+		t.Error("DELETE /api/lineups/{lineup} is missing in the specification")
+	}
+	// The embedded cache statistics are flattened like encoding/json does
+	if stats := spec.Components.Schemas["CacheStatsResponse"].Properties; stats["hit_ratio"] == nil || stats["programs"] == nil {
+		t.Errorf("CacheStatsResponse properties = %v, want hit_ratio and programs", stats)
+	}
+}
+
+func TestParseChannelImport(t *testing.T) {
+	tests := []struct {
+		contentType, data string
+		want              []ChannelImportEntry
+	}{
+		{"text/csv", "station,lineup\n10021,USA-OTA-10001\n# comment\n\n WCBS \n", []ChannelImportEntry{{"10021", "USA-OTA-10001"}, {"WCBS", ""}}},
+		{"application/json", `["10021", {"station": "WCBS", "lineup": "USA-OTA-10001"}]`, []ChannelImportEntry{{"10021", ""}, {"WCBS", "USA-OTA-10001"}}},
+		{"application/octet-stream", ` ["ESPN"]`, []ChannelImportEntry{{"ESPN", ""}}},
+	}
+	for _, tt := range tests {
+		entries, err := parseChannelImport(tt.contentType, []byte(tt.data))
+		if err != nil {
+			t.Errorf("parseChannelImport(%q) failed: %v", tt.data, err)
+			continue
+		}
+		if fmt.Sprint(entries) != fmt.Sprint(tt.want) {
+			t.Errorf("parseChannelImport(%q) = %v, want %v", tt.data, entries, tt.want)
+		}
+	}
+
+	for _, data := range []string{"", "station\n", "[1]", `{"station": "WCBS"}`} {
+		if _, err := parseChannelImport("", []byte(data)); err == nil {
+			t.Errorf("parseChannelImport(%q) succeeded", data)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The server side of RFC 6455 the events need: text messages to the browser, ping and close.
+// Messages of the browser are read and dropped, the connection only pushes.
+
+// webSocketGUID is appended to the key of the handshake
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of the frames
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// maxWebSocketFrame limits the frames of the browser, it only sends control frames
+const maxWebSocketFrame = 1 << 16
+
+// webSocketWriteTimeout ends a connection whose browser does not read
+const webSocketWriteTimeout = 10 * time.Second
+
+// webSocket is an upgraded connection
+type webSocket struct {
+	conn   net.Conn
+	rw     *bufio.ReadWriter
+	mu     sync.Mutex // Writes of the handler and of the read loop
+	closed chan struct{}
+}
+
+// upgradeWebSocket answers the WebSocket handshake of the request and takes over the connection.
+// Only pages of the same host may connect, the session cookie would otherwise authenticate any page.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*webSocket, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, errors.New("websocket upgrade required")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if len(key) == 0 {
+		return nil, errors.New("websocket key missing")
+	}
+	if origin := r.Header.Get("Origin"); len(origin) != 0 {
+		if u, err := url.Parse(origin); err != nil || !strings.EqualFold(u.Host, r.Host) {
+			return nil, errors.New("websocket origin not allowed")
+		}
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("websocket is not supported")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// The timeouts of the server would end the connection, the writes set their own deadline
+	conn.SetDeadline(time.Time{})
+
+	hash := sha1.Sum([]byte(key + webSocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " +
+		base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ws := &webSocket{conn: conn, rw: rw, closed: make(chan struct{})}
+	go ws.readLoop()
+	return ws, nil
+}
+
+// headerContains reports whether a comma separated header contains the token
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeFrame writes an unmasked frame with the opcode
+func (ws *webSocket) writeFrame(opcode byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	ws.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+	ws.rw.Write(header)
+	ws.rw.Write(payload)
+	return ws.rw.Flush()
+}
+
+// writeText sends a text message
+func (ws *webSocket) writeText(data []byte) error {
+	return ws.writeFrame(wsText, data)
+}
+
+// readLoop answers the pings and the close of the browser, the channel closed is closed when the connection ends
+func (ws *webSocket) readLoop() {
+	defer close(ws.closed)
+
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsPing:
+			if ws.writeFrame(wsPong, payload) != nil {
+				return
+			}
+		case wsClose:
+			ws.writeFrame(wsClose, nil)
+			return
+		}
+	}
+}
+
+// readFrame reads a frame of the browser, its frames are masked
+func (ws *webSocket) readFrame() (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.rw, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var n [2]byte
+		if _, err := io.ReadFull(ws.rw, n[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(n[:]))
+	case 127:
+		var n [8]byte
+		if _, err := io.ReadFull(ws.rw, n[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(n[:])
+	}
+	if length > maxWebSocketFrame {
+		return 0, nil, errors.New("websocket frame too large")
+	}
+
+	var mask [4]byte
+	if header[1]&0x80 != 0 {
+		if _, err := io.ReadFull(ws.rw, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return header[0] & 0x0F, payload, nil
+}
+
+// close sends the close frame and ends the connection
+func (ws *webSocket) close() {
+	ws.writeFrame(wsClose, nil)
+	ws.conn.Close()
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// eventsBackend passes the jobs of the test to the events
+type eventsBackend struct {
+	mockBackend
+	jobs chan Job
+}
+
+func (b eventsBackend) SubscribeJobs() (<-chan Job, func()) {
+	return b.jobs, func() {}
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	backend := eventsBackend{jobs: make(chan Job)}
+	r := mux.NewRouter()
+	RegisterRoutes(r, backend)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	// A page of another host must not connect with the session cookie of the browser
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/events", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Origin", "http://example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Cross-origin handshake = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /api/events HTTP/1.1\r\nHost: "+host+"\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nOrigin: http://"+host+"\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err = http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The accept value of the example handshake of RFC 6455
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Handshake = %d %v, want 101 with the accept key", resp.StatusCode, resp.Header)
+	}
+
+	backend.jobs <- Job{ID: 3, Config: "cable", State: "done"}
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != 0x80|wsText || header[1]&0x80 != 0 {
+		t.Fatalf("Frame header = %x, want an unmasked final text frame", header)
+	}
+	length := int(header[1])
+	if length == 126 {
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(br, ext); err != nil {
+			t.Fatal(err)
+		}
+		length = int(binary.BigEndian.Uint16(ext))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "job" || event.Job == nil || event.Job.ID != 3 || event.Job.State != "done" {
+		t.Errorf("Event = %s, want the finished job", payload)
+	}
+}
@@ -0,0 +1,53 @@
+// Shows the banner of the layout when the Schedules Direct account expires within the warning days.
+// The status is requested once per hour of a browser session, not with every page.
+(function () {
+    var banner = document.getElementById("account-warning");
+    if (!banner || location.pathname === "/login") {
+        return;
+    }
+
+    var key = "guide2go-account";
+    var maxAge = 60 * 60 * 1000;
+
+    function show(status) {
+        if (!status.expiry_warning) {
+            banner.hidden = true;
+            return;
+        }
+        var expires = new Date(status.expires).toLocaleDateString();
+        banner.textContent = status.days_left < 0
+            ? "The Schedules Direct account expired on " + expires + ", updates fail until it is renewed. "
+            : "The Schedules Direct account expires on " + expires + " (" + status.days_left + " days). ";
+        var link = document.createElement("a");
+        link.href = "/account";
+        link.textContent = "Account status";
+        banner.appendChild(link);
+        banner.hidden = false;
+    }
+
+    try {
+        var cached = JSON.parse(sessionStorage.getItem(key));
+        if (cached && Date.now() - cached.fetched < maxAge) {
+            show(cached);
+            return;
+        }
+    } catch (e) {
+        // Storage disabled or invalid entry, the status is requested
+    }
+
+    fetch("/api/status")
+        .then(function (resp) { return resp.ok ? resp.json() : null; })
+        .then(function (status) {
+            if (!status) {
+                return;
+            }
+            var entry = { expires: status.expires, days_left: status.days_left, expiry_warning: status.expiry_warning, fetched: Date.now() };
+            try {
+                sessionStorage.setItem(key, JSON.stringify(entry));
+            } catch (e) {
+                // Not cached, the next page requests it again
+            }
+            show(entry);
+        })
+        .catch(function () {});
+})();
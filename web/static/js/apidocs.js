@@ -0,0 +1,137 @@
+// Lists the operations of /api/openapi.json with their parameters and schemas, every operation can be sent with the session of the browser.
+// The page is embedded like the other scripts, it loads nothing from other origins.
+(function () {
+    var list = document.getElementById("apidocs");
+    var message = document.getElementById("apidocs-message");
+    var methods = ["get", "post", "put", "delete"];
+
+    function element(tag, text, parent) {
+        var e = document.createElement(tag);
+        if (text !== undefined) {
+            e.textContent = text;
+        }
+        if (parent) {
+            parent.appendChild(e);
+        }
+        return e;
+    }
+
+    // describe returns the type of a schema, named schemas are shown by name
+    function describe(schema) {
+        if (!schema) {
+            return "";
+        }
+        if (schema.$ref) {
+            return schema.$ref.split("/").pop();
+        }
+        if (schema.type === "array") {
+            return describe(schema.items) + "[]";
+        }
+        if (schema.type === "object" && schema.additionalProperties) {
+            return "map of " + describe(schema.additionalProperties);
+        }
+        return schema.format ? schema.type + " (" + schema.format + ")" : schema.type || "any";
+    }
+
+    function content(body) {
+        var types = Object.keys((body && body.content) || {});
+        if (!types.length) {
+            return "";
+        }
+        var schema = body.content[types[0]].schema;
+        return schema ? describe(schema) : types[0];
+    }
+
+    function schemas(spec, parent) {
+        var all = (spec.components && spec.components.schemas) || {};
+        Object.keys(all).sort().forEach(function (name) {
+            var details = element("details", undefined, parent);
+            details.className = "card";
+            details.id = "schema-" + name;
+            element("summary", name, details);
+            var fields = element("ul", undefined, details);
+            var required = all[name].required || [];
+            Object.keys(all[name].properties || {}).forEach(function (field) {
+                var optional = required.indexOf(field) < 0 ? ", optional" : "";
+                element("li", field + ": " + describe(all[name].properties[field]) + optional, fields);
+            });
+        });
+    }
+
+    function operation(path, method, op, parent) {
+        var details = element("details", undefined, parent);
+        details.className = "card apidocs-operation";
+        element("summary", method.toUpperCase() + " " + path + " - " + (op.summary || ""), details);
+
+        var inputs = {};
+        (op.parameters || []).forEach(function (p) {
+            var label = element("label", p.name + (p.required ? " *" : "") + " ", details);
+            inputs[p.name] = element("input", undefined, label);
+            inputs[p.name].placeholder = p.description || p.in;
+            inputs[p.name].dataset.in = p.in;
+        });
+
+        var body;
+        if (op.requestBody) {
+            element("p", "Request: " + content(op.requestBody), details);
+            body = element("textarea", undefined, details);
+            body.rows = 6;
+        }
+        Object.keys(op.responses || {}).forEach(function (status) {
+            element("p", "Response " + status + ": " + content(op.responses[status]), details);
+        });
+
+        var send = element("button", "Send", details);
+        send.type = "button";
+        var result = element("pre", undefined, details);
+        send.addEventListener("click", function () {
+            var url = path;
+            var query = new URLSearchParams();
+            Object.keys(inputs).forEach(function (name) {
+                var value = inputs[name].value;
+                if (inputs[name].dataset.in === "path") {
+                    url = url.replace("{" + name + "}", encodeURIComponent(value));
+                } else if (value) {
+                    query.set(name, value);
+                }
+            });
+            if (query.toString()) {
+                url += "?" + query.toString();
+            }
+
+            var options = { method: method.toUpperCase() };
+            if (body && body.value) {
+                options.body = body.value;
+                options.headers = { "Content-Type": Object.keys(op.requestBody.content)[0] };
+            }
+            result.textContent = "...";
+            fetch(url, options).then(function (resp) {
+                return resp.text().then(function (text) {
+                    result.textContent = resp.status + " " + resp.statusText + "\n" + text;
+                });
+            }).catch(function (err) {
+                result.textContent = err.message;
+            });
+        });
+    }
+
+    fetch("/api/openapi.json").then(function (resp) {
+        if (!resp.ok) {
+            throw new Error("HTTP " + resp.status);
+        }
+        return resp.json();
+    }).then(function (spec) {
+        message.textContent = spec.info.title + " " + spec.info.version;
+        Object.keys(spec.paths).sort().forEach(function (path) {
+            methods.forEach(function (method) {
+                if (spec.paths[path][method]) {
+                    operation(path, method, spec.paths[path][method], list);
+                }
+            });
+        });
+        element("h2", "Schemas", list);
+        schemas(spec, list);
+    }).catch(function (err) {
+        message.textContent = "Failed to load the specification: " + err.message;
+    });
+})();
@@ -0,0 +1,79 @@
+// Searches the cached programs of /api/cache/programs, a click on a program or a station ID shows its raw entries of /api/cache/entries.
+(function () {
+    var query = document.getElementById("cache-query");
+    var rows = document.querySelector("#cache-programs tbody");
+    var count = document.getElementById("cache-count");
+    var message = document.getElementById("cache-message");
+    var entry = document.getElementById("cache-entry");
+
+    function fetchJSON(url) {
+        return fetch(url)
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data && data.error) {
+                    throw new Error(data.error);
+                }
+                message.hidden = true;
+                return data;
+            });
+    }
+
+    function fail(err) {
+        message.textContent = err.message;
+        message.hidden = false;
+    }
+
+    function cell(row, text) {
+        var td = document.createElement("td");
+        td.textContent = text;
+        row.appendChild(td);
+        return td;
+    }
+
+    function show(id) {
+        fetchJSON("/api/cache/entries/" + encodeURIComponent(id))
+            .then(function (data) {
+                document.getElementById("cache-entry-id").textContent = id;
+                document.getElementById("cache-entry-json").textContent = JSON.stringify(data, null, 2);
+                var details = document.getElementById("cache-entry-details");
+                details.href = "/api/programs/" + encodeURIComponent(id);
+                details.hidden = !data.program;
+                entry.hidden = false;
+            })
+            .catch(fail);
+    }
+
+    function render(result) {
+        rows.textContent = "";
+        result.programs.forEach(function (p) {
+            var row = document.createElement("tr");
+            var link = document.createElement("a");
+            link.href = "#" + p.program_id;
+            link.textContent = p.program_id;
+            link.addEventListener("click", function (event) {
+                event.preventDefault();
+                show(p.program_id);
+            });
+            cell(row, "").appendChild(link);
+            cell(row, p.title + (p.sub_title ? ": " + p.sub_title : ""));
+            cell(row, p.show_type || "");
+            cell(row, p.scheduled ? "yes" : "no");
+            rows.appendChild(row);
+        });
+        count.textContent = result.programs.length + (result.more ? "+" : "") + " programs";
+    }
+
+    document.getElementById("cache-search").addEventListener("submit", function (event) {
+        event.preventDefault();
+        var q = query.value.trim();
+        fetchJSON("/api/cache/programs?q=" + encodeURIComponent(q))
+            .then(function (result) {
+                render(result);
+                // A station ID or a program ID shows its entries at once
+                if (/^\d+$/.test(q) || (result.programs.length === 1 && result.programs[0].program_id === q.toUpperCase())) {
+                    show(/^\d+$/.test(q) ? q : result.programs[0].program_id);
+                }
+            })
+            .catch(fail);
+    });
+})();
@@ -0,0 +1,140 @@
+// Lists the stations of /api/stations by lineup and saves the added and removed stations of a lineup at once.
+(function () {
+    var lineups = document.getElementById("channels-lineups");
+    var search = document.getElementById("channels-search");
+    var message = document.getElementById("channels-message");
+
+    function show(text, error) {
+        message.textContent = text;
+        message.className = error ? "card error" : "card";
+        message.hidden = false;
+    }
+
+    function matches(station, text) {
+        text = text.toLowerCase();
+        return [station.name, station.callsign, station.station_id, station.channel].some(function (value) {
+            return (value || "").toLowerCase().indexOf(text) !== -1;
+        });
+    }
+
+    function filter() {
+        lineups.querySelectorAll("tr[data-station]").forEach(function (row) {
+            row.hidden = !matches(JSON.parse(row.dataset.station), search.value);
+        });
+    }
+
+    function renderLineup(lineup) {
+        var section = document.createElement("section");
+        var heading = document.createElement("h2");
+        heading.textContent = lineup.name + " [" + lineup.lineup + "]";
+        section.appendChild(heading);
+
+        var buttons = document.createElement("p");
+        buttons.className = "admin-only";
+        [["Select all", true], ["Select none", false]].forEach(function (b) {
+            var button = document.createElement("button");
+            button.type = "button";
+            button.textContent = b[0];
+            button.addEventListener("click", function () {
+                section.querySelectorAll("tr:not([hidden]) input").forEach(function (input) { input.checked = b[1]; });
+            });
+            buttons.appendChild(button);
+        });
+        var save = document.createElement("button");
+        save.type = "button";
+        save.textContent = "Save";
+        buttons.appendChild(save);
+        section.appendChild(buttons);
+
+        var table = document.createElement("table");
+        (lineup.stations || []).forEach(function (station) {
+            var row = table.insertRow();
+            row.dataset.station = JSON.stringify(station);
+            var input = document.createElement("input");
+            input.type = "checkbox";
+            input.checked = station.selected;
+            input.disabled = window.readOnly;
+            input.value = station.station_id;
+            row.insertCell().appendChild(input);
+            [station.channel, station.name, station.callsign, station.station_id, (station.languages || []).join(", ")].forEach(function (value) {
+                row.insertCell().textContent = value || "";
+            });
+        });
+        section.appendChild(table);
+
+        save.addEventListener("click", function () {
+            var change = { lineup: lineup.lineup, add: [], remove: [] };
+            table.querySelectorAll("tr").forEach(function (row) {
+                var station = JSON.parse(row.dataset.station);
+                var checked = row.querySelector("input").checked;
+                if (checked && !station.selected) {
+                    change.add.push(station.station_id);
+                } else if (!checked && station.selected) {
+                    change.remove.push(station.station_id);
+                }
+            });
+
+            fetch("/api/stations", { method: "POST", headers: { "Content-Type": "application/json" }, body: JSON.stringify(change) })
+                .then(function (resp) { return resp.json(); })
+                .then(function (data) {
+                    show(data.error || data.message, !!data.error);
+                    if (!data.error) {
+                        load();
+                    }
+                })
+                .catch(function (err) { show("Failed to save channels: " + err.message, true); });
+        });
+
+        return section;
+    }
+
+    function load() {
+        fetch("/api/stations")
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data.error) {
+                    throw new Error(data.error);
+                }
+                lineups.textContent = "";
+                (data || []).forEach(function (lineup) { lineups.appendChild(renderLineup(lineup)); });
+                filter();
+            })
+            .catch(function (err) { show("Failed to load stations: " + err.message, true); });
+    }
+
+    // Uploads the file as it is, the server detects CSV or JSON
+    document.getElementById("channels-import").addEventListener("submit", function (event) {
+        event.preventDefault();
+        var file = document.getElementById("channels-file").files[0];
+        var replace = document.getElementById("channels-replace").checked;
+        if (replace && !confirm("Replace the channels of the guide with the imported stations?")) {
+            return;
+        }
+
+        var type = /\.json$/i.test(file.name) ? "application/json" : "text/csv";
+        fetch("/api/channels/import?replace=" + replace, { method: "POST", headers: { "Content-Type": type }, body: file })
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data.error) {
+                    throw new Error(data.error);
+                }
+                var results = document.getElementById("channels-import-results");
+                results.textContent = "";
+                data.results.forEach(function (r) {
+                    var li = document.createElement("li");
+                    li.textContent = r.entry + ": " + r.status + (r.station_id ? " " + r.name + " [" + r.station_id + "] of " + r.lineup : "");
+                    if (r.status === "not found") {
+                        li.className = "error";
+                    }
+                    results.appendChild(li);
+                });
+                results.hidden = false;
+                show(data.added + " channels added" + (replace ? ", " + data.removed + " removed" : "") + ", " + data.channels + " channels in the guide", false);
+                load();
+            })
+            .catch(function (err) { show("Failed to import channels: " + err.message, true); });
+    });
+
+    search.addEventListener("input", filter);
+    load();
+})();
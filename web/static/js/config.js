@@ -0,0 +1,164 @@
+// Renders the configuration of /api/config as form and saves the changed values.
+// Objects become fieldsets, lists and maps are edited as JSON. The changes are shown as YAML diff and only saved after the confirmation.
+(function () {
+    var form = document.getElementById("config-form");
+    var fields = document.getElementById("config-fields");
+    var message = document.getElementById("config-message");
+    var preview = document.getElementById("config-preview");
+    var config = {};
+
+    function show(text, error) {
+        message.textContent = text;
+        message.className = error ? "card error" : "card";
+        message.hidden = false;
+    }
+
+    function render(parent, value, path) {
+        Object.keys(value).forEach(function (key) {
+            var v = value[key];
+            var name = path.concat(key).join(".");
+
+            if (v !== null && typeof v === "object" && !Array.isArray(v) && key !== "category_mapping" && key !== "artwork_policies" && key !== "broadcast_flags") {
+                var fieldset = document.createElement("fieldset");
+                var legend = document.createElement("legend");
+                legend.textContent = key;
+                fieldset.appendChild(legend);
+                render(fieldset, v, path.concat(key));
+                parent.appendChild(fieldset);
+                return;
+            }
+
+            var label = document.createElement("label");
+            label.textContent = key;
+            var input;
+            if (typeof v === "boolean") {
+                input = document.createElement("input");
+                input.type = "checkbox";
+                input.checked = v;
+            } else if (typeof v === "number") {
+                input = document.createElement("input");
+                input.type = "number";
+                input.value = v;
+            } else if (typeof v === "string") {
+                input = document.createElement("input");
+                input.type = key === "password" || key === "api_key" ? "password" : "text";
+                input.value = v;
+            } else {
+                input = document.createElement("textarea");
+                input.rows = 4;
+                input.value = JSON.stringify(v, null, 2);
+            }
+            input.name = name;
+            label.appendChild(input);
+            parent.appendChild(label);
+        });
+    }
+
+    function collect(value, path) {
+        Object.keys(value).forEach(function (key) {
+            var input = form.elements[path.concat(key).join(".")];
+            if (!input) {
+                if (value[key] !== null && typeof value[key] === "object") {
+                    collect(value[key], path.concat(key));
+                }
+                return;
+            }
+
+            if (input.type === "checkbox") {
+                value[key] = input.checked;
+            } else if (input.type === "number") {
+                value[key] = Number(input.value);
+            } else if (input.tagName === "TEXTAREA") {
+                value[key] = JSON.parse(input.value);
+            } else {
+                value[key] = input.value;
+            }
+        });
+    }
+
+    fetch("/api/config")
+        .then(function (resp) { return resp.json(); })
+        .then(function (data) {
+            if (data.error) {
+                throw new Error(data.error);
+            }
+            config = data;
+            fields.textContent = "";
+            render(fields, config, []);
+        })
+        .catch(function (err) { show("Failed to load configuration: " + err.message, true); });
+
+    function post(path, body) {
+        return fetch(path, { method: "POST", headers: { "Content-Type": "application/json" }, body: body })
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data.error) {
+                    throw new Error(data.error);
+                }
+                return data;
+            });
+    }
+
+    // Shows the diff, the body is saved as it was previewed
+    function showPreview(data, body) {
+        var diff = document.getElementById("config-diff");
+        diff.textContent = "";
+        data.diff.split("\n").forEach(function (line) {
+            var span = document.createElement("span");
+            span.textContent = line + "\n";
+            if (line.charAt(0) === "+" && line.indexOf("+++") !== 0) {
+                span.className = "added";
+            } else if (line.charAt(0) === "-" && line.indexOf("---") !== 0) {
+                span.className = "removed";
+            } else if (line.indexOf("@@") === 0) {
+                span.className = "hunk";
+            }
+            diff.appendChild(span);
+        });
+
+        var summary = document.getElementById("config-summary");
+        summary.textContent = data.changes + " changed lines, " + data.stations + " channels.";
+        summary.className = data.removed_stations ? "error" : "";
+        if (data.removed_stations) {
+            summary.textContent += " " + data.removed_stations + " channels are removed!";
+        }
+
+        document.getElementById("config-confirm").onclick = function () {
+            post("/api/config", body)
+                .then(function (result) {
+                    preview.hidden = true;
+                    show(result.message, false);
+                })
+                .catch(function (err) { show("Failed to save configuration: " + err.message, true); });
+        };
+        preview.hidden = false;
+        preview.scrollIntoView();
+    }
+
+    document.getElementById("config-cancel").addEventListener("click", function () {
+        preview.hidden = true;
+    });
+
+    form.addEventListener("submit", function (event) {
+        event.preventDefault();
+        try {
+            collect(config, []);
+        } catch (err) {
+            show("Invalid JSON: " + err.message, true);
+            return;
+        }
+
+        var body = JSON.stringify(config);
+        post("/api/config/preview", body)
+            .then(function (data) {
+                if (!data.changes) {
+                    preview.hidden = true;
+                    show("No changes", false);
+                    return;
+                }
+                message.hidden = true;
+                showPreview(data, body);
+            })
+            .catch(function (err) { show("Failed to check configuration: " + err.message, true); });
+    });
+})();
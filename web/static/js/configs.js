@@ -0,0 +1,32 @@
+// Switches the configuration file of the web UI with /api/configs, the select is only shown with several files.
+(function () {
+    var select = document.getElementById("config-select");
+
+    function configs(method, body) {
+        return fetch("/api/configs", { method: method, headers: { "Content-Type": "application/json" }, body: body })
+            .then(function (resp) { return resp.json(); });
+    }
+
+    configs("GET").then(function (list) {
+        if (!Array.isArray(list) || list.length < 2) {
+            return;
+        }
+        list.forEach(function (config) {
+            var option = document.createElement("option");
+            option.value = config.path;
+            option.textContent = config.name;
+            option.selected = config.selected;
+            select.appendChild(option);
+        });
+        select.hidden = false;
+    });
+
+    select.addEventListener("change", function () {
+        configs("POST", JSON.stringify({ path: select.value })).then(function (data) {
+            if (data && data.error) {
+                alert(data.error);
+            }
+            location.reload();
+        });
+    });
+})();
@@ -0,0 +1,35 @@
+// Sends the CSRF token of the cookie guide2go_csrf with every change: as header X-CSRF-Token of fetch and as field csrf_token of forms.
+// It is loaded before the other scripts of the pages, they use fetch as before.
+(function () {
+    function token() {
+        var match = document.cookie.match(/(?:^|;\s*)guide2go_csrf=([^;]*)/);
+        return match ? decodeURIComponent(match[1]) : "";
+    }
+
+    var fetch = window.fetch;
+    window.fetch = function (resource, options) {
+        options = options || {};
+        var method = (options.method || "GET").toUpperCase();
+        if (method !== "GET" && method !== "HEAD") {
+            var headers = new Headers(options.headers || {});
+            headers.set("X-CSRF-Token", token());
+            options = Object.assign({}, options, { headers: headers });
+        }
+        return fetch.call(window, resource, options);
+    };
+
+    document.addEventListener("submit", function (event) {
+        var form = event.target;
+        if (form.method.toLowerCase() !== "post") {
+            return;
+        }
+        var field = form.querySelector("input[name=csrf_token]");
+        if (!field) {
+            field = document.createElement("input");
+            field.type = "hidden";
+            field.name = "csrf_token";
+            form.appendChild(field);
+        }
+        field.value = token();
+    }, true);
+})();
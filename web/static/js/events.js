@@ -0,0 +1,62 @@
+// Receives the events of /api/events: a toast for every update which finishes, the dashboard reloads its guide and cache cards.
+// The connection is opened again after it ends, e.g. by a restart of guide2go.
+(function () {
+    if (location.pathname === "/login") {
+        return;
+    }
+
+    var toasts = document.createElement("div");
+    toasts.id = "toasts";
+    document.body.appendChild(toasts);
+
+    function toast(text, error) {
+        var t = document.createElement("div");
+        t.className = "toast" + (error ? " error" : "");
+        t.textContent = text;
+        toasts.appendChild(t);
+        setTimeout(function () { toasts.removeChild(t); }, 8000);
+    }
+
+    // The cards are rendered by the server, they are taken from the dashboard page
+    function refreshDashboard() {
+        var sections = ["dashboard-guide", "dashboard-cache"].filter(function (id) { return document.getElementById(id); });
+        if (!sections.length) {
+            return;
+        }
+        fetch("/")
+            .then(function (resp) { return resp.text(); })
+            .then(function (html) {
+                var page = new DOMParser().parseFromString(html, "text/html");
+                sections.forEach(function (id) {
+                    var section = page.getElementById(id);
+                    if (section) {
+                        document.getElementById(id).innerHTML = section.innerHTML;
+                    }
+                });
+            });
+    }
+
+    function connect(delay) {
+        var ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/api/events");
+        ws.onopen = function () { delay = 1000; };
+        ws.onmessage = function (msg) {
+            var event = JSON.parse(msg.data);
+            if (event.type !== "job") {
+                return;
+            }
+            var job = event.job;
+            if (job.state === "failed") {
+                toast("Update of " + job.config + " failed: " + job.error, true);
+            } else {
+                toast("Update of " + job.config + " finished", false);
+            }
+            refreshDashboard();
+            document.dispatchEvent(new CustomEvent("guide2go:job", { detail: job }));
+        };
+        ws.onclose = function () {
+            setTimeout(function () { connect(Math.min(delay * 2, 60000)); }, delay);
+        };
+    }
+
+    connect(1000);
+})();
@@ -0,0 +1,160 @@
+// Renders the broadcasts of /api/grid as rows of channels over three hours, a click loads the program of /api/programs.
+(function () {
+    var hours = 3;
+    var grid = document.getElementById("grid");
+    var fromInput = document.getElementById("grid-from");
+    var message = document.getElementById("grid-message");
+    var details = document.getElementById("grid-program");
+    var from = startOfHour(new Date());
+
+    function startOfHour(date) {
+        var d = new Date(date);
+        d.setMinutes(0, 0, 0);
+        return d;
+    }
+
+    function localValue(date) {
+        var d = new Date(date.getTime() - date.getTimezoneOffset() * 60000);
+        return d.toISOString().slice(0, 16);
+    }
+
+    function time(value) {
+        return new Date(value).toLocaleTimeString([], { hour: "2-digit", minute: "2-digit" });
+    }
+
+    function fetchJSON(url) {
+        return fetch(url)
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data && data.error) {
+                    throw new Error(data.error);
+                }
+                message.hidden = true;
+                return data;
+            })
+            .catch(function (err) {
+                message.textContent = err.message;
+                message.hidden = false;
+            });
+    }
+
+    function showProgram(broadcast) {
+        fetchJSON("/api/programs/" + encodeURIComponent(broadcast.program_id)).then(function (p) {
+            if (!p) {
+                return;
+            }
+            details.textContent = "";
+            var title = document.createElement("h2");
+            title.textContent = p.title + (p.sub_title ? ": " + p.sub_title : "");
+            details.appendChild(title);
+
+            [
+                ["Time", time(broadcast.start) + " - " + time(broadcast.stop)],
+                ["Episode", p.episode],
+                ["Original air date", p.original_air_date],
+                ["Categories", (p.categories || []).join(", ")],
+                ["Cast", (p.cast || []).join(", ")],
+                ["Program ID", p.program_id]
+            ].forEach(function (row) {
+                if (!row[1]) {
+                    return;
+                }
+                var line = document.createElement("p");
+                var label = document.createElement("strong");
+                label.textContent = row[0] + ": ";
+                line.appendChild(label);
+                line.appendChild(document.createTextNode(row[1]));
+                details.appendChild(line);
+            });
+            if (p.description) {
+                var desc = document.createElement("p");
+                desc.textContent = p.description;
+                details.appendChild(desc);
+            }
+            details.hidden = false;
+        });
+    }
+
+    function render(data) {
+        grid.textContent = "";
+        var start = new Date(data.from).getTime();
+        var span = new Date(data.to).getTime() - start;
+
+        var header = document.createElement("div");
+        header.className = "grid-row grid-header";
+        var corner = document.createElement("div");
+        corner.className = "grid-channel";
+        header.appendChild(corner);
+        var times = document.createElement("div");
+        times.className = "grid-broadcasts";
+        for (var t = start; t < start + span; t += 30 * 60000) {
+            var mark = document.createElement("span");
+            mark.style.left = ((t - start) * 100 / span) + "%";
+            mark.textContent = time(t);
+            times.appendChild(mark);
+        }
+        header.appendChild(times);
+        grid.appendChild(header);
+
+        (data.channels || []).forEach(function (channel) {
+            var row = document.createElement("div");
+            row.className = "grid-row";
+
+            var name = document.createElement("div");
+            name.className = "grid-channel";
+            name.textContent = (channel.number ? channel.number + " " : "") + channel.name;
+            row.appendChild(name);
+
+            var broadcasts = document.createElement("div");
+            broadcasts.className = "grid-broadcasts";
+            (channel.broadcasts || []).forEach(function (b) {
+                var left = Math.max(new Date(b.start).getTime() - start, 0);
+                var right = Math.min(new Date(b.stop).getTime() - start, span);
+                var cell = document.createElement("button");
+                cell.type = "button";
+                cell.className = "grid-broadcast" + (b.live ? " live" : "") + (b.new ? " new" : "");
+                cell.style.left = (left * 100 / span) + "%";
+                cell.style.width = ((right - left) * 100 / span) + "%";
+                cell.title = time(b.start) + " " + b.title;
+                cell.textContent = b.title;
+                cell.addEventListener("click", function () { showProgram(b); });
+                broadcasts.appendChild(cell);
+            });
+            row.appendChild(broadcasts);
+            grid.appendChild(row);
+        });
+        if (!(data.channels || []).length) {
+            grid.textContent = "No channels configured.";
+        }
+    }
+
+    function load() {
+        fromInput.value = localValue(from);
+        fetchJSON("/api/grid?hours=" + hours + "&from=" + encodeURIComponent(from.toISOString())).then(function (data) {
+            if (data) {
+                render(data);
+            }
+        });
+    }
+
+    document.getElementById("grid-prev").addEventListener("click", function () {
+        from = new Date(from.getTime() - hours * 3600000);
+        load();
+    });
+    document.getElementById("grid-next").addEventListener("click", function () {
+        from = new Date(from.getTime() + hours * 3600000);
+        load();
+    });
+    document.getElementById("grid-now").addEventListener("click", function () {
+        from = startOfHour(new Date());
+        load();
+    });
+    fromInput.addEventListener("change", function () {
+        if (fromInput.value) {
+            from = new Date(fromInput.value);
+            load();
+        }
+    });
+
+    load();
+})();
@@ -0,0 +1,107 @@
+// Lists the images of /api/images page by page with lazy loaded thumbnails, the buttons purge orphaned images and download failed images again.
+(function () {
+    var limit = 100;
+    var offset = 0;
+    var list = document.getElementById("images");
+    var message = document.getElementById("images-message");
+    var status = document.getElementById("images-status");
+    var more = document.getElementById("images-more");
+
+    function size(bytes) {
+        var units = ["B", "KB", "MB", "GB"];
+        var i = 0;
+        while (bytes >= 1024 && i < units.length - 1) {
+            bytes /= 1024;
+            i++;
+        }
+        return (i ? bytes.toFixed(1) : bytes) + " " + units[i];
+    }
+
+    function fetchJSON(url, method) {
+        return fetch(url, { method: method || "GET" })
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data && data.error) {
+                    throw new Error(data.error);
+                }
+                message.hidden = true;
+                return data;
+            })
+            .catch(function (err) {
+                message.textContent = err.message;
+                message.hidden = false;
+            });
+    }
+
+    function render(data) {
+        document.getElementById("images-files").textContent = data.files;
+        document.getElementById("images-size").textContent = size(data.size);
+        document.getElementById("images-orphaned").textContent = data.orphaned + " (" + size(data.orphaned_size) + ")";
+        document.getElementById("images-failed").textContent = data.failed;
+
+        data.images.forEach(function (image) {
+            var item = document.createElement("figure");
+            item.className = "image-item " + image.status;
+
+            var thumb = document.createElement("img");
+            thumb.loading = "lazy";
+            thumb.alt = image.name;
+            thumb.src = "/api/images/" + encodeURIComponent(image.name);
+            item.appendChild(thumb);
+
+            var caption = document.createElement("figcaption");
+            caption.textContent = image.name + " - " + size(image.size) + " - " + image.status;
+            caption.title = "Modified " + new Date(image.modified).toLocaleString();
+            item.appendChild(caption);
+            list.appendChild(item);
+        });
+
+        offset += data.images.length;
+        more.hidden = offset >= data.total;
+        if (!data.total) {
+            list.textContent = "No images.";
+        }
+    }
+
+    function load(reset) {
+        if (reset) {
+            offset = 0;
+            list.textContent = "";
+        }
+        var url = "/api/images?status=" + encodeURIComponent(status.value) + "&offset=" + offset + "&limit=" + limit;
+        fetchJSON(url).then(function (data) {
+            if (data) {
+                render(data);
+            }
+        });
+    }
+
+    function action(button, url, done) {
+        button.disabled = true;
+        fetchJSON(url, "POST").then(function (data) {
+            button.disabled = false;
+            if (data) {
+                alert(done(data));
+                load(true);
+            }
+        });
+    }
+
+    document.getElementById("images-purge").addEventListener("click", function () {
+        if (!confirm("Remove all orphaned images?")) {
+            return;
+        }
+        action(this, "/api/images/purge", function (data) {
+            return "Removed " + data.count + " images (" + size(data.size || 0) + ").";
+        });
+    });
+    document.getElementById("images-redownload").addEventListener("click", function () {
+        action(this, "/api/images/redownload", function (data) {
+            return "Downloaded " + data.count + " images, " + (data.failed || 0) + " failed.";
+        });
+    });
+    status.addEventListener("change", function () { load(true); });
+    more.addEventListener("click", function () { load(false); });
+
+    load(true);
+})();
@@ -0,0 +1,143 @@
+// Lists the updates of /api/jobs with the progress of their current step, refreshed every two seconds.
+(function () {
+    var jobs = document.getElementById("jobs");
+    var message = document.getElementById("jobs-message");
+
+    function time(value) {
+        return value && value.indexOf("0001-") !== 0 ? new Date(value).toLocaleString() : "";
+    }
+
+    function render(list) {
+        jobs.textContent = "";
+        (list || []).forEach(function (job) {
+            var card = document.createElement("div");
+            card.className = job.state === "failed" ? "card job error" : "card job";
+
+            var title = document.createElement("strong");
+            title.textContent = "Update " + job.id + " of " + job.config + ": " + job.state;
+            card.appendChild(title);
+
+            var details = document.createElement("small");
+            details.textContent = " " + (time(job.finished) || time(job.started) || time(job.queued));
+            card.appendChild(details);
+
+            if (job.state === "running") {
+                var step = document.createElement("p");
+                step.textContent = job.step + (job.total ? " " + job.done + "/" + job.total : "");
+                card.appendChild(step);
+
+                var bar = document.createElement("progress");
+                if (job.total) {
+                    bar.max = job.total;
+                    bar.value = job.done;
+                }
+                card.appendChild(bar);
+            }
+            if (job.error) {
+                var error = document.createElement("p");
+                error.textContent = job.error;
+                card.appendChild(error);
+            }
+            jobs.appendChild(card);
+        });
+        if (!jobs.children.length) {
+            jobs.textContent = "No updates since the start of guide2go.";
+        }
+    }
+
+    function load(method) {
+        return fetch("/api/jobs", { method: method })
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data && data.error) {
+                    throw new Error(data.error);
+                }
+                message.hidden = true;
+                return data;
+            })
+            .catch(function (err) {
+                message.textContent = err.message;
+                message.hidden = false;
+            });
+    }
+
+    function showSchedule(schedule) {
+        if (!schedule) {
+            return;
+        }
+        document.getElementById("schedule").value = schedule.schedule;
+        document.getElementById("schedule-enabled").checked = schedule.enabled;
+        document.getElementById("schedule-next").textContent = schedule.enabled ? "Next update: " + time(schedule.next) : "Scheduled updates are disabled";
+    }
+
+    function schedule(method, body) {
+        return fetch("/api/schedule", { method: method, headers: { "Content-Type": "application/json" }, body: body })
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data.error) {
+                    throw new Error(data.error);
+                }
+                message.hidden = true;
+                showSchedule(data);
+            })
+            .catch(function (err) {
+                message.textContent = err.message;
+                message.hidden = false;
+            });
+    }
+
+    document.getElementById("schedule-form").addEventListener("submit", function (event) {
+        event.preventDefault();
+        schedule("POST", JSON.stringify({
+            schedule: document.getElementById("schedule").value,
+            enabled: document.getElementById("schedule-enabled").checked
+        }));
+    });
+
+    function refresh() {
+        load("GET").then(render);
+    }
+
+    document.getElementById("jobs-start").addEventListener("click", function () {
+        load("POST").then(refresh);
+    });
+
+    function showDryRun(run) {
+        var plan = document.getElementById("dry-run-plan");
+        var refresh = run.lineups.filter(function (l) { return l.refresh; }).map(function (l) { return l.lineup; });
+        var r = run.requests;
+        plan.textContent = "";
+        [
+            "Lineups to refresh: " + (refresh.length ? refresh.join(", ") : "none") + " of " + run.lineups.length,
+            "Schedules: " + run.schedule_days + " days (" + run.dates[0] + " to " + run.dates[run.dates.length - 1] + ") of " + run.channels + " channels, " + run.new_channels + " without cached schedule",
+            "Programs: " + run.programs + " new or changed",
+            "Artwork: " + run.metadata + " programs and series",
+            "API calls: about " + run.api_calls + " (login " + r.login + ", status " + r.status + ", lineups " + r.lineups + ", schedules " + r.schedules + ", programs " + r.programs + ", artwork " + r.metadata + ")"
+        ].forEach(function (text) {
+            var li = document.createElement("li");
+            li.textContent = text;
+            plan.appendChild(li);
+        });
+        document.getElementById("dry-run").hidden = false;
+    }
+
+    document.getElementById("dry-run-start").addEventListener("click", function () {
+        fetch("/api/dry-run")
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data.error) {
+                    throw new Error(data.error);
+                }
+                message.hidden = true;
+                showDryRun(data);
+            })
+            .catch(function (err) {
+                message.textContent = err.message;
+                message.hidden = false;
+            });
+    });
+
+    schedule("GET");
+    refresh();
+    setInterval(refresh, 2000);
+})();
@@ -0,0 +1,114 @@
+// Searches the lineups by country and postal code and adds or removes them in the Schedules Direct account.
+(function () {
+    var account = document.getElementById("lineups-account");
+    var country = document.getElementById("lineups-country");
+    var postalCode = document.getElementById("lineups-postalcode");
+    var headends = document.getElementById("lineups-headends");
+    var message = document.getElementById("lineups-message");
+    var countries = [];
+
+    function show(text, error) {
+        message.textContent = text;
+        message.className = error ? "card error" : "card";
+        message.hidden = false;
+    }
+
+    function request(method, url) {
+        return fetch(url, { method: method })
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data && data.error) {
+                    throw new Error(data.error);
+                }
+                return data;
+            });
+    }
+
+    function button(text, onclick) {
+        var b = document.createElement("button");
+        b.type = "button";
+        b.className = "admin-only";
+        b.textContent = text;
+        b.addEventListener("click", onclick);
+        return b;
+    }
+
+    function change(method, lineup) {
+        request(method, "/api/lineups/" + encodeURIComponent(lineup))
+            .then(function (data) {
+                show(data.message + ": " + lineup, false);
+                loadAccount();
+            })
+            .catch(function (err) { show(err.message, true); });
+    }
+
+    function loadAccount() {
+        request("GET", "/api/lineups")
+            .then(function (lineups) {
+                account.textContent = "";
+                lineups.forEach(function (lineup) {
+                    var item = document.createElement("li");
+                    item.textContent = lineup.name + " [" + lineup.lineup + "] ";
+                    item.appendChild(button("Remove", function () { change("DELETE", lineup.lineup); }));
+                    account.appendChild(item);
+                });
+                if (!account.children.length) {
+                    account.textContent = "No lineups in the account.";
+                }
+            })
+            .catch(function (err) { show("Failed to load the account: " + err.message, true); });
+    }
+
+    country.addEventListener("change", function () {
+        var c = countries[country.selectedIndex];
+        postalCode.value = c.postal_code || "";
+        postalCode.placeholder = c.postal_code_example || "";
+    });
+
+    document.getElementById("lineups-search").addEventListener("submit", function (event) {
+        event.preventDefault();
+        headends.textContent = "Searching...";
+
+        request("GET", "/api/headends?country=" + encodeURIComponent(country.value) + "&postalcode=" + encodeURIComponent(postalCode.value))
+            .then(function (data) {
+                headends.textContent = "";
+                (data || []).forEach(function (headend) {
+                    var heading = document.createElement("h3");
+                    heading.textContent = headend.location + " (" + headend.transport + ")";
+                    headends.appendChild(heading);
+
+                    var list = document.createElement("ul");
+                    list.className = "messages";
+                    (headend.lineups || []).forEach(function (lineup) {
+                        var item = document.createElement("li");
+                        item.textContent = lineup.name + " [" + lineup.lineup + "] ";
+                        item.appendChild(button("Add", function () { change("PUT", lineup.lineup); }));
+                        list.appendChild(item);
+                    });
+                    headends.appendChild(list);
+                });
+                if (!headends.children.length) {
+                    headends.textContent = "No lineups found.";
+                }
+            })
+            .catch(function (err) {
+                headends.textContent = "";
+                show("Failed to search lineups: " + err.message, true);
+            });
+    });
+
+    request("GET", "/api/countries")
+        .then(function (data) {
+            countries = data || [];
+            countries.forEach(function (c) {
+                var option = document.createElement("option");
+                option.value = c.code;
+                option.textContent = c.name;
+                country.appendChild(option);
+            });
+            country.dispatchEvent(new Event("change"));
+        })
+        .catch(function (err) { show("Failed to load countries: " + err.message, true); });
+
+    loadAccount();
+})();
@@ -0,0 +1,17 @@
+// Shows the log entries of /api/logs as they are written, the browser reconnects after a lost connection.
+(function () {
+    var logs = document.getElementById("logs");
+    var follow = document.getElementById("logs-follow");
+    var maxLines = 1000;
+
+    var source = new EventSource("/api/logs");
+    source.onmessage = function (event) {
+        logs.appendChild(document.createTextNode(event.data + "\n"));
+        while (logs.childNodes.length > maxLines) {
+            logs.removeChild(logs.firstChild);
+        }
+        if (follow.checked) {
+            window.scrollTo(0, document.body.scrollHeight);
+        }
+    };
+})();
@@ -0,0 +1,9 @@
+// Reads the role of the cookie guide2go_role, read-only users get the class read-only on the page which hides the controls
+// with the class admin-only. The server refuses their changes anyway.
+(function () {
+    var match = document.cookie.match(/(?:^|;\s*)guide2go_role=([^;]*)/);
+    window.readOnly = match !== null && decodeURIComponent(match[1]) === "read-only";
+    if (window.readOnly) {
+        document.documentElement.classList.add("read-only");
+    }
+})();
@@ -0,0 +1,68 @@
+// Lists the run history of /api/runs with links to the log of every update, the list is loaded again when an update finishes.
+(function () {
+    var rows = document.querySelector("#runs tbody");
+    var message = document.getElementById("runs-message");
+
+    function duration(seconds) {
+        var m = Math.floor(seconds / 60);
+        return (m ? m + " min " : "") + Math.round(seconds % 60) + " s";
+    }
+
+    function size(bytes) {
+        return bytes ? (bytes / 1048576).toFixed(1) + " MB" : "";
+    }
+
+    function cell(row, text) {
+        var td = document.createElement("td");
+        td.textContent = text;
+        row.appendChild(td);
+        return td;
+    }
+
+    function render(runs) {
+        rows.textContent = "";
+        runs.forEach(function (run) {
+            var row = document.createElement("tr");
+            if (run.error) {
+                row.className = "error";
+            }
+            cell(row, run.id);
+            cell(row, new Date(run.started).toLocaleString());
+            cell(row, duration(run.duration));
+            cell(row, run.programs);
+            cell(row, size(run.file_size));
+            cell(row, run.error || "successful");
+
+            var log = cell(row, "");
+            if (run.log) {
+                var link = document.createElement("a");
+                link.href = "/api/runs/" + run.id + "/log";
+                link.textContent = "Download";
+                log.appendChild(link);
+            }
+            rows.appendChild(row);
+        });
+        if (!runs.length) {
+            cell(rows.insertRow(), "No updates yet.").colSpan = 7;
+        }
+    }
+
+    function load() {
+        fetch("/api/runs")
+            .then(function (resp) { return resp.json(); })
+            .then(function (data) {
+                if (data.error) {
+                    throw new Error(data.error);
+                }
+                message.hidden = true;
+                render(data);
+            })
+            .catch(function (err) {
+                message.textContent = err.message;
+                message.hidden = false;
+            });
+    }
+
+    document.addEventListener("guide2go:job", load);
+    load();
+})();
@@ -0,0 +1,54 @@
+// Shows the Schedules Direct status the server polls on the dashboard and refreshes it every minute and after every update.
+// A failed last update while Schedules Direct reports a problem shows a hint that the update failed on their side.
+(function () {
+    var panel = document.getElementById("dashboard-sd");
+
+    function render(status, guide) {
+        var state = document.getElementById("sd-state");
+        var problem = !!status.error || !status.online;
+        state.className = "card" + (problem ? " error" : "");
+        if (status.error) {
+            state.textContent = "Status: not reachable";
+            state.title = status.error;
+        } else {
+            state.textContent = "Status: " + (status.system_status || []).map(function (s) { return s.status; }).join(", ");
+            state.title = (status.system_status || []).map(function (s) { return s.message; }).join("\n");
+        }
+        var checked = document.createElement("small");
+        checked.textContent = "checked " + new Date(status.checked).toLocaleTimeString();
+        state.appendChild(document.createTextNode(" "));
+        state.appendChild(checked);
+
+        document.getElementById("sd-data").textContent = "Last data update: " + (status.last_data_update ? new Date(status.last_data_update).toLocaleString() : "unknown");
+
+        var list = document.getElementById("sd-announcements");
+        list.textContent = "";
+        (status.announcements || []).forEach(function (m) {
+            var item = document.createElement("li");
+            var date = document.createElement("strong");
+            date.textContent = m.date;
+            item.appendChild(date);
+            item.appendChild(document.createTextNode(" " + m.message));
+            list.appendChild(item);
+        });
+
+        var failed = guide && guide.last_run && guide.last_run.error;
+        document.getElementById("sd-hint").hidden = !(problem && failed);
+        panel.hidden = false;
+    }
+
+    function refresh() {
+        Promise.all([
+            fetch("/api/sd-status").then(function (resp) { return resp.json(); }),
+            fetch("/api/guide").then(function (resp) { return resp.ok ? resp.json() : null; })
+        ]).then(function (results) {
+            if (!results[0].error || results[0].checked) {
+                render(results[0], results[1]);
+            }
+        });
+    }
+
+    refresh();
+    setInterval(refresh, 60000);
+    document.addEventListener("guide2go:job", refresh);
+})();
@@ -0,0 +1,15 @@
+// Switches between the light and the dark theme. The cookie guide2go_theme keeps the choice for a year,
+// the server renders the pages in it so they do not flash in the other theme.
+(function () {
+    var toggle = document.getElementById("theme-toggle");
+    if (!toggle) {
+        return;
+    }
+
+    toggle.addEventListener("click", function () {
+        var theme = document.documentElement.dataset.theme === "dark" ? "light" : "dark";
+        document.cookie = "guide2go_theme=" + theme + "; path=/; max-age=31536000; SameSite=Strict";
+        document.documentElement.dataset.theme = theme;
+        toggle.textContent = theme === "dark" ? toggle.dataset.light : toggle.dataset.dark;
+    });
+})();
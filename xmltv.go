@@ -2,13 +2,17 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding/xml"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,32 +23,119 @@ import (
 // Pre-compile the regexp for SanitizeID
 var sanitizeIDRegexp = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
 
-// XMLTVGenerator represents an XMLTV file generator
+// xmltvBufferSize is the size of the write buffer of the XMLTV file
+const xmltvBufferSize = 64 * 1024
+
+// xmltvTimeLayout is the layout of the start and stop times of the programmes
+const xmltvTimeLayout = "20060102150405 -0700"
+
+// Default titles of programs missing in the cache and of gaps in the schedule
+const (
+	defaultPlaceholderTitle = "No EPG Info"
+	defaultGapTitle         = "To be announced"
+)
+
+// Default marks of live and new programs, superscript letters after the title
+const (
+	defaultLiveMark = " ᴸᶦᵛᵉ"
+	defaultNewMark  = " ᴺᵉʷ"
+)
+
+// XMLTVGenerator represents an XMLTV file generator.
+// The document is encoded directly into temporary files, which replace the XMLTV files once they are complete.
 type XMLTVGenerator struct {
-	encoder *xml.Encoder
-	buffer  *bytes.Buffer
-	logger  *logrus.Entry
+	encoder     *xml.Encoder
+	guide       epgWriter // JSON, CSV guide or M3U playlist instead of XMLTV, nil for XMLTV
+	files       []*xmltvFile
+	idScheme    string // Channel ID option, the channel and programme elements use the same ID
+	marks       titleMarks
+	placeholder placeholder
+	include     func(channel G2GCache) bool // Channels of the file, nil for all channels of the cache
+	location    func(stationID string) *time.Location
+	app         *App // Cache and configuration of the channels and programmes
+	logger      *logrus.Entry
+}
+
+// xmltvFile is an XMLTV file the generator writes, plain or gzip compressed
+type xmltvFile struct {
+	file     *os.File
+	buffer   *bufio.Writer
+	gzip     *gzip.Writer // nil for the plain file
+	filename string
+}
+
+// NewXMLTVGenerator creates a new XMLTV generator and the temporary files of filename.
+// compression none writes filename, also writes filename and filename.gz, only writes filename.gz.
+// format json, csv or tsv writes the guide in this format instead of XMLTV, m3u the playlist of the channels.
+func NewXMLTVGenerator(filename, compression, format string) (*XMLTVGenerator, error) {
+	// Create directory if it doesn't exist
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create directory")
+	}
+
+	g := &XMLTVGenerator{logger: logger.WithField("component", "xmltv_generator")}
+
+	var targets []string
+	if compression != "only" {
+		targets = append(targets, filename)
+	}
+	if compression == "also" || compression == "only" {
+		targets = append(targets, filename+".gz")
+	}
+
+	// The plain and the compressed file get the same document
+	var outputs []io.Writer
+	for _, target := range targets {
+		w, err := g.create(target)
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+		outputs = append(outputs, w)
+	}
+
+	w := io.MultiWriter(outputs...)
+	switch format {
+	case "json":
+		g.guide = &jsonEPG{w: w}
+		return g, nil
+	case "csv", "tsv":
+		g.guide = newCSVEPG(w, format == "tsv")
+		return g, nil
+	case "m3u":
+		g.guide = &m3uEPG{w: w}
+		return g, nil
+	}
+	io.WriteString(w, xml.Header)
+
+	g.encoder = xml.NewEncoder(w)
+	g.encoder.Indent("", "  ")
+
+	return g, nil
 }
 
-// NewXMLTVGenerator creates a new XMLTV generator
-func NewXMLTVGenerator() *XMLTVGenerator {
-	buf := &bytes.Buffer{}
-	buf.WriteString(xml.Header)
+// create creates the temporary file of filename and returns the writer of the document into it,
+// files ending with .gz are gzip compressed
+func (g *XMLTVGenerator) create(filename string) (io.Writer, error) {
+	file, err := os.OpenFile(filename+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary file")
+	}
 
-	enc := xml.NewEncoder(buf)
-	enc.Indent("", "  ")
+	f := &xmltvFile{file: file, buffer: bufio.NewWriterSize(file, xmltvBufferSize), filename: filename}
+	g.files = append(g.files, f)
 
-	return &XMLTVGenerator{
-		encoder: enc,
-		buffer:  buf,
-		logger:  logger.WithField("component", "xmltv_generator"),
+	if strings.HasSuffix(filename, ".gz") {
+		f.gzip = gzip.NewWriter(f.buffer)
+		return f.gzip, nil
 	}
+	return f.buffer, nil
 }
 
 // CreateXMLTV generates the XMLTV file using the provided app context
 func (app *App) CreateXMLTV(ctx context.Context, filename string) error {
 	app.Logger.WithField("filename", filename).Info("Starting XMLTV creation")
-	gen := NewXMLTVGenerator()
 	app.Config.File = strings.TrimSuffix(filename, filepath.Ext(filename))
 	if err := app.Config.Open(ctx); err != nil {
 		app.Logger.WithError(err).Error("Failed to open configuration")
@@ -55,15 +146,93 @@ func (app *App) CreateXMLTV(ctx context.Context, filename string) error {
 		return errors.Wrap(err, "failed to open cache")
 	}
 	app.Cache.Init()
-	app.Logger.WithField("path", app.Config.Files.XMLTV).Info("Creating XMLTV file")
+
+	if err := app.writeXMLTV(ctx, app.Config.Files.XMLTV, "xmltv", nil); err != nil {
+		return err
+	}
+
+	// Every lineup gets its own file next to the XMLTV file, e.g. guide_USA-OTA-90210.xml
+	if app.Config.Options.XMLTVPerLineup {
+		for _, lineup := range app.Config.GetLineups() {
+			stations := make(map[string]bool)
+			for _, id := range app.Config.GetChannelList(lineup) {
+				stations[id] = true
+			}
+
+			include := func(channel G2GCache) bool { return stations[channel.StationID] }
+			if err := app.writeXMLTV(ctx, lineupFilename(app.Config.Files.XMLTV, lineup), "xmltv", include); err != nil {
+				return errors.Wrapf(err, "failed to create XMLTV file of lineup %s", lineup)
+			}
+		}
+	}
+
+	// The playlist has the channels of the XMLTV file with the same IDs
+	if len(app.Config.Options.M3UStreamURL) != 0 {
+		if err := app.writeXMLTV(ctx, playlistFilename(app.Config.Files.XMLTV), "m3u", nil); err != nil {
+			return errors.Wrap(err, "failed to create M3U playlist")
+		}
+	}
+
+	// Outputs get the channels of their filters
+	for _, output := range app.Config.Outputs {
+		if err := app.writeXMLTV(ctx, output.File, output.Format, output.includes); err != nil {
+			return errors.Wrapf(err, "failed to create XMLTV file of output %s", output.Name)
+		}
+	}
+
+	runtime.GC()
+	return nil
+}
+
+// includes reports whether the output contains the channel, channels match the filters by station ID or callsign
+func (o xmltvOutput) includes(channel G2GCache) bool {
+	matches := func(list []string) bool {
+		return slices.Contains(list, channel.StationID) || slices.Contains(list, channel.Callsign)
+	}
+	return (len(o.Include) == 0 || matches(o.Include)) && !matches(o.Exclude)
+}
+
+// lineupFilename returns the XMLTV file of a lineup, the lineup ID is added to the name of filename
+func lineupFilename(filename, lineup string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "_" + SanitizeID(lineup) + ext
+}
+
+// writeXMLTV writes the channels include reports and their programs into filename, nil include writes all channels.
+// format json, csv or tsv writes a guide in this format, m3u the playlist of the channels, any other format XMLTV.
+func (app *App) writeXMLTV(ctx context.Context, filename, format string, include func(channel G2GCache) bool) error {
+	app.Logger.WithFields(logrus.Fields{"path": filename, "format": format}).Info("Creating XMLTV file")
+
+	// IPTV front-ends load the playlist uncompressed
+	compression := app.Config.Options.CompressXMLTV
+	if format == "m3u" {
+		compression = "none"
+	}
+	gen, err := NewXMLTVGenerator(filename, compression, format)
+	if err != nil {
+		return errors.Wrap(err, "failed to create XMLTV file")
+	}
+	defer gen.Close()
+	if playlist, ok := gen.guide.(*m3uEPG); ok {
+		playlist.streamURL = app.Config.Options.M3UStreamURL
+	}
+	gen.idScheme = app.Config.Options.ChannelID
+	gen.marks = app.Config.Options.TitleMarks
+	gen.placeholder = app.Config.Options.Placeholder
+	gen.location = app.Config.GetLocation
+	gen.app = app
+	gen.include = include
+
 	if err := gen.writeHeader(); err != nil {
 		return errors.Wrap(err, "failed to write XML header")
 	}
 	if err := gen.writeChannels(ctx); err != nil {
 		return errors.Wrap(err, "failed to write channels")
 	}
-	if err := gen.writePrograms(ctx); err != nil {
-		return errors.Wrap(err, "failed to write programs")
+	if format != "m3u" {
+		if err := gen.writePrograms(ctx); err != nil {
+			return errors.Wrap(err, "failed to write programs")
+		}
 	}
 	if err := gen.writeFooter(); err != nil {
 		return errors.Wrap(err, "failed to write XML footer")
@@ -71,12 +240,18 @@ func (app *App) CreateXMLTV(ctx context.Context, filename string) error {
 	if err := gen.writeFile(); err != nil {
 		return errors.Wrap(err, "failed to write XMLTV file")
 	}
-	runtime.GC()
-	return nil
+	if gen.guide != nil {
+		return nil
+	}
+	return app.checkXMLTV(filename)
 }
 
-// writeHeader writes the XML header and root element
+// writeHeader writes the XML header and root element, or starts the channels of the guide format
 func (g *XMLTVGenerator) writeHeader() error {
+	if g.guide != nil {
+		return g.guide.begin("channels")
+	}
+
 	attrs := []xml.Attr{
 		{Name: xml.Name{Local: AppName}, Value: AppName},
 		{Name: xml.Name{Local: "source-info-name"}, Value: "Schedules Direct"},
@@ -89,27 +264,52 @@ func (g *XMLTVGenerator) writeHeader() error {
 	})
 }
 
+// channels returns the channels of the file sorted by their XMLTV ID, stations sharing an ID by station ID.
+// The order of the file doesn't change between runs, diffs and delta transfers of the file stay small.
+func (g *XMLTVGenerator) channels() []G2GCache {
+	var channels []G2GCache
+	for _, channel := range g.app.Cache.GetChannels(g.app) {
+		if g.includes(channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	sort.Slice(channels, func(i, j int) bool {
+		if a, b := g.channelID(channels[i]), g.channelID(channels[j]); a != b {
+			return a < b
+		}
+		return channels[i].StationID < channels[j].StationID
+	})
+	return channels
+}
+
 // writeChannels writes all channels to the XML file
 func (g *XMLTVGenerator) writeChannels(ctx context.Context) error {
-	for _, cache := range app.Cache.Channel {
+	for _, cache := range g.channels() {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 			channel := ChannelXML{
-				ID: SanitizeID(cache.Callsign),
-				Icon: Icon{
-					Src:    cache.Logo.URL,
-					Height: cache.Logo.Height,
-					Width:  cache.Logo.Width,
-				},
+				ID:        g.channelID(cache),
+				Icon:      logoIcon(cache.Logo.URL, cache.Logo.Width, cache.Logo.Height, g.app),
+				StationID: cache.StationID,
+				Number:    cache.ChannelNumber,
 				DisplayName: []DisplayName{
 					{Value: cache.Callsign},
 					{Value: cache.Name},
 				},
 			}
 
-			if err := g.encoder.Encode(channel); err != nil {
+			// Plex and TVHeadend match the channels of the tuner by the channel number
+			if len(cache.ChannelNumber) != 0 {
+				channel.DisplayName = append(channel.DisplayName,
+					DisplayName{Value: cache.ChannelNumber + " " + cache.Callsign},
+					DisplayName{Value: cache.ChannelNumber},
+				)
+			}
+
+			if err := g.encode(channel); err != nil {
 				return errors.Wrap(err, "failed to encode channel")
 			}
 		}
@@ -120,7 +320,13 @@ func (g *XMLTVGenerator) writeChannels(ctx context.Context) error {
 
 // writePrograms writes all programs to the XML file
 func (g *XMLTVGenerator) writePrograms(ctx context.Context) error {
-	for _, cache := range app.Cache.Channel {
+	if g.guide != nil {
+		if err := g.guide.begin("programmes"); err != nil {
+			return err
+		}
+	}
+
+	for _, cache := range g.channels() {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -132,7 +338,7 @@ func (g *XMLTVGenerator) writePrograms(ctx context.Context) error {
 			}
 
 			for _, program := range programs {
-				if err := g.encoder.Encode(program); err != nil {
+				if err := g.encode(program); err != nil {
 					return errors.Wrap(err, "failed to encode program")
 				}
 			}
@@ -142,8 +348,25 @@ func (g *XMLTVGenerator) writePrograms(ctx context.Context) error {
 	return nil
 }
 
-// writeFooter writes the XML footer
+// includes reports whether the file of the generator contains the channel
+func (g *XMLTVGenerator) includes(channel G2GCache) bool {
+	return g.include == nil || g.include(channel)
+}
+
+// encode writes a channel or programme element, or its entry of the guide format
+func (g *XMLTVGenerator) encode(v interface{}) error {
+	if g.guide != nil {
+		return g.guide.encode(v)
+	}
+	return g.encoder.Encode(v)
+}
+
+// writeFooter writes the XML footer, or ends the guide format
 func (g *XMLTVGenerator) writeFooter() error {
+	if g.guide != nil {
+		return g.guide.end()
+	}
+
 	if err := g.encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "tv"}}); err != nil {
 		return errors.Wrap(err, "failed to write end element")
 	}
@@ -151,22 +374,38 @@ func (g *XMLTVGenerator) writeFooter() error {
 	return g.encoder.Flush()
 }
 
-// writeFile writes the XML content to disk
+// writeFile completes the temporary files and renames them to the XMLTV files
 func (g *XMLTVGenerator) writeFile() error {
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(app.Config.Files.XMLTV)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return errors.Wrap(err, "failed to create directory")
+	for _, f := range g.files {
+		if err := f.complete(); err != nil {
+			return err
+		}
 	}
 
-	// Write to temporary file first
-	tmpFile := app.Config.Files.XMLTV + ".tmp"
-	if err := os.WriteFile(tmpFile, g.buffer.Bytes(), 0644); err != nil {
+	return nil
+}
+
+// complete flushes the temporary file and renames it to the XMLTV file
+func (f *xmltvFile) complete() error {
+	if f.gzip != nil {
+		if err := f.gzip.Close(); err != nil {
+			return errors.Wrap(err, "failed to compress temporary file")
+		}
+	}
+	if err := f.buffer.Flush(); err != nil {
+		return errors.Wrap(err, "failed to write temporary file")
+	}
+
+	file := f.file
+	f.file = nil
+	tmpFile := file.Name()
+	if err := file.Close(); err != nil {
+		os.Remove(tmpFile) // Clean up temp file
 		return errors.Wrap(err, "failed to write temporary file")
 	}
 
 	// Rename temporary file to actual file
-	if err := os.Rename(tmpFile, app.Config.Files.XMLTV); err != nil {
+	if err := os.Rename(tmpFile, f.filename); err != nil {
 		os.Remove(tmpFile) // Clean up temp file
 		return errors.Wrap(err, "failed to rename temporary file")
 	}
@@ -174,22 +413,46 @@ func (g *XMLTVGenerator) writeFile() error {
 	return nil
 }
 
+// Close removes the temporary files of XMLTV files which were not completed, the existing XMLTV files are kept
+func (g *XMLTVGenerator) Close() {
+	for _, f := range g.files {
+		if f.file == nil {
+			continue
+		}
+
+		f.file.Close()
+		os.Remove(f.file.Name())
+		f.file = nil
+	}
+}
+
 // getPrograms gets all programs for a channel
 func (g *XMLTVGenerator) getPrograms(channel G2GCache) ([]Programme, error) {
-	schedule, ok := app.Cache.Schedule[channel.StationID]
-	if !ok {
+	// Programmes are written by start time
+	schedule := g.app.Cache.GetSchedule(channel.StationID, g.app)
+	if len(schedule) == 0 {
 		return nil, nil
 	}
 
 	var programs []Programme
-	countryCode := app.Config.GetLineupCountry(channel.StationID)
+	countryCode := g.app.Config.GetLineupCountry(channel.StationID)
+	loc := g.location(channel.StationID)
 	lang := "en"
 	if len(channel.BroadcastLanguage) > 0 {
 		lang = channel.BroadcastLanguage[0]
 	}
 
+	var end time.Time // End of the broadcasts so far
 	for _, s := range schedule {
-		program, err := g.createProgram(channel, s, countryCode, lang)
+		// Gaps between the broadcasts get a filler programme
+		if g.placeholder.FillGaps && !end.IsZero() && s.AirDateTime.Sub(end) >= time.Minute {
+			programs = append(programs, g.gapProgram(channel, end, s.AirDateTime, loc))
+		}
+		if stop := s.AirDateTime.Add(time.Second * time.Duration(s.Duration)); stop.After(end) {
+			end = stop
+		}
+
+		program, err := g.createProgram(channel, s, countryCode, lang, loc)
 		if err != nil {
 			g.logger.WithError(err).WithFields(logrus.Fields{
 				"channel":   channel.Callsign,
@@ -204,43 +467,56 @@ func (g *XMLTVGenerator) getPrograms(channel G2GCache) ([]Programme, error) {
 	return programs, nil
 }
 
-// createProgram creates a program from schedule data
-func (g *XMLTVGenerator) createProgram(channel G2GCache, schedule G2GCache, countryCode, lang string) (Programme, error) {
+// createProgram creates a program from schedule data, the start and stop times are in the time zone loc
+func (g *XMLTVGenerator) createProgram(channel G2GCache, schedule G2GCache, countryCode, lang string, loc *time.Location) (Programme, error) {
 	program := Programme{
-		Channel: SanitizeID(channel.Callsign),
-	}
-
-	// Set start and stop times
-	timeLayout := "2006-01-02 15:04:05 +0000 UTC"
-	t, err := time.Parse(timeLayout, schedule.AirDateTime.Format(timeLayout))
-	if err != nil {
-		return program, errors.Wrap(err, "failed to parse air time")
+		Channel: g.channelID(channel),
 	}
 
-	dateArray := strings.Fields(t.String())
-	offset := " " + dateArray[2]
-	program.Start = t.Format("20060102150405") + offset
-	program.Stop = t.Add(time.Second*time.Duration(schedule.Duration)).Format("20060102150405") + offset
+	// Set start and stop times, each with its own offset as a broadcast can span a DST change
+	start := schedule.AirDateTime.In(loc)
+	program.Start = start.Format(xmltvTimeLayout)
+	program.Stop = start.Add(time.Second * time.Duration(schedule.Duration)).Format(xmltvTimeLayout)
 
-	// Set title with live/new indicators
-	program.Title = app.Cache.GetTitle(schedule.ProgramID, lang)
-	if len(program.Title) > 0 {
-		if schedule.LiveTapeDelay == "Live" {
-			program.Title[0].Value += " ᴸᶦᵛᵉ"
-		} else if schedule.New {
-			program.Title[0].Value += " ᴺᵉʷ"
-		}
-	}
+	// Set title and sub-title with live/new marks
+	program.Title = g.app.Cache.GetTitle(schedule.ProgramID, lang, g.app)
+	program.SubTitle = g.app.Cache.GetSubTitle(schedule.ProgramID, lang, g.app)
+	g.markTitle(&program, schedule)
 
 	// Set other fields
-	program.SubTitle = app.Cache.GetSubTitle(schedule.ProgramID, lang)
-	program.Desc = app.Cache.GetDescs(schedule.ProgramID, program.SubTitle.Value)
-	program.Credits = app.Cache.GetCredits(schedule.ProgramID)
-	program.Categorys = app.Cache.GetCategory(schedule.ProgramID)
+	program.Desc = g.app.Cache.GetDescs(schedule.ProgramID, program.SubTitle.Value, g.app)
+	program.Credits = g.app.Cache.GetCredits(schedule.ProgramID, g.app)
+	program.Categorys = g.app.Cache.GetCategory(schedule.ProgramID, g.app)
+	program.Keywords = g.app.Cache.GetKeywords(schedule.ProgramID, g.app)
 	program.Language = lang
-	program.EpisodeNums = app.Cache.GetEpisodeNum(schedule.ProgramID)
-	program.Icon = app.Cache.GetIcon(schedule.ProgramID[0:10])
-	program.Rating = app.Cache.GetRating(schedule.ProgramID, countryCode)
+	if schedule.Duration > 0 {
+		program.Length = &Length{Value: (schedule.Duration + 30) / 60, Units: "minutes"}
+	}
+	if len(countryCode) != 0 {
+		program.Country = []Country{{Value: countryCode}}
+	}
+	program.EpisodeNums = g.app.Cache.GetEpisodeNum(schedule.ProgramID, g.app)
+	program.Icon = g.app.Cache.GetIcon(schedule.ProgramID[0:10], g.app)
+	if episodeArtworkID(schedule.ProgramID) {
+		// Series artwork first, then the artwork of the episode
+		program.Icon = append(program.Icon, g.app.Cache.GetIcon(schedule.ProgramID, g.app)...)
+	}
+	program.Rating = g.app.Cache.GetRating(schedule.ProgramID, countryCode, g.app)
+	program.StarRating = g.app.Cache.GetStarRating(schedule.ProgramID, g.app)
+
+	// Set content advisories as ratings or behind the descriptions
+	if advisories := g.app.Cache.GetContentAdvisory(schedule.ProgramID, g.app); len(advisories) != 0 {
+		switch g.app.Config.Options.Rating.ContentAdvisory {
+		case "rating":
+			for _, advisory := range advisories {
+				program.Rating = append(program.Rating, Rating{System: "advisory", Value: advisory})
+			}
+		case "description":
+			for i := range program.Desc {
+				program.Desc[i].Value += "\nContent advisory: " + strings.Join(advisories, ", ")
+			}
+		}
+	}
 
 	// Set video properties
 	for _, v := range schedule.VideoProperties {
@@ -250,9 +526,13 @@ func (g *XMLTVGenerator) createProgram(channel G2GCache, schedule G2GCache, coun
 		}
 	}
 
-	// Set audio properties
+	// Set audio properties, closed captions and subtitles are no audio but subtitles elements
 	for _, a := range schedule.AudioProperties {
 		switch a {
+		case "cc":
+			program.Subtitles = append(program.Subtitles, Subtitles{Type: "teletext"})
+		case "subtitled":
+			program.Subtitles = append(program.Subtitles, Subtitles{Type: "onscreen"})
 		case "stereo", "dvs":
 			program.Audio.Stereo = "stereo"
 		case "DD 5.1", "Atmos":
@@ -266,21 +546,97 @@ func (g *XMLTVGenerator) createProgram(channel G2GCache, schedule G2GCache, coun
 		}
 	}
 
-	// Set new/previously shown status
-	if schedule.New {
-		program.New = &New{Value: ""}
-	} else {
-		program.PreviouslyShown = app.Cache.GetPreviouslyShown(schedule.ProgramID)
+	// Set the new, live, premiere and previously shown flags of the broadcast
+	flags := selectBroadcastFlags(schedule, g.app.Config.Options.BroadcastFlags)
+
+	// Set premiere and finale, e.g. Season Premiere
+	switch {
+	case strings.HasSuffix(schedule.PremiereFinale, "Premiere"):
+		if flags["premiere"] {
+			program.Premiere = &Premiere{Value: schedule.PremiereFinale, Lang: "en"}
+		}
+	case strings.HasSuffix(schedule.PremiereFinale, "Finale"):
+		program.LastChance = &LastChance{Value: schedule.PremiereFinale, Lang: "en"}
+	case schedule.Premiere && flags["premiere"]:
+		program.Premiere = &Premiere{}
 	}
 
-	// Set live status
-	if schedule.LiveTapeDelay == "Live" {
+	if flags["new"] {
+		program.New = &New{Value: ""}
+	}
+	if flags["previously-shown"] {
+		program.PreviouslyShown = g.app.Cache.GetPreviouslyShown(schedule.ProgramID, g.app)
+	}
+	if flags["live"] {
 		program.Live = &Live{Value: ""}
 	}
 
 	return program, nil
 }
 
+// gapProgram returns the filler programme of a gap in the schedule from start to stop
+func (g *XMLTVGenerator) gapProgram(channel G2GCache, start, stop time.Time, loc *time.Location) Programme {
+	title := Title{Value: g.placeholder.GapTitle, Lang: g.placeholder.Lang}
+	if len(title.Value) == 0 {
+		title.Value = defaultGapTitle
+	}
+	if len(title.Lang) == 0 {
+		title.Lang = "en"
+	}
+
+	return Programme{
+		Channel: g.channelID(channel),
+		Start:   start.In(loc).Format(xmltvTimeLayout),
+		Stop:    stop.In(loc).Format(xmltvTimeLayout),
+		Title:   []Title{title},
+	}
+}
+
+// markTitle adds the live or new mark of the broadcast to the title or sub-title by the title marks option
+func (g *XMLTVGenerator) markTitle(program *Programme, schedule G2GCache) {
+	var mark string
+	if schedule.LiveTapeDelay == "Live" {
+		mark = g.marks.Live
+	} else if schedule.New {
+		mark = g.marks.New
+	}
+	if len(strings.TrimSpace(mark)) == 0 || len(program.Title) == 0 {
+		return
+	}
+
+	switch g.marks.Placement {
+	case "none":
+	case "prefix":
+		program.Title[0].Value = strings.TrimSpace(mark) + " " + program.Title[0].Value
+	case "sub-title":
+		if len(program.SubTitle.Value) == 0 {
+			program.SubTitle = SubTitle{Value: strings.TrimSpace(mark), Lang: program.Title[0].Lang}
+			return
+		}
+		program.SubTitle.Value += mark
+	default:
+		program.Title[0].Value += mark
+	}
+}
+
+// channelID returns the XMLTV ID of a channel by the channel ID option.
+// Callsigns are not unique, stations sharing a callsign need the station ID. Channels without number use the station ID.
+func (g *XMLTVGenerator) channelID(channel G2GCache) string {
+	switch g.idScheme {
+	case "stationID":
+		return SanitizeID(channel.StationID)
+	case "callsign.stationID":
+		return SanitizeID(channel.Callsign) + "." + SanitizeID(channel.StationID)
+	case "number":
+		if len(channel.ChannelNumber) != 0 {
+			return SanitizeID(channel.ChannelNumber)
+		}
+		return SanitizeID(channel.StationID)
+	default:
+		return SanitizeID(channel.Callsign)
+	}
+}
+
 // SanitizeID replaces forbidden characters with underscores for Plex compatibility
 func SanitizeID(id string) string {
 	return sanitizeIDRegexp.ReplaceAllString(id, "_")
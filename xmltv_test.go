@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCreateProgram(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	c := newCache()
+	app := &App{Logger: logger, Cache: c}
+
+	programs := []byte(`[
+		{"programID":"MV000000010000","titles":[{"title120":"Movie"}],"originalAirDate":"2004-05-01",
+			"movie":{"year":"2004","qualityRating":[{"ratingsBody":"Gracenote","rating":"3.5","minRating":"1","maxRating":"4","increment":".5"}]},
+			"keyWords":{"Mood":["Suspenseful"]},"contentAdvisory":["Violence"],
+			"descriptions":{"description1000":[{"description":"A heist.","descriptionLanguage":"en"}]}},
+		{"programID":"EP012345670042","titles":[{"title120":"Series"}],"episodeTitle150":"Pilot"}
+	]`)
+	metadata := []byte(`[
+		{"programID":"EP01234567","data":[{"uri":"f.jpg","width":"960","height":"540","aspect":"16x9","category":"Banner-L1"}]},
+		{"programID":"EP012345670042","data":[{"uri":"h.jpg","width":"960","height":"540","aspect":"16x9","category":"Iconic"}]}
+	]`)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	if err := c.AddProgram(context.Background(), &programs, &wg, app); err != nil {
+		t.Fatalf("Failed to add programs: %v", err)
+	}
+	if err := c.AddMetadata(context.Background(), &metadata, &wg, app); err != nil {
+		t.Fatalf("Failed to add metadata: %v", err)
+	}
+	wg.Wait()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Time zone database not available: %v", err)
+	}
+
+	airDateTime := time.Date(2024, 7, 1, 18, 0, 0, 0, time.UTC)
+	movie := G2GCache{ProgramID: "MV000000010000", AirDateTime: airDateTime, Duration: 5400}
+	episode := G2GCache{ProgramID: "EP012345670042", AirDateTime: airDateTime, Duration: 1800}
+
+	cases := []struct {
+		name     string
+		schedule G2GCache
+		setup    func(app *App, g *XMLTVGenerator)
+		loc      *time.Location
+		want     []string
+	}{
+		{name: "time zone", schedule: movie, loc: berlin,
+			want: []string{`start="20240701200000 +0200"`, `stop="20240701213000 +0200"`}},
+		{name: "star rating", schedule: movie,
+			want: []string{`<star-rating system="Gracenote"><value>3.5/4</value></star-rating>`}},
+		{name: "keywords", schedule: movie,
+			setup: func(app *App, g *XMLTVGenerator) { app.Config.Options.Keywords = true },
+			want:  []string{`<keyword lang="en">Suspenseful</keyword>`}},
+		{name: "season premiere", schedule: G2GCache{ProgramID: "EP012345670042", AirDateTime: airDateTime, PremiereFinale: "Season Premiere"},
+			want: []string{`<premiere lang="en">Season Premiere</premiere>`}},
+		{name: "series finale", schedule: G2GCache{ProgramID: "EP012345670042", AirDateTime: airDateTime, PremiereFinale: "Series Finale"},
+			want: []string{`<last-chance lang="en">Series Finale</last-chance>`}},
+		{name: "length and country", schedule: movie,
+			want: []string{`<length units="minutes">90</length>`, `<country>USA</country>`}},
+		{name: "subtitles", schedule: G2GCache{ProgramID: "EP012345670042", AirDateTime: airDateTime, AudioProperties: []string{"cc", "subtitled"}},
+			want: []string{`<subtitles type="teletext">`, `<subtitles type="onscreen">`}},
+		{name: "live mark", schedule: G2GCache{ProgramID: "MV000000010000", AirDateTime: airDateTime, LiveTapeDelay: "Live"},
+			setup: func(app *App, g *XMLTVGenerator) { g.marks = titleMarks{Live: "[Live]", Placement: "prefix"} },
+			want:  []string{`<title lang="en">[Live] Movie</title>`, `<live></live>`}},
+		{name: "new mark as sub-title", schedule: G2GCache{ProgramID: "MV000000010000", AirDateTime: airDateTime, New: true},
+			setup: func(app *App, g *XMLTVGenerator) { g.marks = titleMarks{New: " New", Placement: "sub-title"} },
+			want:  []string{`<sub-title lang="en">New</sub-title>`, `<new></new>`}},
+		{name: "episode artwork", schedule: episode,
+			setup: func(app *App, g *XMLTVGenerator) {
+				app.Config.Options.Hostname = "localhost:8080"
+				app.Config.Options.ArtworkPolicies = defaultArtworkPolicies()
+			},
+			want: []string{`<icon src="http://localhost:8080/images/f.jpg"`, `<icon src="http://localhost:8080/images/h.jpg"`}},
+		{name: "content advisory as rating", schedule: movie,
+			setup: func(app *App, g *XMLTVGenerator) { app.Config.Options.Rating.ContentAdvisory = "rating" },
+			want:  []string{`<rating system="advisory"><value>Violence</value></rating>`}},
+		{name: "content advisory in the description", schedule: movie,
+			setup: func(app *App, g *XMLTVGenerator) { app.Config.Options.Rating.ContentAdvisory = "description" },
+			want:  []string{"A heist.&#xA;Content advisory: Violence</desc>"}},
+		{name: "previously shown", schedule: movie,
+			want: []string{`<previously-shown start="2004-05-01"></previously-shown>`}},
+		{name: "broadcast flags", schedule: G2GCache{ProgramID: "MV000000010000", AirDateTime: airDateTime, LiveTapeDelay: "Live", New: true},
+			setup: func(app *App, g *XMLTVGenerator) {
+				app.Config.Options.BroadcastFlags = map[string][]string{"live-new": {"new"}}
+			},
+			want: []string{`<new></new>`}},
+	}
+	for _, tc := range cases {
+		app.Config = config{}
+		g := &XMLTVGenerator{app: app}
+		if tc.setup != nil {
+			tc.setup(app, g)
+		}
+		loc := tc.loc
+		if loc == nil {
+			loc = time.UTC
+		}
+
+		program, err := g.createProgram(G2GCache{StationID: "10001"}, tc.schedule, "USA", "en", loc)
+		if err != nil {
+			t.Fatalf("%s: createProgram() failed: %v", tc.name, err)
+		}
+		data, err := xml.Marshal(program)
+		if err != nil {
+			t.Fatalf("%s: failed to encode programme: %v", tc.name, err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s: programme %s, want %s", tc.name, data, want)
+			}
+		}
+	}
+
+	// Flags the option leaves out are not written
+	app.Config = config{}
+	app.Config.Options.BroadcastFlags = map[string][]string{"live-new": {"new"}}
+	g := &XMLTVGenerator{app: app}
+	program, _ := g.createProgram(G2GCache{StationID: "10001"}, G2GCache{ProgramID: "MV000000010000", AirDateTime: airDateTime, LiveTapeDelay: "Live", New: true}, "USA", "en", time.UTC)
+	if program.Live != nil || program.PreviouslyShown != nil {
+		t.Errorf("createProgram() = %+v, want only the new flag", program)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxReportedProblems is the number of problems of an XMLTV file which are logged one by one
+const maxReportedProblems = 20
+
+// ErrInvalidXMLTV is returned for an XMLTV file with problems if the XMLTV validation is strict
+var ErrInvalidXMLTV = errors.New("XMLTV file is invalid")
+
+// xmltvSpan is the time of a programme of the validated file
+type xmltvSpan struct {
+	start, stop time.Time
+	title       string
+}
+
+// checkXMLTV validates the written XMLTV file filename by the validate XMLTV option.
+// Problems are logged, a strict validation returns ErrInvalidXMLTV for them.
+func (app *App) checkXMLTV(filename string) error {
+	mode := app.Config.Options.ValidateXMLTV
+	if mode == "" || mode == "none" {
+		return nil
+	}
+	if app.Config.Options.CompressXMLTV == "only" {
+		filename += ".gz"
+	}
+
+	problems, err := validateXMLTVFile(filename)
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		app.Logger.WithField("file", filename).Info("Validated XMLTV file")
+		return nil
+	}
+
+	for i, problem := range problems {
+		if i == maxReportedProblems {
+			break
+		}
+		app.Logger.WithField("file", filename).Warn(problem)
+	}
+	app.Logger.WithFields(logrus.Fields{
+		"file":     filename,
+		"problems": len(problems),
+	}).Warn("XMLTV file has problems")
+
+	if mode == "strict" {
+		return errors.Wrapf(ErrInvalidXMLTV, "%s has %d problems", filename, len(problems))
+	}
+	return nil
+}
+
+// validateXMLTVFile validates the XMLTV file filename, files ending with .gz are gzip compressed
+func validateXMLTVFile(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open XMLTV file")
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(filename, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress XMLTV file")
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	return validateXMLTV(r)
+}
+
+// validateXMLTV checks the structure of an XMLTV document and returns its problems:
+// duplicate channels, programmes of unknown channels, invalid times and overlapping programmes of a channel.
+// The document is decoded element by element, only the times of the programmes are kept.
+func validateXMLTV(r io.Reader) (problems []string, err error) {
+	channels := make(map[string]bool)
+	programmes := make(map[string][]xmltvSpan)
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse XMLTV file")
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch element.Name.Local {
+		case "channel":
+			var channel struct {
+				ID string `xml:"id,attr"`
+			}
+			if err := decoder.DecodeElement(&channel, &element); err != nil {
+				return nil, errors.Wrap(err, "failed to parse channel")
+			}
+			if channels[channel.ID] {
+				problems = append(problems, fmt.Sprintf("channel %s: duplicate channel ID", channel.ID))
+			}
+			channels[channel.ID] = true
+
+		case "programme":
+			var programme struct {
+				Channel string  `xml:"channel,attr"`
+				Start   string  `xml:"start,attr"`
+				Stop    string  `xml:"stop,attr"`
+				Title   []Title `xml:"title"`
+			}
+			if err := decoder.DecodeElement(&programme, &element); err != nil {
+				return nil, errors.Wrap(err, "failed to parse programme")
+			}
+
+			span := xmltvSpan{}
+			if len(programme.Title) != 0 {
+				span.title = programme.Title[0].Value
+			}
+			name := fmt.Sprintf("programme %q of channel %s at %s", span.title, programme.Channel, programme.Start)
+
+			if !channels[programme.Channel] {
+				problems = append(problems, name+": channel is missing")
+			}
+			if span.start, err = time.Parse(xmltvTimeLayout, programme.Start); err != nil {
+				problems = append(problems, name+": invalid start time")
+				continue
+			}
+			if span.stop, err = time.Parse(xmltvTimeLayout, programme.Stop); err != nil {
+				problems = append(problems, name+": invalid stop time")
+				continue
+			}
+			if !span.stop.After(span.start) {
+				problems = append(problems, name+": stop time "+programme.Stop+" is not after the start time")
+				continue
+			}
+			programmes[programme.Channel] = append(programmes[programme.Channel], span)
+		}
+	}
+
+	for _, channel := range sortedKeys(programmes) {
+		spans := programmes[channel]
+		sort.SliceStable(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+		for i := 1; i < len(spans); i++ {
+			if prev := spans[i-1]; spans[i].start.Before(prev.stop) {
+				problems = append(problems, fmt.Sprintf("programme %q of channel %s at %s: overlaps %q until %s",
+					spans[i].title, channel, spans[i].start.Format(xmltvTimeLayout), prev.title, prev.stop.Format(xmltvTimeLayout)))
+			}
+		}
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateXMLTV(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="KABC"><display-name>KABC</display-name></channel>
+  <channel id="KABC"><display-name>KABC</display-name></channel>
+  <programme channel="KABC" start="20300101200000 +0000" stop="20300101210000 +0000"><title lang="en">News</title></programme>
+  <programme channel="KABC" start="20300101203000 +0000" stop="20300101213000 +0000"><title lang="en">Talk</title></programme>
+  <programme channel="KABC" start="20300101220000 +0000" stop="20300101220000 +0000"><title lang="en">Empty</title></programme>
+  <programme channel="KCBS" start="20300101200000 +0000" stop="20300101210000 +0000"><title lang="en">Movie</title></programme>
+</tv>`
+
+	problems, err := validateXMLTV(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("validateXMLTV() failed: %v", err)
+	}
+
+	want := []string{"duplicate channel ID", "is not after the start time", "KCBS at 20300101200000 +0000: channel is missing", `"Talk" of channel KABC at 20300101203000 +0000: overlaps "News"`}
+	if len(problems) != len(want) {
+		t.Fatalf("validateXMLTV() = %q, want %d problems", problems, len(want))
+	}
+	for i, problem := range problems {
+		if !strings.Contains(problem, want[i]) {
+			t.Errorf("problem %d = %q, want %q", i, problem, want[i])
+		}
+	}
+
+	if _, err := validateXMLTV(strings.NewReader("<tv><channel>")); err == nil {
+		t.Error("validateXMLTV() of a truncated file succeeded")
+	}
+}